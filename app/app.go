@@ -2,7 +2,7 @@
 package app
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -13,54 +13,75 @@ import (
 	"github.com/sjmudd/anonymiser"
 	"github.com/sjmudd/ps-top/config"
 	"github.com/sjmudd/ps-top/connector"
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/display"
 	"github.com/sjmudd/ps-top/event"
+	"github.com/sjmudd/ps-top/export"
 	"github.com/sjmudd/ps-top/global"
 	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/metrics"
 	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/pstable"
-	"github.com/sjmudd/ps-top/setupinstruments"
+	"github.com/sjmudd/ps-top/rc"
+	"github.com/sjmudd/ps-top/snapshot"
 	"github.com/sjmudd/ps-top/utils"
 	"github.com/sjmudd/ps-top/view"
 	"github.com/sjmudd/ps-top/wait"
-	"github.com/sjmudd/ps-top/wrapper/fileinfolatency"
-	"github.com/sjmudd/ps-top/wrapper/memoryusage"
-	"github.com/sjmudd/ps-top/wrapper/mutexlatency"
-	"github.com/sjmudd/ps-top/wrapper/stageslatency"
-	"github.com/sjmudd/ps-top/wrapper/tableiolatency"
-	"github.com/sjmudd/ps-top/wrapper/tableioops"
-	"github.com/sjmudd/ps-top/wrapper/tablelocklatency"
-	"github.com/sjmudd/ps-top/wrapper/userlatency"
+	fleetwrapper "github.com/sjmudd/ps-top/wrapper/fleet"
 )
 
 // Settings holds the application configuration settingss from the command line.
 type Settings struct {
-	Anonymise bool                   // Do we want to anonymise data shown?
-	Filter    *filter.DatabaseFilter // optional names of databases to filter on
-	Interval  int                    // default interval to poll information
-	ViewName  string                 // name of the view to start with
+	Anonymise    bool                   // Do we want to anonymise data shown?
+	Batch        bool                   // run non-interactively writing to stdout?
+	Count        int                    // number of iterations to run in batch mode (0 = no limit)
+	Format       export.Format          // format to use in batch mode
+	Filter       *filter.DatabaseFilter // optional names of databases to filter on
+	Interval     int                    // default interval to poll information
+	QueryTimeout time.Duration          // how long a query may take before it is abandoned, 0 for no limit
+	ViewName     string                 // name of the view to start with
+	Compare      bool                   // compare the views of two servers rather than showing the fleet
+	ViewOrder    []string               // names of the views in the order they are shown, all views if empty
+	Views        []rc.View              // views defined by the user
+
+	ListenMetrics string // address to serve Prometheus metrics on (headless mode)
+	Record        string // file to record the collected data to
+	Replay        string // file to replay previously recorded data from
 }
 
 // App holds the data needed by an application
 type App struct {
-	config           *config.Config                     // some config needed by the display
-	db               *sql.DB                            // connection to MySQL
-	display          *display.Display                   // display displays the information to the screen
-	finished         bool                               // has the app finished?
-	sigChan          chan os.Signal                     // signal handler channel
-	waitHandler      wait.Handler                       // for handling waits
-	help             bool                               // show help (during runtime)
-	fileinfolatency  pstable.Tabler                     // file i/o latency information
-	tableiolatency   pstable.Tabler                     // table i/o latency information
-	tableioops       pstable.Tabler                     // table i/o operations information
-	tablelocklatency pstable.Tabler                     // table lock information
-	mutexlatency     pstable.Tabler                     // mutex latency information
-	stageslatency    pstable.Tabler                     // stages latency information
-	memory           pstable.Tabler                     // memory usage information
-	users            pstable.Tabler                     // user information
-	currentTabler    pstable.Tabler                     // current data being collected
-	currentView      view.View                          // holds the view we are currently using
-	setupInstruments *setupinstruments.SetupInstruments // for setting up and restoring performance_schema configuration.
+	*server                             // server being shown
+	servers       []*server             // all servers (fleet mode only)
+	fleet         pstable.Tabler        // totals of each server (fleet mode only)
+	fleetShown    bool                  // is the fleet being shown rather than a single server?
+	compareWith   *server               // server compared with the server shown (compare mode only)
+	comparing     bool                  // are two servers or time windows being compared?
+	display       *display.Display      // display displays the information to the screen
+	batch         *display.BatchDisplay // batch displays the information to stdout (batch mode only)
+	count         int                   // number of iterations to run in batch mode
+	exporter      *export.Writer        // exporter writes the raw rows to stdout (batch mode only)
+	listenMetrics string                // address to serve metrics on (metrics mode only)
+	metrics       *metrics.Handler      // metrics serves the collected data (metrics mode only)
+	recorder      *snapshot.Writer      // recorder records the collected data (record mode only)
+	replay        *snapshot.Reader      // replay provides previously recorded data (replay mode only)
+	pending       *snapshot.Snapshot    // next snapshot to show (replay mode only)
+	current       *snapshot.Snapshot    // snapshot being shown (replay mode only)
+	status        *global.Status        // status set from the recording (replay mode only)
+	finished      bool                  // has the app finished?
+	sigChan       chan os.Signal        // signal handler channel
+	waitHandler   wait.Handler          // for handling waits
+	help          bool                  // show help (during runtime)
+	timings       bool                  // show how long collecting each model takes (during runtime)
+	prompt        string                // text entered at the filter prompt
+	detail        *detail               // detail of the selected row (nil if not shown)
+	currentTabler pstable.Tabler        // current data being collected
+	currentView   view.View             // holds the view we are currently using
+	background    bool                  // collect off the goroutine handling input (interactive mode only)
+	collecting    time.Time             // when collecting in the background started, zero if not collecting
+	collected     chan func()           // receives what to do once collecting in the background has finished
+	cancel        context.CancelFunc    // stops collecting in the background
+	queued        []event.Event         // input handled once collecting in the background has finished
 }
 
 var (
@@ -85,152 +106,465 @@ func performanceSchemaEnabled(variables *global.Variables) error {
 }
 
 // NewApp sets up the application given various parameters returning a possible if initialisation fails.
+// If several hosts are given the totals of each are shown in the fleet view.
 func NewApp(
 	connectorFlags connector.Config,
 	settings Settings) (*App, error) {
 	log.Println("app.NewApp()")
+
+	hosts, err := connectorFlags.Targets()
+	if err != nil {
+		return nil, err
+	}
+	if settings.Compare && (len(hosts) != 2 || settings.Replay != "") {
+		return nil, errors.New("--compare needs two hosts to compare")
+	}
+	if len(hosts) > 1 && settings.Replay == "" {
+		return NewFleetAppFromDataSources(connectTargets(connectorFlags, hosts), settings)
+	}
+
+	var db datasource.DataSource
+	if settings.Replay == "" {
+		db = reconnecting(connector.NewConnector(connectorFlags))
+	}
+
+	return NewAppFromDataSource(db, settings)
+}
+
+// NewAppFromDataSource sets up the application to collect from the
+// given data source. db is not used when replaying recorded data.
+func NewAppFromDataSource(db datasource.DataSource, settings Settings) (*App, error) {
+	log.Println("app.NewAppFromDataSource()")
 	app := new(App)
 
 	anonymiser.Enable(settings.Anonymise)
-	app.db = connector.NewConnector(connectorFlags).DB
+	if settings.Filter == nil {
+		settings.Filter = filter.NewDatabaseFilter("")
+	}
 
-	status := global.NewStatus(app.db)
-	variables := global.NewVariables(app.db)
+	var (
+		status    *global.Status
+		variables *global.Variables
+	)
+	if settings.Replay != "" {
+		// replaying recorded data so no connection to MySQL is needed
+		replay, err := openReplay(settings.Replay)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open recording %q: %w", settings.Replay, err)
+		}
+		app.replay = replay
+		app.status = global.NewStaticStatus()
+		status = app.status
+		variables = global.NewVariablesFromMap(replay.Header().Variables)
+	} else {
+		db = withQueryTimeout(db, settings.QueryTimeout)
+		status = global.NewStatus(db)
+		var err error
+		if variables, err = global.NewVariables(db); err != nil {
+			return nil, err
+		}
 
-	// Prior to setting up screen check that performance_schema is enabled.
-	// On MariaDB this is not the default setting so it will confuse people.
-	if err := performanceSchemaEnabled(variables); err != nil {
-		return nil, err
+		// Prior to setting up screen check that performance_schema is enabled.
+		// On MariaDB this is not the default setting so it will confuse people.
+		if err := performanceSchemaEnabled(variables); err != nil {
+			return nil, err
+		}
 	}
 
-	app.config = config.NewConfig(status, variables, settings.Filter, true)
-	app.display = display.NewDisplay(app.config)
-	app.finished = false
-	app.help = false
-	app.display.Clear()
+	// metrics are exported as counters so must not be relative
+	cfg := config.NewConfig(status, variables, settings.Filter, settings.ListenMetrics == "")
+	app.setupOutput(cfg, settings)
 
-	app.setupInstruments = setupinstruments.NewSetupInstruments(app.db)
-	app.setupInstruments.EnableMonitoring()
-	app.waitHandler.SetWaitInterval(time.Second * time.Duration(settings.Interval))
+	var err error
+	if app.server, err = newServer("", cfg, db, settings.Views); err != nil {
+		app.Cleanup()
+		return nil, err
+	}
+
+	options := viewOptions(settings)
+	if app.replay != nil {
+		if err := app.startReplay(); err != nil {
+			app.Cleanup()
+			return nil, fmt.Errorf("failed to read recording %q: %w", settings.Replay, err)
+		}
+		app.currentView, err = view.Setup(settings.ViewName, options) // recorded views can not be validated
+	} else {
+		if settings.Record != "" {
+			recorder, err := newRecorder(settings.Record, variables)
+			if err != nil {
+				app.Cleanup()
+				return nil, fmt.Errorf("failed to record to %q: %w", settings.Record, err)
+			}
+			app.recorder = recorder
+		}
+		app.resetDBStatistics(context.Background())
 
-	// setup to their initial types/values
-	log.Println("app.NewApp() Setup models")
-	app.fileinfolatency = fileinfolatency.NewFileSummaryByInstance(app.config, app.db)
-	temptableiolatency := tableiolatency.NewTableIoLatency(app.config, app.db) // shared backend/metrics
-	app.tableiolatency = temptableiolatency
-	app.tableioops = tableioops.NewTableIoOps(temptableiolatency)
-	app.tablelocklatency = tablelocklatency.NewTableLockLatency(app.config, app.db)
-	app.mutexlatency = mutexlatency.NewMutexLatency(app.config, app.db)
-	app.stageslatency = stageslatency.NewStagesLatency(app.config, app.db)
-	app.memory = memoryusage.NewMemoryUsage(app.config, app.db)
-	app.users = userlatency.NewUserLatency(app.config, app.db)
-	log.Println("app.NewApp() Finished initialising models")
-
-	app.resetDBStatistics()
-
-	app.currentView = view.SetupAndValidate(settings.ViewName, app.db) // if empty will use the default
+		app.currentView, err = view.SetupAndValidate(settings.ViewName, app.db, options) // if empty will use the default
+	}
+	if err != nil {
+		app.Cleanup()
+		return nil, err
+	}
 	app.UpdateCurrentTabler()
 
 	log.Println("app.NewApp() finishes")
 	return app, nil
 }
 
+// setupOutput sets up where the views are shown using cfg for the top line
+func (app *App) setupOutput(cfg display.Config, settings Settings) {
+	switch {
+	case settings.ListenMetrics != "":
+		app.listenMetrics = settings.ListenMetrics
+		app.metrics = metrics.NewHandler()
+	case settings.Batch:
+		if settings.Format == export.FormatText {
+			app.batch = display.NewBatchDisplay(cfg, os.Stdout)
+		} else {
+			app.exporter = export.NewWriter(os.Stdout, settings.Format)
+		}
+		app.count = settings.Count
+	default:
+		app.display = display.NewDisplay(cfg)
+		app.display.Clear()
+		app.background = true
+	}
+	app.finished = false
+	app.help = false
+	app.timings = false
+
+	app.waitHandler.SetWaitInterval(time.Second * time.Duration(settings.Interval))
+}
+
+// viewOptions returns the order of the views and the views defined by the user
+func viewOptions(settings Settings) view.Options {
+	options := view.Options{Order: settings.ViewOrder}
+	for _, v := range settings.Views {
+		options.Custom = append(options.Custom, view.Custom{Name: v.Name, Query: v.Query})
+	}
+	return options
+}
+
 // UpdateCurrentTabler updates the current tabler to use
 func (app *App) UpdateCurrentTabler() {
-	switch app.currentView.Get() {
-	case view.ViewLatency:
-		app.currentTabler = app.tableiolatency
-	case view.ViewOps:
-		app.currentTabler = app.tableioops
-	case view.ViewIO:
-		app.currentTabler = app.fileinfolatency
-	case view.ViewLocks:
-		app.currentTabler = app.tablelocklatency
-	case view.ViewUsers:
-		app.currentTabler = app.users
-	case view.ViewMutex:
-		app.currentTabler = app.mutexlatency
-	case view.ViewStages:
-		app.currentTabler = app.stageslatency
-	case view.ViewMemory:
-		app.currentTabler = app.memory
-	}
-}
-
-// CollectAll collects all the stats together in one go
-func (app *App) collectAll() {
+	if app.fleetShown {
+		app.currentTabler = app.fleet
+		return
+	}
+	app.currentTabler = app.server.tabler(app.currentView)
+	if app.compareWith == nil {
+		app.comparing = false // time windows are compared until the view changes
+		return
+	}
+	if app.comparing {
+		if c := app.compareServers(); c != nil {
+			app.currentTabler = c
+		}
+	}
+}
+
+// collectAll collects all the stats of every server together in one go
+func (app *App) collectAll(ctx context.Context) {
 	log.Println("app.collectAll() start")
-	app.fileinfolatency.Collect()
-	app.tablelocklatency.Collect()
-	app.tableiolatency.Collect()
-	app.users.Collect()
-	app.stageslatency.Collect()
-	app.mutexlatency.Collect()
-	app.memory.Collect()
+	if app.servers != nil {
+		app.eachServer(func(s *server) { s.collect(ctx, s.collectAll) })
+	} else {
+		app.server.collect(ctx, app.server.collectAll)
+	}
+	if app.recorder != nil && app.server.lost == nil {
+		app.record()
+	}
 	log.Println("app.collectAll() finished")
 }
 
 // resetDBStatistics does a fresh collection of data and then updates the initial values based on that.
-func (app *App) resetDBStatistics() {
+func (app *App) resetDBStatistics(ctx context.Context) {
 	log.Println("app.resetDBStatistcs()")
-	app.collectAll()
+	if app.replay == nil {
+		app.collectAll(ctx)
+	}
 	app.resetStatistics()
 }
 
 func (app *App) resetStatistics() {
 	start := time.Now()
-	app.fileinfolatency.ResetStatistics()
-	app.tablelocklatency.ResetStatistics()
-	app.tableiolatency.ResetStatistics()
-	app.users.ResetStatistics()
-	app.stageslatency.ResetStatistics()
-	app.mutexlatency.ResetStatistics()
-	app.memory.ResetStatistics()
+	if app.servers != nil {
+		for _, s := range app.servers {
+			if s.err == nil {
+				s.resetStatistics()
+			}
+		}
+		if app.fleet != nil {
+			app.fleet.ResetStatistics()
+		}
+	} else {
+		app.server.resetStatistics()
+	}
+	if app.comparing && app.currentTabler != nil {
+		app.currentTabler.ResetStatistics() // recalculate the rows compared
+	}
 
 	log.Println("app.resetStatistics() took", time.Duration(time.Since(start)).String())
 }
 
 // Collect the data we are looking at.
-func (app *App) Collect() {
+func (app *App) Collect(ctx context.Context) {
 	log.Println("app.Collect()")
 	start := time.Now()
 
-	app.currentTabler.Collect()
+	switch {
+	case app.replay != nil:
+		app.replayNext()
+	case app.recorder != nil:
+		app.collectAll(ctx) // record all views so they can be replayed
+	default:
+		app.collectCurrent(ctx)
+	}
+	if app.detail != nil {
+		app.updateDetail(ctx, app.detailCollects())
+	}
 	app.waitHandler.CollectedNow()
 	log.Println("app.Collect() took", time.Duration(time.Since(start)).String())
 }
 
+// collectCurrent collects the current view. In the fleet view and when
+// comparing servers each server handles its own errors.
+func (app *App) collectCurrent(ctx context.Context) {
+	if app.fleetShown || (app.comparing && app.compareWith != nil) {
+		if err := app.currentTabler.Collect(ctx); err != nil {
+			log.Println("app.collectCurrent():", err)
+		}
+		return
+	}
+	name, tabler := app.currentView.Name(), app.currentTabler
+	app.server.collect(ctx, func(ctx context.Context) error {
+		return app.server.collectModel(ctx, name, tabler)
+	})
+}
+
+// connectionStatus returns the status of the connection to the servers
+// shown, which is empty unless the connection has been lost or the
+// last query timed out
+func (app *App) connectionStatus() string {
+	if app.fleetShown {
+		return "" // shown for each server
+	}
+	if status := app.server.status(); status != "" {
+		return status
+	}
+	if app.comparing && app.compareWith != nil {
+		if status := app.compareWith.status(); status != "" {
+			return anonymiser.Anonymise("hostname", app.compareWith.name) + " " + status
+		}
+	}
+	return ""
+}
+
 // Display shows the output appropriate to the corresponding view and device
 func (app *App) Display() {
-	if app.help {
+	if app.exporter != nil {
+		app.exportRows()
+		return
+	}
+	if app.batch != nil {
+		app.batch.SetStatus(app.connectionStatus())
+		app.batch.Display(app.currentTabler)
+		return
+	}
+	app.display.SetStatus(app.connectionStatus())
+	switch {
+	case app.help:
 		app.display.Display(display.Help)
-	} else {
+	case app.timings:
+		app.display.Display(app.newTimingsView())
+	case app.detail != nil:
+		app.display.ShowDetail(app.detail)
+	default:
 		app.display.Display(app.currentTabler)
 	}
 }
 
+// exportRows writes the raw rows of the current view in the requested format
+func (app *App) exportRows() {
+	name := app.currentView.Name()
+	if app.fleetShown {
+		name = "fleet"
+	}
+	exporter, ok := app.currentTabler.(export.Exporter)
+	if !ok {
+		log.Printf("app.exportRows(): view %s can not be exported", name)
+		return
+	}
+	if err := app.exporter.Write(name, app.currentTabler.LastCollectTime(), exporter.Rows()); err != nil {
+		log.Fatalf("Failed to export view %s: %v", name, err)
+	}
+}
+
+// sort changes how the current view is sorted, if it can be sorted
+func (app *App) sort(eventType event.Type) {
+	sortable, ok := app.currentTabler.(pstable.Sortable)
+	if !ok {
+		return
+	}
+	if eventType == event.EventSortNext {
+		sortable.SortNext()
+	} else {
+		sortable.SortReverse()
+	}
+	app.Display()
+}
+
+// nextClass shows the next class of rows of the current view, if it has classes
+func (app *App) nextClass() {
+	classifier, ok := app.currentTabler.(pstable.Classifier)
+	if !ok {
+		return
+	}
+	classifier.NextClass()
+	app.display.ClearSelection()
+	// the rows of the new class may not have been collected yet
+	if app.replay == nil {
+		app.collectThen(app.collectCurrent, app.Display)
+		return
+	}
+	if app.current != nil {
+		app.restoreSnapshot(app.current)
+	}
+	app.Display()
+}
+
+// toggleTree switches the current view between showing a tree and a
+// list, if it can show a tree
+func (app *App) toggleTree() {
+	tree, ok := app.currentTabler.(pstable.Tree)
+	if !ok {
+		return
+	}
+	tree.ToggleTree()
+	app.display.ClearSelection()
+	app.Display()
+}
+
+// toggleTimers switches the current view between showing how the
+// latency is split and the minimum, average and maximum latency, if
+// it can show both
+func (app *App) toggleTimers() {
+	toggler, ok := app.currentTabler.(pstable.TimerToggler)
+	if !ok {
+		return
+	}
+	toggler.ToggleTimers()
+	app.display.ClearSelection()
+	app.Display()
+}
+
+// setNameFilter changes the filter on row names and shows the
+// current view again with the new filter applied
+func (app *App) setNameFilter(pattern string) {
+	app.config.DatabaseFilter().SetNameFilter(pattern)
+	app.display.SetFilter(pattern)
+	app.refresh()
+}
+
+// toggleWantRelative switches between showing the values since
+// resetting statistics and those since performance_schema started
+// collecting them, on all servers
+func (app *App) toggleWantRelative() {
+	want := !app.config.WantRelativeStats()
+	if app.servers == nil {
+		app.config.SetWantRelativeStats(want)
+		return
+	}
+	for _, s := range app.servers {
+		if s.err == nil {
+			s.config.SetWantRelativeStats(want)
+		}
+	}
+}
+
+// refresh shows the current view again, e.g. after changing the
+// filter. The rows already collected are filtered again, so the
+// database is not queried and nothing more is recorded.
+func (app *App) refresh() {
+	app.refilter()
+	app.Display()
+}
+
+// refilter applies the filter again to the rows last collected by each
+// server and recalculates the fleet totals or the rows compared. The
+// current view is filtered again last so that its order applies to rows
+// it shares with another view.
+func (app *App) refilter() {
+	if app.servers == nil {
+		app.server.refilter()
+	}
+	for _, s := range app.servers {
+		if s.err == nil {
+			s.refilter()
+		}
+	}
+	if f, ok := app.fleet.(*fleetwrapper.Wrapper); ok {
+		f.Update(app.fleetRows())
+	}
+	if refilterer, ok := app.currentTabler.(pstable.Refilterer); ok {
+		refilterer.Refilter()
+	}
+}
+
 // change to the previous display mode
 func (app *App) displayPrevious() {
+	if app.fleetShown {
+		return
+	}
 	app.currentView.SetPrev()
-	app.UpdateCurrentTabler()
-	app.display.Clear()
-	app.Display()
+	app.viewChanged()
 }
 
 // change to the next display mode
 func (app *App) displayNext() {
+	if app.fleetShown {
+		return
+	}
 	app.currentView.SetNext()
+	app.viewChanged()
+}
+
+// change to the given display mode in the order they are shown, starting at 1
+func (app *App) displayNumber(number int) {
+	if !app.fleetShown && app.currentView.SetNumber(number) {
+		app.viewChanged()
+	}
+}
+
+// viewChanged shows the view which has just been chosen
+func (app *App) viewChanged() {
 	app.UpdateCurrentTabler()
+	app.display.ClearSelection()
+	app.detail = nil
 	app.display.Clear()
 	app.Display()
 }
 
 // Cleanup prepares the application prior to shutting down
 func (app *App) Cleanup() {
-	app.display.Fini()
-	if app.db != nil {
-		app.setupInstruments.RestoreConfiguration()
-		_ = app.db.Close()
+	if app.display != nil {
+		app.display.Fini()
+	}
+	switch {
+	case app.servers != nil:
+		for _, s := range app.servers {
+			s.cleanup()
+		}
+	case app.server != nil:
+		app.server.cleanup()
+	}
+	if app.recorder != nil {
+		if err := app.recorder.Close(); err != nil {
+			log.Println("App.Cleanup: failed to close recording:", err)
+		}
+	}
+	if app.replay != nil {
+		_ = app.replay.Close()
 	}
 	log.Println("App.Cleanup completed")
 }
@@ -244,48 +578,145 @@ func (app *App) Run() {
 	app.sigChan = make(chan os.Signal, 10) // 10 entries
 	signal.Notify(app.sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	if app.metrics != nil {
+		app.runMetrics()
+		return
+	}
+	if app.batch != nil || app.exporter != nil {
+		app.runBatch()
+		return
+	}
+
 	eventChan := app.display.EventChan()
+	defer app.stopCollecting()
 
 	for !app.finished {
+		// wait for the next collection or, while collecting, update how long it is taking
+		var next, collecting <-chan time.Time
+		if app.collecting.IsZero() {
+			next = app.waitHandler.WaitUntilNextPeriod()
+		} else {
+			collecting = time.After(time.Second)
+		}
+
+		select {
+		case sig := <-app.sigChan:
+			log.Println("Caught signal: ", sig)
+			app.finished = true
+		case <-next:
+			app.collectThen(app.Collect, app.Display)
+		case done := <-app.collected:
+			app.finishCollecting(done)
+		case <-collecting:
+			app.showCollectingStatus()
+		case inputEvent := <-eventChan:
+			app.event(inputEvent)
+		}
+	}
+}
+
+// event handles input from the display. While collecting in the
+// background only input which does not use the data being collected is
+// handled, the rest is handled once collecting has finished.
+func (app *App) event(inputEvent event.Event) {
+	if !app.collecting.IsZero() && !handledWhileCollecting(inputEvent.Type) {
+		app.queued = append(app.queued, inputEvent)
+		app.showCollectingStatus()
+		return
+	}
+
+	switch inputEvent.Type {
+	case event.EventAnonymise:
+		anonymiser.Enable(!anonymiser.Enabled()) // toggle current behaviour
+	case event.EventFinished:
+		app.finished = true
+	case event.EventViewNext:
+		app.displayNext()
+	case event.EventViewPrev:
+		app.displayPrevious()
+	case event.EventViewNumber:
+		app.displayNumber(inputEvent.Number)
+	case event.EventDecreasePollTime:
+		if app.waitHandler.WaitInterval() > time.Second {
+			app.waitHandler.SetWaitInterval(app.waitHandler.WaitInterval() - time.Second)
+		}
+	case event.EventIncreasePollTime:
+		app.waitHandler.SetWaitInterval(app.waitHandler.WaitInterval() + time.Second)
+	case event.EventHelp:
+		app.help = !app.help
+		app.display.Clear()
+	case event.EventTimings:
+		app.timings = !app.timings
+		app.display.Clear()
+		app.Display()
+	case event.EventToggleWantRelative:
+		app.toggleWantRelative()
+		app.Display()
+	case event.EventResetStatistics:
+		app.collectThen(app.resetDBStatistics, app.Display)
+	case event.EventSortNext, event.EventSortReverse:
+		app.sort(inputEvent.Type)
+	case event.EventFilter:
+		app.prompt = app.config.DatabaseFilter().NameFilter()
+		app.display.SetPrompt(app.prompt)
+	case event.EventPromptRune:
+		app.prompt += string(inputEvent.Rune)
+		app.display.SetPrompt(app.prompt)
+	case event.EventPromptBackspace:
+		if runes := []rune(app.prompt); len(runes) > 0 {
+			app.prompt = string(runes[:len(runes)-1])
+		}
+		app.display.SetPrompt(app.prompt)
+	case event.EventPromptEnter:
+		app.setNameFilter(app.prompt)
+	case event.EventPromptCancel:
+		app.Display()
+	case event.EventCursorUp:
+		app.display.MoveCursor(-1)
+		app.Display()
+	case event.EventCursorDown:
+		app.display.MoveCursor(1)
+		app.Display()
+	case event.EventNextClass:
+		app.nextClass()
+	case event.EventToggleTree:
+		app.toggleTree()
+	case event.EventToggleTimers:
+		app.toggleTimers()
+	case event.EventDetail:
+		app.showDetail()
+	case event.EventDetailClose:
+		app.detail = nil
+		app.Display()
+	case event.EventFleet:
+		app.showFleet()
+	case event.EventCompare:
+		app.toggleCompare()
+	case event.EventResizeScreen:
+		width, height := inputEvent.Width, inputEvent.Height
+		app.display.Resize(width, height)
+		app.Display()
+	case event.EventError:
+		log.Fatalf("Quitting because of EventError error")
+	}
+}
+
+// runBatch collects and writes the current view to stdout once per
+// interval until the requested number of iterations has been shown.
+func (app *App) runBatch() {
+	log.Println("app.runBatch()")
+
+	// statistics were reset on startup so wait a full interval before the first output
+	app.waitHandler.CollectedNow()
+
+	for iteration := 0; !app.finished && (app.count == 0 || iteration < app.count); iteration++ {
 		select {
 		case sig := <-app.sigChan:
 			log.Println("Caught signal: ", sig)
 			app.finished = true
 		case <-app.waitHandler.WaitUntilNextPeriod():
-			app.Collect()
+			app.Collect(context.Background())
 			app.Display()
-		case inputEvent := <-eventChan:
-			switch inputEvent.Type {
-			case event.EventAnonymise:
-				anonymiser.Enable(!anonymiser.Enabled()) // toggle current behaviour
-			case event.EventFinished:
-				app.finished = true
-			case event.EventViewNext:
-				app.displayNext()
-			case event.EventViewPrev:
-				app.displayPrevious()
-			case event.EventDecreasePollTime:
-				if app.waitHandler.WaitInterval() > time.Second {
-					app.waitHandler.SetWaitInterval(app.waitHandler.WaitInterval() - time.Second)
-				}
-			case event.EventIncreasePollTime:
-				app.waitHandler.SetWaitInterval(app.waitHandler.WaitInterval() + time.Second)
-			case event.EventHelp:
-				app.help = !app.help
-				app.display.Clear()
-			case event.EventToggleWantRelative:
-				app.config.SetWantRelativeStats(!app.config.WantRelativeStats())
-				app.Display()
-			case event.EventResetStatistics:
-				app.resetDBStatistics()
-				app.Display()
-			case event.EventResizeScreen:
-				width, height := inputEvent.Width, inputEvent.Height
-				app.display.Resize(width, height)
-				app.Display()
-			case event.EventError:
-				log.Fatalf("Quitting because of EventError error")
-			}
 		}
 	}
 }
@@ -0,0 +1,989 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/display"
+	"github.com/sjmudd/ps-top/event"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/rc"
+	"github.com/sjmudd/ps-top/view"
+	comparewrapper "github.com/sjmudd/ps-top/wrapper/compare"
+	"github.com/sjmudd/ps-top/wrapper/waitevents"
+)
+
+// newFixture returns a data source with enough canned performance_schema
+// content for the application to start and collect every view
+func newFixture() *datasource.Fixture {
+	return datasource.NewFixture().
+		Add("LIMIT 1", []any{1}). // view access checks
+		Add("GLOBAL_VARIABLES",
+			[]any{"PERFORMANCE_SCHEMA", "ON"},
+			[]any{"HOSTNAME", "myhost.example.com"},
+			[]any{"VERSION", "8.0.36"},
+			[]any{"DATADIR", "/var/lib/mysql/"},
+		).
+		Add("GLOBAL_STATUS", []any{"3600"}).
+		Add("setup_instruments").
+		Add("setup_consumers WHERE NAME IN").
+		Add("setup_consumers WHERE NAME =", []any{"YES"}).
+		Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 10, 1000000000000)).
+		Add("file_summary_by_instance").
+		Add("table_lock_waits_summary_by_table").
+		Add("events_waits_summary_global_by_event_name").
+		Add("events_stages_summary_global_by_event_name").
+		Add("events_stages_current e LEFT JOIN").
+		Add("memory_summary_global_by_event_name").
+		Add("events_statements_summary_by_digest").
+		Add("events_statements_summary_by_user_by_event_name").
+		Add("events_waits_summary_by_user_by_event_name").
+		Add("FROM accounts").
+		Add("FROM threads").
+		Add("table_io_waits_summary_by_index_usage").
+		Add("metadata_locks").
+		Add("data_lock_waits").
+		Add("replication_connection_status").
+		Add("replication_applier_status_by_coordinator").
+		Add("replication_applier_status_by_worker").
+		Add("INFORMATION_SCHEMA.PROCESSLIST")
+}
+
+// tableIoRow returns a table_io_waits_summary_by_table row with all waits as
+// fetches, each taking the same time
+func tableIoRow(schema, table string, count, wait uint64) []any {
+	return []any{schema, table, count, wait, wait / count, wait / count, count, wait, 0, 0, count, wait, 0, 0, 0, 0, 0, 0}
+}
+
+// digestRow returns an events_statements_summary_by_digest row
+func digestRow(schema, digest, text string, count, wait uint64) []any {
+	return []any{schema, digest, text, count, wait, 10 * count, count, 0, 0}
+}
+
+// indexRow returns a table_io_waits_summary_by_index_usage row with all waits
+// as fetches, each taking the same time
+func indexRow(schema, table string, index any, count, wait uint64) []any {
+	var latency uint64
+	if count > 0 {
+		latency = wait / count
+	}
+	return []any{schema, table, index, count, wait, latency, latency, count, wait, 0, 0, 0, 0, 0, 0}
+}
+
+func TestNewAppFromDataSource(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Count:    1,
+		Filter:   filter.NewDatabaseFilter(""),
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	// relative values are shown so only the change since startup is seen
+	fixture.Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 30, 3000000000000))
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{"myhost / 8.0.36", "db1.t1", "2.00 s"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if len(fixture.Executed()) != 0 {
+		t.Errorf("unexpected statements executed: %q", fixture.Executed())
+	}
+}
+
+func TestNameFilter(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("table_io_waits_summary_by_table",
+		tableIoRow("db1", "t1", 10, 1000000000000),
+		tableIoRow("db2", "t2", 10, 3000000000000),
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	app.config.SetWantRelativeStats(false)
+	app.config.DatabaseFilter().SetNameFilter("^db1")
+	app.Collect(context.Background())
+
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+	app.Display()
+
+	output := buf.String()
+	if !strings.Contains(output, "db1.t1") || strings.Contains(output, "db2.t2") {
+		t.Errorf("expected only db1.t1 in output:\n%s", output)
+	}
+	// totals only include the rows shown
+	if !strings.Contains(output, "1.00 s 100.0%|100.0%                     |Totals") {
+		t.Errorf("expected totals of the filtered rows in output:\n%s", output)
+	}
+
+	// changing the filter filters the rows already collected again
+	// rather than collecting them
+	fixture.AddError("table_io_waits_summary_by_table", errors.New("unexpected query"))
+	app.config.DatabaseFilter().SetNameFilter("^db2")
+	buf.Reset()
+	app.refresh()
+
+	output = buf.String()
+	if !strings.Contains(output, "db2.t2") || strings.Contains(output, "db1.t1") {
+		t.Errorf("expected only db2.t2 in output after changing the filter:\n%s", output)
+	}
+}
+
+func TestStatementDigests(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("events_statements_summary_by_digest",
+		digestRow("db1", "abc", "SELECT * FROM `t1`", 10, 1000000000000),
+		digestRow("db2", "def", "SELECT * FROM `t2` WHERE `id` = ?", 5, 500000000000),
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "statement_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	fixture.Add("events_statements_summary_by_digest",
+		digestRow("db1", "abc", "SELECT * FROM `t1`", 30, 3000000000000),
+		digestRow("db2", "def", "SELECT * FROM `t2` WHERE `id` = ?", 5, 500000000000),
+	)
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{"Statement Digests", "2.00 s", "db1: SELECT * FROM `t1`"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	// the second statement has not run since startup
+	if strings.Contains(output, "`t2`") {
+		t.Errorf("unexpected idle statement in output:\n%s", output)
+	}
+}
+
+func TestIndexUsage(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("table_io_waits_summary_by_index_usage",
+		indexRow("db1", "t1", "PRIMARY", 10, 1000000000000),
+		indexRow("db1", "t1", "idx_a", 5, 500000000000),
+		indexRow("db1", "t1", "idx_b", 0, 0),
+		indexRow("db1", "t1", nil, 2, 200000000000),
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "index_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	fixture.Add("table_io_waits_summary_by_index_usage",
+		indexRow("db1", "t1", "PRIMARY", 30, 3000000000000),
+		indexRow("db1", "t1", "idx_a", 5, 500000000000),
+		indexRow("db1", "t1", "idx_b", 0, 0),
+		indexRow("db1", "t1", nil, 4, 400000000000),
+	)
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{"Index Latency", "2.00 s", "db1.t1: PRIMARY", "db1.t1: <full scan>"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "idx_") {
+		t.Errorf("unexpected unused index in output:\n%s", output)
+	}
+
+	// idx_a has not been used since startup, idx_b never
+	buf.Reset()
+	app.currentView.Set(view.ViewUnusedIndexes)
+	app.UpdateCurrentTabler()
+	app.Display()
+
+	output = buf.String()
+	for _, expected := range []string{"Unused Indexes", "idx_a", "idx_b"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "PRIMARY") || strings.Contains(output, "<full scan>") {
+		t.Errorf("unexpected index in unused indexes:\n%s", output)
+	}
+}
+
+func TestLockWaits(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("data_lock_waits", []any{"TABLE", "db1", "t1", "X,REC_NOT_GAP", 13, "app", 75, "X", 11, "batch"})
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "lock_waits",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{"Lock Waits", "1m 15s", "innodb", "batch", "db1.t1", "1 lock wait(s)"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestDetail(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("events_statements_summary_by_digest",
+		digestRow("db1", "abc", "SELECT * FROM `t1`", 10, 1000000000000),
+		digestRow("db1", "def", "SELECT * FROM `t2`", 10, 1000000000000),
+	)
+	fixture.Add("events_waits_current", []any{12, "app", "h1", "db1", "Query", 3, "updating", "UPDATE t1 SET a = 1"})
+	fixture.Add("events_stages_current e JOIN", []any{13, "app", "h2", "db1", "Query", 5, "Sending data", "SELECT * FROM t2"})
+	fixture.Add("setup_consumers WHERE NAME IN", []any{"events_waits_current", "NO"})
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	app.config.SetWantRelativeStats(false)
+
+	// the consumers are only enabled once the threads waiting are shown
+	consumerUpdates := func() int {
+		count := 0
+		for _, statement := range fixture.Executed() {
+			if strings.HasPrefix(statement, "UPDATE setup_consumers") {
+				count++
+			}
+		}
+		return count
+	}
+	if count := consumerUpdates(); count != 0 {
+		t.Errorf("expected no consumers to be enabled on startup, got %d updates", count)
+	}
+
+	tests := []struct {
+		view     view.Code
+		name     string
+		expected []string
+		hidden   []string
+	}{
+		{view.ViewLatency, "db1.t1", []string{"Statements using the table", "db1: SELECT * FROM `t1`", "Table lock latency", "(none)"}, []string{"`t2`"}},
+		{view.ViewMutex, "trx_mutex", []string{"Threads currently waiting", "UPDATE t1 SET a = 1"}, nil},
+		{view.ViewCurrentStages, "Sending data", []string{"Threads currently waiting", "SELECT * FROM t2"}, nil},
+		{view.ViewIO, "<redo_log>", nil, nil},
+	}
+	for _, test := range tests {
+		app.currentView.Set(test.view)
+		app.UpdateCurrentTabler()
+
+		d := app.newDetail(test.name)
+		if test.expected == nil {
+			if d != nil {
+				t.Errorf("newDetail(%q) in %s: expected no detail", test.name, test.view)
+			}
+			continue
+		}
+		d.update(context.Background(), true)
+		lines := strings.Join(d.RowContent(), "\n")
+		for _, expected := range test.expected {
+			if !strings.Contains(lines, expected) {
+				t.Errorf("newDetail(%q) in %s: expected %q in:\n%s", test.name, test.view, expected, lines)
+			}
+		}
+		for _, hidden := range test.hidden {
+			if strings.Contains(lines, hidden) {
+				t.Errorf("newDetail(%q) in %s: unexpected %q in:\n%s", test.name, test.view, hidden, lines)
+			}
+		}
+	}
+
+	if count := consumerUpdates(); count != 1 {
+		t.Errorf("expected the consumers to be enabled once, got %d updates", count)
+	}
+
+	// the threads waiting are not collected if the consumer is disabled
+	fixture.Add("setup_consumers WHERE NAME =", []any{"NO"})
+	app.currentView.Set(view.ViewCurrentStages)
+	app.UpdateCurrentTabler()
+	d := app.newDetail("Sending data")
+	d.update(context.Background(), true)
+	if lines := strings.Join(d.RowContent(), "\n"); !strings.Contains(lines, "events_stages_current is disabled in setup_consumers") {
+		t.Errorf("expected the consumer to be disabled in:\n%s", lines)
+	}
+}
+
+func TestWaitEvents(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("events_waits_summary_global_by_event_name",
+		[]any{"wait/synch/mutex/innodb/trx_mutex", 3000000000000, 30, 1000000000, 500000000000},
+		[]any{"wait/synch/mutex/sql/LOCK_open", 1000000000000, 10, 2000000000, 200000000000},
+		[]any{"wait/io/file/innodb/innodb_data_file", 4000000000000, 40, 1000000, 900000000000},
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "wait_events",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	app.config.SetWantRelativeStats(false)
+	app.Collect(context.Background())
+	waits := app.waits.(*waitevents.Wrapper)
+
+	tests := []struct {
+		change   func()
+		expected []string
+	}{
+		{func() {}, []string{"io/file/innodb/innodb_data_file", "synch/mutex/innodb/trx_mutex", "synch/mutex/sql/LOCK_open"}},
+		{waits.ToggleTree, []string{"+ io", "+ synch"}},
+		{func() { waits.Toggle(1) }, []string{"+ io", "- synch", "  + mutex"}},
+		{func() { waits.Toggle(2) }, []string{"+ io", "- synch", "  - mutex", "    + innodb", "    + sql"}},
+		{waits.NextClass, []string{"+ innodb", "+ sql"}},
+	}
+	for i, test := range tests {
+		test.change()
+		rows := waits.RowContent()
+		if len(rows) != len(test.expected) {
+			t.Errorf("test %d: expected %d rows, got:\n%s", i, len(test.expected), strings.Join(rows, "\n"))
+			continue
+		}
+		for j, expected := range test.expected {
+			if name := rows[j][strings.Index(rows[j], "|")+1:]; name != expected {
+				t.Errorf("test %d: row %d: got %q, expected %q", i, j, name, expected)
+			}
+		}
+	}
+
+	if got := waits.RowName(0); got != "wait/synch/mutex/innodb" {
+		t.Errorf("RowName(0) failed: got %q", got)
+	}
+	if !strings.Contains(waits.TotalRowContent(), "4.00 s") {
+		t.Errorf("expected the totals of the mutex class, got: %q", waits.TotalRowContent())
+	}
+
+	// the minimum and maximum of a level combine those of the events below it
+	waits.ToggleTimers()
+	for _, expected := range []string{"Latency▼", "Count", "Min", "Avg", "Max"} {
+		if !strings.Contains(waits.Headings(), expected) {
+			t.Errorf("expected %q in the headings: %q", expected, waits.Headings())
+		}
+	}
+	for i, expected := range []string{
+		"    3.00 s  75.0%|      30    1.00 ms  100.00 ms  500.00 ms|+ innodb",
+		"    1.00 s  25.0%|      10    2.00 ms  100.00 ms  200.00 ms|+ sql",
+	} {
+		if got := waits.RowContent()[i]; got != expected {
+			t.Errorf("row %d with timers: got %q, expected %q", i, got, expected)
+		}
+	}
+
+	// the mutex view shows the InnoDB mutexes of the wait events collected
+	if rows := app.mutexlatency.RowContent(); len(rows) != 1 || !strings.HasSuffix(rows[0], "|trx_mutex") {
+		t.Errorf("expected the mutex view to show trx_mutex, got:\n%s", strings.Join(rows, "\n"))
+	}
+}
+
+func TestMemoryUsage(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("memory_summary_global_by_event_name",
+		[]any{"memory/sql/THD::main_mem_root", 3, 5, 3000, 9000, 20, 40000},
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "memory_usage",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	// the growth since startup is shown together with the high water mark
+	fixture.Add("memory_summary_global_by_event_name",
+		[]any{"memory/sql/THD::main_mem_root", 4, 6, 5048, 10000, 30, 50000},
+	)
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	if !strings.Contains(output, "    2.00 k  100.0%      9.77 k|        10 100.0%|       1  100.0%         6|memory/sql/THD::main_mem_root") {
+		t.Errorf("expected the growth in memory usage in output:\n%s", output)
+	}
+
+	// break the usage down by thread
+	fixture.Add("memory_summary_by_thread_by_event_name",
+		[]any{52, 12, "app", "h1", "thread/sql/one_connection", "memory/sql/THD::main_mem_root", 3, 5, 3000, 9000, 20, 40000},
+	)
+	app.memory.(pstable.Classifier).NextClass()
+	app.memory.Collect(context.Background())
+	fixture.Add("memory_summary_by_thread_by_event_name",
+		[]any{52, 12, "app", "h1", "thread/sql/one_connection", "memory/sql/THD::main_mem_root", 4, 5, 4000, 9000, 22, 42000},
+	)
+	app.memory.Collect(context.Background())
+	buf.Reset()
+	app.Display()
+
+	output = buf.String()
+	for _, expected := range []string{"memory_summary_by_thread_by_event_name", "Thread: Memory Area", "1000  100.0%      8.79 k|         2", "12 app@h1: memory/sql/THD::main_mem_root"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestStages(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("events_stages_summary_by_thread_by_event_name",
+		[]any{52, 12, "app", "h1", "thread/sql/one_connection", "stage/sql/Sending data", 10, 3000000000000, 1000000000, 900000000000},
+		[]any{53, 13, "app", "h2", "thread/sql/one_connection", "stage/sql/Sending data", 5, 1000000000000, 1000000000, 500000000000},
+	)
+	fixture.Add("events_stages_current e LEFT JOIN",
+		[]any{54, 14, "app", "h3", "thread/sql/one_connection", "stage/innodb/alter table (read PK and internal sort)", 9000000000000, 25, 100, "ALTER TABLE t1 ADD INDEX (a)"},
+		[]any{52, 12, "app", "h1", "thread/sql/one_connection", "stage/sql/Sending data", 3000000000000, nil, nil, "SELECT * FROM t1"},
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "stages_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	app.config.SetWantRelativeStats(false)
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	// break the stages down by thread
+	app.stageslatency.(pstable.Classifier).NextClass()
+	app.stageslatency.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{"events_stages_summary_by_thread_by_event_name", "Thread: Stage Name", "    3.00 s  75.0%       10|12 app@h1: Sending data", "13 app@h2: Sending data"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if got := app.stageslatency.(pstable.Selectable).RowName(0); got != "Sending data" {
+		t.Errorf("RowName(0) failed: got %q", got)
+	}
+
+	// the stages being executed now, longest first
+	app.currentView.Set(view.ViewCurrentStages)
+	app.UpdateCurrentTabler()
+	buf.Reset()
+	app.Display()
+
+	output = buf.String()
+	for _, expected := range []string{
+		"    9.00 s  25.0% 14 app@h3                stage/innodb/alter table (read P|ALTER TABLE t1 ADD INDEX (a)",
+		"    3.00 s        12 app@h1                Sending data                    |SELECT * FROM t1",
+		"2 stage(s)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestCustomView(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+	fixture.Add("x$schema_table_statistics",
+		[]any{"db1", "t1", "3000000000000.00", 1500, 2048},
+		[]any{"db1", "t2", "1000000000000.00", 10, nil},
+	)
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:     true,
+		Interval:  1,
+		ViewOrder: []string{"threads", "schema_tables"},
+		Views: []rc.View{
+			{
+				Name:        "schema_tables",
+				Description: "Table statistics",
+				Query:       "SELECT table_schema, table_name, total_latency, rows_fetched, io_read FROM sys.x$schema_table_statistics",
+				Columns:     "table_schema, table_name, total_latency:time, rows_fetched:count, io_read:bytes",
+			},
+			{
+				Name:    "broken",
+				Query:   "SELECT * FROM sys.unknown",
+				Columns: "name",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	if app.currentView.Name() != "threads" {
+		t.Errorf("expected to start with the first view in the order, got: %s", app.currentView.Name())
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	app.currentView.SetNumber(2)
+	app.UpdateCurrentTabler()
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Table statistics (schema_tables) 2 rows",
+		"table_schema         table_name           total_lat▼ rows_fetch    io_read",
+		"db1                  t1                       3.00 s     1.46 k     2.00 k",
+		"db1                  t2                       1.00 s         10           ",
+		"Totals                                        4.00 s     1.47 k     2.00 k",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	// a failing query is shown rather than stopping
+	app.currentView.SetByName("broken")
+	app.UpdateCurrentTabler()
+	buf.Reset()
+	app.Display()
+	if output := buf.String(); !strings.Contains(output, "Custom view (broken) error: datasource.Fixture: no result for query") {
+		t.Errorf("expected the error of the query in output:\n%s", output)
+	}
+
+	// invalid columns are reported when starting
+	_, err = NewAppFromDataSource(fixture, Settings{Batch: true, Views: []rc.View{{Name: "bad", Query: "SELECT 1", Columns: "a:seconds"}}})
+	if err == nil || !strings.Contains(err.Error(), "view bad: unknown format") {
+		t.Errorf("expected an error for invalid columns, got: %v", err)
+	}
+}
+
+func TestFleet(t *testing.T) {
+	log.SetupLogging(false, "")
+	db1 := newFixture()
+	db2 := newFixture()
+	db2.Add("GLOBAL_VARIABLES",
+		[]any{"PERFORMANCE_SCHEMA", "ON"},
+		[]any{"HOSTNAME", "otherhost.example.com"},
+		[]any{"VERSION", "8.4.0"},
+	)
+
+	app, err := NewFleetAppFromDataSources([]Target{
+		{Name: "db1", DB: db1},
+		{Name: "db2:3307", DB: db2},
+		{Name: "db3", Err: errors.New("connection refused")},
+	}, Settings{
+		Batch:    true,
+		Interval: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewFleetAppFromDataSources() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(fleetConfig{app.servers}, &buf)
+
+	db1.Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 30, 3000000000000))
+	db2.Add("table_io_waits_summary_by_table",
+		tableIoRow("db1", "t1", 20, 2000000000000),
+		tableIoRow("db2", "t2", 10, 5000000000000),
+	)
+	// a view which can not be read only leaves its totals empty
+	db2.AddError("metadata_locks", &mysql.MySQLError{Number: 1142, Message: "SELECT command denied"})
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{
+		"3 servers / mixed versions",
+		"Fleet of 3 servers",
+		"db2:3307             8.4.0     |    6.00 s                             |    5.00 s db2.t2",
+		"db1                  8.0.36    |    2.00 s                            0|    2.00 s db1.t1",
+		"db3                            |connection refused",
+		"Totals                         |    8.00 s                            0|    5.00 s db2:3307:db2.t2",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	selectable := app.currentTabler.(pstable.Selectable)
+	for row, expected := range []string{"db2:3307", "db1", ""} {
+		if got := selectable.RowName(row); got != expected {
+			t.Errorf("RowName(%d) failed: got: %q, expected: %q", row, got, expected)
+		}
+	}
+
+	// the views of each server are still available
+	app.fleetShown = false
+	app.server = app.servers[1]
+	app.UpdateCurrentTabler()
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+	buf.Reset()
+	app.Collect(context.Background())
+	app.Display()
+	if output := buf.String(); !strings.Contains(output, "otherhost / 8.4.0") || !strings.Contains(output, "db2.t2") {
+		t.Errorf("expected the table I/O latency of db2 in output:\n%s", output)
+	}
+
+	if _, err := NewFleetAppFromDataSources([]Target{{Name: "db3", Err: errors.New("connection refused")}}, Settings{Batch: true}); err == nil {
+		t.Errorf("expected an error when no server can be used")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	log.SetupLogging(false, "")
+	db1 := newFixture()
+	db2 := newFixture()
+
+	if _, err := NewFleetAppFromDataSources([]Target{{Name: "db1", DB: db1}}, Settings{Batch: true, Compare: true}); err == nil {
+		t.Errorf("expected an error comparing a single server")
+	}
+
+	app, err := NewFleetAppFromDataSources([]Target{
+		{Name: "db1", DB: db1},
+		{Name: "db2", DB: db2},
+	}, Settings{
+		Batch:    true,
+		Compare:  true,
+		Interval: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewFleetAppFromDataSources() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	db1.Add("table_io_waits_summary_by_table",
+		tableIoRow("db", "t1", 30, 3000000000000),
+		tableIoRow("db", "t2", 10, 1000000000000),
+	)
+	db2.Add("table_io_waits_summary_by_table", tableIoRow("db", "t1", 10, 5000000000000))
+	app.Collect(context.Background())
+	app.Display()
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Compare db1 with db2 (table_io_latency) 2 rows",
+		"    3.00 s     5.00 s     +2.00 s|db.t1",
+		"    1.00 s                -1.00 s|db.t2",
+		"    4.00 s     5.00 s     +1.00 s|Totals",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+
+	// x shows the view of the first server alone
+	app.display = new(display.Display) // only the selection is cleared
+	app.toggleCompare()
+	if _, ok := app.currentTabler.(*comparewrapper.Wrapper); ok || app.comparing {
+		t.Errorf("expected toggleCompare() to stop comparing")
+	}
+	app.toggleCompare()
+	if _, ok := app.currentTabler.(*comparewrapper.Wrapper); !ok {
+		t.Errorf("expected toggleCompare() to compare again")
+	}
+}
+
+func TestCompareWindows(t *testing.T) {
+	log.SetupLogging(false, "")
+	db := newFixture()
+	app, err := NewAppFromDataSource(db, Settings{Batch: true, Interval: 1})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+
+	db.Add("table_io_waits_summary_by_table", tableIoRow("db", "t1", 10, 1000000000000))
+	app.Collect(context.Background())
+	app.display = new(display.Display) // only the selection is cleared
+	app.toggleCompare()
+	w, ok := app.currentTabler.(*comparewrapper.Wrapper)
+	if !ok {
+		t.Fatalf("expected toggleCompare() to compare time windows, got %T", app.currentTabler)
+	}
+	if got := w.Headings(); !strings.Contains(got, "Before/s") || !strings.Contains(got, "After/s") {
+		t.Errorf("unexpected headings: %q", got)
+	}
+
+	// changing view stops comparing
+	app.currentView.SetNext()
+	app.UpdateCurrentTabler()
+	if _, ok := app.currentTabler.(*comparewrapper.Wrapper); ok || app.comparing {
+		t.Errorf("expected changing view to stop comparing")
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	// the status is shown instead of exiting
+	fixture.AddError("table_io_waits_summary_by_table", driver.ErrBadConn)
+	app.Collect(context.Background())
+	app.Display()
+	if output := buf.String(); !strings.Contains(output, "connection lost, retrying in 1s") {
+		t.Errorf("expected the connection to be lost in output:\n%s", output)
+	}
+
+	// collecting is not tried again until the backoff has passed
+	fixture.Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 30, 3000000000000))
+	app.Collect(context.Background())
+	if app.server.lost == nil {
+		t.Errorf("expected the connection to still be lost before retrying")
+	}
+	app.server.retryAt = time.Now()
+	app.Collect(context.Background())
+	if app.server.lost != nil {
+		t.Errorf("expected the connection to be restored, got: %v", app.server.lost)
+	}
+	buf.Reset()
+	app.Display()
+	if output := buf.String(); strings.Contains(output, "connection lost") || !strings.Contains(output, "2.00 s") {
+		t.Errorf("expected the rows collected once the connection was restored in output:\n%s", output)
+	}
+
+	// the counters start again when MySQL restarts so the statistics are
+	// reset, even those which have not gone backwards
+	restarted := time.Now()
+	fixture.Add("GLOBAL_STATUS", []any{"10"})
+	fixture.Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 40, 4000000000000))
+	app.Collect(context.Background())
+	if app.currentTabler.FirstCollectTime().Before(restarted) {
+		t.Errorf("expected the statistics to be reset once MySQL restarted")
+	}
+}
+
+func TestQueryFailed(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+
+	// a query failing on a working connection does not lose the connection
+	denied := &mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}
+	fixture.AddError("table_io_waits_summary_by_table", denied)
+	app.Collect(context.Background())
+	app.Display()
+	if app.server.lost != nil || strings.Contains(buf.String(), "connection lost") {
+		t.Errorf("expected the connection not to be lost, got: %v, output:\n%s", app.server.lost, buf.String())
+	}
+	if err := app.server.collectAll(context.Background()); !errors.Is(err, denied) {
+		t.Errorf("expected collectAll() to return the error, got: %v", err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:        true,
+		Interval:     1,
+		QueryTimeout: 10 * time.Millisecond,
+		ViewName:     "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+	app.config.SetWantRelativeStats(false)
+	app.Collect(context.Background())
+
+	// the values collected before are shown as the connection is fine
+	fixture.AddDelay("table_io_waits_summary_by_table", time.Minute, tableIoRow("db1", "t1", 30, 3000000000000))
+	app.Collect(context.Background())
+	app.Display()
+	if output := buf.String(); !strings.Contains(output, "query timed out, stale data") || !strings.Contains(output, "db1.t1") {
+		t.Errorf("expected the query to time out in output:\n%s", output)
+	}
+	if app.server.lost != nil {
+		t.Errorf("expected the connection not to be lost, got: %v", app.server.lost)
+	}
+
+	fixture.Add("table_io_waits_summary_by_table", tableIoRow("db1", "t1", 30, 3000000000000))
+	app.Collect(context.Background())
+	buf.Reset()
+	app.Display()
+	if output := buf.String(); strings.Contains(output, "timed out") || !strings.Contains(output, "3.00 s") {
+		t.Errorf("expected the rows collected once the query no longer times out in output:\n%s", output)
+	}
+}
+
+func TestCollectInBackground(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	app.batch = display.NewBatchDisplay(app.config, &buf)
+	app.background = true
+
+	fixture.AddDelay("table_io_waits_summary_by_table", 50*time.Millisecond, tableIoRow("db1", "t1", 30, 3000000000000))
+	app.collectThen(app.Collect, app.Display)
+	if app.collecting.IsZero() {
+		t.Fatalf("expected to be collecting in the background")
+	}
+
+	// input using the data being collected waits until collecting finishes
+	app.event(event.Event{Type: event.EventIncreasePollTime})
+	app.event(event.Event{Type: event.EventSortNext})
+	if app.waitHandler.WaitInterval() != 2*time.Second {
+		t.Errorf("expected the poll time to change while collecting, got: %v", app.waitHandler.WaitInterval())
+	}
+	if len(app.queued) != 1 || app.collectingStatus() != "stale data, collecting for 0s" {
+		t.Errorf("expected sorting to wait for collecting to finish, queued: %v, status: %q", app.queued, app.collectingStatus())
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be shown while collecting:\n%s", buf.String())
+	}
+
+	app.finishCollecting(<-app.collected)
+	if !app.collecting.IsZero() || len(app.queued) != 0 {
+		t.Errorf("expected collecting to finish and the input waiting to be handled, queued: %v", app.queued)
+	}
+	if output := buf.String(); !strings.Contains(output, "2.00 s") {
+		t.Errorf("expected the rows collected in output:\n%s", output)
+	}
+
+	// stopping does not wait for queries to finish
+	fixture.AddDelay("table_io_waits_summary_by_table", time.Minute)
+	app.collectThen(app.Collect, app.Display)
+	start := time.Now()
+	app.stopCollecting()
+	if took := time.Since(start); took > 10*time.Second || !app.collecting.IsZero() {
+		t.Errorf("expected collecting to stop straight away, took: %v", took)
+	}
+}
+
+func TestCollectTimings(t *testing.T) {
+	log.SetupLogging(false, "")
+	fixture := newFixture()
+
+	app, err := NewAppFromDataSource(fixture, Settings{
+		Batch:    true,
+		Interval: 1,
+		ViewName: "table_io_latency",
+	})
+	if err != nil {
+		t.Fatalf("NewAppFromDataSource() failed: %v", err)
+	}
+
+	// the models are collected concurrently so the slow queries overlap
+	delay := 100 * time.Millisecond
+	for _, pattern := range []string{
+		"table_io_waits_summary_by_table",
+		"file_summary_by_instance",
+		"table_lock_waits_summary_by_table",
+		"memory_summary_global_by_event_name",
+		"events_statements_summary_by_digest",
+	} {
+		fixture.AddDelay(pattern, delay)
+	}
+	start := time.Now()
+	if err := app.server.collectAll(context.Background()); err != nil {
+		t.Fatalf("collectAll() failed: %v", err)
+	}
+	if took := time.Since(start); took >= 4*delay {
+		t.Errorf("expected the models to be collected concurrently, took: %v", took)
+	}
+
+	models, all := app.server.timings.sorted()
+	if len(models) != len(app.server.models()) || all == 0 {
+		t.Fatalf("expected a timing for each of the %d models, got: %v, all: %v", len(app.server.models()), models, all)
+	}
+	if models[0].last < delay || models[0].count != 2 {
+		t.Errorf("expected the slowest model first, collected on startup and now, got: %+v", models[0])
+	}
+
+	var buf bytes.Buffer
+	display.NewBatchDisplay(app.config, &buf).Display(app.newTimingsView())
+	for _, expected := range []string{"Collection timings", "memory_usage", "wait_events", "Totals"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/event"
+	"github.com/sjmudd/ps-top/log"
+)
+
+// withQueryTimeout returns db abandoning queries which take longer
+// than timeout, if there is a timeout
+func withQueryTimeout(db datasource.DataSource, timeout time.Duration) datasource.DataSource {
+	if timeout <= 0 {
+		return db
+	}
+	return datasource.NewTimeout(db, timeout)
+}
+
+// collectThen calls collect and then done. When interactive collect is
+// called in the background so that input is still handled if MySQL is
+// slow to respond, and done once it has finished.
+func (app *App) collectThen(collect func(context.Context), done func()) {
+	if !app.background {
+		collect(context.Background())
+		done()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	collected := make(chan func(), 1)
+	app.collecting = time.Now()
+	app.collected = collected
+	app.cancel = cancel
+
+	go func() {
+		collect(ctx)
+		collected <- done
+	}()
+}
+
+// finishCollecting calls done once collecting in the background has
+// finished and then handles the input received meanwhile
+func (app *App) finishCollecting(done func()) {
+	log.Println("app.finishCollecting() collecting took", time.Since(app.collecting))
+	app.cancel()
+	app.collecting = time.Time{}
+	app.collected = nil
+
+	done()
+
+	queued := app.queued
+	app.queued = nil
+	for _, inputEvent := range queued {
+		app.event(inputEvent) // queued again if it collects
+	}
+}
+
+// stopCollecting stops collecting in the background, if collecting,
+// and waits for it to finish
+func (app *App) stopCollecting() {
+	if app.collecting.IsZero() {
+		return
+	}
+	app.cancel()
+	<-app.collected
+	app.collecting = time.Time{}
+	app.collected = nil
+}
+
+// collectingStatus returns what is shown while collecting in the
+// background once the data shown is out of date or input is waiting
+// for collecting to finish
+func (app *App) collectingStatus() string {
+	took := time.Since(app.collecting)
+	if len(app.queued) == 0 && took < app.waitHandler.WaitInterval() {
+		return ""
+	}
+	return fmt.Sprintf("stale data, collecting for %ds", int(took.Seconds()))
+}
+
+// showCollectingStatus shows how long collecting has taken without
+// showing the data being collected
+func (app *App) showCollectingStatus() {
+	if app.display == nil {
+		return
+	}
+	if status := app.collectingStatus(); status != "" {
+		app.display.ShowStatus(status)
+	}
+}
+
+// handledWhileCollecting returns whether input of the given type can be
+// handled while collecting in the background as it does not use the
+// data being collected
+func handledWhileCollecting(eventType event.Type) bool {
+	switch eventType {
+	case event.EventFinished,
+		event.EventDecreasePollTime,
+		event.EventIncreasePollTime,
+		event.EventFilter,
+		event.EventPromptRune,
+		event.EventPromptBackspace:
+		return true
+	}
+	return false
+}
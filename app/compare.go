@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/compare"
+	"github.com/sjmudd/ps-top/pstable"
+	wrapper "github.com/sjmudd/ps-top/wrapper/compare"
+)
+
+// setupCompare compares the first server with the second one
+func (app *App) setupCompare() error {
+	if len(app.servers) != 2 {
+		return errors.New("--compare needs two hosts to compare")
+	}
+	for _, s := range app.servers {
+		if s.err != nil {
+			return s.err
+		}
+	}
+	app.compareWith = app.servers[1]
+	app.comparing = true
+
+	return nil
+}
+
+// compareServers returns the current view of the server shown compared
+// with the same view of the other server, or nil if the view can not
+// be compared
+func (app *App) compareServers() pstable.Tabler {
+	w := wrapper.NewServers(app.currentView.Name(), app.side(app.server), app.side(app.compareWith))
+	if w == nil {
+		return nil
+	}
+	return w
+}
+
+// side returns the current view of s to compare. Collecting it does not
+// fail as the server keeps track of losing the connection itself.
+func (app *App) side(s *server) compare.Side {
+	tabler := s.tabler(app.currentView)
+
+	return compare.Side{
+		Label:  anonymiser.Anonymise("hostname", s.name),
+		Tabler: tabler,
+		Collect: func(ctx context.Context) error {
+			if tabler != nil {
+				s.collect(ctx, tabler.Collect)
+			}
+			return nil
+		},
+	}
+}
+
+// toggleCompare starts or stops comparing. With two servers their
+// current views are compared, otherwise what has been collected so far
+// is compared with what is collected from now on.
+func (app *App) toggleCompare() {
+	if app.fleetShown || app.replay != nil || app.recorder != nil {
+		return
+	}
+	switch {
+	case app.comparing:
+		app.comparing = false
+		app.UpdateCurrentTabler()
+	case app.compareWith != nil:
+		app.comparing = true
+		app.UpdateCurrentTabler()
+	default:
+		w := wrapper.NewWindows(app.currentView.Name(), app.currentTabler)
+		if w == nil {
+			log.Printf("app.toggleCompare(): view %s can not be compared", app.currentView.Name())
+			return
+		}
+		app.comparing = true
+		app.currentTabler = w
+	}
+	app.display.ClearSelection()
+	app.detail = nil
+	app.Display()
+}
@@ -0,0 +1,222 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/mutexlatency"
+	threadsmodel "github.com/sjmudd/ps-top/model/threads"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/view"
+)
+
+// waiter is implemented by the threads view to show the threads waiting on an event
+type waiter interface {
+	Waiting(ctx context.Context, eventName string) (headings string, rows []string, err error)
+}
+
+// detail is shown instead of the current view to show what is related
+// to the selected row
+type detail struct {
+	tabler      pstable.Tabler                 // view the row was selected from
+	description string                         // what the detail is about
+	collect     func(context.Context) error    // collects the data needed (may be nil)
+	build       func(context.Context) []string // returns the lines to show
+	lines       []string                       // the lines last built
+}
+
+// Description describes the detail being shown
+func (d *detail) Description() string {
+	return d.description + " (Enter or Esc to return)"
+}
+
+// Headings returns no headings as each section has its own
+func (d *detail) Headings() string { return "" }
+
+// FirstCollectTime returns the time the view was first collected
+func (d *detail) FirstCollectTime() time.Time { return d.tabler.FirstCollectTime() }
+
+// LastCollectTime returns the time the view was last collected
+func (d *detail) LastCollectTime() time.Time { return d.tabler.LastCollectTime() }
+
+// RowContent returns the lines to show
+func (d *detail) RowContent() []string { return d.lines }
+
+// TotalRowContent returns an empty line as there are no totals
+func (d *detail) TotalRowContent() string { return "" }
+
+// EmptyRowContent returns an empty line
+func (d *detail) EmptyRowContent() string { return "" }
+
+// HaveRelativeStats returns whether the view has relative statistics
+func (d *detail) HaveRelativeStats() bool { return d.tabler.HaveRelativeStats() }
+
+// update collects the data, if needed, and builds the lines to show
+func (d *detail) update(ctx context.Context, collect bool) error {
+	if collect && d.collect != nil {
+		if err := d.collect(ctx); err != nil {
+			return err
+		}
+	}
+	d.lines = d.build(ctx)
+
+	return nil
+}
+
+// section returns the lines of a section of the detail: a title, the headings and the rows
+func section(title, headings string, rows []string) []string {
+	lines := []string{title, headings}
+	if len(rows) == 0 {
+		rows = []string{"  (none)"}
+	}
+	return append(append(lines, rows...), "")
+}
+
+// newDetail returns the detail for the row with the given name in the
+// current view or nil if there is nothing more to show
+func (app *App) newDetail(name string) *detail {
+	switch app.currentView.Get() {
+	case view.ViewLatency, view.ViewOps, view.ViewIndexes, view.ViewUnusedIndexes, view.ViewLocks, view.ViewLockWaits, view.ViewIO:
+		if !strings.Contains(name, ".") || strings.HasPrefix(name, "<") || strings.HasPrefix(name, "/") {
+			return nil // not a table
+		}
+		return app.tableDetail(name)
+	case view.ViewMutex:
+		return app.waitingDetail("mutex "+name, mutexlatency.MutexPrefix+name)
+	case view.ViewWaits:
+		return app.waitingDetail("wait event "+name, name)
+	case view.ViewStages, view.ViewCurrentStages:
+		eventName := name
+		if !strings.HasPrefix(name, "stage/") {
+			eventName = "stage/sql/" + name
+		}
+		return app.waitingDetail("stage "+name, eventName)
+	}
+
+	return nil
+}
+
+// tableDetail returns the statements, lock waits and file I/O of a table
+func (app *App) tableDetail(name string) *detail {
+	sections := []struct {
+		title  string
+		tabler pstable.Tabler
+	}{
+		{"Statements using the table (events_statements_summary_by_digest)", app.digests},
+		{"Table lock latency (table_lock_waits_summary_by_table)", app.tablelocklatency},
+		{"File I/O latency (file_summary_by_instance)", app.fileinfolatency},
+	}
+
+	return &detail{
+		tabler:      app.currentTabler,
+		description: "Detail of table " + name,
+		collect: func(ctx context.Context) error {
+			for _, s := range sections {
+				if err := s.tabler.Collect(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		build: func(context.Context) []string {
+			var lines []string
+			for _, s := range sections {
+				if relater, ok := s.tabler.(pstable.Relater); ok {
+					headings, rows := relater.Related(name)
+					lines = append(lines, section(s.title, headings, rows)...)
+				}
+			}
+			return lines
+		},
+	}
+}
+
+// waitingDetail returns the threads currently waiting on the given event
+func (app *App) waitingDetail(description, eventName string) *detail {
+	return &detail{
+		tabler:      app.currentTabler,
+		description: "Detail of " + description,
+		build: func(ctx context.Context) []string {
+			if app.db == nil {
+				return []string{"Threads currently waiting can not be shown when replaying"}
+			}
+			threads, ok := app.threads.(waiter)
+			if !ok {
+				return nil
+			}
+			if err := app.enableCurrentEvents(ctx); err != nil {
+				return []string{"Failed to enable the consumers of the events currently waited on: " + err.Error()}
+			}
+			headings, rows, err := threads.Waiting(ctx, eventName)
+			if errors.Is(err, threadsmodel.ErrConsumerDisabled) {
+				return []string{"Threads currently waiting can not be shown as " + err.Error() + " and could not be enabled"}
+			}
+			if err != nil {
+				return []string{"Failed to collect the threads currently waiting: " + err.Error()}
+			}
+			return section("Threads currently waiting (threads)", headings, rows)
+		},
+	}
+}
+
+// enableCurrentEvents enables the setup_consumers needed to show the
+// threads currently waiting the first time they are shown, rather than
+// on startup, as they add overhead to every session
+func (s *server) enableCurrentEvents(ctx context.Context) error {
+	if s.currentEvents || s.setupInstruments == nil {
+		return nil
+	}
+	if err := s.setupInstruments.EnableCurrentEventsMonitoring(ctx); err != nil {
+		return err
+	}
+	s.currentEvents = true
+
+	return nil
+}
+
+// detailCollects returns whether the detail collects the data it needs,
+// which is not the case when replaying or when recording as all the
+// views are collected then
+func (app *App) detailCollects() bool {
+	return app.replay == nil && app.recorder == nil
+}
+
+// updateDetail updates the detail being shown, collecting the data
+// needed if wanted. Nothing changes while the connection is lost.
+func (app *App) updateDetail(ctx context.Context, collect bool) {
+	if !collect {
+		_ = app.detail.update(ctx, false)
+		return
+	}
+	app.server.collect(ctx, func(ctx context.Context) error { return app.detail.update(ctx, true) })
+}
+
+// showDetail shows the detail of the selected row, if there is any.
+// In a tree the row is expanded or collapsed instead if it has children.
+func (app *App) showDetail() {
+	selectable, ok := app.currentTabler.(pstable.Selectable)
+	if !ok || app.display.Selected() < 0 {
+		app.Display()
+		return
+	}
+	if tree, ok := app.currentTabler.(pstable.Tree); ok && tree.Toggle(app.display.Selected()) {
+		app.Display()
+		return
+	}
+	name := selectable.RowName(app.display.Selected())
+	if app.fleetShown {
+		app.showServer(name)
+		return
+	}
+	log.Printf("app.showDetail(): selected %q in %s", name, app.currentView.Name())
+
+	if app.detail = app.newDetail(name); app.detail == nil {
+		app.Display()
+		return
+	}
+	collect := app.detailCollects()
+	app.collectThen(func(ctx context.Context) { app.updateDetail(ctx, collect) }, app.Display)
+}
@@ -0,0 +1,318 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/connector"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/global"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/model/fleet"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/view"
+	wrapper "github.com/sjmudd/ps-top/wrapper/fleet"
+)
+
+// Target is one of the servers to collect from in fleet mode
+type Target struct {
+	Name string                // name of the server or group as given on the command line
+	DB   datasource.DataSource // connection to the server, nil if connecting failed
+	Err  error                 // why connecting failed
+}
+
+// connectTargets connects to each of the hosts or groups concurrently
+func connectTargets(connectorFlags connector.Config, hosts []connector.Target) []Target {
+	targets := make([]Target, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		targets[i].Name = host.String()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := connector.NewTargetConnector(connectorFlags, host)
+			if err != nil {
+				log.Printf("app.connectTargets(): failed to connect to %s: %v", host, err)
+				targets[i].Err = err
+				return
+			}
+			targets[i].DB = reconnecting(c)
+		}()
+	}
+	wg.Wait()
+
+	return targets
+}
+
+// NewFleetAppFromDataSources sets up the application to show the
+// totals of each of the targets side by side. A server can be chosen
+// from there to show the usual views for it. Servers which could not
+// be connected to are shown with the error. With settings.Compare
+// the views of two targets are compared instead.
+func NewFleetAppFromDataSources(targets []Target, settings Settings) (*App, error) {
+	log.Println("app.NewFleetAppFromDataSources()")
+	if settings.Replay != "" || settings.Record != "" || settings.ListenMetrics != "" {
+		return nil, errors.New("several hosts can not be used with --record, --replay or --listen-metrics")
+	}
+	app := new(App)
+
+	anonymiser.Enable(settings.Anonymise)
+	if settings.Filter == nil {
+		settings.Filter = filter.NewDatabaseFilter("")
+	}
+
+	for _, target := range targets {
+		s, err := newFleetServer(target, settings)
+		if err != nil {
+			app.Cleanup()
+			return nil, err
+		}
+		app.servers = append(app.servers, s)
+		if app.server == nil && s.err == nil {
+			app.server = s
+		}
+	}
+	if app.server == nil {
+		app.Cleanup()
+		return nil, errors.New("failed to collect from any of the hosts given")
+	}
+
+	if settings.Compare {
+		if err := app.setupCompare(); err != nil {
+			app.Cleanup()
+			return nil, err
+		}
+		app.setupOutput(app.config, settings)
+	} else {
+		app.fleet = wrapper.NewFleet(fleetConfig{app.servers}, app.collectFleet)
+		app.fleetShown = true
+		app.setupOutput(fleetConfig{app.servers}, settings)
+	}
+	app.resetDBStatistics(context.Background())
+
+	var err error
+	if app.currentView, err = view.SetupAndValidate(settings.ViewName, app.db, viewOptions(settings)); err != nil {
+		app.Cleanup()
+		return nil, err
+	}
+	app.UpdateCurrentTabler()
+
+	log.Println("app.NewFleetAppFromDataSources() finishes")
+	return app, nil
+}
+
+// newFleetServer sets up the models to collect from a target. If the
+// target can not be used the server returned only records why.
+func newFleetServer(target Target, settings Settings) (*server, error) {
+	if target.Err != nil {
+		return &server{name: target.Name, err: target.Err}, nil
+	}
+
+	db := withQueryTimeout(target.DB, settings.QueryTimeout)
+	variables, err := global.NewVariables(db)
+	if err == nil {
+		err = performanceSchemaEnabled(variables)
+	}
+	if err != nil {
+		_ = db.Close()
+		return &server{name: target.Name, err: err}, nil
+	}
+	cfg := config.NewConfig(global.NewStatus(db), variables, settings.Filter, true)
+
+	return newServer(target.Name, cfg, db, settings.Views)
+}
+
+// eachServer calls f for each server which could be connected to.
+// They are called concurrently unless anonymising as the anonymiser
+// can not be used concurrently.
+func (app *App) eachServer(f func(*server)) {
+	var wg sync.WaitGroup
+
+	for _, s := range app.servers {
+		if s.err != nil {
+			continue
+		}
+		if anonymiser.Enabled() {
+			f(s)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(s)
+		}()
+	}
+	wg.Wait()
+}
+
+// collectFleet collects the totals of each server
+func (app *App) collectFleet(ctx context.Context) fleet.Rows {
+	app.eachServer(func(s *server) { s.collect(ctx, s.collectSummary) })
+
+	return app.fleetRows()
+}
+
+// fleetRows returns the totals of each server last collected
+func (app *App) fleetRows() fleet.Rows {
+	rows := make(fleet.Rows, 0, len(app.servers))
+	for _, s := range app.servers {
+		rows = append(rows, s.summary())
+	}
+
+	return rows
+}
+
+// collectSummary collects the models needed for the totals of the
+// server. They are collected independently so a model which fails, e.g.
+// as it can not be read, only leaves its totals empty. Losing the
+// connection or a query timing out stops collecting.
+func (s *server) collectSummary(ctx context.Context) error {
+	s.failed = nil
+	for _, m := range []model{
+		{fleet.TableIOLatency, s.tableiolatency},
+		{fleet.FileIOLatency, s.fileinfolatency},
+		{fleet.TableLockLatency, s.tablelocklatency},
+		{fleet.LockWaits, s.lockwaits},
+	} {
+		err := s.collectModel(ctx, m.name, m.tabler)
+		switch {
+		case err == nil:
+		case ctx.Err() != nil, errors.Is(err, context.DeadlineExceeded), datasource.ConnectionLost(err):
+			return err
+		default:
+			log.Printf("app.server.collectSummary(): collecting %s from %q failed: %v", m.name, s.name, err)
+			s.failed = append(s.failed, m.name)
+		}
+	}
+	return nil
+}
+
+// summary returns the totals of the server shown in the fleet view
+func (s *server) summary() fleet.Row {
+	row := fleet.Row{Host: s.name}
+	if s.err != nil {
+		row.Error = s.err.Error()
+		return row
+	}
+	row.Version = s.config.MySQLVersion()
+	if s.lost != nil {
+		row.Error = s.status() + ": " + s.lost.Error()
+		return row
+	}
+
+	row.Failed = s.failed
+
+	if row.Collected(fleet.TableIOLatency) {
+		tableIO := summarise(s.tableiolatency)
+		row.TableIOLatency = tableIO.Latency
+		row.TopTable = tableIO.Top
+		row.TopTableLatency = tableIO.TopLatency
+	}
+	if row.Collected(fleet.FileIOLatency) {
+		row.FileIOLatency = summarise(s.fileinfolatency).Latency
+	}
+	if row.Collected(fleet.TableLockLatency) {
+		row.TableLockLatency = summarise(s.tablelocklatency).Latency
+	}
+	if row.Collected(fleet.LockWaits) {
+		row.LockWaits = summarise(s.lockwaits).Rows
+	}
+
+	return row
+}
+
+// summarise returns the summary of the rows of a tabler if it has one
+func summarise(tabler pstable.Tabler) pstable.Summary {
+	if summariser, ok := tabler.(pstable.Summariser); ok {
+		return summariser.Summary()
+	}
+	return pstable.Summary{}
+}
+
+// showServer changes from the fleet view to the current view of the named server
+func (app *App) showServer(name string) {
+	for _, s := range app.servers {
+		if s.name == name && s.err == nil {
+			log.Printf("app.showServer(): showing %s", name)
+			app.server = s
+			app.fleetShown = false
+			app.display.SetConfig(s.config)
+			app.viewChanged()
+			return
+		}
+	}
+	app.Display()
+}
+
+// showFleet changes back to the fleet view from a server's view
+func (app *App) showFleet() {
+	if app.fleet == nil || app.fleetShown {
+		return
+	}
+	app.fleetShown = true
+	app.display.SetConfig(fleetConfig{app.servers})
+	app.viewChanged()
+}
+
+// fleetConfig provides the settings shown in the top line of the fleet view
+type fleetConfig struct {
+	servers []*server
+}
+
+// connected returns the configuration of the servers which could be connected to
+func (fc fleetConfig) connected() []*config.Config {
+	var configs []*config.Config
+	for _, s := range fc.servers {
+		if s.err == nil {
+			configs = append(configs, s.config)
+		}
+	}
+	return configs
+}
+
+// Hostname returns the number of servers
+func (fc fleetConfig) Hostname() string {
+	return fmt.Sprintf("%d servers", len(fc.servers))
+}
+
+// MySQLVersion returns the version of the servers if they all have the same one
+func (fc fleetConfig) MySQLVersion() string {
+	var version string
+	for _, cfg := range fc.connected() {
+		switch {
+		case version == "":
+			version = cfg.MySQLVersion()
+		case version != cfg.MySQLVersion():
+			return "mixed versions"
+		}
+	}
+	return version
+}
+
+// Uptime returns the lowest uptime of the servers
+func (fc fleetConfig) Uptime() int {
+	uptime := math.MaxInt
+	for _, cfg := range fc.connected() {
+		uptime = min(uptime, cfg.Uptime())
+	}
+	if uptime == math.MaxInt {
+		return 0
+	}
+	return uptime
+}
+
+// WantRelativeStats returns whether relative statistics are wanted, as
+// this is changed on all servers together
+func (fc fleetConfig) WantRelativeStats() bool {
+	for _, cfg := range fc.connected() {
+		return cfg.WantRelativeStats()
+	}
+	return true
+}
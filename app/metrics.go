@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/sjmudd/ps-top/export"
+	"github.com/sjmudd/ps-top/log"
+)
+
+// metricsPath is the URL path where metrics are served
+const metricsPath = "/metrics"
+
+// runMetrics runs headless, collecting all models once per interval
+// and serving the results as Prometheus metrics until we are stopped.
+func (app *App) runMetrics() {
+	log.Println("app.runMetrics() listening on", app.listenMetrics)
+
+	listener, err := net.Listen("tcp", app.listenMetrics)
+	if err != nil {
+		log.Fatalf("Failed to listen for metrics on %q: %v", app.listenMetrics, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, app.metrics)
+	server := &http.Server{Handler: mux}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}()
+	defer server.Close()
+
+	// statistics were collected on startup so make them available immediately
+	app.updateMetrics()
+	app.waitHandler.CollectedNow()
+
+	for !app.finished {
+		select {
+		case sig := <-app.sigChan:
+			log.Println("Caught signal: ", sig)
+			app.finished = true
+		case <-app.waitHandler.WaitUntilNextPeriod():
+			app.collectAll(context.Background())
+			app.waitHandler.CollectedNow()
+			app.updateMetrics()
+		}
+	}
+}
+
+// updateMetrics generates the metrics to serve from each model.
+// table_io_ops is not included as it shares table_io_latency's data.
+// Statement digests, threads, indexes and wait events are not included
+// as there may be very many of them, nor are lock waits and current
+// stages which are not counters.
+func (app *App) updateMetrics() {
+	var rowSets []any
+
+	for _, tabler := range []any{
+		app.tableiolatency,
+		app.fileinfolatency,
+		app.tablelocklatency,
+		app.mutexlatency,
+		app.stageslatency,
+		app.memory,
+		app.users,
+		app.replication,
+	} {
+		if exporter, ok := tabler.(export.Exporter); ok {
+			rowSets = append(rowSets, exporter.Rows())
+		}
+	}
+
+	app.metrics.Update(rowSets...)
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/connector"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+)
+
+const (
+	minBackoff = time.Second // time to wait before reconnecting after losing the connection
+	maxBackoff = time.Minute // longest time to wait between attempts to reconnect
+)
+
+// reconnecting returns a data source using the connection of c which
+// connects again with the same settings after losing the connection
+func reconnecting(c *connector.Connector) datasource.DataSource {
+	return datasource.NewReconnectingSQL(c.DB, func() (*sql.DB, error) {
+		if err := c.Reconnect(); err != nil {
+			return nil, err
+		}
+		return c.DB, nil
+	})
+}
+
+// collect collects from the server with f. If the connection has been
+// lost it is tried again once the backoff has passed, waiting twice as
+// long each time it fails. If MySQL has restarted, as its uptime has
+// gone backwards, the statistics are reset as the counters start again.
+// A query which times out leaves the values collected before, as does
+// a query which fails without losing the connection, e.g. as a table
+// does not exist or can not be read.
+func (s *server) collect(ctx context.Context, f func(context.Context) error) {
+	if s.lost != nil {
+		if time.Now().Before(s.retryAt) {
+			return
+		}
+		if err := s.reconnect(); err != nil {
+			s.connectionLost(err)
+			return
+		}
+	}
+
+	restarted, err := s.config.CollectUptime(ctx)
+	if err == nil && restarted {
+		err = s.restarted(ctx)
+	}
+	if err == nil {
+		err = f(ctx)
+	}
+	switch {
+	case ctx.Err() != nil:
+		return // stopped collecting
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("app.server.collect(): query to %q timed out: %v", s.name, err)
+		s.timedOut = true
+		return
+	case datasource.ConnectionLost(err):
+		s.connectionLost(err)
+		return
+	case err != nil:
+		// the connection works so the other models are still shown
+		log.Printf("app.server.collect(): collecting from %q failed: %v", s.name, err)
+	}
+	s.timedOut = false
+	if s.lost != nil {
+		log.Printf("app.server.collect(): connection to %q restored", s.name)
+		s.lost = nil
+	}
+}
+
+// reconnect connects to the server again, if the connection can do so
+func (s *server) reconnect() error {
+	log.Printf("app.server.reconnect(): reconnecting to %q", s.name)
+	if reconnecter, ok := s.db.(datasource.Reconnecter); ok {
+		return reconnecter.Reconnect()
+	}
+	return nil
+}
+
+// restarted makes the values collected after MySQL has restarted the
+// initial values. The setup_instruments configuration is applied again
+// as it is lost when MySQL restarts, and the setup_consumers one once
+// it is needed again.
+func (s *server) restarted(ctx context.Context) error {
+	log.Printf("app.server.restarted(): %q has restarted, resetting statistics", s.name)
+	s.currentEvents = false
+	if s.setupInstruments != nil {
+		if err := s.setupInstruments.EnableMonitoring(ctx); err != nil {
+			return err
+		}
+	}
+	err := s.collectAll(ctx)
+	if datasource.ConnectionLost(err) {
+		return err
+	}
+	if err != nil {
+		log.Printf("app.server.restarted(): collecting from %q failed: %v", s.name, err)
+	}
+	s.resetStatistics()
+
+	return nil
+}
+
+// connectionLost records that collecting failed and when to try again
+func (s *server) connectionLost(err error) {
+	if s.lost == nil {
+		s.backoff = minBackoff
+	} else {
+		s.backoff = min(2*s.backoff, maxBackoff)
+	}
+	s.lost = err
+	s.retryAt = time.Now().Add(s.backoff)
+
+	log.Printf("app.server.connectionLost(): %q: %v, retrying in %v", s.name, err, s.backoff)
+}
+
+// status returns when the connection will be tried again if it has
+// been lost, or that the values shown are out of date if the last query
+// timed out
+func (s *server) status() string {
+	switch {
+	case s.lost != nil:
+		wait := max(time.Until(s.retryAt), 0).Round(time.Second)
+		return fmt.Sprintf("connection lost, retrying in %ds", int(wait.Seconds()))
+	case s.timedOut:
+		return "query timed out, stale data"
+	}
+	return ""
+}
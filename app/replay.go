@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sjmudd/ps-top/global"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/snapshot"
+)
+
+// recordedVariables are the global variables stored in a recording which
+// are needed to display the data later
+var recordedVariables = []string{"hostname", "version"}
+
+// snapshotters returns the models which can be recorded by name.
+// table_io_ops, unused_indexes and mutex_latency are not included as
+// they share table_io_latency's, index_io_latency's and wait_events' data. Views defined by the
+// user are recorded as view:<name>.
+func (app *App) snapshotters() map[string]snapshot.Snapshotter {
+	snapshotters := make(map[string]snapshot.Snapshotter)
+
+	for name, tabler := range map[string]any{
+		"table_io":       app.tableiolatency,
+		"file_io":        app.fileinfolatency,
+		"table_locks":    app.tablelocklatency,
+		"stages":         app.stageslatency,
+		"current_stages": app.currentstages,
+		"memory":         app.memory,
+		"users":          app.users,
+		"digests":        app.digests,
+		"threads":        app.threads,
+		"indexes":        app.indexusage,
+		"lock_waits":     app.lockwaits,
+		"replication":    app.replication,
+		"waits":          app.waits,
+	} {
+		if s, ok := tabler.(snapshot.Snapshotter); ok {
+			snapshotters[name] = s
+		}
+	}
+	for name, tabler := range app.custom {
+		if s, ok := tabler.(snapshot.Snapshotter); ok {
+			snapshotters["view:"+name] = s
+		}
+	}
+
+	return snapshotters
+}
+
+// newRecorder creates the file to record to and writes the header
+func newRecorder(filename string, variables *global.Variables) (*snapshot.Writer, error) {
+	header := snapshot.Header{Variables: make(map[string]string)}
+	for _, name := range recordedVariables {
+		header.Variables[name] = variables.Get(name)
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot.NewWriter(file, header)
+}
+
+// record writes the rows last collected by each model to the recording
+func (app *App) record() {
+	rows := make(map[string]any)
+	for name, s := range app.snapshotters() {
+		rows[name] = s.Snapshot()
+	}
+
+	if err := app.recorder.Write(time.Now(), app.config.Uptime(), rows); err != nil {
+		log.Fatalf("Failed to record collected data: %v", err)
+	}
+}
+
+// openReplay opens a recording to replay
+func openReplay(filename string) (*snapshot.Reader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := snapshot.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return reader, nil
+}
+
+// startReplay reads the first recorded snapshot and uses it as the
+// initial values, as resetDBStatistics does when connected to MySQL.
+func (app *App) startReplay() error {
+	first, err := app.replay.Next()
+	if err != nil {
+		return err
+	}
+	app.pending = first
+	app.replayNext()
+	app.resetStatistics()
+
+	return nil
+}
+
+// replayNext restores the pending snapshot into the models and reads
+// the following one, adjusting the wait interval so that snapshots are
+// shown with the same spacing as when they were recorded. Once the end
+// of the recording is reached the last snapshot remains on screen.
+func (app *App) replayNext() {
+	if app.pending == nil {
+		return
+	}
+	app.restoreSnapshot(app.pending)
+	app.current = app.pending
+
+	next, err := app.replay.Next()
+	switch {
+	case err == io.EOF:
+		log.Println("app.replayNext(): end of recording reached")
+		app.pending = nil
+		return
+	case err != nil:
+		log.Fatalf("Failed to read recording: %v", err)
+	}
+
+	if interval := next.Collected.Sub(app.pending.Collected); interval > 0 {
+		app.waitHandler.SetWaitInterval(interval)
+	}
+	app.pending = next
+}
+
+// restoreSnapshot replaces the collected rows of each model with those of the snapshot
+func (app *App) restoreSnapshot(s *snapshot.Snapshot) {
+	snapshotters := app.snapshotters()
+
+	for name, rows := range s.Rows {
+		snapshotter, found := snapshotters[name]
+		if !found {
+			log.Printf("app.restoreSnapshot(): ignoring unknown model %q", name)
+			continue
+		}
+		if err := snapshotter.Restore(rows, s.Collected); err != nil {
+			log.Fatalf("Failed to restore %s from recording: %v", name, err)
+		}
+	}
+	app.status.Set("Uptime", s.Uptime)
+	if _, err := app.config.CollectUptime(context.Background()); err != nil {
+		log.Printf("app.restoreSnapshot(): failed to set the uptime: %v", err)
+	}
+}
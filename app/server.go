@@ -0,0 +1,285 @@
+package app
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/connector"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/rc"
+	"github.com/sjmudd/ps-top/setupinstruments"
+	"github.com/sjmudd/ps-top/view"
+	"github.com/sjmudd/ps-top/wrapper/currentstages"
+	"github.com/sjmudd/ps-top/wrapper/customview"
+	"github.com/sjmudd/ps-top/wrapper/fileinfolatency"
+	"github.com/sjmudd/ps-top/wrapper/indexusage"
+	"github.com/sjmudd/ps-top/wrapper/lockwaits"
+	"github.com/sjmudd/ps-top/wrapper/memoryusage"
+	"github.com/sjmudd/ps-top/wrapper/mutexlatency"
+	"github.com/sjmudd/ps-top/wrapper/replication"
+	"github.com/sjmudd/ps-top/wrapper/stageslatency"
+	"github.com/sjmudd/ps-top/wrapper/statementdigest"
+	"github.com/sjmudd/ps-top/wrapper/tableiolatency"
+	"github.com/sjmudd/ps-top/wrapper/tableioops"
+	"github.com/sjmudd/ps-top/wrapper/tablelocklatency"
+	"github.com/sjmudd/ps-top/wrapper/threads"
+	"github.com/sjmudd/ps-top/wrapper/unusedindexes"
+	"github.com/sjmudd/ps-top/wrapper/userlatency"
+	"github.com/sjmudd/ps-top/wrapper/waitevents"
+)
+
+// server holds the connection to a MySQL server and the models
+// collecting from it. Normally there is only one but several are used
+// in fleet mode.
+type server struct {
+	name             string                             // name of the server as given on the command line (fleet mode only)
+	err              error                              // error connecting to the server (fleet mode only)
+	config           *config.Config                     // some config needed by the display
+	db               datasource.DataSource              // connection to MySQL
+	setupInstruments *setupinstruments.SetupInstruments // for setting up and restoring performance_schema configuration.
+	fileinfolatency  pstable.Tabler                     // file i/o latency information
+	tableiolatency   pstable.Tabler                     // table i/o latency information
+	tableioops       pstable.Tabler                     // table i/o operations information
+	tablelocklatency pstable.Tabler                     // table lock information
+	mutexlatency     pstable.Tabler                     // mutex latency information
+	stageslatency    pstable.Tabler                     // stages latency information
+	currentstages    pstable.Tabler                     // stages currently being executed
+	memory           pstable.Tabler                     // memory usage information
+	digests          pstable.Tabler                     // statement digest information
+	threads          pstable.Tabler                     // connection information
+	indexusage       pstable.Tabler                     // index latency information
+	unusedindexes    pstable.Tabler                     // indexes which have not been used
+	lockwaits        pstable.Tabler                     // sessions waiting for locks
+	replication      pstable.Tabler                     // replication threads
+	waits            pstable.Tabler                     // wait events
+	users            pstable.Tabler                     // user information
+	custom           map[string]pstable.Tabler          // views defined by the user by name
+	lost             error                              // why the connection was lost, nil if connected
+	retryAt          time.Time                          // when to try connecting again after losing the connection
+	backoff          time.Duration                      // how long to wait before trying to connect again
+	timedOut         bool                               // did the last query time out?
+	timings          *timings                           // how long collecting each model takes
+	currentEvents    bool                               // have the consumers of the events currently waited on been enabled?
+	failed           []string                           // views whose totals could not be collected (fleet mode only)
+}
+
+// newServer sets up the models to collect from db, which is nil when
+// replaying recorded data
+func newServer(name string, cfg *config.Config, db datasource.DataSource, views []rc.View) (*server, error) {
+	s := &server{
+		name:    name,
+		config:  cfg,
+		db:      db,
+		timings: newTimings(),
+	}
+
+	if s.db != nil {
+		s.setupInstruments = setupinstruments.NewSetupInstruments(s.db)
+		if err := s.setupInstruments.EnableMonitoring(context.Background()); err != nil {
+			s.cleanup()
+			return nil, err
+		}
+	}
+
+	// setup to their initial types/values
+	log.Println("app.newServer() Setup models")
+	s.fileinfolatency = fileinfolatency.NewFileSummaryByInstance(s.config, s.db)
+	temptableiolatency := tableiolatency.NewTableIoLatency(s.config, s.db) // shared backend/metrics
+	s.tableiolatency = temptableiolatency
+	s.tableioops = tableioops.NewTableIoOps(temptableiolatency)
+	s.tablelocklatency = tablelocklatency.NewTableLockLatency(s.config, s.db)
+	s.stageslatency = stageslatency.NewStagesLatency(s.config, s.db)
+	s.currentstages = currentstages.NewCurrentStages(s.config, s.db)
+	s.memory = memoryusage.NewMemoryUsage(s.config, s.db)
+	s.digests = statementdigest.NewStatementDigest(s.config, s.db)
+	s.threads = threads.NewThreads(s.config, s.db)
+	tempindexusage := indexusage.NewIndexUsage(s.config, s.db) // shared backend
+	s.indexusage = tempindexusage
+	s.unusedindexes = unusedindexes.NewUnusedIndexes(tempindexusage)
+	s.lockwaits = lockwaits.NewLockWaits(s.config, s.db)
+	s.replication = replication.NewReplication(s.config, s.db)
+	tempwaits := waitevents.NewWaitEvents(s.config, s.db) // shared backend
+	s.waits = tempwaits
+	s.mutexlatency = mutexlatency.NewMutexLatency(tempwaits)
+	s.users = userlatency.NewUserLatency(s.config, s.db)
+	s.custom = make(map[string]pstable.Tabler)
+	for _, v := range views {
+		custom, err := customview.NewCustomView(s.config, s.db, v.Name, v.Description, v.Query, v.Columns)
+		if err != nil {
+			s.cleanup()
+			return nil, err
+		}
+		s.custom[v.Name] = custom
+	}
+	log.Println("app.newServer() Finished initialising models")
+
+	return s, nil
+}
+
+// tabler returns the model of the given view
+func (s *server) tabler(v view.View) pstable.Tabler {
+	switch v.Get() {
+	case view.ViewLatency:
+		return s.tableiolatency
+	case view.ViewOps:
+		return s.tableioops
+	case view.ViewIO:
+		return s.fileinfolatency
+	case view.ViewLocks:
+		return s.tablelocklatency
+	case view.ViewUsers:
+		return s.users
+	case view.ViewMutex:
+		return s.mutexlatency
+	case view.ViewStages:
+		return s.stageslatency
+	case view.ViewCurrentStages:
+		return s.currentstages
+	case view.ViewMemory:
+		return s.memory
+	case view.ViewDigest:
+		return s.digests
+	case view.ViewThreads:
+		return s.threads
+	case view.ViewIndexes:
+		return s.indexusage
+	case view.ViewUnusedIndexes:
+		return s.unusedindexes
+	case view.ViewLockWaits:
+		return s.lockwaits
+	case view.ViewReplication:
+		return s.replication
+	case view.ViewWaits:
+		return s.waits
+	default:
+		return s.custom[v.Name()]
+	}
+}
+
+// model is a model collected by collectAll with the name of its view
+type model struct {
+	name   string
+	tabler pstable.Tabler
+}
+
+// models returns the models collected by collectAll. Models sharing
+// their data with another model are only included once.
+func (s *server) models() []model {
+	models := []model{
+		{"file_io_latency", s.fileinfolatency},
+		{"table_lock_latency", s.tablelocklatency},
+		{"table_io_latency", s.tableiolatency},
+		{"user_latency", s.users},
+		{"stages_latency", s.stageslatency},
+		{"current_stages", s.currentstages},
+		{"memory_usage", s.memory},
+		{"statement_latency", s.digests},
+		{"threads", s.threads},
+		{"index_io_latency", s.indexusage},
+		{"lock_waits", s.lockwaits},
+		{"replication", s.replication},
+		{"wait_events", s.waits},
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.custom)) {
+		models = append(models, model{name, s.custom[name]})
+	}
+	return models
+}
+
+// collectAll collects all the stats together in one go. The models are
+// independent so they are collected concurrently, using no more
+// connections than the connection pool allows, unless anonymising as
+// the anonymiser can not be used concurrently. The first error in the
+// order of the models is returned.
+func (s *server) collectAll(ctx context.Context) error {
+	log.Println("app.server.collectAll() start", s.name)
+	start := time.Now()
+
+	limit := connector.MaxOpenConns
+	if anonymiser.Enabled() {
+		limit = 1
+	}
+	models := s.models()
+	errs := make([]error, len(models))
+	running := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, m := range models {
+		running <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.collectModel(ctx, m.name, m.tabler)
+			<-running
+		}()
+	}
+	wg.Wait()
+
+	s.timings.collectedAll(time.Since(start))
+	log.Println("app.server.collectAll() finished", s.name, "took", time.Since(start))
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectModel collects a model recording how long it took
+func (s *server) collectModel(ctx context.Context, name string, tabler pstable.Tabler) error {
+	start := time.Now()
+	err := tabler.Collect(ctx)
+	s.timings.collected(name, time.Since(start))
+
+	return err
+}
+
+// refilter applies the filter again to the rows last collected by each
+// model without querying the database
+func (s *server) refilter() {
+	for _, m := range s.models() {
+		if refilterer, ok := m.tabler.(pstable.Refilterer); ok {
+			refilterer.Refilter()
+		}
+	}
+}
+
+// resetStatistics makes the values last collected the initial values
+func (s *server) resetStatistics() {
+	s.fileinfolatency.ResetStatistics()
+	s.tablelocklatency.ResetStatistics()
+	s.tableiolatency.ResetStatistics()
+	s.users.ResetStatistics()
+	s.stageslatency.ResetStatistics()
+	s.currentstages.ResetStatistics()
+	s.memory.ResetStatistics()
+	s.digests.ResetStatistics()
+	s.threads.ResetStatistics()
+	s.indexusage.ResetStatistics()
+	s.lockwaits.ResetStatistics()
+	s.replication.ResetStatistics()
+	s.waits.ResetStatistics()
+	for _, custom := range s.custom {
+		custom.ResetStatistics()
+	}
+}
+
+// cleanup restores the performance_schema configuration and closes the connection
+func (s *server) cleanup() {
+	if s.db != nil {
+		if s.setupInstruments != nil {
+			if err := s.setupInstruments.RestoreConfiguration(); err != nil {
+				log.Printf("app.server.cleanup(): failed to restore the setup_instruments configuration of %q: %v", s.name, err)
+			}
+		}
+		_ = s.db.Close()
+	}
+}
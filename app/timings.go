@@ -0,0 +1,190 @@
+package app
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// timing holds how long collecting a model has taken
+type timing struct {
+	name  string        // name of the view of the model
+	last  time.Duration // time taken by the last collection
+	total time.Duration // time taken by all collections
+	count int           // number of collections
+}
+
+// average returns the average time taken by a collection
+func (t timing) average() time.Duration {
+	if t.count == 0 {
+		return 0
+	}
+	return t.total / time.Duration(t.count)
+}
+
+// timings records how long collecting each model of a server takes.
+// Models may be collected concurrently so access is protected by a mutex.
+type timings struct {
+	mu     sync.Mutex
+	models map[string]timing
+	all    time.Duration // time taken by the last collection of all models together
+}
+
+// newTimings returns timings with nothing collected yet
+func newTimings() *timings {
+	return &timings{models: make(map[string]timing)}
+}
+
+// collected records that collecting the named model took the given time
+func (t *timings) collected(name string, took time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	model := t.models[name]
+	model.name = name
+	model.last = took
+	model.total += took
+	model.count++
+	t.models[name] = model
+}
+
+// collectedAll records that collecting all the models together took the given time
+func (t *timings) collectedAll(took time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.all = took
+}
+
+// sorted returns the timing of each model, the slowest last collection first,
+// and the time taken by the last collection of all models together
+func (t *timings) sorted() ([]timing, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	models := make([]timing, 0, len(t.models))
+	for _, model := range t.models {
+		models = append(models, model)
+	}
+	slices.SortFunc(models, func(a, b timing) int {
+		if c := cmp.Compare(b.last, a.last); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.name, b.name)
+	})
+
+	return models, t.all
+}
+
+// timingsView shows how long collecting each model of the servers takes,
+// which is the load ps-top itself puts on them
+type timingsView struct {
+	servers []*server
+}
+
+// newTimingsView returns the timings of the servers shown
+func (app *App) newTimingsView() timingsView {
+	if app.fleetShown {
+		return timingsView{servers: app.servers}
+	}
+	servers := []*server{app.server}
+	if app.comparing && app.compareWith != nil {
+		servers = append(servers, app.compareWith)
+	}
+	return timingsView{servers: servers}
+}
+
+// connected returns the servers which could be connected to
+func (tv timingsView) connected() []*server {
+	var servers []*server
+	for _, s := range tv.servers {
+		if s.err == nil {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// Description returns how long collecting all the models together last took
+func (tv timingsView) Description() string {
+	var all time.Duration
+	for _, s := range tv.connected() {
+		_, took := s.timings.sorted()
+		all = max(all, took)
+	}
+	return fmt.Sprintf("Collection timings - collecting all models together last took %s (d to return)",
+		strings.TrimSpace(utils.FormatTime(picoseconds(all))))
+}
+
+// Headings returns the headings of the timings
+func (tv timingsView) Headings() string {
+	return fmt.Sprintf("%-40s %10s %10s %10s %8s", "Model", "Last", "Average", "Total", "Count")
+}
+
+// FirstCollectTime returns the current time as the timings are not collected
+func (tv timingsView) FirstCollectTime() time.Time { return time.Now() }
+
+// LastCollectTime returns the current time as the timings are not collected
+func (tv timingsView) LastCollectTime() time.Time { return time.Now() }
+
+// RowContent returns the timing of each model. With several servers
+// the model is prefixed by the server.
+func (tv timingsView) RowContent() []string {
+	servers := tv.connected()
+
+	var rows []string
+	for _, s := range servers {
+		models, _ := s.timings.sorted()
+		for _, model := range models {
+			name := model.name
+			if len(servers) > 1 {
+				name = anonymiser.Anonymise("hostname", s.name) + ": " + name
+			}
+			rows = append(rows, tv.content(name, model.last, model.average(), model.total, model.count))
+		}
+	}
+	return rows
+}
+
+// TotalRowContent returns the timings of all the models added together
+func (tv timingsView) TotalRowContent() string {
+	var last, average, total time.Duration
+	var count int
+	for _, s := range tv.connected() {
+		models, _ := s.timings.sorted()
+		for _, model := range models {
+			last += model.last
+			average += model.average()
+			total += model.total
+			count += model.count
+		}
+	}
+	return tv.content("Totals", last, average, total, count)
+}
+
+// EmptyRowContent returns an empty row
+func (tv timingsView) EmptyRowContent() string { return "" }
+
+// HaveRelativeStats is false as the timings are always since ps-top started
+func (tv timingsView) HaveRelativeStats() bool { return false }
+
+// content returns a printable row of timings
+func (tv timingsView) content(name string, last, average, total time.Duration, count int) string {
+	return fmt.Sprintf("%-40.40s %10s %10s %10s %8d",
+		name,
+		utils.FormatTime(picoseconds(last)),
+		utils.FormatTime(picoseconds(average)),
+		utils.FormatTime(picoseconds(total)),
+		count)
+}
+
+// picoseconds converts a duration to the picoseconds used by performance_schema
+func picoseconds(d time.Duration) uint64 {
+	return uint64(d.Nanoseconds()) * 1000
+}
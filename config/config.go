@@ -4,6 +4,7 @@
 package config
 
 import (
+	"context"
 	"strings"
 
 	"github.com/sjmudd/anonymiser"
@@ -17,6 +18,7 @@ type Config struct {
 	status            *global.Status
 	variables         *global.Variables
 	wantRelativeStats bool
+	uptime            int // uptime last collected
 }
 
 // NewConfig returns the pointer to a new (empty) config
@@ -48,9 +50,22 @@ func (c Config) MySQLVersion() string {
 	return c.variables.Get("version")
 }
 
-// Uptime returns the time that MySQL has been up (in seconds)
+// Uptime returns the time that MySQL has been up (in seconds) when last collected
 func (c Config) Uptime() int {
-	return c.status.Get("Uptime")
+	return c.uptime
+}
+
+// CollectUptime collects the time that MySQL has been up, returning
+// true if it has gone backwards as MySQL has been restarted
+func (c *Config) CollectUptime(ctx context.Context) (bool, error) {
+	uptime, err := c.status.Get(ctx, "Uptime")
+	if err != nil {
+		return false, err
+	}
+	restarted := uptime < c.uptime
+	c.uptime = uptime
+
+	return restarted, nil
 }
 
 // Variables returns a pointer to global.Variables
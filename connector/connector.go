@@ -4,6 +4,7 @@ package connector
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/sjmudd/mysql_defaults_file"
 	"github.com/sjmudd/ps-top/log"
@@ -13,9 +14,10 @@ import (
 type ConnectMethod int
 
 const (
-	db           = "performance_schema" // database to connect to
-	maxOpenConns = 5                    // maximum number of connections the go driver should keep open. Hard-coded value!
-	sqlDriver    = "mysql"              // name of the go-sql-driver to use
+	db        = "performance_schema" // database to connect to
+	sqlDriver = "mysql"              // name of the go-sql-driver to use
+	// MaxOpenConns is the maximum number of connections the go driver should keep open. Hard-coded value!
+	MaxOpenConns = 5
 	// ConnectByDefaultsFile indicates we want to connect using a MySQL defaults file
 	ConnectByDefaultsFile ConnectMethod = iota
 	// ConnectByConfig indicates we want to connect by various components (fields)
@@ -43,6 +45,28 @@ func (c *Connector) SetConnectBy(method ConnectMethod) {
 
 // Connect makes a connection to the database using the previously defined settings
 func (c *Connector) Connect() {
+	if err := c.connect(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Reconnect connects again with the same settings after the connection
+// has been lost, closing the previous connection pool if it succeeds
+func (c *Connector) Reconnect() error {
+	previous := c.DB
+	if err := c.connect(); err != nil {
+		c.DB = previous
+		return err
+	}
+	if previous != nil {
+		_ = previous.Close()
+	}
+
+	return nil
+}
+
+// connect makes a connection to the database returning an error if it fails
+func (c *Connector) connect() error {
 	var err error
 
 	switch c.method {
@@ -67,21 +91,24 @@ func (c *Connector) Connect() {
 		c.DB, err = mysql_defaults_file.OpenUsingEnvironment(sqlDriver)
 
 	default:
-		log.Fatal("Connector.Connect() c.method not ConnectByDefaultsFile/ConnectByConfig/ConnectByEnvironment")
+		return errors.New("Connector.Connect() c.method not ConnectByDefaultsFile/ConnectByConfig/ConnectByEnvironment")
 	}
 
 	// we catch Open...() errors here
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// without calling Ping() we don't actually connect.
 	if err = c.DB.Ping(); err != nil {
-		log.Fatal(err)
+		_ = c.DB.Close()
+		return err
 	}
 
 	// Deliberately limit the pool size to 5 to avoid "problems" if any queries hang.
-	c.DB.SetMaxOpenConns(maxOpenConns)
+	c.DB.SetMaxOpenConns(MaxOpenConns)
+
+	return nil
 }
 
 // ConnectByConfig connects to MySQL using various configuration settings
@@ -1,17 +1,26 @@
 package connector
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/sjmudd/mysql_defaults_file"
 	"github.com/sjmudd/ps-top/utils"
+	go_ini "github.com/vaughan0/go-ini"
 )
 
 // Config holds various command line flags related to connecting to the database
 type Config struct {
-	Host           *string // the host to connect to
+	Hosts          *Hosts  // the hosts to connect to, given as host[:port]
+	HostsFile      *string // name of a file listing more hosts to connect to
+	Groups         *string // groups of the defaults file giving more servers to connect to, separated by commas
 	Socket         *string // the unix socket to connect with
 	Port           *int    // the port to connect to
 	User           *string // the user to connect with
@@ -20,41 +29,266 @@ type Config struct {
 	UseEnvironment *bool   // use the environment to set connection settings?
 }
 
-// NewConnector returns a connected Connector given the provided flags
+// Hosts holds the hosts given by a command line flag which may be repeated
+type Hosts []string
+
+// String returns the hosts separated by commas
+func (h *Hosts) String() string {
+	if h == nil {
+		return ""
+	}
+	return strings.Join(*h, ",")
+}
+
+// Set adds another host
+func (h *Hosts) Set(value string) error {
+	if value == "" {
+		return errors.New("empty host")
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// Target is a server to connect to, given as host[:port] or as a group
+// of the defaults file
+type Target struct {
+	Host  string // host[:port] given with --host or in --hosts-file
+	Group string // group of the defaults file given with --defaults-groups
+}
+
+// String returns the name of the target: the host or the group
+func (t Target) String() string {
+	if t.Group != "" {
+		return t.Group
+	}
+	return t.Host
+}
+
+// Targets returns the hosts given with --host followed by those listed
+// in --hosts-file and the groups given with --defaults-groups
+func (flags Config) Targets() ([]Target, error) {
+	var hosts []string
+
+	if flags.Hosts != nil {
+		hosts = append(hosts, *flags.Hosts...)
+	}
+	if flags.HostsFile != nil && *flags.HostsFile != "" {
+		listed, err := readHostsFile(*flags.HostsFile)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, listed...)
+	}
+
+	var targets []Target
+	for _, host := range hosts {
+		targets = append(targets, Target{Host: host})
+	}
+	if flags.Groups != nil {
+		for _, group := range strings.Split(*flags.Groups, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				targets = append(targets, Target{Group: group})
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// readHostsFile reads a file with one host[:port] per line. Empty lines
+// and everything after a # are ignored.
+func readHostsFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hosts, err := parseHosts(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", filename, err)
+	}
+
+	return hosts, nil
+}
+
+// parseHosts returns the hosts read from a hosts file
+func parseHosts(r io.Reader) ([]string, error) {
+	var hosts []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			hosts = append(hosts, line)
+		}
+	}
+
+	return hosts, scanner.Err()
+}
+
+// splitHostPort splits host[:port] into the host and port, using
+// defaultPort (which may be 0) if no port is given
+func splitHostPort(target string, defaultPort int) (string, uint16, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		// no port given, or an IPv6 address without brackets
+		return target, uint16(defaultPort), nil
+	}
+	number, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || number == 0 {
+		return "", 0, fmt.Errorf("invalid port in %q", target)
+	}
+
+	return host, uint16(number), nil
+}
+
+// defaultsFile returns the name of the defaults file to use, with ~
+// replaced by the home directory as done when connecting with it
+func (flags Config) defaultsFile() string {
+	filename := "~/.my.cnf"
+	if flags.DefaultsFile != nil && *flags.DefaultsFile != "" {
+		filename = *flags.DefaultsFile
+	}
+	return strings.Replace(filename, "~", os.Getenv("HOME"), 1)
+}
+
+// groupConfig returns the settings to connect to the server given by a
+// group of the defaults file. The --user and --password given are used
+// in preference to those of the group.
+func (flags Config) groupConfig(group string) (mysql_defaults_file.Config, error) {
+	filename := flags.defaultsFile()
+	file, err := go_ini.LoadFile(filename)
+	if err != nil {
+		return mysql_defaults_file.Config{}, fmt.Errorf("could not load defaults-file %q: %w", filename, err)
+	}
+	config, err := readGroup(file, group)
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", filename, err)
+	}
+	config.Filename = filename
+
+	if *flags.User != "" {
+		config.User = *flags.User
+	}
+	if *flags.Password != "" {
+		config.Password = *flags.Password
+	}
+
+	return config, nil
+}
+
+// readGroup returns the settings of a group of a defaults file, using
+// those of the [client] group for the settings the group does not have
+func readGroup(file go_ini.File, group string) (mysql_defaults_file.Config, error) {
+	var config mysql_defaults_file.Config
+
+	if _, ok := file[group]; !ok {
+		return config, fmt.Errorf("group [%s] not found", group)
+	}
+	for _, name := range []string{"client", group} {
+		for key, value := range file.Section(name) {
+			switch key {
+			case "host":
+				config.Host = value
+			case "port":
+				port, err := strconv.ParseUint(value, 10, 16)
+				if err != nil {
+					return config, fmt.Errorf("invalid port in group [%s]: %q", name, value)
+				}
+				config.Port = uint16(port)
+			case "socket":
+				config.Socket = value
+			case "user":
+				config.User = unquote(value)
+			case "password":
+				config.Password = unquote(value)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// unquote removes the quotes around a value of the defaults file, if quoted
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// targetConfig returns the settings to connect to the given target, or
+// to the socket if no target is given
+func (flags Config) targetConfig(target Target) (mysql_defaults_file.Config, error) {
+	if target.Group != "" {
+		return flags.groupConfig(target.Group)
+	}
+	return flags.hostConfig(target.Host)
+}
+
+// hostConfig returns the settings to connect to the given host, or
+// to the socket if no host is given
+func (flags Config) hostConfig(target string) (mysql_defaults_file.Config, error) {
+	var config mysql_defaults_file.Config
+
+	if target != "" && *flags.Socket != "" {
+		return config, errors.New("do not specify --host and --socket together")
+	}
+	if *flags.Port != 0 && *flags.Socket != "" {
+		return config, errors.New("do not specify --socket and --port together")
+	}
+	if target != "" {
+		host, port, err := splitHostPort(target, *flags.Port)
+		if err != nil {
+			return config, err
+		}
+		config.Host = host
+		config.Port = port
+	}
+	if *flags.Socket != "" {
+		config.Socket = *flags.Socket
+	}
+	if *flags.User != "" {
+		config.User = *flags.User
+	}
+	if *flags.Password != "" {
+		config.Password = *flags.Password
+	}
+
+	return config, nil
+}
+
+// NewConnector returns a connected Connector given the provided flags.
+// If several hosts or groups are given the first is used.
 func NewConnector(flags Config) *Connector {
 	var defaultsFile string
 	connector := new(Connector)
 
+	targets, err := flags.Targets()
+	if err != nil {
+		fmt.Println(utils.ProgName + ": " + err.Error())
+		os.Exit(1)
+	}
+	var target Target
+	if len(targets) > 0 {
+		target = targets[0]
+	}
+
 	if *flags.UseEnvironment {
 		connector.ConnectByEnvironment()
 	} else {
-		if *flags.Host != "" || *flags.Socket != "" {
-			log.Println("--host= or --socket= defined")
-			var config mysql_defaults_file.Config
-			if *flags.Host != "" && *flags.Socket != "" {
-				fmt.Println(utils.ProgName + ": Do not specify --host and --socket together")
+		if target != (Target{}) || *flags.Socket != "" {
+			log.Println("--host=, --defaults-groups= or --socket= defined")
+			config, err := flags.targetConfig(target)
+			if err != nil {
+				fmt.Println(utils.ProgName + ": " + err.Error())
 				os.Exit(1)
 			}
-			if *flags.Host != "" {
-				config.Host = *flags.Host
-			}
-			if *flags.Port != 0 {
-				if *flags.Socket == "" {
-					config.Port = uint16(*flags.Port)
-				} else {
-					fmt.Println(utils.ProgName + ": Do not specify --socket and --port together")
-					os.Exit(1)
-				}
-			}
-			if *flags.Socket != "" {
-				config.Socket = *flags.Socket
-			}
-			if *flags.User != "" {
-				config.User = *flags.User
-			}
-			if *flags.Password != "" {
-				config.Password = *flags.Password
-			}
 			connector.ConnectByConfig(config)
 		} else {
 			// no host or socket provided so assume connecting by a defaults file.
@@ -74,3 +308,24 @@ func NewConnector(flags Config) *Connector {
 
 	return connector
 }
+
+// NewTargetConnector returns a Connector connected to one of several
+// hosts or groups, returning an error rather than exiting if that fails
+// so that the remaining ones can still be used. The user and password
+// are taken from the flags as when connecting to a single host.
+func NewTargetConnector(flags Config, target Target) (*Connector, error) {
+	if *flags.UseEnvironment {
+		return nil, errors.New("--use-environment can not be used with several hosts")
+	}
+	config, err := flags.targetConfig(target)
+	if err != nil {
+		return nil, err
+	}
+
+	connector := &Connector{config: config, method: ConnectByConfig}
+	if err := connector.connect(); err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+
+	return connector, nil
+}
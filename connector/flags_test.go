@@ -0,0 +1,113 @@
+package connector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sjmudd/mysql_defaults_file"
+	go_ini "github.com/vaughan0/go-ini"
+)
+
+func TestParseHosts(t *testing.T) {
+	input := `# replicas of the main cluster
+db1.example.com
+  db2.example.com:3307   # moved
+
+[::1]:3306
+`
+	expected := []string{"db1.example.com", "db2.example.com:3307", "[::1]:3306"}
+
+	hosts, err := parseHosts(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseHosts() failed: %v", err)
+	}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Errorf("parseHosts() failed: got: %q, expected: %q", hosts, expected)
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		target string
+		host   string
+		port   uint16
+		err    bool
+	}{
+		{"db1", "db1", 3310, false},
+		{"db1:3307", "db1", 3307, false},
+		{"[::1]:3307", "::1", 3307, false},
+		{"::1", "::1", 3310, false},
+		{"db1:port", "", 0, true},
+		{"db1:0", "", 0, true},
+		{"db1:70000", "", 0, true},
+	}
+	for _, test := range tests {
+		host, port, err := splitHostPort(test.target, 3310)
+		if (err != nil) != test.err {
+			t.Errorf("splitHostPort(%q) returned error %v, expected error: %v", test.target, err, test.err)
+			continue
+		}
+		if host != test.host || port != test.port {
+			t.Errorf("splitHostPort(%q) failed: got: %q %d, expected: %q %d", test.target, host, port, test.host, test.port)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	hosts := Hosts{"db1", "db2:3307"}
+	groups := " replica1, ,replica2"
+	flags := Config{Hosts: &hosts, Groups: &groups}
+
+	expected := []Target{{Host: "db1"}, {Host: "db2:3307"}, {Group: "replica1"}, {Group: "replica2"}}
+	targets, err := flags.Targets()
+	if err != nil {
+		t.Fatalf("Targets() failed: %v", err)
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Targets() failed: got: %v, expected: %v", targets, expected)
+	}
+}
+
+func TestReadGroup(t *testing.T) {
+	file, err := go_ini.Load(strings.NewReader(`[client]
+user = "monitor"
+password = 'secret'
+port = 3306
+
+[replica1]
+host = db1.example.com
+port = 3307
+
+[replica2]
+socket = /var/run/mysqld/replica2.sock
+user = other
+
+[broken]
+port = none
+`))
+	if err != nil {
+		t.Fatalf("failed to load the defaults file: %v", err)
+	}
+
+	tests := []struct {
+		group    string
+		expected mysql_defaults_file.Config
+		err      bool
+	}{
+		{"replica1", mysql_defaults_file.Config{Host: "db1.example.com", Port: 3307, User: "monitor", Password: "secret"}, false},
+		{"replica2", mysql_defaults_file.Config{Socket: "/var/run/mysqld/replica2.sock", Port: 3306, User: "other", Password: "secret"}, false},
+		{"broken", mysql_defaults_file.Config{}, true},
+		{"missing", mysql_defaults_file.Config{}, true},
+	}
+	for _, test := range tests {
+		config, err := readGroup(file, test.group)
+		if (err != nil) != test.err {
+			t.Errorf("readGroup(%q) returned error %v, expected error: %v", test.group, err, test.err)
+			continue
+		}
+		if !test.err && config != test.expected {
+			t.Errorf("readGroup(%q) failed: got: %+v, expected: %+v", test.group, config, test.expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Package datasource provides the interface used by ps-top to query
+// MySQL. This allows the models to be driven by something other than a
+// live server, e.g. canned performance_schema result sets in tests.
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Rows is the result of a query. It is implemented by *sql.Rows.
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close() error
+}
+
+// Row is the result of a query which returns at most one row.
+// It is implemented by *sql.Row.
+type Row interface {
+	Scan(dest ...any) error
+}
+
+// DataSource is the interface used to run queries against MySQL.
+// Queries are abandoned when the context is done.
+type DataSource interface {
+	Query(ctx context.Context, query string, args ...any) (Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) Row
+	Exec(ctx context.Context, query string, args ...any) (sql.Result, error)
+	Close() error
+}
+
+// Reconnecter is implemented by a DataSource which can connect to
+// MySQL again after the connection has been lost
+type Reconnecter interface {
+	Reconnect() error
+}
+
+// ConnectionLost returns whether err means the connection to MySQL has
+// been lost, rather than a query failing on a working connection
+func ConnectionLost(err error) bool {
+	var netError net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.As(err, &netError)
+}
+
+// TableMissing returns whether err is MySQL saying that the table
+// queried does not exist, as some performance_schema tables are not
+// available in older versions or MariaDB:
+// Error 1146: Table 'performance_schema.xxx' doesn't exist
+func TableMissing(err error) bool {
+	var mysqlError *mysql.MySQLError
+	return errors.As(err, &mysqlError) && mysqlError.Number == 1146
+}
+
+// AccessDenied returns whether err is MySQL saying that the user may
+// not read the table queried:
+// Error 1142: SELECT command denied to user ...
+// Error 1227: Access denied; you need (at least one of) the PROCESS privilege(s) ...
+func AccessDenied(err error) bool {
+	var mysqlError *mysql.MySQLError
+	return errors.As(err, &mysqlError) && (mysqlError.Number == 1142 || mysqlError.Number == 1227)
+}
+
+// SQL is a DataSource using a database/sql connection pool
+type SQL struct {
+	mu      sync.RWMutex
+	db      *sql.DB
+	connect func() (*sql.DB, error) // connects again, nil if not possible
+}
+
+// NewSQL returns a DataSource which uses the given connection pool
+func NewSQL(db *sql.DB) *SQL {
+	return &SQL{db: db}
+}
+
+// NewReconnectingSQL returns a DataSource which uses the given
+// connection pool, replacing it with the one returned by connect when
+// asked to reconnect
+func NewReconnectingSQL(db *sql.DB, connect func() (*sql.DB, error)) *SQL {
+	return &SQL{db: db, connect: connect}
+}
+
+// pool returns the connection pool in use
+func (s *SQL) pool() *sql.DB {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.db
+}
+
+// Query runs a query returning rows
+func (s *SQL) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	rows, err := s.pool().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// QueryRow runs a query returning at most one row
+func (s *SQL) QueryRow(ctx context.Context, query string, args ...any) Row {
+	return s.pool().QueryRowContext(ctx, query, args...)
+}
+
+// Exec runs a statement which returns no rows
+func (s *SQL) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.pool().ExecContext(ctx, query, args...)
+}
+
+// Reconnect replaces the connection pool with a new one. Without a
+// way to connect again the pool is pinged, which makes database/sql
+// open a new connection if the server can be reached.
+func (s *SQL) Reconnect() error {
+	if s.connect == nil {
+		return s.pool().Ping()
+	}
+	db, err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.db = db
+
+	return nil
+}
+
+// Close closes the connection pool
+func (s *SQL) Close() error {
+	return s.pool().Close()
+}
@@ -0,0 +1,72 @@
+package datasource
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestConnectionLost(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{driver.ErrBadConn, true},
+		{mysql.ErrInvalidConn, true},
+		{fmt.Errorf("reading rows: %w", io.EOF), true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, false},
+		{&mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}, false},
+		{errors.New("some error"), false},
+	}
+
+	for _, test := range tests {
+		if got := ConnectionLost(test.err); got != test.expected {
+			t.Errorf("ConnectionLost(%v) = %v, expected %v", test.err, got, test.expected)
+		}
+	}
+}
+
+func TestTableMissing(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, true},
+		{fmt.Errorf("collecting: %w", &mysql.MySQLError{Number: 1146}), true},
+		{&mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}, false},
+		{driver.ErrBadConn, false},
+	}
+
+	for _, test := range tests {
+		if got := TableMissing(test.err); got != test.expected {
+			t.Errorf("TableMissing(%v) = %v, expected %v", test.err, got, test.expected)
+		}
+	}
+}
+
+func TestAccessDenied(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}, true},
+		{&mysql.MySQLError{Number: 1227, Message: "Access denied"}, true},
+		{&mysql.MySQLError{Number: 1146, Message: "Table doesn't exist"}, false},
+		{driver.ErrBadConn, false},
+	}
+
+	for _, test := range tests {
+		if got := AccessDenied(test.err); got != test.expected {
+			t.Errorf("AccessDenied(%v) = %v, expected %v", test.err, got, test.expected)
+		}
+	}
+}
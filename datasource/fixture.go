@@ -0,0 +1,253 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixture is an in-memory DataSource which returns canned result sets.
+// A query is answered by the first result whose pattern is contained
+// in the query text (ignoring case). Arguments are not checked.
+type Fixture struct {
+	mu       sync.Mutex
+	results  []result
+	executed []string
+}
+
+// result holds the rows or error returned for queries matching pattern
+type result struct {
+	pattern string
+	rows    [][]any
+	err     error
+	delay   time.Duration // how long the query takes
+}
+
+// fixtureResult is returned by Fixture.Exec
+type fixtureResult int64
+
+func (r fixtureResult) LastInsertId() (int64, error) { return 0, nil }
+func (r fixtureResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// NewFixture returns an empty Fixture
+func NewFixture() *Fixture {
+	return &Fixture{}
+}
+
+// Add sets the rows returned by queries matching pattern, replacing
+// any previous result for the same pattern (ignoring case) so that
+// values can be changed between collections.
+func (f *Fixture) Add(pattern string, rows ...[]any) *Fixture {
+	return f.set(result{pattern: pattern, rows: rows})
+}
+
+// AddError sets the error returned by queries matching pattern
+func (f *Fixture) AddError(pattern string, err error) *Fixture {
+	return f.set(result{pattern: pattern, err: err})
+}
+
+// AddDelay sets the rows returned by queries matching pattern once
+// delay has passed, to behave like a slow or hung query
+func (f *Fixture) AddDelay(pattern string, delay time.Duration, rows ...[]any) *Fixture {
+	return f.set(result{pattern: pattern, rows: rows, delay: delay})
+}
+
+func (f *Fixture) set(r result) *Fixture {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.results {
+		if strings.EqualFold(f.results[i].pattern, r.pattern) {
+			f.results[i] = r
+			return f
+		}
+	}
+	f.results = append(f.results, r)
+
+	return f
+}
+
+// find returns the result for the given query
+func (f *Fixture) find(query string) (result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lower := strings.ToLower(query)
+	for _, r := range f.results {
+		if strings.Contains(lower, strings.ToLower(r.pattern)) {
+			return r, nil
+		}
+	}
+
+	return result{}, fmt.Errorf("datasource.Fixture: no result for query: %s", query)
+}
+
+// Executed returns the statements run with Exec including their arguments
+func (f *Fixture) Executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.executed...)
+}
+
+// Query returns the rows of the matching result, or the error of the
+// context if it is done before the delay of the result has passed
+func (f *Fixture) Query(ctx context.Context, query string, _ ...any) (Rows, error) {
+	r, err := f.find(query)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(r.delay):
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &fixtureRows{rows: r.rows, current: -1}, nil
+}
+
+// QueryRow returns the first row of the matching result
+func (f *Fixture) QueryRow(ctx context.Context, query string, args ...any) Row {
+	rows, err := f.Query(ctx, query, args...)
+	return fixtureRow{rows: rows, err: err}
+}
+
+// Exec records the statement and returns the error of the matching
+// result if there is one. Statements without a result succeed.
+func (f *Fixture) Exec(_ context.Context, query string, args ...any) (sql.Result, error) {
+	f.mu.Lock()
+	f.executed = append(f.executed, strings.TrimSpace(fmt.Sprintln(append([]any{query}, args...)...)))
+	f.mu.Unlock()
+
+	if r, err := f.find(query); err == nil && r.err != nil {
+		return nil, r.err
+	}
+
+	return fixtureResult(1), nil
+}
+
+// Close does nothing
+func (f *Fixture) Close() error {
+	return nil
+}
+
+// fixtureRows iterates over the rows of a result
+type fixtureRows struct {
+	rows    [][]any
+	current int
+}
+
+func (r *fixtureRows) Next() bool {
+	r.current++
+	return r.current < len(r.rows)
+}
+
+func (r *fixtureRows) Scan(dest ...any) error {
+	if r.current < 0 || r.current >= len(r.rows) {
+		return errors.New("datasource.Fixture: Scan called without a row")
+	}
+	row := r.rows[r.current]
+	if len(dest) != len(row) {
+		return fmt.Errorf("datasource.Fixture: expected %d destination arguments in Scan, not %d", len(row), len(dest))
+	}
+	for i := range dest {
+		if err := assign(dest[i], row[i]); err != nil {
+			return fmt.Errorf("datasource.Fixture: column %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *fixtureRows) Err() error   { return nil }
+func (r *fixtureRows) Close() error { return nil }
+
+// fixtureRow holds the result of QueryRow
+type fixtureRow struct {
+	rows Rows
+	err  error
+}
+
+func (r fixtureRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if !r.rows.Next() {
+		return sql.ErrNoRows
+	}
+
+	return r.rows.Scan(dest...)
+}
+
+// assign stores value in dest converting between strings and numbers
+// as the MySQL driver would.
+func assign(dest, value any) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return fmt.Errorf("destination %T is not a pointer", dest)
+	}
+	d = d.Elem()
+
+	if value == nil {
+		d.Set(reflect.Zero(d.Type()))
+		return nil
+	}
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	v := reflect.ValueOf(value)
+
+	switch {
+	case d.Kind() == reflect.String:
+		d.SetString(fmt.Sprint(value))
+	case v.Kind() == reflect.String:
+		return parse(d, v.String())
+	case v.Type().ConvertibleTo(d.Type()):
+		d.Set(v.Convert(d.Type()))
+	default:
+		return fmt.Errorf("can not assign %T to %s", value, d.Type())
+	}
+
+	return nil
+}
+
+// parse stores the number in s in d
+func parse(d reflect.Value, s string) error {
+	switch d.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, d.Type().Bits())
+		if err != nil {
+			return err
+		}
+		d.SetFloat(n)
+	default:
+		return fmt.Errorf("can not assign string to %s", d.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAssign(t *testing.T) {
+	var (
+		s  string
+		u  uint64
+		i  int
+		f  float64
+		ns sql.NullString
+		ni sql.NullInt64
+	)
+
+	tests := []struct {
+		dest     any
+		value    any
+		expected any
+	}{
+		{&s, "name", "name"},
+		{&s, []byte("bytes"), "bytes"},
+		{&s, 42, "42"},
+		{&u, uint64(10), uint64(10)},
+		{&u, 11, uint64(11)},
+		{&u, "12", uint64(12)},
+		{&i, "-13", -13},
+		{&f, "1.5", 1.5},
+		{&u, nil, uint64(0)},
+		{&ns, "ok", sql.NullString{String: "ok", Valid: true}},
+		{&ns, nil, sql.NullString{}},
+		{&ni, 14, sql.NullInt64{Int64: 14, Valid: true}},
+	}
+
+	for _, test := range tests {
+		if err := assign(test.dest, test.value); err != nil {
+			t.Errorf("assign(%T, %#v) failed: %v", test.dest, test.value, err)
+			continue
+		}
+		if got := reflect.ValueOf(test.dest).Elem().Interface(); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("assign(%T, %#v) failed: got: %#v, expected: %#v", test.dest, test.value, got, test.expected)
+		}
+	}
+
+	if err := assign(&u, "not a number"); err == nil {
+		t.Errorf("assign() of an invalid number did not return an error")
+	}
+	if err := assign(u, 1); err == nil {
+		t.Errorf("assign() to a non-pointer did not return an error")
+	}
+}
+
+func TestFixture(t *testing.T) {
+	someError := errors.New("some error")
+	f := NewFixture().
+		Add("LIMIT 1", []any{1}).
+		Add("FROM my_table", []any{"a", 1}, []any{"b", 2}).
+		AddError("broken_table", someError)
+	ctx := context.Background()
+
+	// the first matching pattern is used and values can be replaced
+	f.Add("from my_table", []any{"c", 3})
+	rows, err := f.Query(ctx, "SELECT name, value FROM my_table")
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	var got []string
+	for rows.Next() {
+		var (
+			name  string
+			value int
+		)
+		if err := rows.Scan(&name, &value); err != nil {
+			t.Fatalf("Scan() failed: %v", err)
+		}
+		got = append(got, name)
+	}
+	if !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("Query() returned unexpected rows: %v", got)
+	}
+
+	var one int
+	if err := f.QueryRow(ctx, "SELECT 1 FROM my_table LIMIT 1").Scan(&one); err != nil || one != 1 {
+		t.Errorf("QueryRow() failed: got: %v, %v", one, err)
+	}
+	if _, err := f.Query(ctx, "SELECT * FROM broken_table"); err != someError {
+		t.Errorf("Query() did not return the expected error: %v", err)
+	}
+	if _, err := f.Query(ctx, "SELECT * FROM unknown_table"); err == nil {
+		t.Errorf("Query() of an unknown query did not return an error")
+	}
+
+	f.Add("empty_table")
+	if err := f.QueryRow(ctx, "SELECT 1 FROM empty_table").Scan(&one); err != sql.ErrNoRows {
+		t.Errorf("QueryRow() with no rows: expected sql.ErrNoRows, got: %v", err)
+	}
+
+	if _, err := f.Exec(ctx, "UPDATE t SET a = ?", "YES"); err != nil {
+		t.Errorf("Exec() failed: %v", err)
+	}
+	if _, err := f.Exec(ctx, "UPDATE broken_table SET a = 1"); err != someError {
+		t.Errorf("Exec() did not return the expected error: %v", err)
+	}
+	if executed := f.Executed(); !reflect.DeepEqual(executed, []string{"UPDATE t SET a = ? YES", "UPDATE broken_table SET a = 1"}) {
+		t.Errorf("Executed() returned unexpected statements: %q", executed)
+	}
+}
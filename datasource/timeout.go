@@ -0,0 +1,91 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Timeout is a DataSource which abandons each query taking longer than
+// the given time, so that a query which hangs can not stop ps-top from
+// collecting or responding
+type Timeout struct {
+	db      DataSource
+	timeout time.Duration
+}
+
+// NewTimeout returns a DataSource running the queries of db, each of
+// which is abandoned after timeout
+func NewTimeout(db DataSource, timeout time.Duration) *Timeout {
+	return &Timeout{db: db, timeout: timeout}
+}
+
+// Query runs a query returning rows. The time taken to read the rows
+// counts towards the timeout.
+func (t *Timeout) Query(ctx context.Context, query string, args ...any) (Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	rows, err := t.db.Query(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+// QueryRow runs a query returning at most one row
+func (t *Timeout) QueryRow(ctx context.Context, query string, args ...any) Row {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	return timeoutRow{row: t.db.QueryRow(ctx, query, args...), cancel: cancel}
+}
+
+// Exec runs a statement which returns no rows
+func (t *Timeout) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return t.db.Exec(ctx, query, args...)
+}
+
+// Reconnect connects again if the DataSource used can do so
+func (t *Timeout) Reconnect() error {
+	if reconnecter, ok := t.db.(Reconnecter); ok {
+		return reconnecter.Reconnect()
+	}
+	return nil
+}
+
+// Close closes the DataSource used
+func (t *Timeout) Close() error {
+	return t.db.Close()
+}
+
+// timeoutRows stops the timer of the query once all the rows have been
+// read or when the rows are closed
+type timeoutRows struct {
+	Rows
+	cancel context.CancelFunc
+}
+
+func (r timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r timeoutRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// timeoutRow stops the timer of the query once the row has been read
+type timeoutRow struct {
+	row    Row
+	cancel context.CancelFunc
+}
+
+func (r timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
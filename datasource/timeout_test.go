@@ -0,0 +1,61 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	f := NewFixture().
+		Add("FROM fast_table", []any{1}).
+		AddDelay("FROM slow_table", time.Minute, []any{2})
+	db := NewTimeout(f, 10*time.Millisecond)
+	ctx := context.Background()
+
+	// the rows can still be read after the query returns
+	rows, err := db.Query(ctx, "SELECT a FROM fast_table")
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	var a int
+	if !rows.Next() || rows.Scan(&a) != nil || a != 1 {
+		t.Errorf("Query() returned unexpected rows, got: %v", a)
+	}
+	_ = rows.Close()
+	if err := db.QueryRow(ctx, "SELECT a FROM fast_table").Scan(&a); err != nil || a != 1 {
+		t.Errorf("QueryRow() failed: got: %v, %v, expected: 1", a, err)
+	}
+
+	start := time.Now()
+	if _, err := db.Query(ctx, "SELECT a FROM slow_table"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Query() got error: %v, expected: %v", err, context.DeadlineExceeded)
+	}
+	if err := db.QueryRow(ctx, "SELECT a FROM slow_table").Scan(&a); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("QueryRow() got error: %v, expected: %v", err, context.DeadlineExceeded)
+	}
+	if took := time.Since(start); took > 10*time.Second {
+		t.Errorf("queries were not abandoned after the timeout, took: %v", took)
+	}
+
+	// cancelling the context stops the query too
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := NewTimeout(f, time.Minute).Query(cancelled, "SELECT a FROM slow_table"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Query() got error: %v, expected: %v", err, context.Canceled)
+	}
+}
+
+func TestTimeoutRowsRead(t *testing.T) {
+	// the timer is stopped once the rows have been read, even if they are not closed
+	ctx, cancel := context.WithCancel(context.Background())
+	rows := timeoutRows{Rows: &fixtureRows{rows: [][]any{{1}}, current: -1}, cancel: cancel}
+
+	if !rows.Next() || ctx.Err() != nil {
+		t.Fatalf("Next() failed or stopped the timer before reading all rows: %v", ctx.Err())
+	}
+	if rows.Next() || ctx.Err() == nil {
+		t.Errorf("Next() did not stop the timer after reading all rows")
+	}
+}
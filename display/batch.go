@@ -0,0 +1,58 @@
+package display
+
+import (
+	"fmt"
+	"io"
+)
+
+// BatchDisplay writes the wanted view as plain text to a writer
+// rather than to a terminal. It is used when running non-interactively.
+type BatchDisplay struct {
+	config Config
+	writer io.Writer
+	status string // shown instead of the uptime, e.g. when the connection has been lost
+}
+
+// NewBatchDisplay returns a BatchDisplay which writes to the given writer
+func NewBatchDisplay(config Config, writer io.Writer) *BatchDisplay {
+	return &BatchDisplay{
+		config: config,
+		writer: writer,
+	}
+}
+
+// uptime returns config.uptime() protecting against nil pointers
+func (bd *BatchDisplay) uptime() int {
+	if bd == nil || bd.config == nil {
+		return 0
+	}
+	return bd.config.Uptime()
+}
+
+// SetStatus sets the text shown in the top line instead of the uptime, or clears it if empty
+func (bd *BatchDisplay) SetStatus(status string) {
+	bd.status = status
+}
+
+// Display writes the top line, description, headings, rows containing
+// data and the totals, followed by an empty line to separate iterations.
+func (bd *BatchDisplay) Display(gd GenericData) {
+	lines := []string{
+		topLine(bd.config, bd.uptime(), bd.status, gd.HaveRelativeStats(), bd.config.WantRelativeStats(), gd.FirstCollectTime(), gd.LastCollectTime(), 0),
+		gd.Description(),
+		gd.Headings(),
+	}
+
+	// rows without data look the same as an empty row so skip them
+	empty := gd.EmptyRowContent()
+	for _, row := range gd.RowContent() {
+		if row != empty {
+			lines = append(lines, row)
+		}
+	}
+	lines = append(lines, gd.TotalRowContent(), "")
+
+	for _, line := range lines {
+		fmt.Fprintln(bd.writer, line)
+	}
+}
@@ -0,0 +1,46 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct{}
+
+func (testConfig) Hostname() string        { return "myhost" }
+func (testConfig) MySQLVersion() string    { return "8.0.99" }
+func (testConfig) WantRelativeStats() bool { return false }
+func (testConfig) Uptime() int             { return 61 }
+
+type testData struct{}
+
+func (testData) Description() string         { return "Test description" }
+func (testData) Headings() string            { return "Value|Name" }
+func (testData) FirstCollectTime() time.Time { return time.Now() }
+func (testData) LastCollectTime() time.Time  { return time.Now() }
+func (testData) RowContent() []string        { return []string{"    1|a", "     |", "    2|b"} }
+func (testData) TotalRowContent() string     { return "    3|Totals" }
+func (testData) EmptyRowContent() string     { return "     |" }
+func (testData) HaveRelativeStats() bool     { return true }
+
+func TestBatchDisplay(t *testing.T) {
+	var buf bytes.Buffer
+
+	NewBatchDisplay(testConfig{}, &buf).Display(testData{})
+
+	lines := strings.Split(buf.String(), "\n")
+	expected := []string{"Test description", "Value|Name", "    1|a", "    2|b", "    3|Totals", "", ""}
+	if len(lines) != len(expected)+1 {
+		t.Fatalf("BatchDisplay.Display() wrote %d lines, expected %d: %q", len(lines), len(expected)+1, lines)
+	}
+	if !strings.Contains(lines[0], "myhost / 8.0.99, up 1m 1s") || !strings.HasSuffix(lines[0], "[ABS]             ") {
+		t.Errorf("BatchDisplay.Display() unexpected top line: %q", lines[0])
+	}
+	for i := range expected {
+		if lines[i+1] != expected[i] {
+			t.Errorf("BatchDisplay.Display() line %d: got %q, expected %q", i+1, lines[i+1], expected[i])
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tcell "github.com/gdamore/tcell/v2"
@@ -21,6 +22,7 @@ var (
 	descriptionStyle  = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorTeal)
 	headingStyle      = tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack)
 	tableStyle        = tcell.StyleDefault.Foreground(tcell.ColorGrey).Background(tcell.ColorBlack)
+	selectedStyle     = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite)
 	menuStyle         = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorGrey)
 	menuTextStyle     = tcell.StyleDefault.Foreground(tcell.ColorDarkRed).Background(tcell.ColorGrey)
 	bracketStyle      = tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorGrey)
@@ -40,10 +42,40 @@ type Display struct {
 	config    Config
 	screen    tcell.Screen
 	tcellChan chan tcell.Event
-	height    int // display height
-	width     int // display width
+	height    int         // display height
+	width     int         // display width
+	prompt    string      // text being entered at the prompt (shown instead of the menu)
+	prompting atomic.Bool // are key presses being sent to the prompt?
+	filter    string      // current filter on row names (shown in the menu)
+	selected  int         // selected row of the table, -1 if none
+	rows      int         // number of rows in the table last shown
+	detail    atomic.Bool // is a detail pane being shown?
+	status    string      // shown instead of the uptime, e.g. when the connection has been lost
+	shown     shown       // what the top line was last generated from
 }
 
+// shown holds what the top line was last generated from so that the
+// status can be shown without using the data or configuration again,
+// as they may be being collected
+type shown struct {
+	config            shownConfig
+	haveRelativeStats bool
+	initial, last     time.Time
+}
+
+// shownConfig holds the configuration last shown in the top line
+type shownConfig struct {
+	hostname          string
+	version           string
+	wantRelativeStats bool
+	uptime            int
+}
+
+func (c shownConfig) Hostname() string        { return c.hostname }
+func (c shownConfig) MySQLVersion() string    { return c.version }
+func (c shownConfig) WantRelativeStats() bool { return c.wantRelativeStats }
+func (c shownConfig) Uptime() int             { return c.uptime }
+
 // NewDisplay returns a Display with an empty terminal
 func NewDisplay(config Config) *Display {
 	screen, err := tcell.NewScreen()
@@ -67,6 +99,7 @@ func NewDisplay(config Config) *Display {
 		tcellChan: tcellPoller(screen),
 		height:    height,
 		width:     width,
+		selected:  -1,
 	}
 }
 
@@ -99,12 +132,22 @@ func (display *Display) printLine(y int, text string, style tcell.Style) {
 	}
 }
 
-// printTableData displays the provided content, filling lines with an empty row if needed
-func (display *Display) printTableData(content []string, lastRow, maxRows int, emptyRow string, style tcell.Style) {
+// printTableData displays the provided content, filling lines with an empty row if needed.
+// The selected row, if any, is highlighted and the rows are scrolled so it can be seen.
+func (display *Display) printTableData(content []string, lastRow, maxRows int, emptyRow string, style tcell.Style, selected int) {
+	offset := 0
+	if selected >= maxRows {
+		offset = selected - maxRows + 1
+	}
+
 	for k := 0; k < maxRows; k++ {
 		y := 3 + k
-		if k <= len(content)-1 && k < maxRows {
-			display.printLine(y, content[k], style)
+		if i := k + offset; i < len(content) {
+			rowStyle := style
+			if i == selected {
+				rowStyle = selectedStyle
+			}
+			display.printLine(y, content[i], rowStyle)
 		} else {
 			if y < lastRow {
 				display.printLine(y, emptyRow, style)
@@ -113,15 +156,25 @@ func (display *Display) printTableData(content []string, lastRow, maxRows int, e
 	}
 }
 
-// printMenu prints the menu bar at the bottom
+// printMenu prints the menu bar at the bottom, or the prompt if one is being shown
 // - styling - normally inverted style (black on grey), except between [ ] where we use tcell.ColorBlue
 func (display *Display) printMenu(bottomRow int) {
 	const (
-		menu         = "[+-] Delay  [<] Prev  [>] Next  [h]elp  [r] Abs/Rel  [q]uit  [z] Reset stats"
 		openBracket  = rune('[')
 		closeBracket = rune(']')
 	)
 
+	if display.prompting.Load() {
+		display.printPrompt(bottomRow)
+		return
+	}
+	display.screen.HideCursor()
+
+	menu := "[+-] Delay  [<] Prev  [>] Next  [h]elp  [r] Abs/Rel  [s]ort  [/] Filter  [q]uit  [z] Reset stats"
+	if display.filter != "" {
+		menu += "  Filter: " + display.filter
+	}
+
 	style := menuStyle
 	x := 0
 	for _, r := range menu {
@@ -148,37 +201,111 @@ func (display *Display) printMenu(bottomRow int) {
 	}
 }
 
+// printPrompt prints the filter prompt and the text entered so far on the bottom row
+func (display *Display) printPrompt(bottomRow int) {
+	const label = "Filter (regexp or text, empty to clear): "
+
+	text := label + display.prompt
+	display.printLine(bottomRow, text, menuStyle)
+	display.screen.ShowCursor(len([]rune(text)), bottomRow)
+}
+
+// SetPrompt sets the text entered at the prompt and shows it
+func (display *Display) SetPrompt(text string) {
+	display.prompt = text
+	display.printMenu(display.height - 1)
+	display.screen.Show()
+}
+
+// SetFilter sets the filter on row names to show in the menu
+func (display *Display) SetFilter(filter string) {
+	display.filter = filter
+}
+
 // Clear clears the screen and flushes out the result to the terminal
 func (display *Display) Clear() {
 	display.screen.Clear()
 	display.screen.Sync()
 }
 
+// MoveCursor moves the selected row up (delta < 0) or down (delta > 0).
+// If no row is selected the first row is selected.
+func (display *Display) MoveCursor(delta int) {
+	if display.selected < 0 {
+		display.selected = 0
+	} else {
+		display.selected += delta
+	}
+	if display.selected >= display.rows {
+		display.selected = display.rows - 1
+	}
+	if display.selected < 0 {
+		display.selected = 0
+	}
+}
+
+// ClearSelection removes the row selection, e.g. when changing views
+func (display *Display) ClearSelection() {
+	display.selected = -1
+}
+
+// Selected returns the selected row of the table last shown or -1 if none is selected
+func (display *Display) Selected() int {
+	if display.selected >= display.rows {
+		return -1
+	}
+	return display.selected
+}
+
 // Display displays the wanted view to the screen
 func (display *Display) Display(gd GenericData) {
+	display.detail.Store(false)
+	content := gd.RowContent()
+	display.rows = len(content)
+
+	display.show(gd, content, display.selected)
+}
+
+// ShowDetail displays the detail of the selected row. The row selection is kept for when the view is shown again.
+func (display *Display) ShowDetail(gd GenericData) {
+	display.detail.Store(true)
+	display.show(gd, gd.RowContent(), -1)
+}
+
+// show displays the data to the screen highlighting the selected row
+func (display *Display) show(gd GenericData, content []string, selected int) {
 	maxRows := display.height - 5   // maximum number of rows we can show (taking into account headers/footers)
 	lastRow := display.height - 2   // last row where we can print things
 	bottomRow := display.height - 1 // the bottom row where the menu goes
 
-	display.printLine(0,
-		display.generateTopLine(
-			gd.HaveRelativeStats(),
-			display.config.WantRelativeStats(),
-			gd.FirstCollectTime(),
-			gd.LastCollectTime(),
-			display.width,
-		),
-		topLineStyle)
+	display.shown = shown{
+		config: shownConfig{
+			hostname:          display.config.Hostname(),
+			version:           display.config.MySQLVersion(),
+			wantRelativeStats: display.config.WantRelativeStats(),
+			uptime:            display.uptime(),
+		},
+		haveRelativeStats: gd.HaveRelativeStats(),
+		initial:           gd.FirstCollectTime(),
+		last:              gd.LastCollectTime(),
+	}
+	display.printLine(0, display.topLine(), topLineStyle)
 	display.printLine(1, gd.Description(), descriptionStyle) // display table description
 	display.printLine(2, gd.Headings(), headingStyle)
 	// display table headings, data and totals
-	display.printTableData(gd.RowContent(), lastRow, maxRows, gd.EmptyRowContent(), tableStyle)
+	display.printTableData(content, lastRow, maxRows, gd.EmptyRowContent(), tableStyle, selected)
 	display.printLine(lastRow, gd.TotalRowContent(), defaultStyle)
 	display.printMenu(bottomRow)
 
 	display.screen.Show()
 }
 
+// SetConfig changes the configuration shown in the top line, e.g. when
+// changing from one server to another
+func (display *Display) SetConfig(config Config) {
+	display.config = config
+}
+
 // Resize records the new size of the screen and clears it
 func (display *Display) Resize(width, height int) {
 	log.Printf("Display.Resize(width: %v, height: %v), previous values: (width: %v, height: %v)", width, height, display.width, display.height)
@@ -203,6 +330,19 @@ func (display *Display) poll() event.Event {
 	case *tcell.EventKey:
 		log.Printf("tcell.EventKey: %+v", tcellEvent)
 		ev := tcellEvent.(*tcell.EventKey)
+		if display.prompting.Load() {
+			return display.promptEvent(ev)
+		}
+		if display.detail.Load() {
+			switch ev.Key() {
+			case tcell.KeyEsc, tcell.KeyEnter:
+				// switch back here so following keys go to the view
+				display.detail.Store(false)
+				return event.Event{Type: event.EventDetailClose}
+			case tcell.KeyUp, tcell.KeyDown:
+				return e
+			}
+		}
 		switch ev.Key() {
 		case tcell.KeyCtrlZ, tcell.KeyCtrlC, tcell.KeyEsc:
 			e = event.Event{Type: event.EventFinished}
@@ -210,6 +350,14 @@ func (display *Display) poll() event.Event {
 			e = event.Event{Type: event.EventViewPrev}
 		case tcell.KeyTab, tcell.KeyRight:
 			e = event.Event{Type: event.EventViewNext}
+		case tcell.KeyUp:
+			e = event.Event{Type: event.EventCursorUp}
+		case tcell.KeyDown:
+			e = event.Event{Type: event.EventCursorDown}
+		case tcell.KeyEnter:
+			// switch to the detail here so a following Esc closes it
+			display.detail.Store(true)
+			e = event.Event{Type: event.EventDetail}
 		case tcell.KeyRune:
 			switch ev.Rune() {
 			case '-':
@@ -218,10 +366,32 @@ func (display *Display) poll() event.Event {
 				e = event.Event{Type: event.EventIncreasePollTime}
 			case 'h', '?':
 				e = event.Event{Type: event.EventHelp}
+			case 'd':
+				e = event.Event{Type: event.EventTimings}
 			case 'q':
 				e = event.Event{Type: event.EventFinished}
+			case 's':
+				e = event.Event{Type: event.EventSortNext}
+			case 'S':
+				e = event.Event{Type: event.EventSortReverse}
+			case '/':
+				// switch to the prompt here so following keys go to it
+				display.prompting.Store(true)
+				e = event.Event{Type: event.EventFilter}
 			case 't':
 				e = event.Event{Type: event.EventToggleWantRelative}
+			case 'c':
+				e = event.Event{Type: event.EventNextClass}
+			case 'e':
+				e = event.Event{Type: event.EventToggleTree}
+			case 'm':
+				e = event.Event{Type: event.EventToggleTimers}
+			case 'f':
+				e = event.Event{Type: event.EventFleet}
+			case 'x':
+				e = event.Event{Type: event.EventCompare}
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				e = event.Event{Type: event.EventViewNumber, Number: int(ev.Rune() - '0')}
 			}
 		}
 	case *tcell.EventResize:
@@ -238,6 +408,26 @@ func (display *Display) poll() event.Event {
 	return e
 }
 
+// promptEvent converts a key pressed while the prompt is shown to an app event
+func (display *Display) promptEvent(ev *tcell.EventKey) event.Event {
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return event.Event{Type: event.EventFinished}
+	case tcell.KeyEnter:
+		display.prompting.Store(false)
+		return event.Event{Type: event.EventPromptEnter}
+	case tcell.KeyEsc:
+		display.prompting.Store(false)
+		return event.Event{Type: event.EventPromptCancel}
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		return event.Event{Type: event.EventPromptBackspace}
+	case tcell.KeyRune:
+		return event.Event{Type: event.EventPromptRune, Rune: ev.Rune()}
+	}
+
+	return event.Event{Type: event.EventUnknown}
+}
+
 // EventChan creates a channel of display events and run a poller to send
 // these events to the channel.  Return the channel which the application can use
 func (display *Display) EventChan() chan event.Event {
@@ -250,32 +440,64 @@ func (display *Display) EventChan() chan event.Event {
 	return eventChan
 }
 
-// generateTopLine returns the heading line as a string
-func (display *Display) generateTopLine(haveRelativeStats, wantRelativeStats bool, initial, last time.Time, width int) string {
+// topLine returns the heading line of what was last shown as a string
+func (display *Display) topLine() string {
+	shown := display.shown
+	return topLine(shown.config, shown.config.uptime, display.status, shown.haveRelativeStats, shown.config.wantRelativeStats, shown.initial, shown.last, display.width)
+}
+
+// SetStatus sets the text shown in the top line instead of the uptime, or clears it if empty
+func (display *Display) SetStatus(status string) {
+	display.status = status
+}
+
+// ShowStatus shows the status in the top line, leaving the rest of the
+// screen as it is, e.g. while the data shown is being collected again
+func (display *Display) ShowStatus(status string) {
+	display.status = status
+	display.printLine(0, display.topLine(), topLineStyle)
+	display.screen.Show()
+}
+
+// topLine returns the heading line as a string, right aligning the
+// relative / absolute stats indicator if width is large enough.
+// A width of 0 means there is no limit so the indicator is appended.
+// Times are shown relative to last, when the data was collected, so
+// that recorded data is shown as it was seen. A status, if given, is
+// shown instead of the uptime.
+func topLine(config Config, uptimeSeconds int, status string, haveRelativeStats, wantRelativeStats bool, initial, last time.Time, width int) string {
+	if last.IsZero() {
+		last = time.Now()
+	}
+	if status == "" {
+		status = "up " + fmt.Sprintf("%-16s", uptime(uptimeSeconds))
+	}
 	heading := utils.ProgName + " " +
 		utils.Version + " - " +
-		now() + " " +
-		display.config.Hostname() + " / " +
-		display.config.MySQLVersion() + ", up " +
-		fmt.Sprintf("%-16s", uptime(display.uptime()))
+		clock(last) + " " +
+		config.Hostname() + " / " +
+		config.MySQLVersion() + ", " +
+		status
 
 	if haveRelativeStats {
 		var suffix string
 		if wantRelativeStats {
-			suffix = " [REL] " + fmt.Sprintf("%.0f seconds", time.Since(initial).Seconds())
+			suffix = " [REL] " + fmt.Sprintf("%.0f seconds", last.Sub(initial).Seconds())
 		} else {
 			suffix = " [ABS]             "
 		}
-		if len(heading)+len(suffix) < width {
+		switch {
+		case width == 0:
+			heading += suffix
+		case len(heading)+len(suffix) < width:
 			heading += strings.Repeat(" ", width-len(heading)-len(suffix)) + suffix
 		}
 	}
 	return heading
 }
 
-// now returns the current time in format hh:mm:ss
-func now() string {
-	t := time.Now()
+// clock returns the given time in format hh:mm:ss
+func clock(t time.Time) string {
 	return fmt.Sprintf("%2d:%02d:%02d", t.Hour(), t.Minute(), t.Second())
 }
 
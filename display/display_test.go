@@ -0,0 +1,37 @@
+package display
+
+import (
+	"testing"
+)
+
+func TestMoveCursor(t *testing.T) {
+	display := &Display{selected: -1, rows: 3}
+
+	tests := []struct {
+		delta    int
+		expected int
+	}{
+		{-1, 0}, // the first row is selected when there is no selection
+		{1, 1},
+		{1, 2},
+		{1, 2}, // can not move past the last row
+		{-1, 1},
+		{-1, 0},
+		{-1, 0},
+	}
+	for i, test := range tests {
+		display.MoveCursor(test.delta)
+		if got := display.Selected(); got != test.expected {
+			t.Errorf("test %d: MoveCursor(%d) failed: got: %d, expected: %d", i, test.delta, got, test.expected)
+		}
+	}
+
+	display.rows = 0 // the view has changed and is empty
+	if got := display.Selected(); got != -1 {
+		t.Errorf("Selected() with no rows failed: got: %d, expected: -1", got)
+	}
+	display.ClearSelection()
+	if got := display.Selected(); got != -1 {
+		t.Errorf("Selected() after ClearSelection() failed: got: %d, expected: -1", got)
+	}
+}
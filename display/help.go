@@ -23,14 +23,38 @@ func (h HelpType) RowContent() []string {
 		"Keys:",
 		"   - - reduce the poll interval by 1 second (minimum 1 second)",
 		"   + - increase the poll interval by 1 second",
+		"   / - only show rows whose name matches a regexp or text, an empty value clears it",
+		"   d - toggle showing how long collecting each view takes, the load",
+		"       ps-top puts on MySQL",
 		"   h/? - this help screen",
 		"   q - quit",
-		"   s - sort differently (where enabled) - sorts on a different column",
+		"   s - sort differently - sorts on the next column marked with ▼ or ▲",
+		"   S - reverse the sort order",
 		"   t - toggle between showing time since resetting statistics or since P_S data was collected",
+		"   c - show the next class of wait events (wait events view) or the",
+		"       next breakdown of stages or memory usage (stages and memory views)",
+		"   e - toggle showing the wait events as a tree (wait events view)",
+		"   m - toggle between showing how the latency is split and the number",
+		"       of waits with their min/avg/max latency (latency views). Min/max",
+		"       are only kept since the server started so are blank in relative mode",
 		"   z - reset statistics",
 		"   <tab> or <right arrow> - change display modes between: latency, ops,",
-		"                            file I/O, lock and user modes",
+		"                            index latency, unused indexes, file I/O, lock,",
+		"                            lock waits, statement, user, threads,",
+		"                            replication, mutex, wait events, stages,",
+		"                            current stages and memory modes, or the views",
+		"                            configured in ~/.pstoprc",
 		"   <left arrow> - change display modes to the previous screen (see above)",
+		"   1-9 - change to the first to ninth display mode in the order shown",
+		"   <up arrow> / <down arrow> - select a row",
+		"   <enter> - show the detail of the selected row: the statements, lock waits",
+		"             and file I/O of a table or the threads waiting on a mutex or stage.",
+		"             <enter> or <esc> returns to the view. In a tree <enter>",
+		"             expands or collapses the selected row. In the fleet view <enter>",
+		"             shows the views of the selected server",
+		"   f - return to the fleet view (when connected to several servers)",
+		"   x - compare the view of two servers (with --compare) or what has been",
+		"       collected so far with what is collected from now on, press again to stop",
 		"",
 		"Press h to return to main screen",
 	}
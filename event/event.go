@@ -11,11 +11,29 @@ const (
 	EventFinished                       // please exit the program
 	EventViewNext                       // show me the next view
 	EventViewPrev                       // show me the previous view
+	EventViewNumber                     // show me the view with the given number
 	EventDecreasePollTime               // reduce the poll time (if possible)
 	EventIncreasePollTime               // increase the poll time
 	EventHelp                           // provide me with help
+	EventTimings                        // show how long collecting each model takes
 	EventToggleWantRelative             // toggle between wanting absolute or relative stats
 	EventResetStatistics                // reset the current stats back to zero
+	EventSortNext                       // sort by the next column
+	EventSortReverse                    // reverse the sort order
+	EventFilter                         // start entering a filter on row names
+	EventPromptRune                     // a character was typed at the prompt
+	EventPromptBackspace                // remove the last character typed at the prompt
+	EventPromptEnter                    // accept the text typed at the prompt
+	EventPromptCancel                   // leave the prompt without accepting the text
+	EventCursorUp                       // select the previous row
+	EventCursorDown                     // select the next row
+	EventDetail                         // show the detail of the selected row
+	EventDetailClose                    // return from the detail to the view
+	EventNextClass                      // show the next class of rows
+	EventToggleTree                     // switch between showing rows as a tree or a list
+	EventToggleTimers                   // switch between showing the split of the latency or the min/avg/max latency
+	EventFleet                          // show the totals of each server (fleet mode)
+	EventCompare                        // start or stop comparing two servers or time windows
 	EventResizeScreen                   // not really a event but a state change
 	EventUnknown                        // something weird has happened
 	EventError                          // some error
@@ -26,4 +44,6 @@ type Event struct {
 	Type   Type
 	Width  int
 	Height int
+	Rune   rune // character typed (EventPromptRune)
+	Number int  // number of the view (EventViewNumber)
 }
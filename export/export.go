@@ -0,0 +1,206 @@
+// Package export writes the raw rows collected by the models in a
+// structured format (JSON Lines, CSV or TSV) so that they can be
+// processed by other tools without parsing the formatted text output.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format indicates how the exported rows should be written
+type Format int
+
+// Format* constants represent the different output formats
+const (
+	FormatText Format = iota // formatted text as shown on the screen (not handled here)
+	FormatJSON               // JSON Lines, one object per row
+	FormatCSV                // comma separated values with a header line
+	FormatTSV                // tab separated values with a header line
+)
+
+// timeFormat is the format used to show when rows were collected
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+var formatNames = map[Format]string{
+	FormatText: "text",
+	FormatJSON: "json",
+	FormatCSV:  "csv",
+	FormatTSV:  "tsv",
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// ParseFormat returns the Format matching the given name
+func ParseFormat(name string) (Format, error) {
+	if name == "" {
+		return FormatText, nil
+	}
+	for format, formatName := range formatNames {
+		if strings.EqualFold(name, formatName) {
+			return format, nil
+		}
+	}
+	return FormatText, fmt.Errorf("unknown format %q, expected one of: text, json, csv, tsv", name)
+}
+
+// Exporter is implemented by Tablers which can provide the raw rows
+// they have collected. Rows must return a slice of structs.
+type Exporter interface {
+	Rows() any
+}
+
+// Writer writes rows in the requested format
+type Writer struct {
+	format      Format
+	writer      io.Writer
+	csv         *csv.Writer
+	wroteHeader bool
+}
+
+// NewWriter returns a Writer which writes to w in the given format
+func NewWriter(w io.Writer, format Format) *Writer {
+	writer := &Writer{
+		format: format,
+		writer: w,
+	}
+	if format == FormatCSV || format == FormatTSV {
+		writer.csv = csv.NewWriter(w)
+		if format == FormatTSV {
+			writer.csv.Comma = '\t'
+		}
+	}
+
+	return writer
+}
+
+// Write writes the given rows, which must be a slice of structs,
+// adding the time the rows were collected and the view name.
+func (w *Writer) Write(view string, collected time.Time, rows any) error {
+	value := reflect.ValueOf(rows)
+	if value.Kind() != reflect.Slice || value.Type().Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("export.Writer.Write(%q): expected a slice of structs, got %T", view, rows)
+	}
+	when := collected.Format(timeFormat)
+
+	switch w.format {
+	case FormatJSON:
+		return w.writeJSON(view, when, value)
+	case FormatCSV, FormatTSV:
+		return w.writeCSV(view, when, value)
+	}
+	return fmt.Errorf("export.Writer.Write(%q): format %q can not be exported", view, w.format)
+}
+
+// writeJSON writes one JSON object per row. The collection time and
+// view are added before the row's own fields.
+func (w *Writer) writeJSON(view, when string, rows reflect.Value) error {
+	prefix, err := json.Marshal(struct {
+		Collected string `json:"collected"`
+		View      string `json:"view"`
+	}{when, view})
+	if err != nil {
+		return err
+	}
+	prefix = prefix[:len(prefix)-1] // strip the closing brace
+
+	for i := 0; i < rows.Len(); i++ {
+		row, err := json.Marshal(rows.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		line := string(prefix)
+		if len(row) > 2 {
+			line += "," + string(row[1:])
+		} else {
+			line += "}"
+		}
+		if _, err := fmt.Fprintln(w.writer, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCSV writes the header line (once) and then a line per row.
+func (w *Writer) writeCSV(view, when string, rows reflect.Value) error {
+	fields := exportedFields(rows.Type().Elem())
+
+	if !w.wroteHeader {
+		header := []string{"collected", "view"}
+		for _, field := range fields {
+			header = append(header, field.name)
+		}
+		if err := w.csv.Write(header); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	for i := 0; i < rows.Len(); i++ {
+		record := []string{when, view}
+		for _, field := range fields {
+			record = append(record, formatValue(rows.Index(i).Field(field.index)))
+		}
+		if err := w.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// field holds the position and exported name of a struct field
+type field struct {
+	index int
+	name  string
+}
+
+// exportedFields returns the exported fields of the given struct type
+// using the json tag name if one is provided.
+func exportedFields(t reflect.Type) []field {
+	var fields []field
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		name := structField.Name
+		if tag := strings.Split(structField.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, field{index: i, name: name})
+	}
+
+	return fields
+}
+
+// formatValue returns the raw (unformatted) value as a string
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	}
+	return fmt.Sprint(value.Interface())
+}
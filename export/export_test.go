@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	Name      string `json:"name"`
+	CountStar uint64 `json:"count_star"`
+	Bytes     int64
+	hidden    string
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Format
+		wantErr  bool
+	}{
+		{"", FormatText, false},
+		{"text", FormatText, false},
+		{"json", FormatJSON, false},
+		{"CSV", FormatCSV, false},
+		{"tsv", FormatTSV, false},
+		{"xml", FormatText, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseFormat(test.input)
+		if got != test.expected || (err != nil) != test.wantErr {
+			t.Errorf("ParseFormat(%q) failed: got: %v, %v, expected: %v, error: %v", test.input, got, err, test.expected, test.wantErr)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	collected := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	rows := []testRow{
+		{"db.t1", 18446744073709551615, -1, "x"},
+		{"db,t2", 2, 20, "y"},
+	}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			FormatJSON,
+			`{"collected":"2024-01-02T03:04:05.600Z","view":"v","name":"db.t1","count_star":18446744073709551615,"Bytes":-1}` + "\n" +
+				`{"collected":"2024-01-02T03:04:05.600Z","view":"v","name":"db,t2","count_star":2,"Bytes":20}` + "\n",
+		},
+		{
+			FormatCSV,
+			"collected,view,name,count_star,Bytes\n" +
+				"2024-01-02T03:04:05.600Z,v,db.t1,18446744073709551615,-1\n" +
+				"2024-01-02T03:04:05.600Z,v,\"db,t2\",2,20\n",
+		},
+		{
+			FormatTSV,
+			"collected\tview\tname\tcount_star\tBytes\n" +
+				"2024-01-02T03:04:05.600Z\tv\tdb.t1\t18446744073709551615\t-1\n" +
+				"2024-01-02T03:04:05.600Z\tv\tdb,t2\t2\t20\n",
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := NewWriter(&buf, test.format).Write("v", collected, rows); err != nil {
+			t.Errorf("Write(%v) failed: %v", test.format, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("Write(%v) failed: got:\n%s\nexpected:\n%s", test.format, buf.String(), test.expected)
+		}
+	}
+
+	if err := NewWriter(&bytes.Buffer{}, FormatJSON).Write("v", collected, "not a slice"); err == nil {
+		t.Errorf("Write() with invalid rows did not return an error")
+	}
+}
@@ -2,8 +2,11 @@
 package global
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
@@ -17,11 +20,12 @@ var globalStatusTable = informationSchemaGlobalStatus
 
 // Status holds a handle to the database where the status can be queried
 type Status struct {
-	db *sql.DB
+	db     datasource.DataSource
+	values map[string]int // values to use if there is no database
 }
 
 // NewStatus returns a *Status structure to the user
-func NewStatus(db *sql.DB) *Status {
+func NewStatus(db datasource.DataSource) *Status {
 	if db == nil {
 		log.Fatal("NewStatus() db is nil")
 	}
@@ -30,6 +34,20 @@ func NewStatus(db *sql.DB) *Status {
 	}
 }
 
+// NewStaticStatus returns a *Status which does not query the database
+// but returns values previously provided with Set. It is used when
+// replaying recorded data.
+func NewStaticStatus() *Status {
+	return &Status{
+		values: make(map[string]int),
+	}
+}
+
+// Set sets the value of a status variable of a static Status
+func (status *Status) Set(name string, value int) {
+	status.values[name] = value
+}
+
 /*
 ** mysql> select VARIABLE_VALUE from global_status where VARIABLE_NAME = 'UPTIME';
 * +----------------+
@@ -42,24 +60,22 @@ func NewStatus(db *sql.DB) *Status {
 
 // Get returns the value of the variable name requested (if found), or if not an error
 // - note: we assume we have checked a variable first as there's no logic here to switch between I_S and P_S
-func (status *Status) Get(name string) int {
+func (status *Status) Get(ctx context.Context, name string) (int, error) {
 	var value int
 
+	if status.db == nil {
+		return status.values[name], nil
+	}
+
 	query := "SELECT VARIABLE_VALUE FROM " + globalStatusTable + " WHERE VARIABLE_NAME = ?"
 
-	err := status.db.QueryRow(query, name).Scan(&value)
-	switch {
-	case err == sql.ErrNoRows:
+	err := status.db.QueryRow(ctx, query, name).Scan(&value)
+	if err == sql.ErrNoRows {
 		log.Println("Status.Get("+name+"): no status with this name, query:", query)
-	case err != nil:
-		log.Fatal(err)
-	default:
-		// fmt.Println("value for", name, "is", value)
 	}
-
 	if err != nil {
-		log.Fatal("Unable to retrieve status for '"+name+"':", err)
+		return 0, fmt.Errorf("unable to retrieve status for %q: %w", name, err)
 	}
 
-	return value
+	return value, nil
 }
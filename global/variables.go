@@ -2,10 +2,12 @@
 package global
 
 import (
-	"database/sql"
+	"context"
+	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
@@ -28,7 +30,7 @@ var globalVariablesTable = informationSchemaGlobalVariables // default
 
 // Variables holds the handle and variables collected from the database
 type Variables struct {
-	db        *sql.DB
+	db        datasource.DataSource
 	variables map[string]string
 }
 
@@ -68,7 +70,7 @@ func IsMysqlError(err error, wantedErrNum int) bool {
 }
 
 // NewVariables returns a pointer to an initialised Variables structure with one collection done.
-func NewVariables(db *sql.DB) *Variables {
+func NewVariables(db datasource.DataSource) (*Variables, error) {
 	if db == nil {
 		log.Fatal("NewVariables(): db == nil")
 	}
@@ -76,7 +78,19 @@ func NewVariables(db *sql.DB) *Variables {
 	v := &Variables{
 		db: db,
 	}
-	return v.selectAll()
+	if err := v.selectAll(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// NewVariablesFromMap returns a pointer to a Variables structure holding
+// the given values, e.g. when replaying previously recorded data.
+// Keys are expected to be lower-cased.
+func NewVariablesFromMap(variables map[string]string) *Variables {
+	return &Variables{
+		variables: variables,
+	}
 }
 
 // Get returns the value of the given variable if found or an empty string if not.
@@ -93,13 +107,13 @@ func (v Variables) Get(key string) string {
 
 // selectAll collects all variables from the database and stores for later use.
 // - all returned keys are lower-cased.
-func (v *Variables) selectAll() *Variables {
+func (v *Variables) selectAll() error {
 	hashref := make(map[string]string)
 
 	query := "SELECT VARIABLE_NAME, VARIABLE_VALUE FROM " + globalVariablesTable
 	log.Println("query:", query)
 
-	rows, err := v.db.Query(query)
+	rows, err := v.db.Query(context.Background(), query)
 	if err != nil {
 		if !seenCompatibilityError && (IsMysqlError(err, showCompatibility56ErrorNum) || IsMysqlError(err, globalVariablesNotInISErrorNum)) {
 			log.Println("selectAll() I_S query failed, trying with P_S")
@@ -107,10 +121,10 @@ func (v *Variables) selectAll() *Variables {
 			query = "SELECT VARIABLE_NAME, VARIABLE_VALUE FROM " + globalVariablesTable
 			log.Println("query:", query)
 
-			rows, err = v.db.Query(query)
+			rows, err = v.db.Query(context.Background(), query)
 		}
 		if err != nil {
-			log.Fatal("selectAll() query", query, "failed with:", err)
+			return fmt.Errorf("selectAll() query %s failed with: %w", query, err)
 		}
 	}
 	log.Println("selectAll() query succeeded")
@@ -118,12 +132,14 @@ func (v *Variables) selectAll() *Variables {
 	for rows.Next() {
 		var variable, value string
 		if err := rows.Scan(&variable, &value); err != nil {
-			log.Fatal(err)
+			_ = rows.Close()
+			return err
 		}
 		hashref[strings.ToLower(variable)] = value
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return err
 	}
 	_ = rows.Close()
 
@@ -131,5 +147,5 @@ func (v *Variables) selectAll() *Variables {
 
 	v.variables = hashref
 
-	return v
+	return nil
 }
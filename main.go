@@ -7,13 +7,16 @@ import (
 	"fmt"
 	"os"
 	"runtime/pprof"
+	"time"
 
 	"github.com/howeyc/gopass"
 
 	"github.com/sjmudd/ps-top/app"
 	"github.com/sjmudd/ps-top/connector"
+	"github.com/sjmudd/ps-top/export"
 	"github.com/sjmudd/ps-top/log"
 	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/rc"
 	"github.com/sjmudd/ps-top/utils"
 )
 
@@ -21,15 +24,25 @@ var (
 	connectorFlags connector.Config
 
 	// command line flags
-	cpuprofile         = flag.String("cpuprofile", "", "write cpu profile to file")
-	flagAnonymise      = flag.Bool("anonymise", false, "Anonymise hostname, user, db and table names (default: false)")
-	flagAskpass        = flag.Bool("askpass", false, "Ask for password interactively")
-	flagDatabaseFilter = flag.String("database-filter", "", "Optional comma-separated filter of database names")
-	flagDebug          = flag.Bool("debug", false, "Enabling debug logging")
-	flagHelp           = flag.Bool("help", false, "Provide some help for "+utils.ProgName)
-	flagInterval       = flag.Int("interval", 1, "Set the initial poll interval (default 1 second)")
-	flagVersion        = flag.Bool("version", false, "Show the version of "+utils.ProgName)
-	flagView           = flag.String("view", "", "Provide view to show when starting "+utils.ProgName+" (default: table_io_latency)")
+	cpuprofile          = flag.String("cpuprofile", "", "write cpu profile to file")
+	flagAnonymise       = flag.Bool("anonymise", false, "Anonymise hostname, user, db and table names (default: false)")
+	flagAskpass         = flag.Bool("askpass", false, "Ask for password interactively")
+	flagBatch           = flag.Bool("batch", false, "Run non-interactively writing the view to stdout")
+	flagCompare         = flag.Bool("compare", false, "Compare the views of the two hosts given rather than showing a fleet view")
+	flagCount           = flag.Int("count", 1, "Number of iterations to show in batch mode (0: run until interrupted)")
+	flagDatabaseExclude = flag.String("database-exclude", "", "Optional comma-separated list of database names to exclude")
+	flagDatabaseFilter  = flag.String("database-filter", "", "Optional comma-separated filter of database names")
+	flagDebug           = flag.Bool("debug", false, "Enabling debug logging")
+	flagFormat          = flag.String("format", "text", "Output format in batch mode: text, json, csv or tsv")
+	flagHelp            = flag.Bool("help", false, "Provide some help for "+utils.ProgName)
+	flagInterval        = flag.Int("interval", 1, "Set the initial poll interval (default 1 second)")
+	flagListenMetrics   = flag.String("listen-metrics", "", "Run headless serving Prometheus metrics on the given address, e.g. :9105")
+	flagQueryTimeout    = flag.Int("query-timeout", 10, "Seconds a query may take before it is abandoned (0: no limit)")
+	flagRecord          = flag.String("record", "", "Record the collected data to the given file for later replay")
+	flagReplay          = flag.String("replay", "", "Replay previously recorded data from the given file instead of connecting to MySQL")
+	flagTableFilter     = flag.String("table-filter", "", "Optional comma-separated filter of table names, e.g. db.table")
+	flagVersion         = flag.Bool("version", false, "Show the version of "+utils.ProgName)
+	flagView            = flag.String("view", "", "Provide view to show when starting "+utils.ProgName+" (default: table_io_latency)")
 
 	getPasswdFunc = gopass.GetPasswd // to allow me to test
 )
@@ -46,19 +59,32 @@ func usage() {
 		"Options:",
 		"--anonymise=<true|false>                 Anonymise hostname, user, db and table names",
 		"--askpass                                Request password to be provided interactively",
+		"--batch                                  Run non-interactively, writing the view to stdout every interval",
+		"--compare                                Compare the views of the two hosts given side by side",
+		"--count=<iterations>                     Number of iterations to show in batch mode, default 1 (0 means run until interrupted)",
+		"--database-exclude=db1[,db2,db3,...]     Optional database names to exclude, default ''",
 		"--database-filter=db1[,db2,db3,...]      Optional database names to filter on, default ''",
 		"--defaults-file=/path/to/defaults.file   Connect to MySQL using given defaults-file, default ~/.my.cnf",
+		"--defaults-groups=group1[,group2,...]    Also connect to the servers given by these groups of the defaults-file",
+		"--format=<text|json|csv|tsv>             Output format in batch mode, default text. Formats other than text write the raw collected values and imply --batch",
 		"--help                                   Show this help message",
-		"--host=<hostname>                        MySQL host to connect to",
+		"--host=<hostname>[:port]                 MySQL host to connect to. Repeat to show a summary of several hosts",
+		"--hosts-file=/path/to/hosts.file         Also connect to the hosts listed in the file, one host[:port] per line",
 		"--interval=<seconds>                     Set the default poll interval (in seconds)",
+		"--listen-metrics=<[host]:port>           Run headless serving Prometheus metrics on http://<[host]:port>/metrics",
 		"--password=<password>                    Password to use when connecting",
 		"--port=<port>                            MySQL port to connect to",
+		"--query-timeout=<seconds>                Abandon queries taking longer than this, default 10 (0 means no limit)",
+		"--record=<file>                          Record the data collected for all views to <file> so it can be replayed later",
+		"--replay=<file>                          Replay data recorded with --record instead of connecting to MySQL",
 		"--socket=<path>                          MySQL path of the socket to connect to",
+		"--table-filter=[db.]tbl1[,...]           Optional table names to filter on, default ''",
 		"--user=<user>                            User to connect with",
 		"--use-environment                        Connect to MySQL using a go dsn collected from MYSQL_DSN e.g. MYSQL_DSN='test_user:test_pass@tcp(127.0.0.1:3306)/performance_schema'",
 		"--version                                Show the version",
-		"--view=<view>                            Determine the view you want to see when " + utils.ProgName + " starts (default: table_io_latency)",
-		"                                         Possible values: table_io_latency table_io_ops file_io_latency table_lock_latency user_latency mutex_latency stages_latency",
+		"--view=<view>                            Determine the view you want to see when " + utils.ProgName + " starts (default: the first view, table_io_latency unless configured)",
+		"                                         Possible values: table_io_latency table_io_ops index_io_latency unused_indexes file_io_latency table_lock_latency lock_waits user_latency replication mutex_latency wait_events stages_latency current_stages memory_usage statement_latency threads",
+		"                                         or a view defined in ~/.pstoprc",
 	}
 
 	for _, line := range lines {
@@ -82,25 +108,34 @@ func askPass() (string, error) {
 func connectorConfig() connector.Config {
 	config := connector.Config{
 		DefaultsFile:   flag.String("defaults-file", "", "Define the defaults file to read"),
-		Host:           flag.String("host", "", "Provide the hostname of the MySQL to connect to"),
+		Groups:         flag.String("defaults-groups", "", "Provide groups of the defaults file giving the MySQL servers to connect to, separated by commas"),
+		Hosts:          new(connector.Hosts),
+		HostsFile:      flag.String("hosts-file", "", "Provide a file listing the MySQL hosts to connect to, one host[:port] per line"),
 		Password:       flag.String("password", "", "Provide the password when connecting to the MySQL server"),
 		Port:           flag.Int("port", 0, "Provide the port number of the MySQL to connect to (default: 3306)"), /* Port is deliberately 0 here, defaults to 3306 elsewhere */
 		Socket:         flag.String("socket", "", "Provide the path to the local MySQL server to connect to"),
 		User:           flag.String("user", "", "Provide the username to connect with to MySQL (default: $USER)"),
 		UseEnvironment: flag.Bool("use-environment", false, "Use the environment variable MYSQL_DSN (go dsn) to connect with to MySQL"),
 	}
+	flag.Var(config.Hosts, "host", "Provide the hostname[:port] of the MySQL to connect to, repeat to connect to several")
 
 	return config
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs ps-top and returns the exit code. Exiting is left to main so
+// that the deferred calls, e.g. stopping the CPU profile, are made first.
+func run() int {
 	connectorFlags = connectorConfig()
 
 	flag.Parse()
 
 	if *flagHelp {
 		usage()
-		return
+		return 0
 	}
 
 	// Enable logging if requested or PSTOP_DEBUG=1
@@ -111,7 +146,7 @@ func main() {
 		password, err := askPass()
 		if err != nil {
 			fmt.Printf("Failed to read password: %v\n", err)
-			return
+			return 1
 		}
 		connectorFlags.Password = &password
 	}
@@ -121,6 +156,7 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		defer f.Close()
 		if err := pprof.StartCPUProfile(f); err != nil {
 			log.Fatal("could not start CPU profile: ", err)
 		}
@@ -129,21 +165,63 @@ func main() {
 
 	if *flagVersion {
 		fmt.Println(utils.ProgName + " version " + utils.Version)
-		return
+		return 0
+	}
+
+	format, err := export.ParseFormat(*flagFormat)
+	if err != nil {
+		fmt.Printf("%s: --format: %v\n", utils.ProgName, err)
+		return 1
+	}
+
+	if *flagCount < 0 {
+		fmt.Printf("%s: --count=%d must not be negative\n", utils.ProgName, *flagCount)
+		return 1
+	}
+
+	if *flagQueryTimeout < 0 {
+		fmt.Printf("%s: --query-timeout=%d must not be negative\n", utils.ProgName, *flagQueryTimeout)
+		return 1
+	}
+
+	if *flagReplay != "" && (*flagRecord != "" || *flagListenMetrics != "") {
+		fmt.Printf("%s: --replay can not be combined with --record or --listen-metrics\n", utils.ProgName)
+		return 1
+	}
+
+	databaseFilter, err := filter.NewFilter(*flagDatabaseFilter, *flagDatabaseExclude, *flagTableFilter)
+	if err != nil {
+		fmt.Printf("%s: invalid filter: %v\n", utils.ProgName, err)
+		return 1
 	}
 
 	app, err := app.NewApp(
 		connectorFlags,
 		app.Settings{
-			Anonymise: *flagAnonymise,
-			Filter:    filter.NewDatabaseFilter(*flagDatabaseFilter),
-			Interval:  *flagInterval,
-			ViewName:  *flagView,
+			Anonymise:    *flagAnonymise,
+			Batch:        *flagBatch || format != export.FormatText,
+			Compare:      *flagCompare,
+			Count:        *flagCount,
+			Format:       format,
+			Filter:       databaseFilter,
+			Interval:     *flagInterval,
+			QueryTimeout: time.Duration(*flagQueryTimeout) * time.Second,
+			ViewName:     *flagView,
+			ViewOrder:    rc.ViewOrder(),
+			Views:        rc.Views(),
+
+			ListenMetrics: *flagListenMetrics,
+			Record:        *flagRecord,
+			Replay:        *flagReplay,
 		},
 	)
 
 	if err != nil {
-		log.Fatalf("Failed to start %s: %s", utils.ProgName, err)
+		log.Printf("Failed to start %s: %s", utils.ProgName, err)
+		fmt.Fprintf(os.Stderr, "Failed to start %s: %s\n", utils.ProgName, err)
+		return 1
 	}
 	app.Run()
+
+	return 0
 }
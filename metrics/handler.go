@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/sjmudd/ps-top/log"
+)
+
+// Handler serves the most recently generated metrics over HTTP.
+// Metrics are generated after each collection so scraping does not
+// access the models while they are being updated.
+type Handler struct {
+	mu      sync.RWMutex
+	content []byte
+}
+
+// NewHandler returns a Handler with no metrics
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+// Update generates the metrics to serve from the given sets of rows
+func (h *Handler) Update(rowSets ...any) {
+	m := New()
+	for _, rows := range rowSets {
+		if err := m.Add(rows); err != nil {
+			log.Println("metrics.Handler.Update():", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		log.Println("metrics.Handler.Update(): failed to generate metrics:", err)
+		return
+	}
+
+	h.mu.Lock()
+	h.content = buf.Bytes()
+	h.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics. Until the first collection has
+// completed we return 503 Service Unavailable.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.mu.RLock()
+	content := h.content
+	h.mu.RUnlock()
+
+	if content == nil {
+		http.Error(w, "no metrics collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(content)
+}
@@ -0,0 +1,320 @@
+// Package metrics converts the rows collected by the models into
+// Prometheus metrics using the text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sjmudd/ps-top/model/fileinfo"
+	"github.com/sjmudd/ps-top/model/memoryusage"
+	"github.com/sjmudd/ps-top/model/mutexlatency"
+	"github.com/sjmudd/ps-top/model/replication"
+	"github.com/sjmudd/ps-top/model/stageslatency"
+	"github.com/sjmudd/ps-top/model/tableio"
+	"github.com/sjmudd/ps-top/model/tablelocks"
+	"github.com/sjmudd/ps-top/model/userlatency"
+)
+
+const (
+	prefix               = "pstop_"
+	picosecondsPerSecond = 1e12
+	counter              = "counter"
+	gauge                = "gauge"
+)
+
+// label is a metric label name and value
+type label struct {
+	name  string
+	value string
+}
+
+// sample is a single value of a metric with its labels
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// family holds all the samples of a metric
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+	byKey   map[string]int // index of the sample with the given labels
+}
+
+// add adds a sample to the family given the label name and value pairs.
+// Rows whose names become the same once munged or anonymised would give
+// the same series more than once, so samples with the same labels are
+// summed.
+func (f *family) add(value float64, labelPairs ...string) {
+	s := sample{value: value}
+	for i := 0; i+1 < len(labelPairs); i += 2 {
+		s.labels = append(s.labels, label{labelPairs[i], labelPairs[i+1]})
+	}
+
+	key := strings.Join(labelPairs, "\xff")
+	if i, found := f.byKey[key]; found {
+		f.samples[i].value += value
+		return
+	}
+	if f.byKey == nil {
+		f.byKey = make(map[string]int)
+	}
+	f.byKey[key] = len(f.samples)
+	f.samples = append(f.samples, s)
+}
+
+// Metrics holds the metric families generated from the collected rows
+type Metrics struct {
+	families []*family
+	byName   map[string]*family
+}
+
+// New returns an empty set of metrics
+func New() *Metrics {
+	return &Metrics{
+		byName: make(map[string]*family),
+	}
+}
+
+// family returns the named family creating it if needed
+func (m *Metrics) family(name, typ, help string) *family {
+	name = prefix + name
+	if f, found := m.byName[name]; found {
+		return f
+	}
+	f := &family{name: name, help: help, typ: typ}
+	m.families = append(m.families, f)
+	m.byName[name] = f
+
+	return f
+}
+
+// seconds converts picoseconds to seconds
+func seconds(picoseconds uint64) float64 {
+	return float64(picoseconds) / picosecondsPerSecond
+}
+
+// splitTableName splits a <schema>.<table> name into its components
+func splitTableName(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Add converts the given rows, as returned by export.Exporter.Rows(),
+// into metrics. It returns an error if the type of rows is not known.
+func (m *Metrics) Add(rows any) error {
+	switch rows := rows.(type) {
+	case tableio.Rows:
+		m.addTableIo(rows)
+	case fileinfo.Rows:
+		m.addFileInfo(rows)
+	case tablelocks.Rows:
+		m.addTableLocks(rows)
+	case mutexlatency.Rows:
+		m.addMutexLatency(rows)
+	case stageslatency.Rows:
+		m.addStagesLatency(rows)
+	case []memoryusage.Row:
+		m.addMemoryUsage(rows)
+	case userlatency.Rows:
+		m.addUserLatency(rows)
+	case replication.Rows:
+		m.addReplication(rows)
+	default:
+		return fmt.Errorf("metrics.Add(): unexpected rows of type %T", rows)
+	}
+
+	return nil
+}
+
+func (m *Metrics) addTableIo(rows tableio.Rows) {
+	latency := m.family("table_io_wait_seconds_total", counter, "Time spent waiting on table I/O by operation (table_io_waits_summary_by_table)")
+	ops := m.family("table_io_operations_total", counter, "Number of table I/O operations by operation (table_io_waits_summary_by_table)")
+
+	for _, row := range rows {
+		schema, table := splitTableName(row.Name)
+		for _, op := range []struct {
+			name  string
+			timer uint64
+			count uint64
+		}{
+			{"fetch", row.SumTimerFetch, row.CountFetch},
+			{"insert", row.SumTimerInsert, row.CountInsert},
+			{"update", row.SumTimerUpdate, row.CountUpdate},
+			{"delete", row.SumTimerDelete, row.CountDelete},
+		} {
+			latency.add(seconds(op.timer), "schema", schema, "table", table, "operation", op.name)
+			ops.add(float64(op.count), "schema", schema, "table", table, "operation", op.name)
+		}
+	}
+}
+
+func (m *Metrics) addFileInfo(rows fileinfo.Rows) {
+	latency := m.family("file_io_wait_seconds_total", counter, "Time spent waiting on file I/O by operation (file_summary_by_instance)")
+	ops := m.family("file_io_operations_total", counter, "Number of file I/O operations by operation (file_summary_by_instance)")
+	bytes := m.family("file_io_bytes_total", counter, "Number of bytes read or written (file_summary_by_instance)")
+
+	for _, row := range rows {
+		latency.add(seconds(row.SumTimerRead), "file", row.Name, "operation", "read")
+		latency.add(seconds(row.SumTimerWrite), "file", row.Name, "operation", "write")
+		latency.add(seconds(row.SumTimerMisc), "file", row.Name, "operation", "misc")
+		ops.add(float64(row.CountRead), "file", row.Name, "operation", "read")
+		ops.add(float64(row.CountWrite), "file", row.Name, "operation", "write")
+		ops.add(float64(row.CountMisc), "file", row.Name, "operation", "misc")
+		bytes.add(float64(row.SumNumberOfBytesRead), "file", row.Name, "operation", "read")
+		bytes.add(float64(row.SumNumberOfBytesWrite), "file", row.Name, "operation", "write")
+	}
+}
+
+func (m *Metrics) addTableLocks(rows tablelocks.Rows) {
+	latency := m.family("table_lock_wait_seconds_total", counter, "Time spent waiting on table locks by lock type (table_lock_waits_summary_by_table)")
+
+	for _, row := range rows {
+		schema, table := splitTableName(row.Name)
+		for _, lock := range []struct {
+			name  string
+			timer uint64
+		}{
+			{"read_with_shared_locks", row.SumTimerReadWithSharedLocks},
+			{"read_high_priority", row.SumTimerReadHighPriority},
+			{"read_no_insert", row.SumTimerReadNoInsert},
+			{"read_normal", row.SumTimerReadNormal},
+			{"read_external", row.SumTimerReadExternal},
+			{"write_allow_write", row.SumTimerWriteAllowWrite},
+			{"write_concurrent_insert", row.SumTimerWriteConcurrentInsert},
+			{"write_low_priority", row.SumTimerWriteLowPriority},
+			{"write_normal", row.SumTimerWriteNormal},
+			{"write_external", row.SumTimerWriteExternal},
+		} {
+			latency.add(seconds(lock.timer), "schema", schema, "table", table, "lock_type", lock.name)
+		}
+	}
+}
+
+func (m *Metrics) addMutexLatency(rows mutexlatency.Rows) {
+	latency := m.family("mutex_wait_seconds_total", counter, "Time spent waiting on InnoDB mutexes (events_waits_summary_global_by_event_name)")
+	waits := m.family("mutex_waits_total", counter, "Number of waits on InnoDB mutexes (events_waits_summary_global_by_event_name)")
+
+	for _, row := range rows {
+		latency.add(seconds(row.SumTimerWait), "event_name", row.Name)
+		waits.add(float64(row.CountStar), "event_name", row.Name)
+	}
+}
+
+func (m *Metrics) addStagesLatency(rows stageslatency.Rows) {
+	latency := m.family("stage_seconds_total", counter, "Time spent in SQL stages (events_stages_summary_global_by_event_name)")
+	count := m.family("stages_total", counter, "Number of SQL stages executed (events_stages_summary_global_by_event_name)")
+
+	for _, row := range rows {
+		latency.add(seconds(row.SumTimerWait), "event_name", row.Name)
+		count.add(float64(row.CountStar), "event_name", row.Name)
+	}
+}
+
+func (m *Metrics) addMemoryUsage(rows []memoryusage.Row) {
+	current := m.family("memory_current_bytes", gauge, "Memory currently in use (memory_summary_global_by_event_name)")
+	high := m.family("memory_high_bytes", gauge, "High water mark of memory in use (memory_summary_global_by_event_name)")
+	allocations := m.family("memory_current_allocations", gauge, "Number of current memory allocations (memory_summary_global_by_event_name)")
+	ops := m.family("memory_operations_total", counter, "Number of memory allocations and frees (memory_summary_global_by_event_name)")
+
+	for _, row := range rows {
+		current.add(float64(row.CurrentBytesUsed), "event_name", row.Name)
+		high.add(float64(row.HighBytesUsed), "event_name", row.Name)
+		allocations.add(float64(row.CurrentCountUsed), "event_name", row.Name)
+		ops.add(float64(row.TotalMemoryOps), "event_name", row.Name)
+	}
+}
+
+func (m *Metrics) addUserLatency(rows userlatency.Rows) {
+	latency := m.family("user_statement_seconds_total", counter, "Time spent executing statements by user and statement type (events_statements_summary_by_user_by_event_name)")
+	statements := m.family("user_statements_total", counter, "Number of statements executed by user (events_statements_summary_by_user_by_event_name)")
+	waits := m.family("user_wait_seconds_total", counter, "Time spent waiting, excluding idle time, by user (events_waits_summary_by_user_by_event_name)")
+	connections := m.family("user_connections", gauge, "Number of connections by user (accounts, or processlist when filtering databases)")
+	totalConnections := m.family("user_connections_total", counter, "Number of connections made by user (accounts, not filtered)")
+	active := m.family("user_active_connections", gauge, "Number of active connections by user (processlist)")
+
+	for _, row := range rows {
+		for _, statement := range []struct {
+			name  string
+			timer uint64
+		}{
+			{"select", row.SumTimerSelect},
+			{"insert", row.SumTimerInsert},
+			{"update", row.SumTimerUpdate},
+			{"delete", row.SumTimerDelete},
+			{"other", row.SumTimerOther},
+		} {
+			latency.add(seconds(statement.timer), "user", row.Username, "statement", statement.name)
+		}
+		statements.add(float64(row.CountStar), "user", row.Username)
+		waits.add(seconds(row.SumTimerWaits), "user", row.Username)
+		connections.add(float64(row.Connections), "user", row.Username)
+		totalConnections.add(float64(row.TotalConnections), "user", row.Username)
+		active.add(float64(row.Active), "user", row.Username)
+	}
+}
+
+func (m *Metrics) addReplication(rows replication.Rows) {
+	running := m.family("replication_running", gauge, "Whether the replication thread is running (replication_connection_status and replication_applier_status_by_*)")
+	lag := m.family("replication_lag_seconds", gauge, "Time since the original commit of the transaction being processed by the replication thread")
+	immediateLag := m.family("replication_immediate_lag_seconds", gauge, "Time since the commit on the immediate source of the transaction being processed by the replication thread")
+	last := m.family("replication_last_transaction_seconds", gauge, "Time taken to queue or apply the last transaction by the replication thread")
+	lastError := m.family("replication_last_error", gauge, "Number of the last error of the replication thread, 0 if none")
+
+	for _, row := range rows {
+		var on float64
+		if row.Running() {
+			on = 1
+		}
+		running.add(on, "channel", row.Channel, "thread", row.Name())
+		lag.add(seconds(row.Lag), "channel", row.Channel, "thread", row.Name())
+		immediateLag.add(seconds(row.ImmediateLag), "channel", row.Channel, "thread", row.Name())
+		last.add(seconds(row.LastLatency), "channel", row.Channel, "thread", row.Name())
+		lastError.add(float64(row.ErrorNumber), "channel", row.Channel, "thread", row.Name())
+	}
+}
+
+// escape escapes a label value as required by the exposition format
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Write writes the metrics in the Prometheus text exposition format.
+// Samples are sorted by their labels so the output is stable.
+func (m *Metrics) Write(w io.Writer) error {
+	for _, f := range m.families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ); err != nil {
+			return err
+		}
+
+		lines := make([]string, 0, len(f.samples))
+		for _, s := range f.samples {
+			labels := make([]string, 0, len(s.labels))
+			for _, l := range s.labels {
+				labels = append(labels, l.name+`="`+escape(l.value)+`"`)
+			}
+			line := f.name
+			if len(labels) > 0 {
+				line += "{" + strings.Join(labels, ",") + "}"
+			}
+			lines = append(lines, line+" "+strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+		sort.Strings(lines)
+
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sjmudd/ps-top/model/mutexlatency"
+	"github.com/sjmudd/ps-top/model/replication"
+	"github.com/sjmudd/ps-top/model/userlatency"
+)
+
+func TestSplitTableName(t *testing.T) {
+	tests := []struct {
+		input  string
+		schema string
+		table  string
+	}{
+		{"", "", ""},
+		{"table", "", "table"},
+		{"db.table", "db", "table"},
+		{"db.table.with.dots", "db", "table.with.dots"},
+	}
+
+	for _, test := range tests {
+		schema, table := splitTableName(test.input)
+		if schema != test.schema || table != test.table {
+			t.Errorf("splitTableName(%q) failed: got: %q, %q, expected: %q, %q", test.input, schema, table, test.schema, test.table)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m := New()
+	if err := m.Add(mutexlatency.Rows{
+		{Name: `trx_mutex`, SumTimerWait: 2500000000000, CountStar: 10},
+		{Name: `buf_pool"mutex`, SumTimerWait: 1000000, CountStar: 1},
+	}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := m.Add(userlatency.Rows{}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+	if err := m.Add("unknown"); err == nil {
+		t.Errorf("Add() with unknown rows did not return an error")
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	expected := `# HELP pstop_mutex_wait_seconds_total Time spent waiting on InnoDB mutexes (events_waits_summary_global_by_event_name)
+# TYPE pstop_mutex_wait_seconds_total counter
+pstop_mutex_wait_seconds_total{event_name="buf_pool\"mutex"} 1e-06
+pstop_mutex_wait_seconds_total{event_name="trx_mutex"} 2.5
+# HELP pstop_mutex_waits_total Number of waits on InnoDB mutexes (events_waits_summary_global_by_event_name)
+# TYPE pstop_mutex_waits_total counter
+pstop_mutex_waits_total{event_name="buf_pool\"mutex"} 1
+pstop_mutex_waits_total{event_name="trx_mutex"} 10
+# HELP pstop_user_statement_seconds_total Time spent executing statements by user and statement type (events_statements_summary_by_user_by_event_name)
+# TYPE pstop_user_statement_seconds_total counter
+# HELP pstop_user_statements_total Number of statements executed by user (events_statements_summary_by_user_by_event_name)
+# TYPE pstop_user_statements_total counter
+# HELP pstop_user_wait_seconds_total Time spent waiting, excluding idle time, by user (events_waits_summary_by_user_by_event_name)
+# TYPE pstop_user_wait_seconds_total counter
+# HELP pstop_user_connections Number of connections by user (accounts, or processlist when filtering databases)
+# TYPE pstop_user_connections gauge
+# HELP pstop_user_connections_total Number of connections made by user (accounts, not filtered)
+# TYPE pstop_user_connections_total counter
+# HELP pstop_user_active_connections Number of active connections by user (processlist)
+# TYPE pstop_user_active_connections gauge
+`
+	if buf.String() != expected {
+		t.Errorf("Write() failed: got:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	m := New()
+	if err := m.Add(replication.Rows{
+		{Channel: "", Thread: replication.IO, State: "ON"},
+		{Channel: "", Thread: replication.Worker, Worker: 2, State: "OFF", Lag: 3000000000000, ErrorNumber: 1062},
+	}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		`pstop_replication_running{channel="",thread="IO"} 1`,
+		`pstop_replication_running{channel="",thread="worker 2"} 0`,
+		`pstop_replication_lag_seconds{channel="",thread="worker 2"} 3`,
+		`pstop_replication_last_error{channel="",thread="worker 2"} 1062`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestDuplicateSeries(t *testing.T) {
+	// different names may be the same once anonymised
+	m := New()
+	if err := m.Add(mutexlatency.Rows{
+		{Name: `mutex1`, SumTimerWait: 2000000000000, CountStar: 10},
+		{Name: `mutex1`, SumTimerWait: 500000000000, CountStar: 1},
+	}); err != nil {
+		t.Fatalf("Add() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"pstop_mutex_wait_seconds_total{event_name=\"mutex1\"} 2.5\n",
+		"pstop_mutex_waits_total{event_name=\"mutex1\"} 11\n",
+	} {
+		if strings.Count(buf.String(), `{event_name="mutex1"}`) != 2 || !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected a single %q in:\n%s", expected, buf.String())
+		}
+	}
+}
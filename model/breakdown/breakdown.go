@@ -0,0 +1,120 @@
+// Package breakdown contains the routines for breaking down the
+// performance_schema summaries by the thread, account, user or host
+// they belong to, e.g. memory_summary_by_thread_by_event_name.
+package breakdown
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/sjmudd/anonymiser"
+)
+
+// The ways a summary can be broken down
+const (
+	Global    = ""        // by event name only
+	ByThread  = "thread"  // by thread and event name
+	ByAccount = "account" // by account (user@host) and event name
+	ByUser    = "user"    // by user and event name
+	ByHost    = "host"    // by host and event name
+)
+
+// All lists the ways a summary can be broken down in the order they are shown
+var All = []string{Global, ByThread, ByAccount, ByUser, ByHost}
+
+// Background is shown as the owner of the events of background threads
+const Background = "<background>"
+
+// ownerColumns are the columns identifying the owner of the events in
+// each breakdown. Threads are joined as t.
+var ownerColumns = map[string][]string{
+	Global:    nil,
+	ByThread:  {"THREAD_ID", "t.PROCESSLIST_ID", "t.PROCESSLIST_USER", "t.PROCESSLIST_HOST", "t.NAME"},
+	ByAccount: {"USER", "HOST"},
+	ByUser:    {"USER"},
+	ByHost:    {"HOST"},
+}
+
+// Table returns the summary table for the given breakdown, e.g.
+// memory_summary_by_user_by_event_name for memory_summary and ByUser
+func Table(prefix, breakdown string) string {
+	if breakdown == Global {
+		return prefix + "_global_by_event_name"
+	}
+	return prefix + "_by_" + breakdown + "_by_event_name"
+}
+
+// Columns returns the columns identifying the owner of the events in
+// the summary table with the given alias, each followed by a comma
+func Columns(breakdown, alias string) string {
+	var columns string
+	for _, column := range ownerColumns[breakdown] {
+		if !strings.Contains(column, ".") {
+			column = alias + "." + column
+		}
+		columns += column + ", "
+	}
+	return columns
+}
+
+// Join returns the join needed by Columns for the summary table with the given alias
+func Join(breakdown, alias string) string {
+	if breakdown != ByThread {
+		return ""
+	}
+	return " LEFT JOIN threads t ON t.THREAD_ID = " + alias + ".THREAD_ID"
+}
+
+// Dest returns the values to scan the columns returned by Columns into
+func Dest(breakdown string) []sql.NullString {
+	return make([]sql.NullString, len(ownerColumns[breakdown]))
+}
+
+// Owner returns the name shown for the owner of the events given the
+// values scanned into Dest. Connections are shown as
+// "<processlist id> user@host" and background threads by name.
+func Owner(breakdown string, owner []sql.NullString) string {
+	switch breakdown {
+	case ByThread:
+		threadID, processlistID, user, host, name := owner[0], owner[1], owner[2], owner[3], owner[4]
+		switch {
+		case processlistID.Valid:
+			return processlistID.String + " " + account(user, host)
+		case name.Valid:
+			return strings.TrimPrefix(name.String, "thread/")
+		default:
+			return "thread " + threadID.String // the thread has gone
+		}
+	case ByAccount:
+		return account(owner[0], owner[1])
+	case ByUser:
+		if !owner[0].Valid {
+			return Background
+		}
+		return anonymiser.Anonymise("user", owner[0].String)
+	case ByHost:
+		if !owner[0].Valid {
+			return Background
+		}
+		return anonymiser.Anonymise("host", owner[0].String)
+	}
+
+	return ""
+}
+
+// account returns user@host or Background if there is no user
+func account(user, host sql.NullString) string {
+	if !user.Valid {
+		return Background
+	}
+	return anonymiser.Anonymise("user", user.String) + "@" + anonymiser.Anonymise("host", host.String)
+}
+
+// Heading returns the heading of the owner shown before the event name
+// heading, e.g. "Thread: "
+func Heading(breakdown string) string {
+	if breakdown == Global {
+		return ""
+	}
+	return strings.ToUpper(breakdown[:1]) + breakdown[1:] + ": "
+}
@@ -0,0 +1,67 @@
+package breakdown
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/sjmudd/anonymiser"
+)
+
+// owner returns the given owner columns as scanned, nil being NULL
+func owner(values ...any) []sql.NullString {
+	owner := make([]sql.NullString, len(values))
+	for i, value := range values {
+		if value != nil {
+			owner[i] = sql.NullString{String: value.(string), Valid: true}
+		}
+	}
+	return owner
+}
+
+func TestOwner(t *testing.T) {
+	anonymiser.Enable(false)
+	tests := []struct {
+		breakdown string
+		owner     []sql.NullString
+		expected  string
+	}{
+		{ByThread, owner("52", "12", "app", "h1", "thread/sql/one_connection"), "12 app@h1"},
+		{ByThread, owner("1", nil, nil, nil, "thread/sql/main"), "sql/main"},
+		{ByThread, owner("60", nil, nil, nil, nil), "thread 60"},
+		{ByAccount, owner("app", "h1"), "app@h1"},
+		{ByAccount, owner(nil, nil), Background},
+		{ByUser, owner("app"), "app"},
+		{ByUser, owner(nil), Background},
+		{ByHost, owner("h1"), "h1"},
+		{Global, nil, ""},
+	}
+	for _, test := range tests {
+		if got := Owner(test.breakdown, test.owner); got != test.expected {
+			t.Errorf("Owner(%q, %v) failed: got: %q, expected: %q", test.breakdown, test.owner, got, test.expected)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		breakdown string
+		table     string
+		columns   string
+		join      string
+	}{
+		{Global, "memory_summary_global_by_event_name", "", ""},
+		{ByThread, "memory_summary_by_thread_by_event_name", "m.THREAD_ID, t.PROCESSLIST_ID, t.PROCESSLIST_USER, t.PROCESSLIST_HOST, t.NAME, ", " LEFT JOIN threads t ON t.THREAD_ID = m.THREAD_ID"},
+		{ByAccount, "memory_summary_by_account_by_event_name", "m.USER, m.HOST, ", ""},
+	}
+	for _, test := range tests {
+		if got := Table("memory_summary", test.breakdown); got != test.table {
+			t.Errorf("Table(%q) failed: got: %q, expected: %q", test.breakdown, got, test.table)
+		}
+		if got := Columns(test.breakdown, "m"); got != test.columns {
+			t.Errorf("Columns(%q) failed: got: %q, expected: %q", test.breakdown, got, test.columns)
+		}
+		if got := Join(test.breakdown, "m"); got != test.join {
+			t.Errorf("Join(%q) failed: got: %q, expected: %q", test.breakdown, got, test.join)
+		}
+	}
+}
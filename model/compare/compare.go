@@ -0,0 +1,156 @@
+// Package compare contains the routines for comparing the rows of a
+// view from two servers, or from two time windows on one server.
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/pstable"
+)
+
+// Side is one of the sides being compared
+type Side struct {
+	Label   string                      // shown in the headings
+	Tabler  pstable.Tabler              // collects the rows, nil if the values are fixed
+	Values  []pstable.Value             // the fixed values of an earlier time window
+	Seconds float64                     // the length of the earlier time window
+	Collect func(context.Context) error // collects the rows instead of Tabler.Collect if set
+}
+
+// collect collects the rows of the side if they are not fixed
+func (s Side) collect(ctx context.Context) error {
+	switch {
+	case s.Collect != nil:
+		return s.Collect(ctx)
+	case s.Tabler != nil:
+		return s.Tabler.Collect(ctx)
+	}
+	return nil
+}
+
+// values returns the latency of each row of the side, per second if wanted
+func (s Side) values(perSecond bool) []pstable.Value {
+	values, seconds := s.Values, s.Seconds
+	if s.Tabler != nil {
+		values = s.Tabler.(pstable.Comparable).Values()
+		seconds = s.Tabler.LastCollectTime().Sub(s.Tabler.FirstCollectTime()).Seconds()
+	}
+	if !perSecond {
+		return values
+	}
+	return perSecondValues(values, seconds)
+}
+
+// Compare holds the rows of both sides joined by name
+type Compare struct {
+	left, right    Side
+	perSecond      bool // are the time windows of different length?
+	FirstCollected time.Time
+	LastCollected  time.Time
+	Results        Rows // rows of both sides
+	Totals         Row  // totals of both sides
+}
+
+// NewServers returns a Compare of the rows of the same view of two
+// servers, or nil if the view can not be compared
+func NewServers(left, right Side) *Compare {
+	if !comparable(left.Tabler) || !comparable(right.Tabler) {
+		return nil
+	}
+	c := &Compare{left: left, right: right}
+	c.calculate()
+
+	return c
+}
+
+// NewWindows returns a Compare of the rows collected by tabler so far
+// with those collected from now on, or nil if they can not be
+// compared. The statistics of tabler are reset to start the new time
+// window. As the windows may have different lengths the latency per
+// second is compared.
+func NewWindows(tabler pstable.Tabler) *Compare {
+	if !comparable(tabler) {
+		return nil
+	}
+	first, last := tabler.FirstCollectTime(), tabler.LastCollectTime()
+	c := &Compare{
+		left: Side{
+			Label:   "Before/s",
+			Values:  tabler.(pstable.Comparable).Values(),
+			Seconds: last.Sub(first).Seconds(),
+		},
+		right:     Side{Label: "After/s", Tabler: tabler},
+		perSecond: true,
+	}
+	tabler.ResetStatistics()
+	c.calculate()
+
+	return c
+}
+
+// comparable returns whether the rows of the tabler can be compared
+func comparable(tabler pstable.Tabler) bool {
+	_, ok := tabler.(pstable.Comparable)
+	return ok
+}
+
+// Labels returns the labels of the left and right sides
+func (c Compare) Labels() (string, string) {
+	return c.left.Label, c.right.Label
+}
+
+// Collect collects the rows of both sides and joins them. The rows
+// last collected are used for a side which fails and its error returned.
+func (c *Compare) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	var errs []error
+	for _, side := range []Side{c.left, c.right} {
+		if err := side.collect(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", side.Label, err))
+		}
+	}
+	c.calculate()
+
+	log.Println("Compare.Collect() took:", time.Duration(time.Since(start)).String())
+	return errors.Join(errs...)
+}
+
+// Refilter joins the rows last collected by both sides again, e.g.
+// once they have been filtered again
+func (c *Compare) Refilter() {
+	c.calculate()
+}
+
+// calculate joins the rows of both sides
+func (c *Compare) calculate() {
+	c.FirstCollected = c.right.Tabler.FirstCollectTime()
+	c.LastCollected = c.right.Tabler.LastCollectTime()
+	c.Results = join(c.left.values(c.perSecond), c.right.values(c.perSecond))
+	c.Totals = totals(c.Results)
+}
+
+// ResetStatistics resets the statistics of both sides. The values of
+// an earlier time window are kept.
+func (c *Compare) ResetStatistics() {
+	for _, side := range []Side{c.left, c.right} {
+		if side.Tabler != nil {
+			side.Tabler.ResetStatistics()
+		}
+	}
+	c.calculate()
+}
+
+// HaveRelativeStats returns whether the rows compared have relative statistics
+func (c Compare) HaveRelativeStats() bool {
+	return c.right.Tabler.HaveRelativeStats()
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (c Compare) WantRelativeStats() bool {
+	return c.right.Tabler.WantRelativeStats()
+}
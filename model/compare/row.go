@@ -0,0 +1,27 @@
+package compare
+
+// Row holds the latency of a row on both sides being compared
+type Row struct {
+	Name  string `json:"name"`
+	Left  uint64 `json:"left"`  // picoseconds
+	Right uint64 `json:"right"` // picoseconds
+}
+
+// Diff returns how much higher the latency is on the right
+func (r Row) Diff() int64 {
+	return int64(r.Right) - int64(r.Left)
+}
+
+// AbsDiff returns the size of the difference
+func (r Row) AbsDiff() uint64 {
+	if r.Right > r.Left {
+		return r.Right - r.Left
+	}
+	return r.Left - r.Right
+}
+
+// add adds the values of another row
+func (r *Row) add(other Row) {
+	r.Left += other.Left
+	r.Right += other.Right
+}
@@ -0,0 +1,64 @@
+package compare
+
+import (
+	"github.com/sjmudd/ps-top/pstable"
+)
+
+// Rows contains the rows of both sides joined by name
+type Rows []Row
+
+// join returns the values of both sides joined on name, in the order
+// they are first seen. A name found on only one side has no latency on
+// the other and values with the same name are added together.
+func join(left, right []pstable.Value) Rows {
+	var rows Rows
+	byName := make(map[string]int)
+
+	// iterate over both sides by name
+	for _, side := range []struct {
+		values []pstable.Value
+		right  bool
+	}{{left, false}, {right, true}} {
+		for _, value := range side.values {
+			i, ok := byName[value.Name]
+			if !ok {
+				i = len(rows)
+				byName[value.Name] = i
+				rows = append(rows, Row{Name: value.Name})
+			}
+			if side.right {
+				rows[i].Right += value.Latency
+			} else {
+				rows[i].Left += value.Latency
+			}
+		}
+	}
+
+	return rows
+}
+
+// perSecondValues returns the values divided by the given number of seconds.
+// The values are left as they are if no time has passed.
+func perSecondValues(values []pstable.Value, seconds float64) []pstable.Value {
+	result := make([]pstable.Value, 0, len(values))
+
+	for _, value := range values {
+		if seconds > 0 {
+			value.Latency = uint64(float64(value.Latency) / seconds)
+		}
+		result = append(result, value)
+	}
+
+	return result
+}
+
+// totals returns the totals of both sides
+func totals(rows Rows) Row {
+	total := Row{Name: "Totals"}
+
+	for _, row := range rows {
+		total.add(row)
+	}
+
+	return total
+}
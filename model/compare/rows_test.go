@@ -0,0 +1,53 @@
+package compare
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/ps-top/pstable"
+)
+
+func TestJoin(t *testing.T) {
+	left := []pstable.Value{{Name: "db.t1", Latency: 10}, {Name: "db.t2", Latency: 5}, {Name: "db.t1", Latency: 2}}
+	right := []pstable.Value{{Name: "db.t3", Latency: 7}, {Name: "db.t1", Latency: 20}}
+	expected := Rows{
+		{Name: "db.t1", Left: 12, Right: 20},
+		{Name: "db.t2", Left: 5},
+		{Name: "db.t3", Right: 7},
+	}
+
+	if got := join(left, right); !reflect.DeepEqual(got, expected) {
+		t.Errorf("join() failed: got: %+v, expected: %+v", got, expected)
+	}
+}
+
+func TestPerSecondValues(t *testing.T) {
+	values := []pstable.Value{{Name: "a", Latency: 100}, {Name: "b", Latency: 5}}
+
+	tests := []struct {
+		seconds  float64
+		expected []pstable.Value
+	}{
+		{10, []pstable.Value{{Name: "a", Latency: 10}, {Name: "b", Latency: 0}}},
+		{0.5, []pstable.Value{{Name: "a", Latency: 200}, {Name: "b", Latency: 10}}},
+		{0, values},
+	}
+	for _, test := range tests {
+		if got := perSecondValues(values, test.seconds); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("perSecondValues(%v) failed: got: %+v, expected: %+v", test.seconds, got, test.expected)
+		}
+	}
+}
+
+func TestTotals(t *testing.T) {
+	rows := Rows{{Name: "a", Left: 1, Right: 5}, {Name: "b", Left: 10}}
+	expected := Row{Name: "Totals", Left: 11, Right: 5}
+
+	got := totals(rows)
+	if got != expected {
+		t.Errorf("totals() failed: got: %+v, expected: %+v", got, expected)
+	}
+	if got.Diff() != -6 || got.AbsDiff() != 6 {
+		t.Errorf("Diff() = %d, AbsDiff() = %d, expected -6 and 6", got.Diff(), got.AbsDiff())
+	}
+}
@@ -0,0 +1,84 @@
+package currentstages
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// CurrentStages holds the stages currently being executed
+type CurrentStages struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	last           Rows // last loaded values
+	Results        Rows // stages to show
+	db             datasource.DataSource
+}
+
+// NewCurrentStages returns a CurrentStages object using the given config and db
+func NewCurrentStages(cfg *config.Config, db datasource.DataSource) *CurrentStages {
+	return &CurrentStages{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// Collect collects the stages currently being executed from the db
+func (cs *CurrentStages) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, cs.db)
+	if err != nil {
+		return err
+	}
+	cs.AddRows(rows, time.Now())
+
+	log.Println("CurrentStages.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (cs *CurrentStages) AddRows(rows Rows, collected time.Time) {
+	cs.last = rows
+	cs.LastCollected = collected
+
+	cs.calculate()
+}
+
+// Last returns the last collected rows
+func (cs CurrentStages) Last() Rows {
+	return cs.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (cs *CurrentStages) Refilter() {
+	cs.calculate()
+}
+
+func (cs *CurrentStages) calculate() {
+	cs.Results = utils.DuplicateSlice(cs.last)
+	cs.Results = filter.MatchingRows(cs.config.DatabaseFilter(), cs.Results, func(row Row) string { return row.Thread + ": " + row.Stage })
+}
+
+// ResetStatistics recalculates the results as there are no counters to reset
+func (cs *CurrentStages) ResetStatistics() {
+	cs.calculate()
+}
+
+// HaveRelativeStats is false for this object
+func (cs CurrentStages) HaveRelativeStats() bool {
+	return false
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (cs CurrentStages) WantRelativeStats() bool {
+	return cs.config.WantRelativeStats()
+}
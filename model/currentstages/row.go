@@ -0,0 +1,17 @@
+// Package currentstages contains the routines for managing the stages
+// currently being executed shown in performance_schema.events_stages_current.
+package currentstages
+
+// Row contains a stage being executed together with the thread
+// executing it and the statement the stage belongs to
+type Row struct {
+	Thread        string `json:"thread"` // the thread executing the stage
+	Stage         string `json:"stage"`
+	Time          uint64 `json:"time"` // time spent in the stage so far in picoseconds
+	WorkCompleted uint64 `json:"work_completed"`
+	WorkEstimated uint64 `json:"work_estimated"`
+	Statement     string `json:"statement"` // the statement being executed, if any
+}
+
+// Rows contains a set of rows
+type Rows []Row
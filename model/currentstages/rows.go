@@ -0,0 +1,72 @@
+package currentstages
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/breakdown"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// maxStatementLength is the maximum length of the statement text kept for a stage
+const maxStatementLength = 200
+
+// collect returns the stages which have not yet finished. The thread is
+// identified as in the stages broken down by thread.
+func collect(ctx context.Context, db datasource.DataSource) (Rows, error) {
+	var t Rows
+
+	query := "SELECT " + breakdown.Columns(breakdown.ByThread, "e") +
+		"e.EVENT_NAME, e.TIMER_WAIT, e.WORK_COMPLETED, e.WORK_ESTIMATED, s.SQL_TEXT FROM events_stages_current e" +
+		breakdown.Join(breakdown.ByThread, "e") +
+		" LEFT JOIN events_statements_current s ON s.THREAD_ID = e.THREAD_ID AND s.EVENT_ID = e.NESTING_EVENT_ID" +
+		" WHERE e.END_EVENT_ID IS NULL"
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := breakdown.Dest(breakdown.ByThread)
+	for rows.Next() {
+		var (
+			r                         Row
+			time, completed, estimate sql.NullInt64
+			statement                 sql.NullString
+		)
+		dest := make([]any, 0, len(owner)+5)
+		for i := range owner {
+			dest = append(dest, &owner[i])
+		}
+		if err := rows.Scan(append(dest,
+			&r.Stage,
+			&time,
+			&completed,
+			&estimate,
+			&statement)...); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r.Thread = breakdown.Owner(breakdown.ByThread, owner)
+		r.Stage = strings.TrimPrefix(r.Stage, "stage/sql/")
+		r.Time = uint64(time.Int64)
+		r.WorkCompleted = uint64(completed.Int64)
+		r.WorkEstimated = uint64(estimate.Int64)
+		if !anonymiser.Enabled() {
+			// statements may contain any names or values so are not shown when anonymising
+			r.Statement = utils.Truncate(statement.String, maxStatementLength)
+		}
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
@@ -0,0 +1,53 @@
+package currentstages
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+func TestCollect(t *testing.T) {
+	fixture := datasource.NewFixture().
+		Add("events_stages_current",
+			[]any{52, 12, "app", "h1", "thread/sql/one_connection", "stage/sql/Sending data", 3000000000, nil, nil, "SELECT *\nFROM t1"},
+			[]any{53, 13, "app", "h2", "thread/sql/one_connection", "stage/innodb/alter table (read PK and internal sort)", 9000000000, 20, 80, nil},
+			[]any{1, nil, nil, nil, "thread/innodb/srv_purge_thread", "stage/innodb/buffer pool load", 1000, 5, 10, nil},
+		)
+
+	tests := []struct {
+		anonymise bool
+		expected  Rows
+	}{
+		{
+			false,
+			Rows{
+				{Thread: "12 app@h1", Stage: "Sending data", Time: 3000000000, Statement: "SELECT * FROM t1"},
+				{Thread: "13 app@h2", Stage: "stage/innodb/alter table (read PK and internal sort)", Time: 9000000000, WorkCompleted: 20, WorkEstimated: 80},
+				{Thread: "innodb/srv_purge_thread", Stage: "stage/innodb/buffer pool load", Time: 1000, WorkCompleted: 5, WorkEstimated: 10},
+			},
+		},
+		{
+			true,
+			Rows{
+				{Thread: "12 user1@host1", Stage: "Sending data", Time: 3000000000},
+				{Thread: "13 user1@host2", Stage: "stage/innodb/alter table (read PK and internal sort)", Time: 9000000000, WorkCompleted: 20, WorkEstimated: 80},
+				{Thread: "innodb/srv_purge_thread", Stage: "stage/innodb/buffer pool load", Time: 1000, WorkCompleted: 5, WorkEstimated: 10},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		anonymiser.Enable(test.anonymise)
+		got, err := collect(context.Background(), fixture)
+		if err != nil {
+			t.Fatalf("collect() failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("collect() with anonymise %v failed:\ngot:      %+v\nexpected: %+v", test.anonymise, got, test.expected)
+		}
+	}
+	anonymiser.Enable(false)
+}
@@ -0,0 +1,62 @@
+package customview
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format indicates how the values of a column are shown
+type Format int
+
+// Format* constants represent the different ways a column can be shown
+const (
+	FormatText    Format = iota // shown as returned
+	FormatTime                  // picoseconds shown as a time
+	FormatBytes                 // a number of bytes
+	FormatCount                 // a number of events, rows, etc.
+	FormatPercent               // a percentage between 0 and 100
+)
+
+var formatNames = map[string]Format{
+	"text":    FormatText,
+	"time":    FormatTime,
+	"bytes":   FormatBytes,
+	"count":   FormatCount,
+	"percent": FormatPercent,
+}
+
+// Numeric returns true if the values of the column are numbers
+func (f Format) Numeric() bool {
+	return f != FormatText
+}
+
+// Column is a column returned by the query of a view
+type Column struct {
+	Name   string
+	Format Format
+}
+
+// ParseColumns returns the columns given as a comma-separated list of
+// names, each optionally followed by a colon and its format, e.g.
+// table_name, total_latency:time, rows_fetched:count
+func ParseColumns(columns string) ([]Column, error) {
+	var result []Column
+
+	for _, column := range strings.Split(columns, ",") {
+		name, format, _ := strings.Cut(column, ":")
+		name, format = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(format))
+		if name == "" {
+			return nil, fmt.Errorf("missing column name in %q", columns)
+		}
+		c := Column{Name: name}
+		if format != "" {
+			var found bool
+			if c.Format, found = formatNames[format]; !found {
+				return nil, fmt.Errorf("unknown format %q of column %s, expected one of: text, time, bytes, count, percent", format, name)
+			}
+		}
+		result = append(result, c)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,105 @@
+// Package customview contains the routines for managing the views
+// defined by the user with a query against performance_schema or sys.
+package customview
+
+import (
+	"context"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// CustomView holds the rows returned by the query of a view defined by the user
+type CustomView struct {
+	config         *config.Config
+	Name           string
+	Description    string
+	Query          string
+	Columns        []Column
+	FirstCollected time.Time
+	LastCollected  time.Time
+	Err            error // the error returned by the last collection, if any
+	last           []Row // last loaded values
+	Results        []Row // rows to show
+	Totals         Row   // totals of results
+	db             datasource.DataSource
+}
+
+// NewCustomView returns a view with the given name and description
+// showing the given columns returned by the query
+func NewCustomView(cfg *config.Config, db datasource.DataSource, name, description, query string, columns []Column) *CustomView {
+	return &CustomView{
+		config:      cfg,
+		Name:        name,
+		Description: description,
+		Query:       query,
+		Columns:     columns,
+		db:          db,
+	}
+}
+
+// Collect runs the query of the view. If the query fails no rows are
+// shown and the error is kept to be shown instead. The query is
+// provided by the user so the error is not returned as a lost
+// connection would be noticed by the other views.
+func (cv *CustomView) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, cv.db, cv.Query, len(cv.Columns))
+	if err != nil {
+		log.Printf("CustomView.Collect(): view %s failed: %v", cv.Name, err)
+	}
+	cv.Err = err
+	cv.AddRows(rows, time.Now())
+
+	log.Println("CustomView.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (cv *CustomView) AddRows(rows []Row, collected time.Time) {
+	cv.last = rows
+	cv.LastCollected = collected
+	if cv.FirstCollected.IsZero() {
+		cv.FirstCollected = collected
+	}
+
+	cv.calculate()
+}
+
+// Last returns the last collected rows
+func (cv CustomView) Last() []Row {
+	return cv.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (cv *CustomView) Refilter() {
+	cv.calculate()
+}
+
+func (cv *CustomView) calculate() {
+	cv.Results = utils.DuplicateSlice(cv.last)
+	cv.Results = filter.MatchingRows(cv.config.DatabaseFilter(), cv.Results, func(row Row) string { return row.name(cv.Columns) })
+	cv.Totals = totals(cv.Results, cv.Columns)
+}
+
+// ResetStatistics recalculates the results as the values are shown as returned
+func (cv *CustomView) ResetStatistics() {
+	cv.calculate()
+}
+
+// HaveRelativeStats is false for this object
+func (cv CustomView) HaveRelativeStats() bool {
+	return false
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (cv CustomView) WantRelativeStats() bool {
+	return cv.config.WantRelativeStats()
+}
@@ -0,0 +1,60 @@
+package customview
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+func TestParseColumns(t *testing.T) {
+	got, err := ParseColumns("table_name, total_latency:time, io_read:Bytes,rows:count , pct:percent,text:text")
+	if err != nil {
+		t.Fatalf("ParseColumns() failed: %v", err)
+	}
+	expected := []Column{
+		{"table_name", FormatText},
+		{"total_latency", FormatTime},
+		{"io_read", FormatBytes},
+		{"rows", FormatCount},
+		{"pct", FormatPercent},
+		{"text", FormatText},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseColumns() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+
+	for _, columns := range []string{"", "a,,b", "a:seconds"} {
+		if _, err := ParseColumns(columns); err == nil {
+			t.Errorf("ParseColumns(%q) should fail", columns)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	columns := []Column{{"schema", FormatText}, {"table", FormatText}, {"latency", FormatTime}, {"pct", FormatPercent}}
+	fixture := datasource.NewFixture().
+		Add("x$schema_table_statistics",
+			[]any{"db1", "t1", "3000000000.00", 75},
+			[]any{"db1", nil, 1000000000, nil},
+		)
+
+	rows, err := collect(context.Background(), fixture, "SELECT * FROM sys.x$schema_table_statistics", len(columns))
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	expected := []Row{{"db1", "t1", "3000000000.00", "75"}, {"db1", "", "1000000000", ""}}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("collect() failed:\ngot:      %q\nexpected: %q", rows, expected)
+	}
+
+	// percentages are not added together
+	if got := totals(rows, columns); !reflect.DeepEqual(got, Row{"Totals", "", "4000000000", ""}) {
+		t.Errorf("totals() failed: got: %q", got)
+	}
+
+	if _, err := collect(context.Background(), fixture, "SELECT * FROM unknown", len(columns)); err == nil {
+		t.Errorf("collect() of an unknown query should fail")
+	}
+}
@@ -0,0 +1,93 @@
+package customview
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+// Row holds the values of the columns of a row returned by the query
+// of a view. NULL values are empty.
+type Row []string
+
+// Number returns the value of the given column as a number or 0 if it is not one
+func (row Row) Number(column int) float64 {
+	if column >= len(row) {
+		return 0
+	}
+	value, _ := strconv.ParseFloat(row[column], 64)
+	return value
+}
+
+// name returns the text columns of the row, used for sorting and filtering rows
+func (row Row) name(columns []Column) string {
+	var text []string
+	for i, column := range columns {
+		if !column.Format.Numeric() && i < len(row) {
+			text = append(text, row[i])
+		}
+	}
+	return strings.Join(text, " ")
+}
+
+// totals returns the sum of the numeric columns of the rows except for
+// percentages which can not be added together. The first text column
+// holds "Totals".
+func totals(rows []Row, columns []Column) Row {
+	total := make(Row, len(columns))
+
+	named := false
+	for i, column := range columns {
+		switch column.Format {
+		case FormatText:
+			if !named {
+				total[i] = "Totals"
+				named = true
+			}
+		case FormatTime, FormatBytes, FormatCount:
+			var sum float64
+			for _, row := range rows {
+				sum += row.Number(i)
+			}
+			total[i] = strconv.FormatFloat(sum, 'f', -1, 64)
+		}
+	}
+
+	return total
+}
+
+// collect runs the query of a view returning the given number of columns.
+// Errors are returned rather than being fatal as the query is provided by the user.
+func collect(ctx context.Context, db datasource.DataSource, query string, columns int) ([]Row, error) {
+	var t []Row
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]sql.NullString, columns)
+	dest := make([]any, columns)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r := make(Row, columns)
+		for i := range values {
+			r[i] = values[i].String
+		}
+		t = append(t, r)
+	}
+
+	err = rows.Err()
+	_ = rows.Close()
+
+	return t, err
+}
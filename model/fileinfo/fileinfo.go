@@ -2,11 +2,13 @@
 package fileinfo
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
 )
 
@@ -19,13 +21,13 @@ type FileIoLatency struct {
 	last           Rows
 	Results        Rows
 	Totals         Row
-	db             *sql.DB
+	db             datasource.DataSource
 }
 
 // NewFileSummaryByInstance creates a new structure and include various variable values:
 // - datadir, relay_log
 // There's no checking that these are actually provided!
-func NewFileSummaryByInstance(cfg *config.Config, db *sql.DB) *FileIoLatency {
+func NewFileSummaryByInstance(cfg *config.Config, db datasource.DataSource) *FileIoLatency {
 	fiol := &FileIoLatency{
 		db:     db,
 		config: cfg,
@@ -43,14 +45,30 @@ func (fiol *FileIoLatency) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (fiol *FileIoLatency) Collect() {
+func (fiol *FileIoLatency) Collect(ctx context.Context) error {
 	start := time.Now()
-	fiol.last = FileInfo2MySQLNames(
-		fiol.config.Variables().Get("datadir"),
-		fiol.config.Variables().Get("relaylog"),
-		collect(fiol.db),
+	rows, err := collect(ctx, fiol.db)
+	if err != nil {
+		return err
+	}
+	fiol.AddRows(
+		FileInfo2MySQLNames(
+			fiol.config.Variables().Get("datadir"),
+			fiol.config.Variables().Get("relaylog"),
+			rows,
+		),
+		time.Now(),
 	)
-	fiol.LastCollected = time.Now()
+	log.Println("FileIoLatency.Collect() took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows, with filenames already converted to
+// MySQL object names, collected at the given time and updates the results.
+func (fiol *FileIoLatency) AddRows(rows Rows, collected time.Time) {
+	fiol.last = rows
+	fiol.LastCollected = collected
 
 	// copy in first data if it was not there
 	// or check for reload initial characteristics
@@ -63,7 +81,17 @@ func (fiol *FileIoLatency) Collect() {
 
 	log.Println("fiol.first.totals():", totals(fiol.first))
 	log.Println("fiol.last.totals():", totals(fiol.last))
-	log.Println("FileIoLatency.Collect() took:", time.Duration(time.Since(start)).String())
+}
+
+// Last returns the last collected rows
+func (fiol FileIoLatency) Last() Rows {
+	return fiol.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (fiol *FileIoLatency) Refilter() {
+	fiol.calculate()
 }
 
 func (fiol *FileIoLatency) calculate() {
@@ -73,6 +101,9 @@ func (fiol *FileIoLatency) calculate() {
 		fiol.Results.subtract(fiol.first)
 	}
 
+	f := fiol.config.DatabaseFilter()
+	fiol.Results = filter.Rows(fiol.Results, func(row Row) bool { return f.MatchesObject(row.Name) })
+	fiol.Results = filter.MatchingRows(f, fiol.Results, func(row Row) string { return row.Name })
 	fiol.Totals = totals(fiol.Results)
 }
 
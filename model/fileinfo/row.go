@@ -4,6 +4,8 @@ package fileinfo
 
 import (
 	"log"
+
+	"github.com/sjmudd/ps-top/utils"
 )
 
 /*
@@ -39,17 +41,19 @@ CREATE TABLE `file_summary_by_instance` (
 
 // Row contains a row from file_summary_by_instance
 type Row struct {
-	Name                  string
-	CountStar             uint64
-	CountRead             uint64
-	CountWrite            uint64
-	CountMisc             uint64
-	SumTimerWait          uint64
-	SumTimerRead          uint64
-	SumTimerWrite         uint64
-	SumTimerMisc          uint64
-	SumNumberOfBytesRead  uint64
-	SumNumberOfBytesWrite uint64
+	Name                  string `json:"name"`
+	CountStar             uint64 `json:"count_star"`
+	CountRead             uint64 `json:"count_read"`
+	CountWrite            uint64 `json:"count_write"`
+	CountMisc             uint64 `json:"count_misc"`
+	SumTimerWait          uint64 `json:"sum_timer_wait"`
+	SumTimerRead          uint64 `json:"sum_timer_read"`
+	SumTimerWrite         uint64 `json:"sum_timer_write"`
+	SumTimerMisc          uint64 `json:"sum_timer_misc"`
+	MinTimerWait          uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait          uint64 `json:"max_timer_wait"` // not subtracted
+	SumNumberOfBytesRead  uint64 `json:"sum_number_of_bytes_read"`
+	SumNumberOfBytesWrite uint64 `json:"sum_number_of_bytes_write"`
 }
 
 // Valid checks if the row is valid and if asked to do so logs the problem
@@ -87,6 +91,8 @@ func add(row, other Row) Row {
 		SumTimerRead:  row.SumTimerRead + other.SumTimerRead,
 		SumTimerWrite: row.SumTimerWrite + other.SumTimerWrite,
 		SumTimerMisc:  row.SumTimerMisc + other.SumTimerMisc,
+		MinTimerWait:  utils.MinTimer(row.MinTimerWait, other.MinTimerWait),
+		MaxTimerWait:  max(row.MaxTimerWait, other.MaxTimerWait),
 
 		SumNumberOfBytesRead:  row.SumNumberOfBytesRead + other.SumNumberOfBytesRead,
 		SumNumberOfBytesWrite: row.SumNumberOfBytesWrite + other.SumNumberOfBytesWrite,
@@ -122,6 +128,11 @@ func subtract(row, other Row) Row {
 	return newRow
 }
 
+// Timers returns the number of waits and their latency
+func (row Row) Timers() utils.Timers {
+	return utils.Timers{Count: row.CountStar, Sum: row.SumTimerWait, Min: row.MinTimerWait, Max: row.MaxTimerWait}
+}
+
 // HasData indicates if there is data in the row (for counting valid rows)
 func (row *Row) HasData() bool {
 	return row != nil && row.SumTimerWait > 0
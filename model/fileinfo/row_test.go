@@ -13,9 +13,13 @@ func TestAdd(t *testing.T) {
 		expected Row
 	}{
 		{
-			Row{"name1", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
-			Row{"any__", 101, 102, 103, 104, 105, 106, 107, 108, 109, 110},
-			Row{"name1", 102, 104, 106, 108, 110, 112, 114, 116, 118, 120}},
+			Row{"name1", 1, 2, 3, 4, 5, 6, 7, 8, 3, 50, 9, 10},
+			Row{"any__", 101, 102, 103, 104, 105, 106, 107, 108, 2, 40, 109, 110},
+			Row{"name1", 102, 104, 106, 108, 110, 112, 114, 116, 2, 50, 118, 120}},
+		{
+			Row{"name1", 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 9, 10},
+			Row{"any__", 101, 102, 103, 104, 105, 106, 107, 108, 2, 40, 109, 110},
+			Row{"name1", 102, 104, 106, 108, 110, 112, 114, 116, 2, 40, 118, 120}},
 	}
 
 	for _, test := range tests {
@@ -36,9 +40,9 @@ func TestSubtract(t *testing.T) {
 		expected Row
 	}{
 		{
-			Row{"name1", 102, 104, 106, 108, 110, 112, 114, 116, 118, 120},
-			Row{"any__", 101, 102, 103, 104, 105, 106, 107, 108, 109, 110},
-			Row{"name1", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+			Row{"name1", 102, 104, 106, 108, 110, 112, 114, 116, 2, 50, 118, 120},
+			Row{"any__", 101, 102, 103, 104, 105, 106, 107, 108, 3, 60, 109, 110},
+			Row{"name1", 1, 2, 3, 4, 5, 6, 7, 8, 2, 50, 9, 10}},
 	}
 
 	for _, test := range tests {
@@ -3,9 +3,10 @@
 package fileinfo
 
 import (
-	"database/sql"
+	"context"
 	"time"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
@@ -46,7 +47,7 @@ func (rows Rows) Valid() bool {
 }
 
 // Select the raw data from the database into Rows
-func collect(db *sql.DB) Rows {
+func collect(ctx context.Context, db datasource.DataSource) (Rows, error) {
 	log.Println("collect() starts")
 	var t Rows
 	start := time.Now()
@@ -54,6 +55,8 @@ func collect(db *sql.DB) Rows {
 	sql := `
 SELECT	FILE_NAME,
 	SUM_TIMER_WAIT,
+	MIN_TIMER_WAIT,
+	MAX_TIMER_WAIT,
 	SUM_TIMER_READ,
 	SUM_TIMER_WRITE,
 	SUM_NUMBER_OF_BYTES_READ,
@@ -67,9 +70,9 @@ FROM	file_summary_by_instance
 WHERE	SUM_TIMER_WAIT > 0
 `
 
-	rows, err := db.Query(sql)
+	rows, err := db.Query(ctx, sql)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for rows.Next() {
@@ -78,6 +81,8 @@ WHERE	SUM_TIMER_WAIT > 0
 		if err := rows.Scan(
 			&r.Name, // raw filename
 			&r.SumTimerWait,
+			&r.MinTimerWait,
+			&r.MaxTimerWait,
 			&r.SumTimerRead,
 			&r.SumTimerWrite,
 			&r.SumNumberOfBytesRead,
@@ -87,12 +92,14 @@ WHERE	SUM_TIMER_WAIT > 0
 			&r.CountRead,
 			&r.CountWrite,
 			&r.CountMisc); err != nil {
-			log.Fatal(err)
+			_ = rows.Close()
+			return nil, err
 		}
 		t = append(t, r)
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return nil, err
 	}
 	_ = rows.Close()
 
@@ -101,7 +108,7 @@ WHERE	SUM_TIMER_WAIT > 0
 	}
 	log.Println("collect() took:", time.Duration(time.Since(start)).String(), "and returned", len(t), "rows")
 
-	return t
+	return t, nil
 }
 
 // subtract compares 2 slices of rows by name and removes the initial values
@@ -2,30 +2,110 @@
 package filter
 
 import (
+	"errors"
+	"regexp"
 	"strings"
+	"sync"
 )
 
-// DatabaseFilter stores a list of filtered databases given a comma-separated list of database names
+// DatabaseFilter stores the databases and tables to include or exclude
+// given as comma-separated lists. Each entry may be:
+//   - an exact name, e.g. mydb
+//   - a LIKE or glob pattern, e.g. shard_% or shard_*
+//   - a regular expression between slashes, e.g. /^shard_[0-9]+$/
+//
+// Table entries are of the form <database>.<table> or <table>.
+// It may also hold a filter on the names of the rows shown which can be changed while running.
 type DatabaseFilter struct {
-	userInput     string
-	filteredInput []string
+	userInput string
+	includes  []pattern // databases to include (any may match)
+	excludes  []pattern // databases to exclude
+	tables    []table   // tables to include (any may match)
+	extraSQL  string    // SQL condition built from the patterns
+	args      []string  // arguments for the placeholders in extraSQL
+
+	mu          sync.RWMutex
+	namePattern string         // pattern as given by the user
+	nameRegexp  *regexp.Regexp // compiled pattern or nil if not filtering on names
 }
 
-// NewDatabaseFilter returns the DatabaseFilter based on the comma-separated list of database names given
+// NewDatabaseFilter returns the DatabaseFilter based on the comma-separated list of database names given.
+// Invalid patterns are ignored.
 func NewDatabaseFilter(filter string) *DatabaseFilter {
+	dbf, _ := NewFilter(filter, "", "")
+	return dbf
+}
+
+// NewFilter returns a DatabaseFilter including the databases given,
+// excluding the excluded databases and including the tables given.
+// Each is a comma-separated list. An error is returned if a pattern is
+// invalid in which case the filter without that pattern is also returned.
+func NewFilter(databases, excludedDatabases, tables string) (*DatabaseFilter, error) {
+	var errs []error
+
 	dbf := &DatabaseFilter{
-		userInput:     filter,
-		filteredInput: make([]string, 0),
+		userInput: databases,
 	}
+	dbf.includes, errs = parsePatterns(databases, errs)
+	dbf.excludes, errs = parsePatterns(excludedDatabases, errs)
+	for _, entry := range splitList(tables) {
+		var t table
+		var err error
 
-	// whitespace trim the unfiltered and ignore any empty strings or strings with spaces
-	for _, name := range strings.Split(filter, ",") {
-		name = strings.TrimSpace(name)
-		if len(name) > 0 && !strings.Contains(name, " ") {
-			dbf.filteredInput = append(dbf.filteredInput, name)
+		schema, name, found := strings.Cut(entry, ".")
+		if !found {
+			schema, name = "", entry
+		}
+		if schema != "" {
+			if t.schema, err = parsePattern(schema); err != nil {
+				errs = append(errs, err)
+				continue
+			}
 		}
+		if t.name, err = parsePattern(name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dbf.tables = append(dbf.tables, t)
 	}
-	return dbf
+	dbf.extraSQL, dbf.args = dbf.buildSQL()
+
+	return dbf, errors.Join(errs...)
+}
+
+// splitList returns the whitespace trimmed non-empty entries in a comma-separated list
+func splitList(list string) []string {
+	var entries []string
+
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) > 0 {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// parsePatterns appends the patterns in the comma-separated list to patterns, returning any errors
+func parsePatterns(list string, errs []error) ([]pattern, []error) {
+	var patterns []pattern
+
+	for _, entry := range splitList(list) {
+		p, err := parsePattern(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, errs
+}
+
+// String returns the databases filtered on as given by the user
+func (f *DatabaseFilter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.userInput
 }
 
 // Args returns the arguments to be provided to sql.Query(..., args)
@@ -34,7 +114,7 @@ func (f *DatabaseFilter) Args() []string {
 	if f == nil {
 		return nil
 	}
-	return f.filteredInput
+	return f.args
 }
 
 // return placeholders for each name
@@ -49,9 +129,195 @@ func placeholders(names []string) []string {
 
 // ExtraSQL returns the extra string to apply to the base SQL statement (placeholders)
 func (f *DatabaseFilter) ExtraSQL() string {
-	if len(f.filteredInput) == 0 {
+	if f == nil {
+		return ""
+	}
+	return f.extraSQL
+}
+
+// buildSQL returns the condition on OBJECT_SCHEMA and OBJECT_NAME to
+// add to a query and the arguments for its placeholders
+func (f *DatabaseFilter) buildSQL() (string, []string) {
+	var (
+		sql  string
+		args []string
+	)
+
+	// included databases: exact names are combined into an IN () list
+	var names, conditions []string
+	for _, p := range f.includes {
+		if p.kind == exactPattern {
+			names = append(names, p.value)
+			continue
+		}
+		conditions = append(conditions, p.condition("OBJECT_SCHEMA", false))
+		args = append(args, p.value)
+	}
+	if len(names) > 0 {
+		conditions = append([]string{`OBJECT_SCHEMA IN (` + strings.Join(placeholders(names), `,`) + `)`}, conditions...)
+		args = append(names, args...)
+	}
+	sql += anyOf(conditions)
+
+	// excluded databases
+	names = nil
+	for _, p := range f.excludes {
+		if p.kind == exactPattern {
+			names = append(names, p.value)
+			continue
+		}
+		sql += ` AND ` + p.condition("OBJECT_SCHEMA", true)
+		args = append(args, p.value)
+	}
+	if len(names) > 0 {
+		sql += ` AND OBJECT_SCHEMA NOT IN (` + strings.Join(placeholders(names), `,`) + `)`
+		args = append(args, names...)
+	}
+
+	// included tables
+	conditions = nil
+	for _, t := range f.tables {
+		condition := t.name.condition("OBJECT_NAME", false)
+		if t.schema.value != "" {
+			condition = `(` + t.schema.condition("OBJECT_SCHEMA", false) + ` AND ` + condition + `)`
+			args = append(args, t.schema.value)
+		}
+		conditions = append(conditions, condition)
+		args = append(args, t.name.value)
+	}
+	sql += anyOf(conditions)
+
+	return sql, args
+}
+
+// anyOf returns an SQL condition requiring any of the given conditions to be true
+func anyOf(conditions []string) string {
+	switch len(conditions) {
+	case 0:
 		return ""
+	case 1:
+		return ` AND ` + conditions[0]
+	default:
+		return ` AND (` + strings.Join(conditions, ` OR `) + `)`
+	}
+}
+
+// FiltersDatabases returns true if only some databases are shown
+func (f *DatabaseFilter) FiltersDatabases() bool {
+	return f != nil && (len(f.includes) > 0 || len(f.excludes) > 0)
+}
+
+// MatchesDatabase returns true if rows from the given database should be
+// shown. Table filters are not checked.
+// - if f == nil all databases match
+func (f *DatabaseFilter) MatchesDatabase(schema string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.includes) > 0 && !matchesAny(f.includes, schema) {
+		return false
+	}
+	return !matchesAny(f.excludes, schema)
+}
+
+// MatchesTable returns true if rows for the given table should be shown
+// - if f == nil all tables match
+func (f *DatabaseFilter) MatchesTable(schema, name string) bool {
+	if f == nil {
+		return true
+	}
+	if !f.MatchesDatabase(schema) {
+		return false
+	}
+	if len(f.tables) == 0 {
+		return true
+	}
+	for _, t := range f.tables {
+		if (t.schema.value == "" || t.schema.matches(schema)) && t.name.matches(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesObject returns true if rows for the given object name, as
+// shown by ps-top, should be shown. Names of the form <database>.<table>
+// are checked with MatchesTable. Other names, e.g. <redo_log>, do not
+// belong to a database so are only shown if no databases or tables are
+// being included.
+func (f *DatabaseFilter) MatchesObject(name string) bool {
+	if f == nil {
+		return true
+	}
+	schema, table, found := strings.Cut(name, ".")
+	if !found || strings.HasPrefix(name, "<") || strings.HasPrefix(name, "/") {
+		return len(f.includes) == 0 && len(f.tables) == 0
+	}
+	return f.MatchesTable(schema, table)
+}
+
+// SetNameFilter sets the filter applied to row names. The pattern is
+// treated as a case-insensitive regular expression, or as a plain
+// substring if it is not a valid regular expression. An empty pattern
+// removes the filter.
+func (f *DatabaseFilter) SetNameFilter(pattern string) {
+	var re *regexp.Regexp
+
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile("(?i)" + pattern); err != nil {
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(pattern))
+		}
+	}
+
+	f.mu.Lock()
+	f.namePattern = pattern
+	f.nameRegexp = re
+	f.mu.Unlock()
+}
+
+// NameFilter returns the pattern used to filter row names or an empty string if there is none
+func (f *DatabaseFilter) NameFilter() string {
+	if f == nil {
+		return ""
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.namePattern
+}
+
+// MatchesName returns true if the given row name should be shown
+// - if f == nil all names match
+func (f *DatabaseFilter) MatchesName(name string) bool {
+	if f == nil {
+		return true
+	}
+
+	f.mu.RLock()
+	re := f.nameRegexp
+	f.mu.RUnlock()
+
+	return re == nil || re.MatchString(name)
+}
+
+// MatchingRows returns the rows whose name matches the name filter
+func MatchingRows[S ~[]T, T any](f *DatabaseFilter, rows S, name func(T) string) S {
+	if f.NameFilter() == "" {
+		return rows
+	}
+	return Rows(rows, func(row T) bool { return f.MatchesName(name(row)) })
+}
+
+// Rows returns the rows for which matches returns true
+func Rows[S ~[]T, T any](rows S, matches func(T) bool) S {
+	matching := make(S, 0, len(rows))
+	for _, row := range rows {
+		if matches(row) {
+			matching = append(matching, row)
+		}
 	}
 
-	return ` AND OBJECT_SCHEMA IN (` + strings.Join(placeholders(f.filteredInput), `,`) + `)`
+	return matching
 }
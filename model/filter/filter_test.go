@@ -68,3 +68,123 @@ func TestExtraSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchesName(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"", "db.table", true},
+		{"table", "db.TABLE", true},
+		{"^db\\.", "db.table", true},
+		{"^db\\.", "otherdb.table", false},
+		{"t[", "db.t[1]", true}, // invalid regexp so used as text
+		{"t[", "db.t1", false},
+	}
+
+	for _, test := range tests {
+		f := NewDatabaseFilter("")
+		f.SetNameFilter(test.pattern)
+		if result := f.MatchesName(test.name); result != test.expected {
+			t.Errorf("MatchesName(%q) with pattern %q failed. Got %v, expected %v", test.name, test.pattern, result, test.expected)
+		}
+	}
+
+	var f *DatabaseFilter
+	if !f.MatchesName("anything") || f.NameFilter() != "" {
+		t.Errorf("nil DatabaseFilter should match all names")
+	}
+}
+
+func TestMatchingRows(t *testing.T) {
+	f := NewDatabaseFilter("")
+	rows := []string{"db1.a", "db1.b", "db2.a"}
+	name := func(s string) string { return s }
+
+	if result := MatchingRows(f, rows, name); !slices.Equal(result, rows) {
+		t.Errorf("MatchingRows() without a filter failed. Got %v", result)
+	}
+	f.SetNameFilter("db1")
+	if result := MatchingRows(f, rows, name); !slices.Equal(result, []string{"db1.a", "db1.b"}) {
+		t.Errorf("MatchingRows() failed. Got %v", result)
+	}
+}
+
+func TestNewFilterSQL(t *testing.T) {
+	tests := []struct {
+		databases, excluded, tables string
+		sql                         string
+		args                        []string
+	}{
+		{"a b", "", "", ` AND OBJECT_SCHEMA IN (?)`, []string{"a b"}},
+		{"shard_%", "", "", ` AND OBJECT_SCHEMA LIKE ?`, []string{"shard_%"}},
+		{"a,shard_*", "", "", ` AND (OBJECT_SCHEMA IN (?) OR OBJECT_SCHEMA LIKE ?)`, []string{"a", `shard\_%`}},
+		{"/^s[0-9]+$/", "", "", ` AND OBJECT_SCHEMA REGEXP ?`, []string{"^s[0-9]+$"}},
+		{"", "mysql,sys", "", ` AND OBJECT_SCHEMA NOT IN (?,?)`, []string{"mysql", "sys"}},
+		{"", "tmp%", "", ` AND OBJECT_SCHEMA NOT LIKE ?`, []string{"tmp%"}},
+		{"", "", "db.tbl*", ` AND (OBJECT_SCHEMA = ? AND OBJECT_NAME LIKE ?)`, []string{"db", "tbl%"}},
+		{"", "", "t1,t2", ` AND (OBJECT_NAME = ? OR OBJECT_NAME = ?)`, []string{"t1", "t2"}},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilter(test.databases, test.excluded, test.tables)
+		if err != nil {
+			t.Fatalf("NewFilter(%q, %q, %q) failed: %v", test.databases, test.excluded, test.tables, err)
+		}
+		if got := f.ExtraSQL(); got != test.sql {
+			t.Errorf("NewFilter(%q, %q, %q).ExtraSQL() failed. Got: %q, wanted: %q", test.databases, test.excluded, test.tables, got, test.sql)
+		}
+		if got := f.Args(); !slices.Equal(got, test.args) {
+			t.Errorf("NewFilter(%q, %q, %q).Args() failed. Got: %q, wanted: %q", test.databases, test.excluded, test.tables, got, test.args)
+		}
+	}
+}
+
+func TestNewFilterInvalid(t *testing.T) {
+	if _, err := NewFilter("/[/", "", ""); err == nil {
+		t.Errorf("NewFilter() with an invalid regular expression did not fail")
+	}
+}
+
+func TestMatchesObject(t *testing.T) {
+	tests := []struct {
+		databases, excluded, tables string
+		name                        string
+		expected                    bool
+	}{
+		{"", "", "", "db.t1", true},
+		{"", "", "", "<redo_log>", true},
+		{"db", "", "", "db.t1", true},
+		{"db", "", "", "other.t1", false},
+		{"db", "", "", "<redo_log>", false},
+		{"shard_*", "", "", "shard_1.t1", true},
+		{"shard_*", "", "", "shard.t1", false},
+		{"shard_*", "", "", "shardx1.t1", false},
+		{"Shard_*", "", "", "shard_1.t1", true},
+		{"shard_%", "", "", "shardx.t1", true},
+		{"db", "", "", "DB.t1", true},
+		{"/^S[0-9]$/", "", "", "s1.t1", true},
+		{"/^s[0-9]$/", "", "", "s1.t1", true},
+		{"/^s[0-9]$/", "", "", "s10.t1", false},
+		{"", "mysql,sys", "", "mysql.user", false},
+		{"", "mysql,sys", "", "db.t1", true},
+		{"", "mysql,sys", "", "<binlog>", true},
+		{"", "", "db.tbl*", "db.tbl_1", true},
+		{"", "", "db.tbl*", "db.other", false},
+		{"", "", "db.tbl*", "db2.tbl_1", false},
+		{"", "", "db.tbl?", "db.TBL1", true},
+		{"", "", `db.tbl\*`, `db.tbl\1`, true},
+		{"", "", "t1", "db2.t1", true},
+	}
+
+	for _, test := range tests {
+		f, err := NewFilter(test.databases, test.excluded, test.tables)
+		if err != nil {
+			t.Fatalf("NewFilter(%q, %q, %q) failed: %v", test.databases, test.excluded, test.tables, err)
+		}
+		if got := f.MatchesObject(test.name); got != test.expected {
+			t.Errorf("NewFilter(%q, %q, %q).MatchesObject(%q) failed. Got: %v, wanted: %v", test.databases, test.excluded, test.tables, test.name, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// patternKind indicates how a pattern is matched
+type patternKind int
+
+const (
+	exactPattern  patternKind = iota // the name must be equal
+	likePattern                      // SQL LIKE pattern using % and _, escaped with \\
+	regexpPattern                    // regular expression
+)
+
+// pattern is a database or table name pattern
+type pattern struct {
+	kind  patternKind
+	value string         // name, LIKE pattern or regular expression as used in SQL
+	re    *regexp.Regexp // used to match names in ps-top (not used for exact patterns)
+}
+
+// table is a pattern for table names, optionally restricted to matching databases
+type table struct {
+	schema pattern // empty if any database matches
+	name   pattern
+}
+
+// parsePattern converts a name given by the user into a pattern.
+// /.../ is a regular expression, names containing * or ? are glob
+// patterns, converted to LIKE patterns with * and ? as % and _, names
+// containing % are LIKE patterns and anything else is an exact name.
+// Names are matched case-insensitively in ps-top as they are by MySQL.
+func parsePattern(s string) (pattern, error) {
+	if len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		value := s[1 : len(s)-1]
+		re, err := regexp.Compile("(?i)" + value)
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid regular expression %q: %w", s, err)
+		}
+		return pattern{kind: regexpPattern, value: value, re: re}, nil
+	}
+
+	if strings.ContainsAny(s, "*?") {
+		value := globToLike(s)
+		return pattern{kind: likePattern, value: value, re: likeToRegexp(value)}, nil
+	}
+
+	if strings.Contains(s, "%") {
+		return pattern{kind: likePattern, value: s, re: likeToRegexp(s)}, nil
+	}
+
+	return pattern{kind: exactPattern, value: s}, nil
+}
+
+// globToLike converts a glob pattern into an equivalent LIKE pattern,
+// escaping the characters LIKE treats specially which are literal in a
+// glob, e.g. the _ of shard_*
+func globToLike(glob string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"%", `\%`,
+		"_", `\_`,
+		"*", "%",
+		"?", "_",
+	).Replace(glob)
+}
+
+// likeToRegexp converts a LIKE pattern, with \ escaping the following
+// character, into an equivalent case-insensitive regular expression
+func likeToRegexp(like string) *regexp.Regexp {
+	var b strings.Builder
+
+	b.WriteString("(?i)^")
+	escaped := false
+	for _, r := range like {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		b.WriteString(regexp.QuoteMeta(`\`))
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
+
+// matches returns true if the name matches the pattern
+func (p pattern) matches(name string) bool {
+	if p.kind == exactPattern {
+		return strings.EqualFold(name, p.value)
+	}
+	return p.re.MatchString(name)
+}
+
+// condition returns the SQL condition checking column against the pattern
+func (p pattern) condition(column string, negate bool) string {
+	var op string
+
+	switch p.kind {
+	case likePattern:
+		op = "LIKE"
+	case regexpPattern:
+		op = "REGEXP"
+	default:
+		op = "="
+		if negate {
+			return column + " <> ?"
+		}
+	}
+	if negate {
+		op = "NOT " + op
+	}
+
+	return column + " " + op + " ?"
+}
+
+// matchesAny returns true if the name matches any of the patterns
+func matchesAny(patterns []pattern, name string) bool {
+	for _, p := range patterns {
+		if p.matches(name) {
+			return true
+		}
+	}
+	return false
+}
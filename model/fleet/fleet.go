@@ -0,0 +1,69 @@
+// Package fleet contains the routines for showing the totals of
+// several servers side by side.
+package fleet
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Config provides the settings of the servers
+type Config interface {
+	WantRelativeStats() bool
+}
+
+// Fleet holds the totals of each server
+type Fleet struct {
+	config         Config
+	collect        func(context.Context) Rows // collects the totals of each server
+	FirstCollected time.Time
+	LastCollected  time.Time
+	Results        Rows // totals of each server
+	Totals         Row  // totals of all servers
+}
+
+// NewFleet returns a Fleet which uses collect to collect the totals of each server
+func NewFleet(cfg Config, collect func(context.Context) Rows) *Fleet {
+	return &Fleet{
+		config:  cfg,
+		collect: collect,
+	}
+}
+
+// Collect collects the totals of each server. A server which can not
+// be collected from has the error in its row so none is returned.
+func (f *Fleet) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	f.AddRows(f.collect(ctx), time.Now())
+
+	log.Println("Fleet.Collect() took:", time.Duration(time.Since(start)).String())
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (f *Fleet) AddRows(rows Rows, collected time.Time) {
+	if f.FirstCollected.IsZero() {
+		f.FirstCollected = collected
+	}
+	f.LastCollected = collected
+	f.Results = rows
+	f.Totals = totals(f.Results)
+}
+
+// ResetStatistics records when counting started again. The servers
+// reset their own statistics having just collected them.
+func (f *Fleet) ResetStatistics() {
+	f.FirstCollected = time.Now()
+}
+
+// HaveRelativeStats is true for this object
+func (f Fleet) HaveRelativeStats() bool {
+	return true
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (f Fleet) WantRelativeStats() bool {
+	return f.config.WantRelativeStats()
+}
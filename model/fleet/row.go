@@ -0,0 +1,38 @@
+package fleet
+
+import "slices"
+
+// Names of the views whose models give the totals, as listed in Row.Failed
+const (
+	TableIOLatency   = "table_io_latency"
+	FileIOLatency    = "file_io_latency"
+	TableLockLatency = "table_lock_latency"
+	LockWaits        = "lock_waits"
+)
+
+// Row holds the totals collected from one server
+type Row struct {
+	Host             string   `json:"host"`
+	Version          string   `json:"version"`
+	Error            string   `json:"error,omitempty"`  // why nothing could be collected
+	Failed           []string `json:"failed,omitempty"` // views whose totals could not be collected
+	TableIOLatency   uint64   `json:"table_io_latency"`
+	FileIOLatency    uint64   `json:"file_io_latency"`
+	TableLockLatency uint64   `json:"table_lock_latency"`
+	LockWaits        int      `json:"lock_waits"`
+	TopTable         string   `json:"top_table"`
+	TopTableLatency  uint64   `json:"top_table_latency"`
+}
+
+// Collected returns whether the totals of the given view were collected
+func (r Row) Collected(view string) bool {
+	return !slices.Contains(r.Failed, view)
+}
+
+// add adds the totals of another row
+func (r *Row) add(other Row) {
+	r.TableIOLatency += other.TableIOLatency
+	r.FileIOLatency += other.FileIOLatency
+	r.TableLockLatency += other.TableLockLatency
+	r.LockWaits += other.LockWaits
+}
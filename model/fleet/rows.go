@@ -0,0 +1,20 @@
+package fleet
+
+// Rows contains the totals of each server
+type Rows []Row
+
+// totals returns the totals of all servers and the table with the
+// highest latency on any of them
+func totals(rows Rows) Row {
+	total := Row{Host: "Totals"}
+
+	for _, row := range rows {
+		total.add(row)
+		if row.TopTableLatency > total.TopTableLatency {
+			total.TopTable = row.Host + ":" + row.TopTable
+			total.TopTableLatency = row.TopTableLatency
+		}
+	}
+
+	return total
+}
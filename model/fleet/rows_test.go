@@ -0,0 +1,19 @@
+package fleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTotals(t *testing.T) {
+	rows := Rows{
+		{Host: "db1", TableIOLatency: 10, FileIOLatency: 1, TableLockLatency: 2, LockWaits: 1, TopTable: "db.t1", TopTableLatency: 6},
+		{Host: "db2", Error: "connection refused"},
+		{Host: "db3", TableIOLatency: 20, FileIOLatency: 3, LockWaits: 2, TopTable: "db.t2", TopTableLatency: 15},
+	}
+	expected := Row{Host: "Totals", TableIOLatency: 30, FileIOLatency: 4, TableLockLatency: 2, LockWaits: 3, TopTable: "db3:db.t2", TopTableLatency: 15}
+
+	if got := totals(rows); !reflect.DeepEqual(got, expected) {
+		t.Errorf("totals() failed: got: %+v, expected: %+v", got, expected)
+	}
+}
@@ -0,0 +1,112 @@
+// Package indexusage contains the routines for managing
+// performance_schema.table_io_waits_summary_by_index_usage.
+package indexusage
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// IndexUsage contains performance_schema.table_io_waits_summary_by_index_usage data
+type IndexUsage struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	first          Rows // initial data for relative values
+	last           Rows // last loaded values
+	Results        Rows // results with data (maybe with subtraction)
+	Unused         Rows // indexes which have not been used
+	Totals         Row  // totals of results
+	db             datasource.DataSource
+}
+
+// NewIndexUsage returns an index usage object with config and db handle
+func NewIndexUsage(cfg *config.Config, db datasource.DataSource) *IndexUsage {
+	return &IndexUsage{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// ResetStatistics resets the statistics to current values
+func (iu *IndexUsage) ResetStatistics() {
+	iu.first = utils.DuplicateSlice(iu.last)
+	iu.FirstCollected = iu.LastCollected
+
+	iu.calculate()
+}
+
+// Collect collects data from the db, updating initial values
+// if needed, and then subtracting initial values if we want relative
+// values, after which it stores totals.
+func (iu *IndexUsage) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, iu.db, iu.config.DatabaseFilter())
+	if err != nil {
+		return err
+	}
+	iu.AddRows(rows, time.Now())
+
+	log.Println("IndexUsage.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time, updating
+// initial values if needed and calculating the results and totals.
+func (iu *IndexUsage) AddRows(rows Rows, collected time.Time) {
+	iu.last = rows
+	iu.LastCollected = collected
+
+	// check for no first data or need to reload initial characteristics
+	if (len(iu.first) == 0 && len(iu.last) > 0) || iu.first.needsRefresh(iu.last) {
+		iu.first = utils.DuplicateSlice(iu.last)
+		iu.FirstCollected = iu.LastCollected
+	}
+
+	iu.calculate()
+
+	log.Println("iu.first.totals():", totals(iu.first))
+	log.Println("iu.last.totals():", totals(iu.last))
+}
+
+// Last returns the last collected rows
+func (iu IndexUsage) Last() Rows {
+	return iu.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (iu *IndexUsage) Refilter() {
+	iu.calculate()
+}
+
+func (iu *IndexUsage) calculate() {
+	iu.Results = utils.DuplicateSlice(iu.last)
+
+	if iu.config.WantRelativeStats() {
+		iu.Results.subtract(iu.first)
+	}
+
+	iu.Results = filter.MatchingRows(iu.config.DatabaseFilter(), iu.Results, func(row Row) string { return row.Name })
+	iu.Unused = iu.Results.unused()
+	iu.Results = filter.Rows(iu.Results, func(row Row) bool { return row.HasData() })
+	iu.Totals = totals(iu.Results)
+}
+
+// HaveRelativeStats is true for this object
+func (iu IndexUsage) HaveRelativeStats() bool {
+	return true
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (iu IndexUsage) WantRelativeStats() bool {
+	return iu.config.WantRelativeStats()
+}
@@ -0,0 +1,61 @@
+// Package indexusage contains the routines for managing
+// performance_schema.table_io_waits_summary_by_index_usage.
+package indexusage
+
+import "github.com/sjmudd/ps-top/utils"
+
+// Row contains a row from table_io_waits_summary_by_index_usage
+type Row struct {
+	Name  string `json:"name"`  // the generated table name
+	Index string `json:"index"` // the index name, empty for full scans (INDEX_NAME IS NULL)
+
+	SumTimerWait   uint64 `json:"sum_timer_wait"`
+	SumTimerFetch  uint64 `json:"sum_timer_fetch"`
+	SumTimerInsert uint64 `json:"sum_timer_insert"`
+	SumTimerUpdate uint64 `json:"sum_timer_update"`
+	SumTimerDelete uint64 `json:"sum_timer_delete"`
+	MinTimerWait   uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait   uint64 `json:"max_timer_wait"` // not subtracted
+
+	CountStar   uint64 `json:"count_star"`
+	CountFetch  uint64 `json:"count_fetch"`
+	CountInsert uint64 `json:"count_insert"`
+	CountUpdate uint64 `json:"count_update"`
+	CountDelete uint64 `json:"count_delete"`
+}
+
+// key identifies the row as the same index may exist in different tables
+func (row Row) key() string {
+	return row.Name + "\x00" + row.Index
+}
+
+// FullScan indicates if the row holds the I/O not using an index.
+// MySQL also counts inserts here.
+func (row Row) FullScan() bool {
+	return row.Index == ""
+}
+
+// subtract the countable values in one row from another
+func (row *Row) subtract(other Row) {
+	row.SumTimerWait -= other.SumTimerWait
+	row.SumTimerFetch -= other.SumTimerFetch
+	row.SumTimerInsert -= other.SumTimerInsert
+	row.SumTimerUpdate -= other.SumTimerUpdate
+	row.SumTimerDelete -= other.SumTimerDelete
+
+	row.CountStar -= other.CountStar
+	row.CountFetch -= other.CountFetch
+	row.CountInsert -= other.CountInsert
+	row.CountUpdate -= other.CountUpdate
+	row.CountDelete -= other.CountDelete
+}
+
+// Timers returns the number of waits and their latency
+func (row Row) Timers() utils.Timers {
+	return utils.Timers{Count: row.CountStar, Sum: row.SumTimerWait, Min: row.MinTimerWait, Max: row.MaxTimerWait}
+}
+
+// HasData indicates if there is data in the row (for counting valid rows)
+func (row *Row) HasData() bool {
+	return row != nil && row.SumTimerWait > 0
+}
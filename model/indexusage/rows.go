@@ -0,0 +1,141 @@
+// Package indexusage contains the routines for managing
+// performance_schema.table_io_waits_summary_by_index_usage.
+package indexusage
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Rows contains a set of rows
+type Rows []Row
+
+func totals(rows Rows) Row {
+	total := Row{Name: "Totals"}
+
+	for _, row := range rows {
+		total.SumTimerWait += row.SumTimerWait
+		total.SumTimerFetch += row.SumTimerFetch
+		total.SumTimerInsert += row.SumTimerInsert
+		total.SumTimerUpdate += row.SumTimerUpdate
+		total.SumTimerDelete += row.SumTimerDelete
+		total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+		total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
+
+		total.CountStar += row.CountStar
+		total.CountFetch += row.CountFetch
+		total.CountInsert += row.CountInsert
+		total.CountUpdate += row.CountUpdate
+		total.CountDelete += row.CountDelete
+	}
+
+	return total
+}
+
+func collect(ctx context.Context, db datasource.DataSource, databaseFilter *filter.DatabaseFilter) (Rows, error) {
+	var t Rows
+
+	log.Printf("collect(?,%q)\n", databaseFilter)
+
+	// unused indexes are collected too so that they can be listed
+	query := `SELECT OBJECT_SCHEMA, OBJECT_NAME, INDEX_NAME, COUNT_STAR, SUM_TIMER_WAIT, MIN_TIMER_WAIT, MAX_TIMER_WAIT, COUNT_FETCH, SUM_TIMER_FETCH, COUNT_INSERT, SUM_TIMER_INSERT, COUNT_UPDATE, SUM_TIMER_UPDATE, COUNT_DELETE, SUM_TIMER_DELETE FROM table_io_waits_summary_by_index_usage WHERE OBJECT_TYPE = 'TABLE'`
+	args := []interface{}{}
+
+	// Apply the filter if provided and seems good.
+	if len(databaseFilter.Args()) > 0 {
+		query = query + databaseFilter.ExtraSQL()
+		for _, v := range databaseFilter.Args() {
+			args = append(args, v)
+		}
+		log.Printf("apply databaseFilter: sql: %q, args: %+v\n", query, args)
+	}
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var schema, table string
+		var index sql.NullString
+		var r Row
+		if err := rows.Scan(
+			&schema,
+			&table,
+			&index,
+			&r.CountStar,
+			&r.SumTimerWait,
+			&r.MinTimerWait,
+			&r.MaxTimerWait,
+			&r.CountFetch,
+			&r.SumTimerFetch,
+			&r.CountInsert,
+			&r.SumTimerInsert,
+			&r.CountUpdate,
+			&r.SumTimerUpdate,
+			&r.CountDelete,
+			&r.SumTimerDelete); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r.Name = utils.QualifiedTableName(schema, table)
+		if index.Valid && index.String != "PRIMARY" {
+			r.Index = anonymiser.Anonymise("index", index.String)
+		} else {
+			r.Index = index.String
+		}
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
+
+// unused returns the indexes which have not been used, ordered by
+// table and index name. Primary keys are not included as they are
+// needed even if no query uses them.
+func (rows Rows) unused() Rows {
+	unused := filter.Rows(rows, func(row Row) bool {
+		return row.CountStar == 0 && !row.FullScan() && row.Index != "PRIMARY"
+	})
+	slices.SortFunc(unused, func(a, b Row) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Index, b.Index))
+	})
+
+	return unused
+}
+
+// remove the initial values from those rows where there's a match
+// - if we find a row we can't match ignore it
+func (rows *Rows) subtract(initial Rows) {
+	initialByKey := make(map[string]int)
+
+	for i := range initial {
+		initialByKey[initial[i].key()] = i
+	}
+
+	for i := range *rows {
+		if initialIndex, ok := initialByKey[(*rows)[i].key()]; ok {
+			(*rows)[i].subtract(initial[initialIndex])
+		}
+	}
+}
+
+// if the data in t2 is "newer", "has more values" than t then it needs refreshing.
+// check this by comparing totals.
+func (rows Rows) needsRefresh(otherRows Rows) bool {
+	return totals(rows).SumTimerWait > totals(otherRows).SumTimerWait
+}
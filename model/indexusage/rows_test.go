@@ -0,0 +1,46 @@
+package indexusage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsSubtract(t *testing.T) {
+	initial := Rows{
+		{Name: "db.t1", Index: "PRIMARY", CountStar: 1, SumTimerWait: 10},
+		{Name: "db.t2", Index: "PRIMARY", CountStar: 2, SumTimerWait: 20},
+	}
+	rows := Rows{
+		{Name: "db.t1", Index: "PRIMARY", CountStar: 3, SumTimerWait: 30},
+		{Name: "db.t2", Index: "PRIMARY", CountStar: 5, SumTimerWait: 50},
+		{Name: "db.t1", Index: "", CountStar: 7, SumTimerWait: 70},
+	}
+	expected := Rows{
+		{Name: "db.t1", Index: "PRIMARY", CountStar: 2, SumTimerWait: 20},
+		{Name: "db.t2", Index: "PRIMARY", CountStar: 3, SumTimerWait: 30},
+		{Name: "db.t1", Index: "", CountStar: 7, SumTimerWait: 70},
+	}
+
+	rows.subtract(initial)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows.subtract() failed: got: %+v, expected: %+v", rows, expected)
+	}
+}
+
+func TestRowsUnused(t *testing.T) {
+	rows := Rows{
+		{Name: "db.t2", Index: "idx_b"},
+		{Name: "db.t1", Index: "PRIMARY"},
+		{Name: "db.t1", Index: ""},
+		{Name: "db.t1", Index: "idx_c", CountStar: 1, SumTimerWait: 10},
+		{Name: "db.t1", Index: "idx_a"},
+	}
+	expected := Rows{
+		{Name: "db.t1", Index: "idx_a"},
+		{Name: "db.t2", Index: "idx_b"},
+	}
+
+	if got := rows.unused(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Rows.unused() failed: got: %+v, expected: %+v", got, expected)
+	}
+}
@@ -0,0 +1,89 @@
+// Package lockwaits contains the routines for showing the sessions
+// waiting for metadata or InnoDB locks and the sessions blocking them.
+package lockwaits
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// LockWaits holds the sessions currently waiting for locks
+type LockWaits struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	last           Rows // last loaded values
+	Results        Rows // lock waits to show
+	db             datasource.DataSource
+}
+
+// NewLockWaits returns a lock waits object using the given config and db
+func NewLockWaits(cfg *config.Config, db datasource.DataSource) *LockWaits {
+	return &LockWaits{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// Collect collects the current lock waits from the db
+func (lw *LockWaits) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, lw.db, lw.config.MySQLVersion())
+	if err != nil {
+		return err
+	}
+	lw.AddRows(rows, time.Now())
+
+	log.Println("LockWaits.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (lw *LockWaits) AddRows(rows Rows, collected time.Time) {
+	lw.last = rows
+	lw.LastCollected = collected
+
+	lw.calculate()
+}
+
+// Last returns the last collected rows
+func (lw LockWaits) Last() Rows {
+	return lw.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (lw *LockWaits) Refilter() {
+	lw.calculate()
+}
+
+func (lw *LockWaits) calculate() {
+	f := lw.config.DatabaseFilter()
+
+	lw.Results = utils.DuplicateSlice(lw.last)
+	lw.Results = filter.Rows(lw.Results, func(row Row) bool { return f.MatchesObject(row.Object) })
+	lw.Results = filter.MatchingRows(f, lw.Results, func(row Row) string { return row.Object })
+}
+
+// ResetStatistics recalculates the results as there are no counters to reset
+func (lw *LockWaits) ResetStatistics() {
+	lw.calculate()
+}
+
+// HaveRelativeStats is false for this object
+func (lw LockWaits) HaveRelativeStats() bool {
+	return false
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (lw LockWaits) WantRelativeStats() bool {
+	return lw.config.WantRelativeStats()
+}
@@ -0,0 +1,26 @@
+// Package lockwaits contains the routines for showing the sessions
+// waiting for metadata or InnoDB locks and the sessions blocking them.
+package lockwaits
+
+// Lock types
+const (
+	Metadata = "metadata"
+	InnoDB   = "innodb"
+)
+
+// Row contains a session waiting for a lock and a session holding a
+// lock which blocks it
+type Row struct {
+	Lock         string `json:"lock"`      // Metadata or InnoDB
+	Object       string `json:"object"`    // the locked object, e.g. <database>.<table>
+	WaitTime     uint64 `json:"wait_time"` // seconds the session has been waiting
+	WaitingID    uint64 `json:"waiting_id"`
+	WaitingUser  string `json:"waiting_user"`
+	WaitingMode  string `json:"waiting_mode"`
+	BlockingID   uint64 `json:"blocking_id"`
+	BlockingUser string `json:"blocking_user"`
+	BlockingMode string `json:"blocking_mode"`
+}
+
+// Rows contains a set of rows
+type Rows []Row
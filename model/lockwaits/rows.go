@@ -0,0 +1,182 @@
+// Package lockwaits contains the routines for showing the sessions
+// waiting for metadata or InnoDB locks and the sessions blocking them.
+package lockwaits
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// metadataLockWaits returns the pending metadata locks and the granted
+// locks on the same object held by other threads, which only block the
+// pending lock if their types conflict. Needs the
+// wait/lock/metadata/sql/mdl instrument to be enabled (the default in 8.0).
+const metadataLockWaits = `SELECT w.OBJECT_TYPE, w.OBJECT_SCHEMA, w.OBJECT_NAME, w.LOCK_TYPE, wt.PROCESSLIST_ID, wt.PROCESSLIST_USER, wt.PROCESSLIST_TIME, b.LOCK_TYPE, bt.PROCESSLIST_ID, bt.PROCESSLIST_USER
+FROM metadata_locks w
+JOIN metadata_locks b ON b.OBJECT_TYPE = w.OBJECT_TYPE AND b.OBJECT_SCHEMA <=> w.OBJECT_SCHEMA AND b.OBJECT_NAME <=> w.OBJECT_NAME AND b.LOCK_STATUS = 'GRANTED' AND b.OWNER_THREAD_ID <> w.OWNER_THREAD_ID
+JOIN threads wt ON wt.THREAD_ID = w.OWNER_THREAD_ID
+JOIN threads bt ON bt.THREAD_ID = b.OWNER_THREAD_ID
+WHERE w.LOCK_STATUS = 'PENDING'`
+
+// dataLockWaits returns the InnoDB lock waits in MySQL 8.0+
+const dataLockWaits = `SELECT 'TABLE', r.OBJECT_SCHEMA, r.OBJECT_NAME, r.LOCK_MODE, wt.PROCESSLIST_ID, wt.PROCESSLIST_USER, TIMESTAMPDIFF(SECOND, trx.trx_wait_started, NOW()), b.LOCK_MODE, bt.PROCESSLIST_ID, bt.PROCESSLIST_USER
+FROM data_lock_waits w
+JOIN data_locks r ON r.ENGINE_LOCK_ID = w.REQUESTING_ENGINE_LOCK_ID
+JOIN data_locks b ON b.ENGINE_LOCK_ID = w.BLOCKING_ENGINE_LOCK_ID
+JOIN threads wt ON wt.THREAD_ID = w.REQUESTING_THREAD_ID
+JOIN threads bt ON bt.THREAD_ID = w.BLOCKING_THREAD_ID
+LEFT JOIN information_schema.INNODB_TRX trx ON trx.trx_id = w.REQUESTING_ENGINE_TRANSACTION_ID`
+
+// innodbLockWaits returns the InnoDB lock waits in MySQL 5.7 and
+// MariaDB. The table is given as `<database>`.`<table>`.
+const innodbLockWaits = `SELECT 'TABLE', NULL, r.lock_table, r.lock_mode, wt.PROCESSLIST_ID, wt.PROCESSLIST_USER, TIMESTAMPDIFF(SECOND, rtrx.trx_wait_started, NOW()), b.lock_mode, bt.PROCESSLIST_ID, bt.PROCESSLIST_USER
+FROM information_schema.INNODB_LOCK_WAITS w
+JOIN information_schema.INNODB_LOCKS r ON r.lock_id = w.requested_lock_id
+JOIN information_schema.INNODB_LOCKS b ON b.lock_id = w.blocking_lock_id
+JOIN information_schema.INNODB_TRX rtrx ON rtrx.trx_id = w.requesting_trx_id
+JOIN information_schema.INNODB_TRX btrx ON btrx.trx_id = w.blocking_trx_id
+LEFT JOIN threads wt ON wt.PROCESSLIST_ID = rtrx.trx_mysql_thread_id
+LEFT JOIN threads bt ON bt.PROCESSLIST_ID = btrx.trx_mysql_thread_id`
+
+// hasDataLocks returns true if the server, given its version, has
+// performance_schema.data_locks (MySQL 8.0+) rather than
+// information_schema.innodb_lock_waits
+func hasDataLocks(version string) bool {
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return false
+	}
+	return utils.MajorVersion(version) >= 8
+}
+
+// incompatible holds for each type of metadata lock requested the
+// types of granted metadata locks it has to wait for, following the
+// compatibility matrices in MySQL's sql/mdl.cc. INTENTION_EXCLUSIVE is
+// only used by locks of a scope, e.g. global or schema, where SHARED
+// also conflicts with it.
+var incompatible = map[string][]string{
+	"INTENTION_EXCLUSIVE":   {"SHARED", "EXCLUSIVE"},
+	"SHARED":                {"INTENTION_EXCLUSIVE", "EXCLUSIVE"},
+	"SHARED_HIGH_PRIO":      {"EXCLUSIVE"},
+	"SHARED_READ":           {"SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_WRITE":          {"SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_WRITE_LOW_PRIO": {"SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_UPGRADABLE":     {"SHARED_UPGRADABLE", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_READ_ONLY":      {"SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_NO_WRITE":       {"SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+	"SHARED_NO_READ_WRITE":  {"SHARED_READ", "SHARED_WRITE", "SHARED_WRITE_LOW_PRIO", "SHARED_UPGRADABLE", "SHARED_READ_ONLY", "SHARED_NO_WRITE", "SHARED_NO_READ_WRITE", "EXCLUSIVE"},
+}
+
+// blocks returns whether a granted metadata lock of the given type
+// blocks a pending one. Pending exclusive locks, or those of a type not
+// known, wait for any granted lock.
+func blocks(pending, granted string) bool {
+	types, ok := incompatible[pending]
+	return !ok || slices.Contains(types, granted)
+}
+
+func collect(ctx context.Context, db datasource.DataSource, version string) (Rows, error) {
+	// metadata_locks does not exist in MySQL 5.6 or MariaDB before 10.5
+	t, err := query(ctx, db, Metadata, metadataLockWaits)
+	switch {
+	case datasource.TableMissing(err):
+		log.Println("lockwaits.collect(): unable to collect metadata lock waits:", err)
+	case err != nil:
+		return nil, err
+	}
+	t = slices.DeleteFunc(t, func(r Row) bool { return !blocks(r.WaitingMode, r.BlockingMode) })
+
+	innodbQuery := innodbLockWaits
+	if hasDataLocks(version) {
+		innodbQuery = dataLockWaits
+	}
+
+	// information_schema.INNODB_TRX needs the PROCESS privilege so
+	// show the metadata lock waits if it can not be read.
+	innodb, err := query(ctx, db, InnoDB, innodbQuery)
+	switch {
+	case datasource.TableMissing(err), datasource.AccessDenied(err):
+		log.Println("lockwaits.collect(): unable to collect InnoDB lock waits:", err)
+	case err != nil:
+		return nil, err
+	}
+
+	return append(t, innodb...), nil
+}
+
+// query returns the lock waits of the given type returned by the given query
+func query(ctx context.Context, db datasource.DataSource, lock, query string) (Rows, error) {
+	var t Rows
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var (
+			r                               Row
+			objectType                      string
+			schema, name, waitingMode       sql.NullString
+			waitingUser, blockingMode       sql.NullString
+			blockingUser                    sql.NullString
+			waitingID, waitTime, blockingID sql.NullInt64
+		)
+		if err := rows.Scan(
+			&objectType,
+			&schema,
+			&name,
+			&waitingMode,
+			&waitingID,
+			&waitingUser,
+			&waitTime,
+			&blockingMode,
+			&blockingID,
+			&blockingUser); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r.Lock = lock
+		r.Object = objectName(objectType, schema, name)
+		r.WaitTime = uint64(waitTime.Int64)
+		r.WaitingID = uint64(waitingID.Int64)
+		r.WaitingUser = anonymiser.Anonymise("user", waitingUser.String)
+		r.WaitingMode = waitingMode.String
+		r.BlockingID = uint64(blockingID.Int64)
+		r.BlockingUser = anonymiser.Anonymise("user", blockingUser.String)
+		r.BlockingMode = blockingMode.String
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
+
+// objectName returns the name shown for a locked object: the table name
+// for tables or the lock type in angle brackets for objects not belonging
+// to a table, e.g. <global>.
+func objectName(objectType string, schema, name sql.NullString) string {
+	switch {
+	case objectType == "TABLE" && !schema.Valid:
+		// information_schema.innodb_locks quotes the names: `<database>`.`<table>`
+		database, table, _ := strings.Cut(name.String, "`.`")
+		return utils.QualifiedTableName(strings.TrimPrefix(database, "`"), strings.TrimSuffix(table, "`"))
+	case schema.Valid && name.Valid:
+		return utils.QualifiedTableName(schema.String, name.String)
+	case schema.Valid:
+		return "<" + strings.ToLower(objectType) + " " + anonymiser.Anonymise("schema", schema.String) + ">"
+	default:
+		return "<" + strings.ToLower(objectType) + ">"
+	}
+}
@@ -0,0 +1,159 @@
+package lockwaits
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+func TestHasDataLocks(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected bool
+	}{
+		{"5.7.44-log", false},
+		{"8.0.36", true},
+		{"8.4.0", true},
+		{"10.6.12-MariaDB", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := hasDataLocks(test.version); got != test.expected {
+			t.Errorf("hasDataLocks(%q) failed: got: %v, expected: %v", test.version, got, test.expected)
+		}
+	}
+}
+
+func TestObjectName(t *testing.T) {
+	anonymiser.Enable(false)
+	null := sql.NullString{}
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+	tests := []struct {
+		objectType   string
+		schema, name sql.NullString
+		expected     string
+	}{
+		{"TABLE", str("db1"), str("t1"), "db1.t1"},
+		{"TABLE", null, str("`db1`.`t1`"), "db1.t1"},
+		{"SCHEMA", str("db1"), null, "<schema db1>"},
+		{"GLOBAL", null, null, "<global>"},
+	}
+	for _, test := range tests {
+		if got := objectName(test.objectType, test.schema, test.name); got != test.expected {
+			t.Errorf("objectName(%q, %v, %v) failed: got: %q, expected: %q", test.objectType, test.schema, test.name, got, test.expected)
+		}
+	}
+}
+
+func TestCollect(t *testing.T) {
+	anonymiser.Enable(false)
+	fixture := datasource.NewFixture().
+		Add("metadata_locks",
+			[]any{"TABLE", "db1", "t1", "EXCLUSIVE", 12, "dba", 30, "SHARED_READ", 10, "app"},
+			[]any{"TABLE", "db1", "t3", "SHARED_READ", 14, "app", 3, "SHARED_READ", 15, "app"}, // not blocking
+		).
+		Add("data_lock_waits",
+			[]any{"TABLE", "db1", "t2", "X,REC_NOT_GAP", 13, "app", 5, "X,REC_NOT_GAP", 11, "app"},
+		)
+
+	expected := Rows{
+		{Lock: Metadata, Object: "db1.t1", WaitTime: 30, WaitingID: 12, WaitingUser: "dba", WaitingMode: "EXCLUSIVE", BlockingID: 10, BlockingUser: "app", BlockingMode: "SHARED_READ"},
+		{Lock: InnoDB, Object: "db1.t2", WaitTime: 5, WaitingID: 13, WaitingUser: "app", WaitingMode: "X,REC_NOT_GAP", BlockingID: 11, BlockingUser: "app", BlockingMode: "X,REC_NOT_GAP"},
+	}
+	got, err := collect(context.Background(), fixture, "8.0.36")
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("collect() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+
+	// without access to the InnoDB lock waits only metadata lock waits are shown
+	fixture.AddError("INNODB_LOCK_WAITS", &mysql.MySQLError{Number: 1227, Message: "Access denied"})
+	got, err = collect(context.Background(), fixture, "5.7.44")
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected[:1]) {
+		t.Errorf("collect() for 5.7 failed:\ngot:      %+v\nexpected: %+v", got, expected[:1])
+	}
+}
+
+func TestCollectMissingMetadataLocks(t *testing.T) {
+	// MySQL 5.6 has no metadata_locks but the InnoDB lock waits are shown
+	missing := &mysql.MySQLError{Number: 1146, Message: "Table 'performance_schema.metadata_locks' doesn't exist"}
+	fixture := datasource.NewFixture().
+		AddError("metadata_locks", missing).
+		Add("INNODB_LOCK_WAITS",
+			[]any{"TABLE", nil, "`db1`.`t2`", "X", 13, "app", 5, "X", 11, "app"},
+		)
+	got, err := collect(context.Background(), fixture, "5.6.51")
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Lock != InnoDB {
+		t.Errorf("collect() failed: got: %+v, expected the InnoDB lock wait", got)
+	}
+
+	lost := errors.New("invalid connection")
+	fixture.AddError("metadata_locks", lost)
+	if _, err := collect(context.Background(), fixture, "5.6.51"); !errors.Is(err, lost) {
+		t.Errorf("collect() failed: got error: %v, expected: %v", err, lost)
+	}
+}
+
+func TestCollectInnoDBErrors(t *testing.T) {
+	// the metadata lock waits are shown without the PROCESS privilege
+	denied := &mysql.MySQLError{Number: 1227, Message: "Access denied; you need (at least one of) the PROCESS privilege(s) for this operation"}
+	fixture := datasource.NewFixture().
+		Add("metadata_locks",
+			[]any{"TABLE", "db1", "t1", "EXCLUSIVE", 12, "app", 3, "SHARED_READ", 10, "app"},
+		).
+		AddError("data_lock_waits", denied)
+	got, err := collect(context.Background(), fixture, "8.0.36")
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Lock != Metadata {
+		t.Errorf("collect() failed: got: %+v, expected the metadata lock wait", got)
+	}
+
+	// other errors, e.g. losing the connection, are returned
+	fixture.AddError("data_lock_waits", driver.ErrBadConn)
+	if _, err := collect(context.Background(), fixture, "8.0.36"); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("collect() failed: got error: %v, expected: %v", err, driver.ErrBadConn)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	tests := []struct {
+		pending, granted string
+		expected         bool
+	}{
+		{"SHARED_READ", "SHARED_READ", false},
+		{"SHARED_WRITE", "SHARED_READ", false},
+		{"SHARED_READ", "SHARED_NO_READ_WRITE", true},
+		{"SHARED_WRITE", "SHARED_READ_ONLY", true},
+		{"SHARED_UPGRADABLE", "SHARED_UPGRADABLE", true},
+		{"SHARED_UPGRADABLE", "SHARED_WRITE", false},
+		{"EXCLUSIVE", "SHARED_READ", true},
+		{"SHARED_HIGH_PRIO", "SHARED_NO_READ_WRITE", false},
+		{"INTENTION_EXCLUSIVE", "INTENTION_EXCLUSIVE", false},
+		{"INTENTION_EXCLUSIVE", "SHARED", true},
+		{"SHARED", "SHARED", false},
+		{"SOME_NEW_TYPE", "SHARED_READ", true},
+	}
+	for _, test := range tests {
+		if got := blocks(test.pending, test.granted); got != test.expected {
+			t.Errorf("blocks(%q, %q) failed: got: %v, expected: %v", test.pending, test.granted, got, test.expected)
+		}
+	}
+}
@@ -3,12 +3,15 @@
 package memoryusage
 
 import (
-	"database/sql"
+	"context"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql" // keep golint happy
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
 )
 
 // MemoryUsage represents a table of rows
@@ -16,14 +19,16 @@ type MemoryUsage struct {
 	config         *config.Config
 	FirstCollected time.Time // the first collection time (for relative data)
 	LastCollected  time.Time // the last collection time
+	breakdown      string    // how memory usage is broken down
+	first          []Row     // initial data for relative values
 	last           []Row     // last loaded values
 	Results        []Row     // results (maybe with subtraction)
 	Totals         Row       // totals of results
-	db             *sql.DB
+	db             datasource.DataSource
 }
 
 // NewMemoryUsage returns a pointer to a MemoryUsage struct
-func NewMemoryUsage(cfg *config.Config, db *sql.DB) *MemoryUsage {
+func NewMemoryUsage(cfg *config.Config, db datasource.DataSource) *MemoryUsage {
 	mu := &MemoryUsage{
 		db:     db,
 		config: cfg,
@@ -32,22 +37,63 @@ func NewMemoryUsage(cfg *config.Config, db *sql.DB) *MemoryUsage {
 	return mu
 }
 
-// Collect data from the db, no merging needed
-// DEPRECATED
-func (mu *MemoryUsage) Collect() {
-	mu.AddRows(collect(mu.db))
+// Collect collects data from the db for the current breakdown
+func (mu *MemoryUsage) Collect(ctx context.Context) error {
+	rows, err := collect(ctx, mu.db, mu.breakdown)
+	if err != nil {
+		return err
+	}
+	mu.AddRows(rows, time.Now())
+
+	return nil
 }
 
-// AddRows takes an new set of rows to be added to the dataset
-func (mu *MemoryUsage) AddRows(rows []Row) {
-	mu.last = rows
-	mu.LastCollected = time.Now()
+// AddRows takes an new set of rows collected at the given time to be
+// added to the dataset. Rows of a different breakdown, e.g. when
+// replaying a recording, are ignored.
+func (mu *MemoryUsage) AddRows(rows []Row, collected time.Time) {
+	mu.last = make([]Row, 0, len(rows))
+	for _, row := range rows {
+		if row.Breakdown == mu.breakdown {
+			mu.last = append(mu.last, row)
+		}
+	}
+	mu.LastCollected = collected
+
+	// check for no first data or need to reload initial characteristics
+	if (len(mu.first) == 0 && len(mu.last) > 0) || needsRefresh(mu.first, mu.last) {
+		mu.first = utils.DuplicateSlice(mu.last)
+		mu.FirstCollected = mu.LastCollected
+	}
 
 	mu.calculate()
 }
 
+// Breakdown returns how memory usage is broken down
+func (mu MemoryUsage) Breakdown() string {
+	return mu.breakdown
+}
+
+// SetBreakdown changes how memory usage is broken down. The values
+// already collected are discarded as they can not be compared with
+// those of the new breakdown.
+func (mu *MemoryUsage) SetBreakdown(breakdown string) {
+	mu.breakdown = breakdown
+	mu.first = nil
+	mu.last = nil
+
+	mu.calculate()
+}
+
+// Last returns the last collected rows
+func (mu MemoryUsage) Last() []Row {
+	return mu.last
+}
+
 // ResetStatistics resets the statistics to current values
 func (mu *MemoryUsage) ResetStatistics() {
+	mu.first = utils.DuplicateSlice(mu.last)
+	mu.FirstCollected = mu.LastCollected
 
 	mu.calculate()
 }
@@ -62,7 +108,7 @@ func (mu MemoryUsage) Rows() []Row {
 
 // HaveRelativeStats returns if the values returned are relative to a previous collection
 func (mu MemoryUsage) HaveRelativeStats() bool {
-	return false
+	return true
 }
 
 // WantRelativeStats
@@ -70,8 +116,19 @@ func (mu MemoryUsage) WantRelativeStats() bool {
 	return mu.config.WantRelativeStats()
 }
 
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (mu *MemoryUsage) Refilter() {
+	mu.calculate()
+}
+
 func (mu *MemoryUsage) calculate() {
-	mu.Results = make([]Row, len(mu.last))
-	copy(mu.Results, mu.last)
+	mu.Results = utils.DuplicateSlice(mu.last)
+
+	if mu.config.WantRelativeStats() {
+		subtract(mu.Results, mu.first)
+	}
+
+	mu.Results = filter.MatchingRows(mu.config.DatabaseFilter(), mu.Results, Row.FullName)
 	mu.Totals = totals(mu.Results)
 }
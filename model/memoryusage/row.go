@@ -3,12 +3,15 @@
 package memoryusage
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 
 	"github.com/go-sql-driver/mysql"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/breakdown"
 )
 
 /* This table exists in MySQL 5.7 but not 5.6
@@ -29,20 +32,50 @@ CREATE TABLE `memory_summary_global_by_event_name` (
 
 */
 
-// Row holds a row of data from memory_summary_global_by_event_name
+// Row holds a row of data from memory_summary_global_by_event_name or,
+// if memory usage is broken down, one of the memory_summary_by_*_by_event_name
+// tables. The high water marks are not subtracted when showing relative values.
 type Row struct {
-	Name              string
-	CurrentCountUsed  int64
-	HighCountUsed     int64
-	TotalMemoryOps    int64
-	CurrentBytesUsed  int64
-	HighBytesUsed     int64
-	TotalBytesManaged uint64
+	Breakdown         string `json:"breakdown,omitempty"` // how memory usage is broken down, empty for the global usage
+	Owner             string `json:"owner,omitempty"`     // the thread, account, user or host using the memory
+	Name              string `json:"name"`
+	CurrentCountUsed  int64  `json:"current_count_used"`
+	HighCountUsed     int64  `json:"high_count_used"`
+	TotalMemoryOps    int64  `json:"total_memory_ops"`
+	CurrentBytesUsed  int64  `json:"current_bytes_used"`
+	HighBytesUsed     int64  `json:"high_bytes_used"`
+	TotalBytesManaged uint64 `json:"total_bytes_managed"`
 }
 
-// HasData returns true if there is valid data in the row
+// FullName returns the name of the memory area prefixed by its owner, if any
+func (r Row) FullName() string {
+	if r.Owner == "" {
+		return r.Name
+	}
+	return r.Owner + ": " + r.Name
+}
+
+// key identifies the row as the same memory area is used by different owners
+func (r Row) key() string {
+	return r.Owner + "\x00" + r.Name
+}
+
+// HasData returns true if there is valid data in the row. Memory
+// allocated and freed again since the statistics were reset leaves
+// nothing in use but the row is still shown for its memory operations.
 func (r *Row) HasData() bool {
-	return r != nil && r.Name != "" && r.CurrentCountUsed != 0 && r.TotalMemoryOps != 0
+	return r != nil && r.Name != "" && (r.CurrentCountUsed != 0 || r.TotalMemoryOps != 0)
+}
+
+// subtract the values of one row from another, showing the growth in
+// memory usage and the memory operations since then
+func (r *Row) subtract(other Row) {
+	r.CurrentCountUsed -= other.CurrentCountUsed
+	r.CurrentBytesUsed -= other.CurrentBytesUsed
+	r.TotalMemoryOps -= other.TotalMemoryOps
+	if r.TotalBytesManaged >= other.TotalBytesManaged {
+		r.TotalBytesManaged -= other.TotalBytesManaged
+	}
 }
 
 // return the totals of a slice of rows
@@ -58,79 +91,108 @@ func totals(rows []Row) Row {
 	return total
 }
 
-// catch a SELECT error - specifically this one.
-// Error 1146: Table 'performance_schema.memory_summary_global_by_event_name' doesn't exist
-func sqlErrorHandler(err error) bool {
-	var ignore bool
-
-	// try to convert error to a MySQL error for better handling. The go-mysql-driver now reports differently.
-	if mysqlError, ok := err.(*mysql.MySQLError); ok {
-		log.Println("- SELECT gave error:", mysqlError.Number, mysqlError.SQLState, mysqlError.Message)
-		if mysqlError.Number == 1146 {
-			ignore = true
-			log.Println("- expected error, so ignoring")
-		} else {
-			log.Println("- unexpected error, aborting")
+// subtract removes the initial values from the rows where there's a match
+func subtract(rows, initial []Row) {
+	initialByKey := make(map[string]int)
+
+	for i := range initial {
+		initialByKey[initial[i].key()] = i
+	}
+
+	for i := range rows {
+		if j, ok := initialByKey[rows[i].key()]; ok {
+			rows[i].subtract(initial[j])
 		}
-	} else {
-		log.Println("- SELECT gave error:", err.Error())
-		if err.Error()[0:11] != "Error 1146:" {
-			ignore = true
-			log.Println("- expected error, so ignoring")
-		} else {
-			log.Fatal(fmt.Sprintf("Unexpected error: got: '%s', expecting '%s', full error: '%s', aborting", err.Error()[0:11], "Error 1146:", err.Error()))
+	}
+}
+
+// needsRefresh returns true if the memory operations of a row still
+// collected have gone backwards, which happens if performance_schema has
+// been truncated. Rows which are no longer collected, e.g. of a thread
+// which has disconnected, do not matter.
+func needsRefresh(initial, last []Row) bool {
+	lastByKey := make(map[string]Row, len(last))
+	for _, row := range last {
+		lastByKey[row.key()] = row
+	}
+	for _, row := range initial {
+		if other, ok := lastByKey[row.key()]; ok && row.TotalMemoryOps > other.TotalMemoryOps {
+			return true
 		}
 	}
+	return false
+}
+
+// sqlErrorHandler returns whether the SELECT error can be ignored,
+// which is only the case for this one as the table does not exist in 5.6:
+// Error 1146: Table 'performance_schema.memory_summary_global_by_event_name' doesn't exist
+func sqlErrorHandler(err error) bool {
+	var mysqlError *mysql.MySQLError
+	if errors.As(err, &mysqlError) && mysqlError.Number == 1146 {
+		log.Println("- SELECT gave expected error, so ignoring:", err)
+		return true
+	}
+	log.Println("- SELECT gave error:", err)
 
-	return ignore
+	return false
 }
 
-// Select the raw data from the database
-func collect(db *sql.DB) []Row {
+// Select the raw data broken down as given from the database
+func collect(ctx context.Context, db datasource.DataSource, by string) ([]Row, error) {
 	var t []Row
-	var skip bool
-
-	sql := `-- memoryusage
-SELECT	EVENT_NAME                                           AS eventName,
-	CURRENT_COUNT_USED                                   AS currentCountUsed,
-	HIGH_COUNT_USED                                      AS highCountUsed,
-	CURRENT_NUMBER_OF_BYTES_USED                         AS currentBytesUsed,
-	HIGH_NUMBER_OF_BYTES_USED                            AS highBytesUsed,
-	COUNT_ALLOC + COUNT_FREE                             AS totalMemoryOps,
-	SUM_NUMBER_OF_BYTES_ALLOC + SUM_NUMBER_OF_BYTES_FREE AS totalBytesManaged
-FROM	memory_summary_global_by_event_name
-WHERE	HIGH_COUNT_USED > 0`
-
-	log.Println("Querying db:", sql)
-	rows, err := db.Query(sql)
+
+	statement := `-- memoryusage
+SELECT	` + breakdown.Columns(by, "m") + `
+	m.EVENT_NAME                                             AS eventName,
+	m.CURRENT_COUNT_USED                                     AS currentCountUsed,
+	m.HIGH_COUNT_USED                                        AS highCountUsed,
+	m.CURRENT_NUMBER_OF_BYTES_USED                           AS currentBytesUsed,
+	m.HIGH_NUMBER_OF_BYTES_USED                              AS highBytesUsed,
+	m.COUNT_ALLOC + m.COUNT_FREE                             AS totalMemoryOps,
+	m.SUM_NUMBER_OF_BYTES_ALLOC + m.SUM_NUMBER_OF_BYTES_FREE AS totalBytesManaged
+FROM	` + breakdown.Table("memory_summary", by) + ` m` + breakdown.Join(by, "m") + `
+WHERE	m.HIGH_COUNT_USED > 0`
+	owner := breakdown.Dest(by)
+
+	log.Println("Querying db:", statement)
+	rows, err := db.Query(ctx, statement)
 	if err != nil {
 		// FIXME - This should be caught by the validateViews() upstream but isn't for initial
 		// FIXME   table collection. I'm waiting to clean up by splitting views and models but
 		// FIXME   that has not been done yet so for now work around the initial app.CollectAll()
 		// FIXME   by simply ignoring a request if the table does not exist.
-		skip = sqlErrorHandler(err) // temporarily catch a SELECT error. // should not be necessary now
+		if sqlErrorHandler(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	if !skip {
-		for rows.Next() {
-			var r Row
-			if err := rows.Scan(
-				&r.Name,
-				&r.CurrentCountUsed,
-				&r.HighCountUsed,
-				&r.CurrentBytesUsed,
-				&r.HighBytesUsed,
-				&r.TotalMemoryOps,
-				&r.TotalBytesManaged); err != nil {
-				log.Fatalf("collect: rows.Scan() failed: %+v", err)
-			}
-			t = append(t, r)
+	for rows.Next() {
+		r := Row{Breakdown: by}
+		dest := make([]any, 0, len(owner)+7)
+		for i := range owner {
+			dest = append(dest, &owner[i])
 		}
-		if err := rows.Err(); err != nil {
-			log.Fatalf("collect: rows.Err() returned: %v", err)
+		dest = append(dest,
+			&r.Name,
+			&r.CurrentCountUsed,
+			&r.HighCountUsed,
+			&r.CurrentBytesUsed,
+			&r.HighBytesUsed,
+			&r.TotalMemoryOps,
+			&r.TotalBytesManaged)
+		if err := rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("collect: rows.Scan() failed: %w", err)
 		}
+		r.Owner = breakdown.Owner(by, owner)
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("collect: rows.Err() returned: %w", err)
 	}
 	_ = rows.Close()
 
-	return t
+	return t, nil
 }
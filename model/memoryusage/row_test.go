@@ -0,0 +1,118 @@
+package memoryusage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/breakdown"
+)
+
+func TestCollect(t *testing.T) {
+	anonymiser.Enable(false)
+	fixture := datasource.NewFixture().
+		Add("memory_summary_by_thread_by_event_name",
+			[]any{52, 12, "app", "h1", "thread/sql/one_connection", "memory/sql/THD::main_mem_root", 3, 5, 3000, 9000, 20, 40000},
+			[]any{1, nil, nil, nil, "thread/sql/main", "memory/sql/Log_event", 1, 1, 100, 100, 2, 200},
+			[]any{60, nil, nil, nil, nil, "memory/sql/Filesort_buffer::sort_keys", 0, 1, 0, 500, 2, 1000},
+		)
+
+	expected := []Row{
+		{Breakdown: breakdown.ByThread, Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 3, HighCountUsed: 5, CurrentBytesUsed: 3000, HighBytesUsed: 9000, TotalMemoryOps: 20, TotalBytesManaged: 40000},
+		{Breakdown: breakdown.ByThread, Owner: "sql/main", Name: "memory/sql/Log_event", CurrentCountUsed: 1, HighCountUsed: 1, CurrentBytesUsed: 100, HighBytesUsed: 100, TotalMemoryOps: 2, TotalBytesManaged: 200},
+		{Breakdown: breakdown.ByThread, Owner: "thread 60", Name: "memory/sql/Filesort_buffer::sort_keys", HighCountUsed: 1, HighBytesUsed: 500, TotalMemoryOps: 2, TotalBytesManaged: 1000},
+	}
+	got, err := collect(context.Background(), fixture, breakdown.ByThread)
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("collect() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	missing := &mysql.MySQLError{Number: 1146, Message: "Table 'performance_schema.memory_summary_by_thread_by_event_name' doesn't exist"}
+	fixture := datasource.NewFixture().AddError("memory_summary_by_thread_by_event_name", missing)
+	if got, err := collect(context.Background(), fixture, breakdown.ByThread); err != nil || got != nil {
+		t.Errorf("collect() with a missing table: got: %+v, %v, expected no rows and no error", got, err)
+	}
+
+	lost := errors.New("invalid connection")
+	fixture.AddError("memory_summary_by_thread_by_event_name", lost)
+	if _, err := collect(context.Background(), fixture, breakdown.ByThread); !errors.Is(err, lost) {
+		t.Errorf("collect() failed: got error: %v, expected: %v", err, lost)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	rows := []Row{
+		{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 5, CurrentBytesUsed: 5000, HighBytesUsed: 9000, TotalMemoryOps: 30, TotalBytesManaged: 50000},
+		{Owner: "13 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 1, CurrentBytesUsed: 1000, HighBytesUsed: 1000, TotalMemoryOps: 10, TotalBytesManaged: 1000},
+	}
+	initial := []Row{
+		{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 3, CurrentBytesUsed: 6000, HighBytesUsed: 8000, TotalMemoryOps: 20, TotalBytesManaged: 40000},
+	}
+
+	// memory may be freed so the growth can be negative, high water marks are not subtracted
+	expected := []Row{
+		{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 2, CurrentBytesUsed: -1000, HighBytesUsed: 9000, TotalMemoryOps: 10, TotalBytesManaged: 10000},
+		{Owner: "13 app@h1", Name: "memory/sql/THD::main_mem_root", CurrentCountUsed: 1, CurrentBytesUsed: 1000, HighBytesUsed: 1000, TotalMemoryOps: 10, TotalBytesManaged: 1000},
+	}
+	subtract(rows, initial)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("subtract() failed:\ngot:      %+v\nexpected: %+v", rows, expected)
+	}
+}
+
+func TestHasData(t *testing.T) {
+	name := "memory/sql/THD::main_mem_root"
+	tests := []struct {
+		row      Row
+		expected bool
+	}{
+		{Row{Name: name, CurrentCountUsed: 5, TotalMemoryOps: 30}, true},
+		{Row{Name: name, CurrentCountUsed: -2, TotalMemoryOps: 4}, true}, // freed since the reset
+		{Row{Name: name, CurrentCountUsed: 0, TotalMemoryOps: 10}, true}, // allocated and freed since the reset
+		{Row{Name: name}, false}, // unused since the reset
+		{Row{CurrentCountUsed: 5, TotalMemoryOps: 30}, false},
+	}
+	for _, test := range tests {
+		if got := test.row.HasData(); got != test.expected {
+			t.Errorf("HasData() of %+v failed: got: %v, expected: %v", test.row, got, test.expected)
+		}
+	}
+
+	// relative to the statistics collected on reset
+	rows := []Row{{Owner: "12 app@h1", Name: name, CurrentCountUsed: 3, TotalMemoryOps: 30}}
+	subtract(rows, []Row{{Owner: "12 app@h1", Name: name, CurrentCountUsed: 3, TotalMemoryOps: 20}})
+	if !rows[0].HasData() {
+		t.Errorf("expected the memory allocated and freed since the reset to be shown: %+v", rows[0])
+	}
+}
+
+func TestNeedsRefresh(t *testing.T) {
+	initial := []Row{
+		{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", TotalMemoryOps: 30},
+		{Owner: "13 app@h1", Name: "memory/sql/THD::main_mem_root", TotalMemoryOps: 1000},
+	}
+	tests := []struct {
+		name     string
+		last     []Row
+		expected bool
+	}{
+		{"no change", initial, false},
+		{"thread disconnected", []Row{{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", TotalMemoryOps: 40}}, false},
+		{"new thread", append([]Row{{Owner: "14 app@h1", Name: "memory/sql/THD::main_mem_root", TotalMemoryOps: 1}}, initial...), false},
+		{"truncated", []Row{{Owner: "12 app@h1", Name: "memory/sql/THD::main_mem_root", TotalMemoryOps: 5}}, true},
+	}
+	for _, test := range tests {
+		if got := needsRefresh(initial, test.last); got != test.expected {
+			t.Errorf("needsRefresh() with %s failed: got: %v, expected: %v", test.name, got, test.expected)
+		}
+	}
+}
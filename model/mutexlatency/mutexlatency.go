@@ -3,14 +3,22 @@
 package mutexlatency
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
 )
 
+// Prefixes of the wait events collected
+const (
+	MutexPrefix = "wait/synch/mutex/innodb/" // InnoDB mutexes
+	WaitPrefix  = "wait/"                    // all wait events
+)
+
 // MutexLatency holds a table of rows
 type MutexLatency struct {
 	config         *config.Config
@@ -20,14 +28,17 @@ type MutexLatency struct {
 	last           Rows // last loaded values
 	Results        Rows // results (maybe with subtraction)
 	Totals         Row  // totals of results
-	db             *sql.DB
+	Mutexes        Rows // the InnoDB mutexes of the results
+	MutexTotals    Row  // totals of the mutexes
+	db             datasource.DataSource
 }
 
-// NewMutexLatency returns a mutex latency object using given config and db
-func NewMutexLatency(cfg *config.Config, db *sql.DB) *MutexLatency {
-	log.Println("NewMutexLatency()")
+// NewWaitLatency returns a latency object for all the wait events, and
+// the InnoDB mutexes amongst them, using given config and db
+func NewWaitLatency(cfg *config.Config, db datasource.DataSource) *MutexLatency {
+	log.Println("NewWaitLatency()")
 	if cfg == nil {
-		log.Println("NewMutexLatency() cfg == nil!")
+		log.Println("NewWaitLatency() cfg == nil!")
 	}
 	ml := &MutexLatency{
 		config: cfg,
@@ -40,11 +51,24 @@ func NewMutexLatency(cfg *config.Config, db *sql.DB) *MutexLatency {
 // Collect collects data from the db, updating first
 // values if needed, and then subtracting first values if we want
 // relative values, after which it stores totals.
-func (ml *MutexLatency) Collect() {
+func (ml *MutexLatency) Collect(ctx context.Context) error {
 	start := time.Now()
 
-	ml.last = collect(ml.db)
-	ml.LastCollected = time.Now()
+	rows, err := collect(ctx, ml.db, WaitPrefix)
+	if err != nil {
+		return err
+	}
+	ml.AddRows(rows, time.Now())
+
+	log.Println("MutexLatency.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (ml *MutexLatency) AddRows(rows Rows, collected time.Time) {
+	ml.last = rows
+	ml.LastCollected = collected
 
 	// check if no first data or we need to reload initial characteristics
 	if (len(ml.first) == 0 && len(ml.last) > 0) || ml.first.needsRefresh(ml.last) {
@@ -56,19 +80,32 @@ func (ml *MutexLatency) Collect() {
 
 	log.Println("t.initial.totals():", totals(ml.first))
 	log.Println("t.current.totals():", totals(ml.last))
-	log.Println("MutexLatency.Collect() END, took:", time.Duration(time.Since(start)).String())
+}
+
+// Last returns the last collected rows
+func (ml MutexLatency) Last() Rows {
+	return ml.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (ml *MutexLatency) Refilter() {
+	ml.calculate()
 }
 
 func (ml *MutexLatency) calculate() {
 	// log.Println( "- t.results set from t.current" )
-	ml.Results = make(Rows, len(ml.last))
-	copy(ml.Results, ml.last)
+	results := make(Rows, len(ml.last))
+	copy(results, ml.last)
 	if ml.config.WantRelativeStats() {
 		// log.Println( "- subtracting t.initial from t.results as WantRelativeStats()" )
-		ml.Results.subtract(ml.first)
+		results.subtract(ml.first)
 	}
 
+	ml.Results = filter.MatchingRows(ml.config.DatabaseFilter(), results, func(row Row) string { return row.Name })
 	ml.Totals = totals(ml.Results)
+	ml.Mutexes = filter.MatchingRows(ml.config.DatabaseFilter(), mutexes(results), func(row Row) string { return row.Name })
+	ml.MutexTotals = totals(ml.Mutexes)
 }
 
 // ResetStatistics resets the statistics to current values
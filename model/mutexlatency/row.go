@@ -4,13 +4,22 @@ package mutexlatency
 
 import (
 	"log"
+
+	"github.com/sjmudd/ps-top/utils"
 )
 
 // Row contains a row from performance_schema.events_waits_summary_global_by_event_Name
 type Row struct {
-	Name         string
-	SumTimerWait uint64
-	CountStar    uint64
+	Name         string `json:"name"`
+	SumTimerWait uint64 `json:"sum_timer_wait"`
+	CountStar    uint64 `json:"count_star"`
+	MinTimerWait uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait uint64 `json:"max_timer_wait"` // not subtracted
+}
+
+// Timers returns the number of waits and their latency
+func (row Row) Timers() utils.Timers {
+	return utils.Timers{Count: row.CountStar, Sum: row.SumTimerWait, Min: row.MinTimerWait, Max: row.MaxTimerWait}
 }
 
 // subtract the countable values in one row from another
@@ -3,9 +3,11 @@
 package mutexlatency
 
 import (
-	"database/sql"
+	"context"
+	"strings"
 
-	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/utils"
 )
 
 // Rows contains a slice of Row
@@ -17,20 +19,40 @@ func totals(rows Rows) Row {
 	for _, row := range rows {
 		total.SumTimerWait += row.SumTimerWait
 		total.CountStar += row.CountStar
+		total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+		total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
 	}
 
 	return total
 }
 
-func collect(db *sql.DB) Rows {
+// mutexes returns the InnoDB mutexes of the wait events collected with
+// names relative to MutexPrefix
+func mutexes(rows Rows) Rows {
+	prefix := strings.TrimPrefix(MutexPrefix, WaitPrefix)
+	mutexes := make(Rows, 0, len(rows))
+
+	for _, row := range rows {
+		if strings.HasPrefix(row.Name, prefix) {
+			row.Name = strings.TrimPrefix(row.Name, prefix)
+			mutexes = append(mutexes, row)
+		}
+	}
+
+	return mutexes
+}
+
+// collect returns the wait events whose name starts with prefix. The
+// prefix is removed from the names.
+func collect(ctx context.Context, db datasource.DataSource, prefix string) (Rows, error) {
 	var t Rows
 
 	// we collect all information even if it's mainly empty as we may reference it later
-	sql := "SELECT EVENT_NAME, SUM_TIMER_WAIT, COUNT_STAR FROM events_waits_summary_global_by_event_name WHERE SUM_TIMER_WAIT > 0 AND EVENT_NAME LIKE 'wait/synch/mutex/innodb/%'"
+	sql := "SELECT EVENT_NAME, SUM_TIMER_WAIT, COUNT_STAR, MIN_TIMER_WAIT, MAX_TIMER_WAIT FROM events_waits_summary_global_by_event_name WHERE SUM_TIMER_WAIT > 0 AND EVENT_NAME LIKE ?"
 
-	rows, err := db.Query(sql)
+	rows, err := db.Query(ctx, sql, prefix+"%")
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for rows.Next() {
@@ -38,24 +60,25 @@ func collect(db *sql.DB) Rows {
 		if err := rows.Scan(
 			&r.Name,
 			&r.SumTimerWait,
-			&r.CountStar); err != nil {
-			log.Fatal(err)
+			&r.CountStar,
+			&r.MinTimerWait,
+			&r.MaxTimerWait); err != nil {
+			_ = rows.Close()
+			return nil, err
 		}
 
-		// trim off the leading 'wait/synch/mutex/innodb/'
-		if len(r.Name) >= 24 {
-			r.Name = r.Name[24:]
-		}
+		r.Name = strings.TrimPrefix(r.Name, prefix)
 
 		// we collect all information even if it's mainly empty as we may reference it later
 		t = append(t, r)
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return nil, err
 	}
 	_ = rows.Close()
 
-	return t
+	return t, nil
 }
 
 // remove the initial values from those rows where there's a match
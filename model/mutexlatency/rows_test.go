@@ -0,0 +1,19 @@
+package mutexlatency
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMutexes(t *testing.T) {
+	rows := Rows{
+		{Name: "synch/mutex/innodb/trx_mutex", SumTimerWait: 300, CountStar: 3},
+		{Name: "synch/mutex/sql/LOCK_open", SumTimerWait: 100, CountStar: 1},
+		{Name: "io/file/innodb/innodb_data_file", SumTimerWait: 400, CountStar: 4},
+	}
+	expected := Rows{{Name: "trx_mutex", SumTimerWait: 300, CountStar: 3}}
+
+	if got := mutexes(rows); !reflect.DeepEqual(got, expected) {
+		t.Errorf("mutexes() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+}
@@ -0,0 +1,86 @@
+// Package replication contains the routines for showing the state of
+// the replication threads of each channel.
+package replication
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Replication holds the state of the replication threads
+type Replication struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	last           Rows // last loaded values
+	Results        Rows // replication threads to show
+	db             datasource.DataSource
+}
+
+// NewReplication returns a replication object using the given config and db
+func NewReplication(cfg *config.Config, db datasource.DataSource) *Replication {
+	return &Replication{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// Collect collects the current state of replication from the db
+func (r *Replication) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, r.db, r.config.MySQLVersion())
+	if err != nil {
+		return err
+	}
+	r.AddRows(rows, time.Now())
+
+	log.Println("Replication.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (r *Replication) AddRows(rows Rows, collected time.Time) {
+	r.last = rows
+	r.LastCollected = collected
+
+	r.calculate()
+}
+
+// Last returns the last collected rows
+func (r Replication) Last() Rows {
+	return r.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (r *Replication) Refilter() {
+	r.calculate()
+}
+
+func (r *Replication) calculate() {
+	r.Results = utils.DuplicateSlice(r.last)
+	r.Results = filter.MatchingRows(r.config.DatabaseFilter(), r.Results, func(row Row) string { return row.Channel })
+}
+
+// ResetStatistics recalculates the results as there are no counters to reset
+func (r *Replication) ResetStatistics() {
+	r.calculate()
+}
+
+// HaveRelativeStats is false for this object
+func (r Replication) HaveRelativeStats() bool {
+	return false
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (r Replication) WantRelativeStats() bool {
+	return r.config.WantRelativeStats()
+}
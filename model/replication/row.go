@@ -0,0 +1,41 @@
+// Package replication contains the routines for showing the state of
+// the replication threads of each channel.
+package replication
+
+import "fmt"
+
+// Replication threads
+const (
+	IO     = "IO"     // the receiver (I/O) thread
+	SQL    = "SQL"    // the applier (SQL) thread or the coordinator of the workers
+	Worker = "worker" // an applier worker thread
+)
+
+// Row contains the state of a replication thread
+type Row struct {
+	Channel      string `json:"channel"`
+	Thread       string `json:"thread"` // IO, SQL or Worker
+	Worker       uint64 `json:"worker"` // the worker id for Worker threads
+	State        string `json:"state"`  // ON, OFF or CONNECTING
+	Lag          uint64 `json:"lag"`    // picoseconds since the original commit of the transaction being processed
+	ImmediateLag uint64 `json:"immediate_lag"`
+	LastLatency  uint64 `json:"last_latency"` // picoseconds taken to queue or apply the last transaction
+	ErrorNumber  uint64 `json:"error_number"`
+	Error        string `json:"error"`
+}
+
+// Rows contains a set of rows
+type Rows []Row
+
+// Name returns the name shown for the thread, e.g. "worker 2"
+func (row Row) Name() string {
+	if row.Thread == Worker {
+		return fmt.Sprintf("%s %d", row.Thread, row.Worker)
+	}
+	return row.Thread
+}
+
+// Running returns true if the thread is running
+func (row Row) Running() bool {
+	return row.State == "ON"
+}
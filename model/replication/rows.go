@@ -0,0 +1,150 @@
+// Package replication contains the routines for showing the state of
+// the replication threads of each channel.
+package replication
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// picosecondsPerMicrosecond converts the times returned by TIMESTAMPDIFF
+const picosecondsPerMicrosecond = 1000000
+
+// lag returns the SQL giving the microseconds since the commit timestamp
+// of the transaction being processed, or NULL if there is none. The
+// timestamps are only available from MySQL 8.0.
+func lag(timestamps bool, transaction, commitTimestamp string) string {
+	if !timestamps {
+		return `NULL`
+	}
+	return `IF(` + transaction + ` = '' OR ` + commitTimestamp + ` = 0, NULL, TIMESTAMPDIFF(MICROSECOND, ` + commitTimestamp + `, NOW(6)))`
+}
+
+// latency returns the SQL giving the microseconds taken to process the
+// last transaction, or NULL if there is none
+func latency(timestamps bool, transaction, start, end string) string {
+	if !timestamps {
+		return `NULL`
+	}
+	return `IF(` + transaction + ` = '', NULL, TIMESTAMPDIFF(MICROSECOND, ` + start + `, ` + end + `))`
+}
+
+// receiverQuery returns the state of the receiver thread of each channel
+func receiverQuery(timestamps bool) string {
+	return `SELECT CHANNEL_NAME, 0, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE, ` +
+		lag(timestamps, `QUEUEING_TRANSACTION`, `QUEUEING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP`) + `, ` +
+		lag(timestamps, `QUEUEING_TRANSACTION`, `QUEUEING_TRANSACTION_IMMEDIATE_COMMIT_TIMESTAMP`) + `, ` +
+		latency(timestamps, `LAST_QUEUED_TRANSACTION`, `LAST_QUEUED_TRANSACTION_START_QUEUE_TIMESTAMP`, `LAST_QUEUED_TRANSACTION_END_QUEUE_TIMESTAMP`) +
+		` FROM replication_connection_status ORDER BY CHANNEL_NAME`
+}
+
+// coordinatorQuery returns the state of the coordinator thread of each
+// channel using parallel replication
+func coordinatorQuery(timestamps bool) string {
+	return `SELECT CHANNEL_NAME, 0, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE, ` +
+		lag(timestamps, `PROCESSING_TRANSACTION`, `PROCESSING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP`) + `, ` +
+		lag(timestamps, `PROCESSING_TRANSACTION`, `PROCESSING_TRANSACTION_IMMEDIATE_COMMIT_TIMESTAMP`) + `, ` +
+		latency(timestamps, `LAST_PROCESSED_TRANSACTION`, `LAST_PROCESSED_TRANSACTION_START_BUFFER_TIMESTAMP`, `LAST_PROCESSED_TRANSACTION_END_BUFFER_TIMESTAMP`) +
+		` FROM replication_applier_status_by_coordinator ORDER BY CHANNEL_NAME`
+}
+
+// workerQuery returns the state of the applier threads of each channel.
+// Without parallel replication there is a single row with WORKER_ID 0
+// for the SQL thread.
+func workerQuery(timestamps bool) string {
+	return `SELECT CHANNEL_NAME, WORKER_ID, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE, ` +
+		lag(timestamps, `APPLYING_TRANSACTION`, `APPLYING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP`) + `, ` +
+		lag(timestamps, `APPLYING_TRANSACTION`, `APPLYING_TRANSACTION_IMMEDIATE_COMMIT_TIMESTAMP`) + `, ` +
+		latency(timestamps, `LAST_APPLIED_TRANSACTION`, `LAST_APPLIED_TRANSACTION_START_APPLY_TIMESTAMP`, `LAST_APPLIED_TRANSACTION_END_APPLY_TIMESTAMP`) +
+		` FROM replication_applier_status_by_worker ORDER BY CHANNEL_NAME, WORKER_ID`
+}
+
+// collect returns the state of the replication threads. The replication
+// tables do not exist in MySQL 5.6 or MariaDB so no threads are returned
+// there rather than an error.
+func collect(ctx context.Context, db datasource.DataSource, version string) (Rows, error) {
+	timestamps := utils.MajorVersion(version) >= 8
+
+	var t Rows
+	for _, q := range []struct{ thread, query string }{
+		{IO, receiverQuery(timestamps)},
+		{SQL, coordinatorQuery(timestamps)},
+		{Worker, workerQuery(timestamps)},
+	} {
+		rows, err := query(ctx, db, q.thread, q.query)
+		if datasource.TableMissing(err) {
+			log.Println("replication.collect(): ignoring missing table:", err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		t = append(t, rows...)
+	}
+
+	return t, nil
+}
+
+// query returns the replication threads of the given type returned by the given query
+func query(ctx context.Context, db datasource.DataSource, thread, query string) (Rows, error) {
+	var t Rows
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var (
+			r                          Row
+			errorMessage               sql.NullString
+			lag, immediateLag, latency sql.NullInt64
+		)
+		if err := rows.Scan(
+			&r.Channel,
+			&r.Worker,
+			&r.State,
+			&r.ErrorNumber,
+			&errorMessage,
+			&lag,
+			&immediateLag,
+			&latency); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r.Thread = thread
+		if thread == Worker && r.Worker == 0 {
+			r.Thread = SQL // single threaded replication
+		}
+		r.Lag = picoseconds(lag)
+		r.ImmediateLag = picoseconds(immediateLag)
+		r.LastLatency = picoseconds(latency)
+		if !anonymiser.Enabled() {
+			// errors may contain any names or values so are not shown when anonymising
+			r.Error = errorMessage.String
+		}
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
+
+// picoseconds converts a time in microseconds to picoseconds. Negative
+// times, e.g. due to clock differences between servers, are treated as 0.
+func picoseconds(microseconds sql.NullInt64) uint64 {
+	if microseconds.Int64 <= 0 {
+		return 0
+	}
+	return uint64(microseconds.Int64) * picosecondsPerMicrosecond
+}
@@ -0,0 +1,71 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+func TestCollect(t *testing.T) {
+	anonymiser.Enable(false)
+	fixture := datasource.NewFixture().
+		Add("replication_connection_status",
+			[]any{"", 0, "ON", 0, "", 1500, nil, 200},
+		).
+		Add("replication_applier_status_by_coordinator",
+			[]any{"", 0, "ON", 0, "", nil, nil, 100},
+		).
+		Add("replication_applier_status_by_worker",
+			[]any{"", 1, "ON", 0, "", 2000000, 1000000, 300},
+			[]any{"", 2, "OFF", 1062, "Duplicate entry '1' for key 'PRIMARY'", -5, nil, nil},
+		)
+
+	expected := Rows{
+		{Thread: IO, State: "ON", Lag: 1500000000, LastLatency: 200000000},
+		{Thread: SQL, State: "ON", LastLatency: 100000000},
+		{Thread: Worker, Worker: 1, State: "ON", Lag: 2000000000000, ImmediateLag: 1000000000000, LastLatency: 300000000},
+		{Thread: Worker, Worker: 2, State: "OFF", ErrorNumber: 1062, Error: "Duplicate entry '1' for key 'PRIMARY'"},
+	}
+	got, err := collect(context.Background(), fixture, "8.0.36")
+	if err != nil {
+		t.Fatalf("collect() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("collect() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	// the commit timestamps are only available from MySQL 8.0
+	for _, query := range []func(bool) string{receiverQuery, coordinatorQuery, workerQuery} {
+		if got := query(false); got == query(true) {
+			t.Errorf("query without timestamps should differ: %q", got)
+		}
+	}
+	if got := workerQuery(false); got != "SELECT CHANNEL_NAME, WORKER_ID, SERVICE_STATE, LAST_ERROR_NUMBER, LAST_ERROR_MESSAGE, NULL, NULL, NULL FROM replication_applier_status_by_worker ORDER BY CHANNEL_NAME, WORKER_ID" {
+		t.Errorf("workerQuery(false) failed: got: %q", got)
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	// MySQL 5.6 and MariaDB have no replication tables
+	missing := &mysql.MySQLError{Number: 1146, Message: "Table 'performance_schema.replication_connection_status' doesn't exist"}
+	fixture := datasource.NewFixture().
+		AddError("replication_connection_status", missing).
+		AddError("replication_applier_status_by_coordinator", missing).
+		AddError("replication_applier_status_by_worker", missing)
+	if got, err := collect(context.Background(), fixture, "5.6.51"); err != nil || got != nil {
+		t.Errorf("collect() with missing tables: got: %+v, %v, expected no rows and no error", got, err)
+	}
+
+	lost := errors.New("invalid connection")
+	fixture.AddError("replication_connection_status", lost)
+	if _, err := collect(context.Background(), fixture, "5.6.51"); !errors.Is(err, lost) {
+		t.Errorf("collect() failed: got error: %v, expected: %v", err, lost)
+	}
+}
@@ -2,6 +2,8 @@ package stageslatency
 
 import (
 	"log"
+
+	"github.com/sjmudd/ps-top/utils"
 )
 
 /**************************************************************************
@@ -10,19 +12,43 @@ CREATE TABLE `events_stages_summary_global_by_event_name` (
   `EVENT_NAME` varchar(128) NOT NULL,
   `COUNT_STAR` bigint(20) unsigned NOT NULL,
   `SUM_TIMER_WAIT` bigint(20) unsigned NOT NULL,
-  `MIN_TIMER_WAIT` bigint(20) unsigned NOT NULL, // not used
-  `AVG_TIMER_WAIT` bigint(20) unsigned NOT NULL, // not used
-  `MAX_TIMER_WAIT` bigint(20) unsigned NOT NULL  // not used
+  `MIN_TIMER_WAIT` bigint(20) unsigned NOT NULL,
+  `AVG_TIMER_WAIT` bigint(20) unsigned NOT NULL, // not used, calculated from SUM_TIMER_WAIT / COUNT_STAR
+  `MAX_TIMER_WAIT` bigint(20) unsigned NOT NULL
 ) ENGINE=PERFORMANCE_SCHEMA DEFAULT CHARSET=utf8
 1 row in set (0.00 sec)
 
 **************************************************************************/
 
-// Row contains the information in one row
+// Row contains the information in one row of
+// events_stages_summary_global_by_event_name or, if stages are broken
+// down, one of the events_stages_summary_by_*_by_event_name tables
 type Row struct {
-	Name         string
-	CountStar    uint64
-	SumTimerWait uint64
+	Breakdown    string `json:"breakdown,omitempty"` // how stages are broken down, empty for the global values
+	Owner        string `json:"owner,omitempty"`     // the thread, account, user or host executing the stage
+	Name         string `json:"name"`
+	CountStar    uint64 `json:"count_star"`
+	SumTimerWait uint64 `json:"sum_timer_wait"`
+	MinTimerWait uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait uint64 `json:"max_timer_wait"` // not subtracted
+}
+
+// Timers returns the number of waits and their latency
+func (row Row) Timers() utils.Timers {
+	return utils.Timers{Count: row.CountStar, Sum: row.SumTimerWait, Min: row.MinTimerWait, Max: row.MaxTimerWait}
+}
+
+// FullName returns the name of the stage prefixed by its owner, if any
+func (row Row) FullName() string {
+	if row.Owner == "" {
+		return row.Name
+	}
+	return row.Owner + ": " + row.Name
+}
+
+// key identifies the row as the same stage is executed by different owners
+func (row Row) key() string {
+	return row.Owner + "\x00" + row.Name
 }
 
 // subtract the countable values in one row from another
@@ -1,34 +1,48 @@
 package stageslatency
 
 import (
-	"database/sql"
+	"context"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/model/breakdown"
+	"github.com/sjmudd/ps-top/utils"
 )
 
 // Rows contains a slice of Rows
 type Rows []Row
 
-// select the rows into table
-func collect(db *sql.DB) Rows {
+// select the rows broken down as given into table
+func collect(ctx context.Context, db datasource.DataSource, by string) (Rows, error) {
 	var t Rows
 
-	log.Println("events_stages_summary_global_by_event_name.collect()")
-	sql := "SELECT EVENT_NAME, COUNT_STAR, SUM_TIMER_WAIT FROM events_stages_summary_global_by_event_name WHERE SUM_TIMER_WAIT > 0"
+	table := breakdown.Table("events_stages_summary", by)
+	log.Println(table + ".collect()")
+	sql := "SELECT " + breakdown.Columns(by, "e") + "e.EVENT_NAME, e.COUNT_STAR, e.SUM_TIMER_WAIT, e.MIN_TIMER_WAIT, e.MAX_TIMER_WAIT FROM " +
+		table + " e" + breakdown.Join(by, "e") + " WHERE e.SUM_TIMER_WAIT > 0"
 
-	rows, err := db.Query(sql)
+	rows, err := db.Query(ctx, sql)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
+	owner := breakdown.Dest(by)
 	for rows.Next() {
-		var r Row
-		if err := rows.Scan(
+		r := Row{Breakdown: by}
+		dest := make([]any, 0, len(owner)+5)
+		for i := range owner {
+			dest = append(dest, &owner[i])
+		}
+		if err := rows.Scan(append(dest,
 			&r.Name,
 			&r.CountStar,
-			&r.SumTimerWait); err != nil {
-			log.Fatal(err)
+			&r.SumTimerWait,
+			&r.MinTimerWait,
+			&r.MaxTimerWait)...); err != nil {
+			_ = rows.Close()
+			return nil, err
 		}
+		r.Owner = breakdown.Owner(by, owner)
 
 		// convert the stage name, removing any leading stage/sql/
 		if len(r.Name) > 10 && r.Name[0:10] == "stage/sql/" {
@@ -38,20 +52,32 @@ func collect(db *sql.DB) Rows {
 		t = append(t, r)
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return nil, err
 	}
 	_ = rows.Close()
 
 	log.Printf("recovered %v row(s):", len(t))
 	log.Println(t)
 
-	return t
+	return t, nil
 }
 
-// if the data in t2 is "newer", "has more values" than t then it needs refreshing.
-// check this by comparing totals.
+// needsRefresh returns true if the latency of a row still collected in
+// otherRows has gone backwards, which happens if performance_schema has
+// been truncated. Rows which are no longer collected, e.g. of a thread
+// which has disconnected, do not matter.
 func (rows Rows) needsRefresh(otherRows Rows) bool {
-	return totals(rows).SumTimerWait > totals(otherRows).SumTimerWait
+	otherByKey := make(map[string]Row, len(otherRows))
+	for _, row := range otherRows {
+		otherByKey[row.key()] = row
+	}
+	for _, row := range rows {
+		if other, ok := otherByKey[row.key()]; ok && row.SumTimerWait > other.SumTimerWait {
+			return true
+		}
+	}
+	return false
 }
 
 // generate the totals of a table
@@ -61,6 +87,8 @@ func totals(rows Rows) Row {
 	for _, row := range rows {
 		total.SumTimerWait += row.SumTimerWait
 		total.CountStar += row.CountStar
+		total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+		total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
 	}
 
 	return total
@@ -69,17 +97,17 @@ func totals(rows Rows) Row {
 // remove the initial values from those rows where there's a match
 // - if we find a row we can't match ignore it
 func (rows *Rows) subtract(initial Rows) {
-	initialByName := make(map[string]int)
+	initialByKey := make(map[string]int)
 
-	// iterate over rows by name
+	// iterate over rows by owner and name
 	for i := range initial {
-		initialByName[initial[i].Name] = i
+		initialByKey[initial[i].key()] = i
 	}
 
 	for i := range *rows {
-		name := (*rows)[i].Name
-		if _, ok := initialByName[name]; ok {
-			initialIndex := initialByName[name]
+		key := (*rows)[i].key()
+		if _, ok := initialByKey[key]; ok {
+			initialIndex := initialByKey[key]
 			(*rows)[i].subtract(initial[initialIndex])
 		}
 	}
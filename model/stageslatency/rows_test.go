@@ -0,0 +1,25 @@
+package stageslatency
+
+import "testing"
+
+func TestNeedsRefresh(t *testing.T) {
+	initial := Rows{
+		{Owner: "12 app@h1", Name: "stage/sql/Sending data", SumTimerWait: 300},
+		{Owner: "13 app@h1", Name: "stage/sql/Sending data", SumTimerWait: 10000},
+	}
+	tests := []struct {
+		name     string
+		last     Rows
+		expected bool
+	}{
+		{"no change", initial, false},
+		{"thread disconnected", Rows{{Owner: "12 app@h1", Name: "stage/sql/Sending data", SumTimerWait: 400}}, false},
+		{"new thread", append(Rows{{Owner: "14 app@h1", Name: "stage/sql/Sending data", SumTimerWait: 1}}, initial...), false},
+		{"truncated", Rows{{Owner: "12 app@h1", Name: "stage/sql/Sending data", SumTimerWait: 50}}, true},
+	}
+	for _, test := range tests {
+		if got := initial.needsRefresh(test.last); got != test.expected {
+			t.Errorf("needsRefresh() with %s failed: got: %v, expected: %v", test.name, got, test.expected)
+		}
+	}
+}
@@ -1,12 +1,15 @@
-// Package stageslatency is the nterface to events_stages_summary_global_by_event_name
+// Package stageslatency is the interface to events_stages_summary_global_by_event_name
+// and the events_stages_summary_by_*_by_event_name tables
 package stageslatency
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
 )
 
@@ -48,15 +51,16 @@ type StagesLatency struct {
 	config         *config.Config
 	FirstCollected time.Time
 	LastCollected  time.Time
-	first          Rows // initial data for relative values
-	last           Rows // last loaded values
-	Results        Rows // results (maybe with subtraction)
-	Totals         Row  // totals of results
-	db             *sql.DB
+	breakdown      string // how stages are broken down
+	first          Rows   // initial data for relative values
+	last           Rows   // last loaded values
+	Results        Rows   // results (maybe with subtraction)
+	Totals         Row    // totals of results
+	db             datasource.DataSource
 }
 
 // NewStagesLatency returns a stageslatency StagesLatency
-func NewStagesLatency(cfg *config.Config, db *sql.DB) *StagesLatency {
+func NewStagesLatency(cfg *config.Config, db datasource.DataSource) *StagesLatency {
 	log.Println("NewStagesLatency()")
 	sl := &StagesLatency{
 		config: cfg,
@@ -69,11 +73,29 @@ func NewStagesLatency(cfg *config.Config, db *sql.DB) *StagesLatency {
 // Collect collects data from the db, updating initial
 // values if needed, and then subtracting initial values if we want
 // relative values, after which it stores totals.
-func (sl *StagesLatency) Collect() {
+func (sl *StagesLatency) Collect(ctx context.Context) error {
 	start := time.Now()
-	sl.last = collect(sl.db)
-	sl.LastCollected = time.Now()
+	rows, err := collect(ctx, sl.db, sl.breakdown)
+	if err != nil {
+		return err
+	}
+	sl.AddRows(rows, time.Now())
 	log.Println("t.current collected", len(sl.last), "row(s) from SELECT")
+	log.Println("Table_io_waits_summary_by_table.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+// Rows of a different breakdown, e.g. when replaying a recording, are ignored.
+func (sl *StagesLatency) AddRows(rows Rows, collected time.Time) {
+	sl.last = make(Rows, 0, len(rows))
+	for _, row := range rows {
+		if row.Breakdown == sl.breakdown {
+			sl.last = append(sl.last, row)
+		}
+	}
+	sl.LastCollected = collected
 
 	// check if we need to update first or we need to reload initial characteristics
 	if (len(sl.first) == 0 && len(sl.last) > 0) || sl.first.needsRefresh(sl.last) {
@@ -85,7 +107,27 @@ func (sl *StagesLatency) Collect() {
 
 	log.Println("t.initial.totals():", totals(sl.first))
 	log.Println("t.current.totals():", totals(sl.last))
-	log.Println("Table_io_waits_summary_by_table.Collect() END, took:", time.Duration(time.Since(start)).String())
+}
+
+// Breakdown returns how stages are broken down
+func (sl StagesLatency) Breakdown() string {
+	return sl.breakdown
+}
+
+// SetBreakdown changes how stages are broken down. The values already
+// collected are discarded as they can not be compared with those of
+// the new breakdown.
+func (sl *StagesLatency) SetBreakdown(breakdown string) {
+	sl.breakdown = breakdown
+	sl.first = nil
+	sl.last = nil
+
+	sl.calculate()
+}
+
+// Last returns the last collected rows
+func (sl StagesLatency) Last() Rows {
+	return sl.last
 }
 
 // ResetStatistics  resets the statistics to current values
@@ -96,6 +138,12 @@ func (sl *StagesLatency) ResetStatistics() {
 	sl.calculate()
 }
 
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (sl *StagesLatency) Refilter() {
+	sl.calculate()
+}
+
 // generate the results and totals and sort data
 func (sl *StagesLatency) calculate() {
 	// log.Println( "- t.results set from t.current" )
@@ -104,6 +152,7 @@ func (sl *StagesLatency) calculate() {
 	if sl.config.WantRelativeStats() {
 		sl.Results.subtract(sl.first)
 	}
+	sl.Results = filter.MatchingRows(sl.config.DatabaseFilter(), sl.Results, Row.FullName)
 	sl.Totals = totals(sl.Results)
 }
 
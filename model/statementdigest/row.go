@@ -0,0 +1,52 @@
+// Package statementdigest contains the routines for managing
+// performance_schema.events_statements_summary_by_digest.
+package statementdigest
+
+import (
+	"strings"
+)
+
+// Row contains a row from events_statements_summary_by_digest
+type Row struct {
+	Schema string `json:"schema"`
+	Digest string `json:"digest"`
+	Name   string `json:"name"` // the normalised statement (DIGEST_TEXT)
+
+	CountStar               uint64 `json:"count_star"`
+	SumTimerWait            uint64 `json:"sum_timer_wait"`
+	SumRowsExamined         uint64 `json:"sum_rows_examined"`
+	SumRowsSent             uint64 `json:"sum_rows_sent"`
+	SumNoIndexUsed          uint64 `json:"sum_no_index_used"`
+	SumCreatedTmpDiskTables uint64 `json:"sum_created_tmp_disk_tables"`
+}
+
+// key returns the value identifying the row. A digest is unique per schema.
+func (row Row) key() string {
+	return row.Schema + "\x00" + row.Digest
+}
+
+// References returns true if the statement appears to use the given
+// table. Table names in the statement may be qualified with the schema,
+// otherwise the statement must have been run in the table's schema.
+func (row Row) References(schema, table string) bool {
+	quotedTable := "`" + table + "`"
+	if !strings.Contains(row.Name, quotedTable) {
+		return false
+	}
+	quotedSchema := "`" + schema + "`"
+	if strings.Contains(row.Name, quotedSchema+" . "+quotedTable) || strings.Contains(row.Name, quotedSchema+"."+quotedTable) {
+		return true
+	}
+
+	return row.Schema == schema && !strings.Contains(row.Name, ". "+quotedTable) && !strings.Contains(row.Name, "."+quotedTable)
+}
+
+// subtract the countable values in one row from another
+func (row *Row) subtract(other Row) {
+	row.CountStar -= other.CountStar
+	row.SumTimerWait -= other.SumTimerWait
+	row.SumRowsExamined -= other.SumRowsExamined
+	row.SumRowsSent -= other.SumRowsSent
+	row.SumNoIndexUsed -= other.SumNoIndexUsed
+	row.SumCreatedTmpDiskTables -= other.SumCreatedTmpDiskTables
+}
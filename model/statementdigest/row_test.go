@@ -0,0 +1,25 @@
+package statementdigest
+
+import (
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		row      Row
+		expected bool
+	}{
+		{Row{Schema: "db", Name: "SELECT * FROM `t1`"}, true},
+		{Row{Schema: "db", Name: "SELECT * FROM `t10`"}, false},
+		{Row{Schema: "other", Name: "SELECT * FROM `t1`"}, false},
+		{Row{Schema: "other", Name: "SELECT * FROM `db` . `t1`"}, true},
+		{Row{Schema: "db", Name: "SELECT * FROM `other` . `t1`"}, false},
+		{Row{Name: "UPDATE `db`.`t1` SET `a` = ?"}, true},
+	}
+
+	for _, test := range tests {
+		if got := test.row.References("db", "t1"); got != test.expected {
+			t.Errorf("%+v.References(\"db\", \"t1\") failed: got: %v, expected: %v", test.row, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package statementdigest contains the routines for managing
+// performance_schema.events_statements_summary_by_digest.
+package statementdigest
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sjmudd/ps-top/datasource"
+)
+
+// Rows contains a set of rows
+type Rows []Row
+
+func totals(rows Rows) Row {
+	total := Row{Name: "Totals"}
+
+	for _, row := range rows {
+		total.CountStar += row.CountStar
+		total.SumTimerWait += row.SumTimerWait
+		total.SumRowsExamined += row.SumRowsExamined
+		total.SumRowsSent += row.SumRowsSent
+		total.SumNoIndexUsed += row.SumNoIndexUsed
+		total.SumCreatedTmpDiskTables += row.SumCreatedTmpDiskTables
+	}
+
+	return total
+}
+
+func collect(ctx context.Context, db datasource.DataSource) (Rows, error) {
+	var t Rows
+
+	// SCHEMA_NAME and DIGEST are NULL for statements without a default
+	// database and for the row counting statements which did not fit
+	// in the table.
+	const query = `SELECT SCHEMA_NAME, DIGEST, DIGEST_TEXT, COUNT_STAR, SUM_TIMER_WAIT, SUM_ROWS_EXAMINED, SUM_ROWS_SENT, SUM_NO_INDEX_USED, SUM_CREATED_TMP_DISK_TABLES FROM events_statements_summary_by_digest WHERE SUM_TIMER_WAIT > 0`
+
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var schema, digest, text sql.NullString
+		var r Row
+		if err := rows.Scan(
+			&schema,
+			&digest,
+			&text,
+			&r.CountStar,
+			&r.SumTimerWait,
+			&r.SumRowsExamined,
+			&r.SumRowsSent,
+			&r.SumNoIndexUsed,
+			&r.SumCreatedTmpDiskTables); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		r.Schema = schema.String
+		r.Digest = digest.String
+		r.Name = text.String
+		if !digest.Valid {
+			r.Name = "<other statements>"
+		}
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
+
+// remove the initial values from those rows where there's a match
+// - if we find a row we can't match ignore it
+func (rows *Rows) subtract(initial Rows) {
+	initialByKey := make(map[string]int)
+
+	for i := range initial {
+		initialByKey[initial[i].key()] = i
+	}
+
+	for i := range *rows {
+		if initialIndex, ok := initialByKey[(*rows)[i].key()]; ok {
+			(*rows)[i].subtract(initial[initialIndex])
+		}
+	}
+}
+
+// if the data in t2 is "newer", "has more values" than t then it needs refreshing.
+// check this by comparing totals.
+func (rows Rows) needsRefresh(otherRows Rows) bool {
+	return totals(rows).SumTimerWait > totals(otherRows).SumTimerWait
+}
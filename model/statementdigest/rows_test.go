@@ -0,0 +1,28 @@
+package statementdigest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsSubtract(t *testing.T) {
+	initial := Rows{
+		{Schema: "db1", Digest: "d1", CountStar: 1, SumTimerWait: 10},
+		{Schema: "db2", Digest: "d1", CountStar: 2, SumTimerWait: 20},
+	}
+	rows := Rows{
+		{Schema: "db1", Digest: "d1", CountStar: 3, SumTimerWait: 30},
+		{Schema: "db2", Digest: "d1", CountStar: 5, SumTimerWait: 50},
+		{Schema: "db1", Digest: "d2", CountStar: 7, SumTimerWait: 70},
+	}
+	expected := Rows{
+		{Schema: "db1", Digest: "d1", CountStar: 2, SumTimerWait: 20},
+		{Schema: "db2", Digest: "d1", CountStar: 3, SumTimerWait: 30},
+		{Schema: "db1", Digest: "d2", CountStar: 7, SumTimerWait: 70},
+	}
+
+	rows.subtract(initial)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows.subtract() failed: got: %+v, expected: %+v", rows, expected)
+	}
+}
@@ -0,0 +1,112 @@
+// Package statementdigest contains the routines for managing
+// performance_schema.events_statements_summary_by_digest.
+package statementdigest
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// StatementDigest contains performance_schema.events_statements_summary_by_digest data
+type StatementDigest struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	first          Rows // initial data for relative values
+	last           Rows // last loaded values
+	Results        Rows // results (maybe with subtraction)
+	Totals         Row  // totals of results
+	db             datasource.DataSource
+}
+
+// NewStatementDigest returns a statement digest object with config and db handle
+func NewStatementDigest(cfg *config.Config, db datasource.DataSource) *StatementDigest {
+	return &StatementDigest{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// ResetStatistics resets the statistics to current values
+func (sd *StatementDigest) ResetStatistics() {
+	sd.first = utils.DuplicateSlice(sd.last)
+	sd.FirstCollected = sd.LastCollected
+
+	sd.calculate()
+}
+
+// Collect collects data from the db, updating initial values
+// if needed, and then subtracting initial values if we want relative
+// values, after which it stores totals.
+func (sd *StatementDigest) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, sd.db)
+	if err != nil {
+		return err
+	}
+	sd.AddRows(rows, time.Now())
+
+	log.Println("StatementDigest.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time, updating
+// initial values if needed and calculating the results and totals.
+func (sd *StatementDigest) AddRows(rows Rows, collected time.Time) {
+	sd.last = rows
+	sd.LastCollected = collected
+
+	// check for no first data or need to reload initial characteristics
+	// e.g. after events_statements_summary_by_digest has been truncated
+	if (len(sd.first) == 0 && len(sd.last) > 0) || sd.first.needsRefresh(sd.last) {
+		sd.first = utils.DuplicateSlice(sd.last)
+		sd.FirstCollected = sd.LastCollected
+	}
+
+	sd.calculate()
+
+	log.Println("sd.first.totals():", totals(sd.first))
+	log.Println("sd.last.totals():", totals(sd.last))
+}
+
+// Last returns the last collected rows
+func (sd StatementDigest) Last() Rows {
+	return sd.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (sd *StatementDigest) Refilter() {
+	sd.calculate()
+}
+
+func (sd *StatementDigest) calculate() {
+	sd.Results = utils.DuplicateSlice(sd.last)
+
+	if sd.config.WantRelativeStats() {
+		sd.Results.subtract(sd.first)
+	}
+
+	f := sd.config.DatabaseFilter()
+	sd.Results = filter.Rows(sd.Results, func(row Row) bool { return f.MatchesDatabase(row.Schema) })
+	sd.Results = filter.MatchingRows(f, sd.Results, func(row Row) string { return row.Name })
+	sd.Totals = totals(sd.Results)
+}
+
+// HaveRelativeStats is true for this object
+func (sd StatementDigest) HaveRelativeStats() bool {
+	return true
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (sd StatementDigest) WantRelativeStats() bool {
+	return sd.config.WantRelativeStats()
+}
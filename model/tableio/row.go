@@ -2,25 +2,29 @@
 // performance_schema.tableio_waits_by_table.
 package tableio
 
+import "github.com/sjmudd/ps-top/utils"
+
 // Row contains w from table_io_waits_summary_by_table
 type Row struct {
-	Name string // we don't keep the retrieved columns but store the generated table name
-
-	SumTimerWait   uint64
-	SumTimerRead   uint64
-	SumTimerWrite  uint64
-	SumTimerFetch  uint64
-	SumTimerInsert uint64
-	SumTimerUpdate uint64
-	SumTimerDelete uint64
-
-	CountStar   uint64
-	CountRead   uint64
-	CountWrite  uint64
-	CountFetch  uint64
-	CountInsert uint64
-	CountUpdate uint64
-	CountDelete uint64
+	Name string `json:"name"` // we don't keep the retrieved columns but store the generated table name
+
+	SumTimerWait   uint64 `json:"sum_timer_wait"`
+	SumTimerRead   uint64 `json:"sum_timer_read"`
+	SumTimerWrite  uint64 `json:"sum_timer_write"`
+	SumTimerFetch  uint64 `json:"sum_timer_fetch"`
+	SumTimerInsert uint64 `json:"sum_timer_insert"`
+	SumTimerUpdate uint64 `json:"sum_timer_update"`
+	SumTimerDelete uint64 `json:"sum_timer_delete"`
+	MinTimerWait   uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait   uint64 `json:"max_timer_wait"` // not subtracted
+
+	CountStar   uint64 `json:"count_star"`
+	CountRead   uint64 `json:"count_read"`
+	CountWrite  uint64 `json:"count_write"`
+	CountFetch  uint64 `json:"count_fetch"`
+	CountInsert uint64 `json:"count_insert"`
+	CountUpdate uint64 `json:"count_update"`
+	CountDelete uint64 `json:"count_delete"`
 }
 
 // subtract the countable values in one row from another
@@ -42,6 +46,11 @@ func (row *Row) subtract(other Row) {
 	row.CountWrite -= other.CountWrite
 }
 
+// Timers returns the number of waits and their latency
+func (row Row) Timers() utils.Timers {
+	return utils.Timers{Count: row.CountStar, Sum: row.SumTimerWait, Min: row.MinTimerWait, Max: row.MaxTimerWait}
+}
+
 // HasData indicates if there is data in the row (for counting valid rows)
 func (row *Row) HasData() bool {
 	return row != nil && row.SumTimerWait > 0
@@ -3,8 +3,9 @@
 package tableio
 
 import (
-	"database/sql"
+	"context"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
@@ -24,6 +25,8 @@ func totals(rows Rows) Row {
 		total.SumTimerDelete += row.SumTimerDelete
 		total.SumTimerRead += row.SumTimerRead
 		total.SumTimerWrite += row.SumTimerWrite
+		total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+		total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
 
 		total.CountStar += row.CountStar
 		total.CountFetch += row.CountFetch
@@ -37,13 +40,13 @@ func totals(rows Rows) Row {
 	return total
 }
 
-func collect(db *sql.DB, databaseFilter *filter.DatabaseFilter) Rows {
+func collect(ctx context.Context, db datasource.DataSource, databaseFilter *filter.DatabaseFilter) (Rows, error) {
 	var t Rows
 
 	log.Printf("collect(?,%q)\n", databaseFilter)
 
 	// we collect all information even if it's mainly empty as we may reference it later
-	sql := `SELECT OBJECT_SCHEMA, OBJECT_NAME, COUNT_STAR, SUM_TIMER_WAIT, COUNT_READ, SUM_TIMER_READ, COUNT_WRITE, SUM_TIMER_WRITE, COUNT_FETCH, SUM_TIMER_FETCH, COUNT_INSERT, SUM_TIMER_INSERT, COUNT_UPDATE, SUM_TIMER_UPDATE, COUNT_DELETE, SUM_TIMER_DELETE FROM table_io_waits_summary_by_table WHERE SUM_TIMER_WAIT > 0`
+	sql := `SELECT OBJECT_SCHEMA, OBJECT_NAME, COUNT_STAR, SUM_TIMER_WAIT, MIN_TIMER_WAIT, MAX_TIMER_WAIT, COUNT_READ, SUM_TIMER_READ, COUNT_WRITE, SUM_TIMER_WRITE, COUNT_FETCH, SUM_TIMER_FETCH, COUNT_INSERT, SUM_TIMER_INSERT, COUNT_UPDATE, SUM_TIMER_UPDATE, COUNT_DELETE, SUM_TIMER_DELETE FROM table_io_waits_summary_by_table WHERE SUM_TIMER_WAIT > 0`
 	args := []interface{}{}
 
 	// Apply the filter if provided and seems good.
@@ -55,9 +58,9 @@ func collect(db *sql.DB, databaseFilter *filter.DatabaseFilter) Rows {
 		log.Printf("apply databaseFilter: sql: %q, args: %+v\n", sql, args)
 	}
 
-	rows, err := db.Query(sql, args...)
+	rows, err := db.Query(ctx, sql, args...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for rows.Next() {
@@ -68,6 +71,8 @@ func collect(db *sql.DB, databaseFilter *filter.DatabaseFilter) Rows {
 			&table,
 			&r.CountStar,
 			&r.SumTimerWait,
+			&r.MinTimerWait,
+			&r.MaxTimerWait,
 			&r.CountRead,
 			&r.SumTimerRead,
 			&r.CountWrite,
@@ -80,7 +85,8 @@ func collect(db *sql.DB, databaseFilter *filter.DatabaseFilter) Rows {
 			&r.SumTimerUpdate,
 			&r.CountDelete,
 			&r.SumTimerDelete); err != nil {
-			log.Fatal(err)
+			_ = rows.Close()
+			return nil, err
 		}
 		r.Name = utils.QualifiedTableName(schema, table)
 
@@ -88,11 +94,12 @@ func collect(db *sql.DB, databaseFilter *filter.DatabaseFilter) Rows {
 		t = append(t, r)
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return nil, err
 	}
 	_ = rows.Close()
 
-	return t
+	return t, nil
 }
 
 // remove the initial values from those rows where there's a match
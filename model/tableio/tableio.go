@@ -2,11 +2,13 @@
 package tableio
 
 import (
-	"database/sql"
+	"context"
 	"log"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
 )
 
@@ -20,11 +22,11 @@ type TableIo struct {
 	last           Rows // last loaded values
 	Results        Rows // results (maybe with subtraction)
 	Totals         Row  // totals of results
-	db             *sql.DB
+	db             datasource.DataSource
 }
 
 // NewTableIo returns an i/o latency object with config and db handle
-func NewTableIo(cfg *config.Config, db *sql.DB) *TableIo {
+func NewTableIo(cfg *config.Config, db datasource.DataSource) *TableIo {
 	tiol := &TableIo{
 		config: cfg,
 		db:     db,
@@ -44,11 +46,25 @@ func (tiol *TableIo) ResetStatistics() {
 // Collect collects data from the db, updating initial values
 // if needed, and then subtracting initial values if we want relative
 // values, after which it stores totals.
-func (tiol *TableIo) Collect() {
+func (tiol *TableIo) Collect(ctx context.Context) error {
 	start := time.Now()
 
-	tiol.last = collect(tiol.db, tiol.config.DatabaseFilter())
-	tiol.LastCollected = time.Now()
+	rows, err := collect(ctx, tiol.db, tiol.config.DatabaseFilter())
+	if err != nil {
+		return err
+	}
+	tiol.AddRows(rows, time.Now())
+
+	log.Println("TableIo.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time, updating
+// initial values if needed and calculating the results and totals.
+func (tiol *TableIo) AddRows(rows Rows, collected time.Time) {
+	tiol.last = rows
+	tiol.LastCollected = collected
 
 	// check for no first data or need to reload initial characteristics
 	if (len(tiol.first) == 0 && len(tiol.last) > 0) || tiol.first.needsRefresh(tiol.last) {
@@ -60,7 +76,17 @@ func (tiol *TableIo) Collect() {
 
 	log.Println("tiol.first.totals():", totals(tiol.first))
 	log.Println("tiol.last.totals():", totals(tiol.last))
-	log.Println("TableIo.Collect() END, took:", time.Duration(time.Since(start)).String())
+}
+
+// Last returns the last collected rows
+func (tiol TableIo) Last() Rows {
+	return tiol.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (tiol *TableIo) Refilter() {
+	tiol.calculate()
 }
 
 func (tiol *TableIo) calculate() {
@@ -70,6 +96,7 @@ func (tiol *TableIo) calculate() {
 		tiol.Results.subtract(tiol.first)
 	}
 
+	tiol.Results = filter.MatchingRows(tiol.config.DatabaseFilter(), tiol.Results, func(row Row) string { return row.Name })
 	tiol.Totals = totals(tiol.Results)
 }
 
@@ -78,25 +78,31 @@ CREATE TABLE `table_lock_waits_summary_by_table` (
 
 */
 
+import "github.com/sjmudd/ps-top/utils"
+
 // Row holds a row of data from table_lock_waits_summary_by_table
 type Row struct {
-	Name                          string // combination of <schema>.<table>
-	SumTimerWait                  uint64
-	SumTimerRead                  uint64
-	SumTimerWrite                 uint64
-	SumTimerReadWithSharedLocks   uint64
-	SumTimerReadHighPriority      uint64
-	SumTimerReadNoInsert          uint64
-	SumTimerReadNormal            uint64
-	SumTimerReadExternal          uint64
-	SumTimerWriteAllowWrite       uint64
-	SumTimerWriteConcurrentInsert uint64
-	SumTimerWriteLowPriority      uint64
-	SumTimerWriteNormal           uint64
-	SumTimerWriteExternal         uint64
+	Name                          string `json:"name"` // combination of <schema>.<table>
+	CountStar                     uint64 `json:"count_star"`
+	MinTimerWait                  uint64 `json:"min_timer_wait"` // not subtracted
+	MaxTimerWait                  uint64 `json:"max_timer_wait"` // not subtracted
+	SumTimerWait                  uint64 `json:"sum_timer_wait"`
+	SumTimerRead                  uint64 `json:"sum_timer_read"`
+	SumTimerWrite                 uint64 `json:"sum_timer_write"`
+	SumTimerReadWithSharedLocks   uint64 `json:"sum_timer_read_with_shared_locks"`
+	SumTimerReadHighPriority      uint64 `json:"sum_timer_read_high_priority"`
+	SumTimerReadNoInsert          uint64 `json:"sum_timer_read_no_insert"`
+	SumTimerReadNormal            uint64 `json:"sum_timer_read_normal"`
+	SumTimerReadExternal          uint64 `json:"sum_timer_read_external"`
+	SumTimerWriteAllowWrite       uint64 `json:"sum_timer_write_allow_write"`
+	SumTimerWriteConcurrentInsert uint64 `json:"sum_timer_write_concurrent_insert"`
+	SumTimerWriteLowPriority      uint64 `json:"sum_timer_write_low_priority"`
+	SumTimerWriteNormal           uint64 `json:"sum_timer_write_normal"`
+	SumTimerWriteExternal         uint64 `json:"sum_timer_write_external"`
 }
 
 func (r *Row) subtract(other Row) {
+	r.CountStar -= other.CountStar
 	r.SumTimerWait -= other.SumTimerWait
 	r.SumTimerRead -= other.SumTimerRead
 	r.SumTimerWrite -= other.SumTimerWrite
@@ -112,6 +118,11 @@ func (r *Row) subtract(other Row) {
 	r.SumTimerWriteExternal -= other.SumTimerWriteExternal
 }
 
+// Timers returns the number of waits and their latency
+func (r Row) Timers() utils.Timers {
+	return utils.Timers{Count: r.CountStar, Sum: r.SumTimerWait, Min: r.MinTimerWait, Max: r.MaxTimerWait}
+}
+
 // HasData returnss true if SumTimerWait > 0
 func (r *Row) HasData() bool {
 	return r != nil && r.SumTimerWait > 0
@@ -3,9 +3,10 @@
 package tablelocks
 
 import (
-	"database/sql"
+	"context"
 	_ "github.com/go-sql-driver/mysql" // keep glint happy
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 	"github.com/sjmudd/ps-top/model/filter"
 	"github.com/sjmudd/ps-top/utils"
@@ -19,7 +20,10 @@ func totals(rows []Row) Row {
 	total := Row{Name: "Totals"}
 
 	for _, row := range rows {
+		total.CountStar += row.CountStar
 		total.SumTimerWait += row.SumTimerWait
+		total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+		total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
 		total.SumTimerRead += row.SumTimerRead
 		total.SumTimerWrite += row.SumTimerWrite
 		total.SumTimerReadWithSharedLocks += row.SumTimerReadWithSharedLocks
@@ -40,11 +44,14 @@ func totals(rows []Row) Row {
 // Select the raw data from the database into file_summary_by_instance_rows
 // - filter out empty values
 // - change FILE_NAME into a more descriptive value.
-func collect(db *sql.DB, filter *filter.DatabaseFilter) []Row {
+func collect(ctx context.Context, db datasource.DataSource, filter *filter.DatabaseFilter) ([]Row, error) {
 	sql := `
 SELECT	OBJECT_SCHEMA,
 	OBJECT_NAME,
+	COUNT_STAR,
 	SUM_TIMER_WAIT,
+	MIN_TIMER_WAIT,
+	MAX_TIMER_WAIT,
 	SUM_TIMER_READ,
 	SUM_TIMER_WRITE,
 	SUM_TIMER_READ_WITH_SHARED_LOCKS,
@@ -73,9 +80,9 @@ WHERE	COUNT_STAR > 0`
 
 	var rows []Row // to be returned to caller
 
-	sqlrows, err := db.Query(sql, args...)
+	sqlrows, err := db.Query(ctx, sql, args...)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for sqlrows.Next() {
@@ -85,7 +92,10 @@ WHERE	COUNT_STAR > 0`
 		if err := sqlrows.Scan(
 			&schema,
 			&table,
+			&row.CountStar,
 			&row.SumTimerWait,
+			&row.MinTimerWait,
+			&row.MaxTimerWait,
 			&row.SumTimerRead,
 			&row.SumTimerWrite,
 			&row.SumTimerReadWithSharedLocks,
@@ -98,18 +108,20 @@ WHERE	COUNT_STAR > 0`
 			&row.SumTimerWriteLowPriority,
 			&row.SumTimerWriteNormal,
 			&row.SumTimerWriteExternal); err != nil {
-			log.Fatal(err)
+			_ = sqlrows.Close()
+			return nil, err
 		}
 		row.Name = utils.QualifiedTableName(schema, table)
 
 		rows = append(rows, row)
 	}
 	if err := sqlrows.Err(); err != nil {
-		log.Fatal(err)
+		_ = sqlrows.Close()
+		return nil, err
 	}
 	_ = sqlrows.Close()
 
-	return rows
+	return rows, nil
 }
 
 // remove the initial values from those rows where there's a match
@@ -2,12 +2,14 @@
 package tablelocks
 
 import (
-	"database/sql"
+	"context"
 	_ "github.com/go-sql-driver/mysql" // keep golint happy
 	"log"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 )
 
 // TableLocks represents a table of rows
@@ -19,11 +21,11 @@ type TableLocks struct {
 	current        Rows // last loaded values
 	Results        Rows // results (maybe with subtraction)
 	Totals         Row  // totals of results
-	db             *sql.DB
+	db             datasource.DataSource
 }
 
 // NewTableLocks returns a pointer to an object of this type
-func NewTableLocks(cfg *config.Config, db *sql.DB) *TableLocks {
+func NewTableLocks(cfg *config.Config, db datasource.DataSource) *TableLocks {
 	tl := &TableLocks{
 		config: cfg,
 		db:     db,
@@ -39,10 +41,22 @@ func (tl *TableLocks) copyCurrentToInitial() {
 }
 
 // Collect data from the db, then merge it in.
-func (tl *TableLocks) Collect() {
+func (tl *TableLocks) Collect(ctx context.Context) error {
 	start := time.Now()
-	tl.current = collect(tl.db, tl.config.DatabaseFilter())
-	tl.LastCollected = time.Now()
+	rows, err := collect(ctx, tl.db, tl.config.DatabaseFilter())
+	if err != nil {
+		return err
+	}
+	tl.AddRows(rows, time.Now())
+	log.Println("TableLocks.Collect() took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (tl *TableLocks) AddRows(rows Rows, collected time.Time) {
+	tl.current = rows
+	tl.LastCollected = collected
 
 	// check for no data or check for reload initial characteristics
 	if (len(tl.initial) == 0 && len(tl.current) > 0) || tl.initial.needsRefresh(tl.current) {
@@ -50,7 +64,17 @@ func (tl *TableLocks) Collect() {
 	}
 
 	tl.calculate()
-	log.Println("TableLocks.Collect() took:", time.Duration(time.Since(start)).String())
+}
+
+// Last returns the last collected rows
+func (tl TableLocks) Last() Rows {
+	return tl.current
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (tl *TableLocks) Refilter() {
+	tl.calculate()
 }
 
 func (tl *TableLocks) calculate() {
@@ -59,6 +83,7 @@ func (tl *TableLocks) calculate() {
 	if tl.config.WantRelativeStats() {
 		tl.Results.subtract(tl.initial)
 	}
+	tl.Results = filter.MatchingRows(tl.config.DatabaseFilter(), tl.Results, func(row Row) string { return row.Name })
 	tl.Totals = totals(tl.Results)
 }
 
@@ -0,0 +1,19 @@
+// Package threads contains the routines for managing the connections
+// shown in performance_schema.threads.
+package threads
+
+// Row contains a connection from performance_schema.threads together
+// with the statement it is currently executing
+type Row struct {
+	ID      uint64 `json:"id"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	DB      string `json:"db"`
+	Command string `json:"command"`
+	Time    uint64 `json:"time"`
+	State   string `json:"state"`
+	Info    string `json:"info"` // the current statement, if any
+}
+
+// Rows contains a set of rows
+type Rows []Row
@@ -0,0 +1,113 @@
+// Package threads contains the routines for managing the connections
+// shown in performance_schema.threads.
+package threads
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// ErrConsumerDisabled is returned when the threads currently waiting
+// can not be collected as the setup_consumers row of the table is disabled
+var ErrConsumerDisabled = errors.New("disabled in setup_consumers")
+
+// maxInfoLength is the maximum length of the statement text kept for a connection
+const maxInfoLength = 200
+
+// columns are the columns returned for each connection. Only the top
+// level statement still executing is shown.
+const columns = `SELECT t.PROCESSLIST_ID, t.PROCESSLIST_USER, t.PROCESSLIST_HOST, t.PROCESSLIST_DB, t.PROCESSLIST_COMMAND, t.PROCESSLIST_TIME, t.PROCESSLIST_STATE, s.SQL_TEXT`
+
+// currentStatement joins threads t with the statement each thread is executing
+const currentStatement = ` LEFT JOIN events_statements_current s ON s.THREAD_ID = t.THREAD_ID AND s.NESTING_EVENT_ID IS NULL AND s.END_EVENT_ID IS NULL`
+
+// collect returns the connections using the databases which are not
+// filtered out
+func collect(ctx context.Context, db datasource.DataSource, databaseFilter *filter.DatabaseFilter) (Rows, error) {
+	return query(ctx, db, databaseFilter, columns+` FROM threads t`+currentStatement+` WHERE t.TYPE = 'FOREGROUND' AND t.PROCESSLIST_ID IS NOT NULL`)
+}
+
+// collectWaiting returns the threads currently waiting on the given wait
+// or stage event, e.g. wait/synch/mutex/innodb/trx_mutex or
+// stage/sql/Sending data. Background threads have no id.
+func collectWaiting(ctx context.Context, db datasource.DataSource, eventName string) (Rows, error) {
+	table := "events_waits_current"
+	if strings.HasPrefix(eventName, "stage/") {
+		table = "events_stages_current"
+	}
+
+	// the table is empty unless its consumer is enabled, which it is not by default
+	var enabled string
+	if err := db.QueryRow(ctx, "SELECT ENABLED FROM setup_consumers WHERE NAME = ?", table).Scan(&enabled); err != nil {
+		return nil, err
+	}
+	if enabled != "YES" {
+		return nil, fmt.Errorf("%s is %w", table, ErrConsumerDisabled)
+	}
+
+	return query(ctx, db, nil, columns+` FROM `+table+` e JOIN threads t ON t.THREAD_ID = e.THREAD_ID`+currentStatement+` WHERE e.EVENT_NAME = ? AND e.END_EVENT_ID IS NULL`, eventName)
+}
+
+// query returns the connections returned by the given query which use
+// a database matching the filter. The filter is checked before the
+// database is anonymised.
+func query(ctx context.Context, db datasource.DataSource, databaseFilter *filter.DatabaseFilter, query string, args ...any) (Rows, error) {
+	var t Rows
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	for rows.Next() {
+		var (
+			r                             Row
+			id, time                      sql.NullInt64
+			user, host, database, command sql.NullString
+			state, info                   sql.NullString
+		)
+		if err := rows.Scan(
+			&id,
+			&user,
+			&host,
+			&database,
+			&command,
+			&time,
+			&state,
+			&info); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		if !databaseFilter.MatchesDatabase(database.String) {
+			continue
+		}
+		r.ID = uint64(id.Int64)
+		r.User = anonymiser.Anonymise("user", user.String)
+		r.Host = anonymiser.Anonymise("host", host.String)
+		r.DB = anonymiser.Anonymise("schema", database.String)
+		r.Command = command.String
+		r.Time = uint64(time.Int64)
+		r.State = state.String
+		if !anonymiser.Enabled() {
+			// statements may contain any names or values so are not shown when anonymising
+			r.Info = utils.Truncate(info.String, maxInfoLength)
+		}
+
+		t = append(t, r)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	_ = rows.Close()
+
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package threads
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+)
+
+func TestCollect(t *testing.T) {
+	fixture := datasource.NewFixture().
+		Add("FROM threads",
+			[]any{10, "app", "h1", "db1", "Query", 5, "executing", "SELECT *\nFROM t1"},
+			[]any{11, "app", "h2", nil, "Sleep", 60, nil, nil},
+		)
+
+	tests := []struct {
+		anonymise bool
+		filter    *filter.DatabaseFilter
+		expected  Rows
+	}{
+		{
+			false,
+			nil,
+			Rows{
+				{ID: 10, User: "app", Host: "h1", DB: "db1", Command: "Query", Time: 5, State: "executing", Info: "SELECT * FROM t1"},
+				{ID: 11, User: "app", Host: "h2", Command: "Sleep", Time: 60},
+			},
+		},
+		{
+			true,
+			nil,
+			Rows{
+				{ID: 10, User: "user1", Host: "host1", DB: "schema1", Command: "Query", Time: 5, State: "executing"},
+				{ID: 11, User: "user1", Host: "host2", Command: "Sleep", Time: 60},
+			},
+		},
+		{
+			// the database is filtered on before it is anonymised
+			true,
+			filter.NewDatabaseFilter("db1"),
+			Rows{
+				{ID: 10, User: "user1", Host: "host1", DB: "schema1", Command: "Query", Time: 5, State: "executing"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		anonymiser.Enable(test.anonymise)
+		got, err := collect(context.Background(), fixture, test.filter)
+		if err != nil {
+			t.Fatalf("collect() failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("collect() with anonymise %v failed:\ngot:      %+v\nexpected: %+v", test.anonymise, got, test.expected)
+		}
+	}
+	anonymiser.Enable(false)
+}
@@ -0,0 +1,94 @@
+// Package threads contains the routines for managing the connections
+// shown in performance_schema.threads.
+package threads
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Threads holds the connections to MySQL
+type Threads struct {
+	config         *config.Config
+	FirstCollected time.Time
+	LastCollected  time.Time
+	last           Rows // last loaded values
+	Results        Rows // connections to show
+	db             datasource.DataSource
+}
+
+// NewThreads returns a threads object using the given config and db
+func NewThreads(cfg *config.Config, db datasource.DataSource) *Threads {
+	return &Threads{
+		config: cfg,
+		db:     db,
+	}
+}
+
+// Collect collects the current connections from the db
+func (t *Threads) Collect(ctx context.Context) error {
+	start := time.Now()
+
+	rows, err := collect(ctx, t.db, t.config.DatabaseFilter())
+	if err != nil {
+		return err
+	}
+	t.AddRows(rows, time.Now())
+
+	log.Println("Threads.Collect() END, took:", time.Duration(time.Since(start)).String())
+
+	return nil
+}
+
+// AddRows takes a new set of rows collected at the given time and updates the results.
+func (t *Threads) AddRows(rows Rows, collected time.Time) {
+	t.last = rows
+	t.LastCollected = collected
+
+	t.calculate()
+}
+
+// Waiting returns the threads currently waiting on the given event.
+// These are collected when asked for and are not filtered.
+func (t *Threads) Waiting(ctx context.Context, eventName string) (Rows, error) {
+	return collectWaiting(ctx, t.db, eventName)
+}
+
+// Last returns the last collected rows
+func (t Threads) Last() Rows {
+	return t.last
+}
+
+// Refilter calculates the results again from the rows already collected,
+// e.g. once the filter has changed
+func (t *Threads) Refilter() {
+	t.calculate()
+}
+
+func (t *Threads) calculate() {
+	f := t.config.DatabaseFilter()
+
+	t.Results = utils.DuplicateSlice(t.last)
+	t.Results = filter.MatchingRows(f, t.Results, func(row Row) string { return row.User })
+}
+
+// ResetStatistics recalculates the results as there are no counters to reset
+func (t *Threads) ResetStatistics() {
+	t.calculate()
+}
+
+// HaveRelativeStats is false for this object
+func (t Threads) HaveRelativeStats() bool {
+	return false
+}
+
+// WantRelativeStats returns whether we want to see relative or absolute stats
+func (t Threads) WantRelativeStats() bool {
+	return t.config.WantRelativeStats()
+}
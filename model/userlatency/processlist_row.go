@@ -3,26 +3,30 @@
 package userlatency
 
 import (
+	"context"
 	"database/sql"
+	"sort"
+	"strings"
 
 	"github.com/sjmudd/anonymiser"
-	"github.com/sjmudd/ps-top/log"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
 )
 
 // ProcesslistRow contains a row from from information_schema.processlist
 type ProcesslistRow struct {
-	ID      uint64
-	user    string
-	host    string
-	db      string
-	command string
-	time    uint64
-	state   string
-	info    string
+	ID      uint64 `json:"id"`
+	User    string `json:"user"`
+	Host    string `json:"host"`
+	DB      string `json:"db"`
+	Command string `json:"command"`
+	Time    uint64 `json:"time"`
+	State   string `json:"state"`
+	Info    string `json:"info"`
 }
 
 // get the output of I_S.PROCESSLIST - results only used internally
-func collect(db *sql.DB) []ProcesslistRow {
+func collectProcesslist(ctx context.Context, db datasource.DataSource) ([]ProcesslistRow, error) {
 	// we collect all information even if it's mainly empty as we may reference it later
 	const query = "SELECT ID, USER, HOST, DB, COMMAND, TIME, STATE, INFO FROM INFORMATION_SCHEMA.PROCESSLIST"
 
@@ -38,9 +42,9 @@ func collect(db *sql.DB) []ProcesslistRow {
 		info     sql.NullString
 	)
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(ctx, query)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	for rows.Next() {
@@ -54,31 +58,93 @@ func collect(db *sql.DB) []ProcesslistRow {
 			&time,
 			&state,
 			&info); err != nil {
-			log.Fatal(err)
+			_ = rows.Close()
+			return nil, err
 		}
 		r.ID = uint64(id.Int64)
-
-		// be verbose for debugging.
-		u := user.String
-		a := anonymiser.Anonymise("user", user.String)
-		log.Println("user:", u, ", anonymised:", a)
-		r.user = a
-		r.host = host.String
+		r.User = anonymiser.Anonymise("user", user.String)
+		r.Host = host.String
 		if database.Valid {
-			r.db = database.String
+			r.DB = database.String
 		}
-		r.command = command.String
-		r.time = uint64(time.Int64)
+		r.Command = command.String
+		r.Time = uint64(time.Int64)
 		if state.Valid {
-			r.state = state.String
+			r.State = state.String
 		}
-		r.info = info.String
+		r.Info = info.String
 		t = append(t, r)
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return nil, err
 	}
 	_ = rows.Close()
 
-	return t
+	return t, nil
+}
+
+// return the hostname without the port part
+func getHostname(hostPort string) string {
+	i := strings.Index(hostPort, ":")
+	if i >= 0 {
+		return hostPort[0:i]
+	}
+	return hostPort // shouldn't happen !!!
+}
+
+// summariseProcesslist returns the number of connections, the number of
+// active connections and the number of different hosts and databases used
+// by each user. Connections using databases which are filtered out are
+// ignored.
+func summariseProcesslist(processlist []ProcesslistRow, databaseFilter *filter.DatabaseFilter) Rows {
+	type summary struct {
+		connections uint64
+		active      uint64
+		hosts       map[string]bool
+		dbs         map[string]bool
+	}
+	byUser := make(map[string]*summary)
+
+	for _, p := range processlist {
+		if !databaseFilter.MatchesDatabase(p.DB) {
+			continue
+		}
+
+		s, found := byUser[p.User]
+		if !found {
+			s = &summary{hosts: make(map[string]bool), dbs: make(map[string]bool)}
+			byUser[p.User] = s
+		}
+
+		s.connections++
+		host := getHostname(p.Host)
+		// ignore system SQL threads (may be more to filter out)
+		if p.User != "system user" && host != "" && p.Command != "Binlog Dump" && p.Command != "Sleep" {
+			s.active++
+		}
+		if p.Command == "Binlog Dump" && strings.Contains(p.State, "Sending binlog event to slave") {
+			s.active++
+		}
+		if host != "" {
+			s.hosts[host] = true
+		}
+		if p.DB != "" {
+			s.dbs[p.DB] = true
+		}
+	}
+
+	rows := make(Rows, 0, len(byUser))
+	for user, s := range byUser {
+		rows = append(rows, Row{
+			Username:    user,
+			Connections: s.connections,
+			Active:      s.active,
+			Hosts:       uint64(len(s.hosts)),
+			Dbs:         uint64(len(s.dbs)),
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Username < rows[j].Username })
+
+	return rows
 }
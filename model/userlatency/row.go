@@ -1,57 +1,77 @@
-// Package userlatency manages the output from INFORMATION_SCHEMA.PROCESSLIST
+// Package userlatency contains the routines for managing the activity by
+// user collected from performance_schema and INFORMATION_SCHEMA.PROCESSLIST.
 package userlatency
 
-/*
+import (
+	"log"
+)
 
-CREATE TEMPORARY TABLE `PROCESSLIST` (
-  `ID` bigint unsigned NOT NULL DEFAULT '0',
-  `USER` varchar(32) NOT NULL DEFAULT '',
-  `HOST` varchar(261) NOT NULL DEFAULT '',
-  `DB` varchar(64) DEFAULT NULL,
-  `COMMAND` varchar(16) NOT NULL DEFAULT '',
-  `TIME` int NOT NULL DEFAULT '0',
-  `STATE` varchar(64) DEFAULT NULL,
-  `INFO` longtext
-) ENGINE=InnoDB DEFAULT CHARSET=utf8
+// Row contains the activity of a single user.
+//
+// Statement and wait information is cumulative and comes from
+// events_statements_summary_by_user_by_event_name and
+// events_waits_summary_by_user_by_event_name. Connections and
+// TotalConnections come from accounts. Active, Hosts and Dbs describe the
+// current connections as shown in INFORMATION_SCHEMA.PROCESSLIST, which
+// Connections are also counted from when filtering databases.
+// TotalConnections is never filtered.
+type Row struct {
+	Username string `json:"username"`
 
-*/
+	SumTimerWait   uint64 `json:"sum_timer_wait"` // latency of all statements
+	SumTimerSelect uint64 `json:"sum_timer_select"`
+	SumTimerInsert uint64 `json:"sum_timer_insert"`
+	SumTimerUpdate uint64 `json:"sum_timer_update"`
+	SumTimerDelete uint64 `json:"sum_timer_delete"`
+	SumTimerOther  uint64 `json:"sum_timer_other"`
+	CountStar      uint64 `json:"count_star"`      // number of statements
+	SumTimerWaits  uint64 `json:"sum_timer_waits"` // latency of wait events (excluding idle)
 
-// Row contains a summary row of information taken from information_schema.processlist
-type Row struct {
-	Username    string
-	Runtime     uint64
-	Sleeptime   uint64
-	Connections uint64
-	Active      uint64
-	Hosts       uint64
-	Dbs         uint64
-	Selects     uint64
-	Inserts     uint64
-	Updates     uint64
-	Deletes     uint64
-	Other       uint64
+	Connections      uint64 `json:"connections"`
+	TotalConnections uint64 `json:"total_connections"`
+	Active           uint64 `json:"active"`
+	Hosts            uint64 `json:"hosts"`
+	Dbs              uint64 `json:"dbs"`
 }
 
-// TotalTime returns Runtime + Sleeptime
-func (r Row) TotalTime() uint64 {
-	return r.Runtime + r.Sleeptime
+// subtract the cumulative values in one row from another. The current
+// connection information is left unchanged.
+func (row *Row) subtract(other Row) {
+	// check for issues here (we have a bug) and log it
+	// - this situation should not happen so there's a logic bug somewhere else
+	if row.SumTimerWait < other.SumTimerWait || row.SumTimerWaits < other.SumTimerWaits || row.TotalConnections < other.TotalConnections {
+		log.Println("WARNING: Row.subtract() - subtraction problem! (not subtracting)")
+		log.Println("row=", row)
+		log.Println("other=", other)
+		return
+	}
+
+	row.SumTimerWait -= other.SumTimerWait
+	row.SumTimerSelect -= other.SumTimerSelect
+	row.SumTimerInsert -= other.SumTimerInsert
+	row.SumTimerUpdate -= other.SumTimerUpdate
+	row.SumTimerDelete -= other.SumTimerDelete
+	row.SumTimerOther -= other.SumTimerOther
+	row.CountStar -= other.CountStar
+	row.SumTimerWaits -= other.SumTimerWaits
+	row.TotalConnections -= other.TotalConnections
 }
 
-// totals returns the totals of all rows
-func totals(rows []Row) Row {
-	total := Row{Username: "Totals"}
-
-	for _, row := range rows {
-		total.Runtime += row.Runtime
-		total.Sleeptime += row.Sleeptime
-		total.Connections += row.Connections
-		total.Active += row.Active
-		total.Selects += row.Selects
-		total.Inserts += row.Inserts
-		total.Updates += row.Updates
-		total.Deletes += row.Deletes
-		total.Other += row.Other
-	}
+// addStatements adds the statements of the given type to the row
+func (row *Row) addStatements(eventName string, count, sumTimerWait uint64) {
+	row.CountStar += count
+	row.SumTimerWait += sumTimerWait
 
-	return total
+	switch eventName {
+	case "statement/sql/select":
+		row.SumTimerSelect += sumTimerWait
+	case "statement/sql/insert", "statement/sql/insert_select", "statement/sql/replace", "statement/sql/replace_select":
+		row.SumTimerInsert += sumTimerWait
+	case "statement/sql/update", "statement/sql/update_multi":
+		row.SumTimerUpdate += sumTimerWait
+	case "statement/sql/delete", "statement/sql/delete_multi":
+		row.SumTimerDelete += sumTimerWait
+	default:
+		row.SumTimerOther += sumTimerWait
+	}
 }
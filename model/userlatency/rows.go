@@ -0,0 +1,197 @@
+// Package userlatency contains the routines for managing the activity by
+// user collected from performance_schema and INFORMATION_SCHEMA.PROCESSLIST.
+package userlatency
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+)
+
+// Rows contains a set of rows
+type Rows []Row
+
+// totals returns the totals of all rows. Hosts and Dbs are not set as
+// the same host or database may be used by several users.
+func totals(rows Rows) Row {
+	total := Row{Username: "Totals"}
+
+	for _, row := range rows {
+		total.SumTimerWait += row.SumTimerWait
+		total.SumTimerSelect += row.SumTimerSelect
+		total.SumTimerInsert += row.SumTimerInsert
+		total.SumTimerUpdate += row.SumTimerUpdate
+		total.SumTimerDelete += row.SumTimerDelete
+		total.SumTimerOther += row.SumTimerOther
+		total.CountStar += row.CountStar
+		total.SumTimerWaits += row.SumTimerWaits
+		total.Connections += row.Connections
+		total.TotalConnections += row.TotalConnections
+		total.Active += row.Active
+	}
+
+	return total
+}
+
+// users holds the rows being collected by username
+type users map[string]*Row
+
+// get returns the row of the given user, adding it if needed
+func (u users) get(username string) *Row {
+	row, found := u[username]
+	if !found {
+		row = &Row{Username: username}
+		u[username] = row
+	}
+	return row
+}
+
+// rows returns the collected rows ordered by username
+func (u users) rows() Rows {
+	rows := make(Rows, 0, len(u))
+	for _, row := range u {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Username < rows[j].Username })
+
+	return rows
+}
+
+// collect returns the activity of each user. Connections to databases
+// which are filtered out are not included in the processlist information.
+// As accounts does not know which database a connection uses, the
+// current connections are then also counted from the processlist.
+func collect(ctx context.Context, db datasource.DataSource, databaseFilter *filter.DatabaseFilter) (Rows, error) {
+	u := make(users)
+
+	for _, collect := range []func(context.Context, datasource.DataSource, users) error{
+		collectStatements,
+		collectWaits,
+		collectAccounts,
+	} {
+		if err := collect(ctx, db, u); err != nil {
+			return nil, err
+		}
+	}
+	processlist, err := collectProcesslist(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	filtered := databaseFilter.FiltersDatabases()
+	if filtered {
+		for _, row := range u {
+			row.Connections = 0
+		}
+	}
+	for _, summary := range summariseProcesslist(processlist, databaseFilter) {
+		row := u.get(summary.Username)
+		if filtered {
+			row.Connections = summary.Connections
+		}
+		row.Active = summary.Active
+		row.Hosts = summary.Hosts
+		row.Dbs = summary.Dbs
+	}
+
+	return u.rows(), nil
+}
+
+// query runs the query and calls scan for each row returned
+func query(ctx context.Context, db datasource.DataSource, query string, scan func(rows datasource.Rows) error) error {
+	rows, err := db.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// collectStatements adds the statements run by each user
+func collectStatements(ctx context.Context, db datasource.DataSource, u users) error {
+	const selectSQL = `SELECT USER, EVENT_NAME, COUNT_STAR, SUM_TIMER_WAIT FROM events_statements_summary_by_user_by_event_name WHERE USER IS NOT NULL AND COUNT_STAR > 0`
+
+	return query(ctx, db, selectSQL, func(rows datasource.Rows) error {
+		var (
+			user, eventName     string
+			count, sumTimerWait uint64
+		)
+		if err := rows.Scan(&user, &eventName, &count, &sumTimerWait); err != nil {
+			return err
+		}
+		u.get(anonymiser.Anonymise("user", user)).addStatements(eventName, count, sumTimerWait)
+		return nil
+	})
+}
+
+// collectWaits adds the time each user has spent waiting, excluding idle time
+func collectWaits(ctx context.Context, db datasource.DataSource, u users) error {
+	const selectSQL = `SELECT USER, SUM(SUM_TIMER_WAIT) FROM events_waits_summary_by_user_by_event_name WHERE USER IS NOT NULL AND EVENT_NAME <> 'idle' AND SUM_TIMER_WAIT > 0 GROUP BY USER`
+
+	return query(ctx, db, selectSQL, func(rows datasource.Rows) error {
+		var (
+			user         string
+			sumTimerWait uint64
+		)
+		if err := rows.Scan(&user, &sumTimerWait); err != nil {
+			return err
+		}
+		u.get(anonymiser.Anonymise("user", user)).SumTimerWaits += sumTimerWait
+		return nil
+	})
+}
+
+// collectAccounts adds the current and total connections of each user
+func collectAccounts(ctx context.Context, db datasource.DataSource, u users) error {
+	const selectSQL = `SELECT USER, SUM(CURRENT_CONNECTIONS), SUM(TOTAL_CONNECTIONS) FROM accounts WHERE USER IS NOT NULL GROUP BY USER`
+
+	return query(ctx, db, selectSQL, func(rows datasource.Rows) error {
+		var (
+			user         string
+			current, all sql.NullInt64
+		)
+		if err := rows.Scan(&user, &current, &all); err != nil {
+			return err
+		}
+		row := u.get(anonymiser.Anonymise("user", user))
+		row.Connections += uint64(current.Int64)
+		row.TotalConnections += uint64(all.Int64)
+		return nil
+	})
+}
+
+// remove the initial values from those rows where there's a match
+// - if we find a row we can't match ignore it
+func (rows *Rows) subtract(initial Rows) {
+	initialByName := make(map[string]int)
+
+	// iterate over rows by name
+	for i := range initial {
+		initialByName[initial[i].Username] = i
+	}
+
+	for i := range *rows {
+		if initialIndex, ok := initialByName[(*rows)[i].Username]; ok {
+			(*rows)[i].subtract(initial[initialIndex])
+		}
+	}
+}
+
+// if the data in t2 is "newer", "has more values" than t then it needs refreshing.
+// check this by comparing totals.
+func (rows Rows) needsRefresh(otherRows Rows) bool {
+	rowsTotals, otherTotals := totals(rows), totals(otherRows)
+
+	return rowsTotals.SumTimerWait > otherTotals.SumTimerWait ||
+		rowsTotals.TotalConnections > otherTotals.TotalConnections
+}
@@ -0,0 +1,103 @@
+package userlatency
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/filter"
+)
+
+func TestCollect(t *testing.T) {
+	anonymiser.Enable(false)
+	fixture := datasource.NewFixture().
+		Add("events_statements_summary_by_user_by_event_name",
+			[]any{"app", "statement/sql/select", 10, 3000},
+			[]any{"app", "statement/sql/update_multi", 2, 1000},
+			[]any{"app", "statement/sql/commit", 2, 500},
+			[]any{"admin", "statement/sql/show_status", 1, 100},
+		).
+		Add("events_waits_summary_by_user_by_event_name", []any{"app", 700}).
+		Add("FROM accounts", []any{"app", 3, 20}, []any{"admin", 1, 1}).
+		Add("PROCESSLIST",
+			[]any{1, "app", "h1:1234", "db1", "Query", 1, "executing", "SELECT 1"},
+			[]any{2, "app", "h2:1234", "db2", "Sleep", 10, nil, nil},
+			[]any{3, "app", "h2:1235", "db2", "Sleep", 10, nil, nil},
+			[]any{4, "admin", "localhost", nil, "Query", 0, "init", "SHOW PROCESSLIST"},
+		)
+
+	tests := []struct {
+		filter   *filter.DatabaseFilter
+		expected Rows
+	}{
+		{
+			nil,
+			Rows{
+				{Username: "admin", SumTimerWait: 100, SumTimerOther: 100, CountStar: 1, Connections: 1, TotalConnections: 1, Active: 1, Hosts: 1},
+				{Username: "app", SumTimerWait: 4500, SumTimerSelect: 3000, SumTimerUpdate: 1000, SumTimerOther: 500, CountStar: 14, SumTimerWaits: 700, Connections: 3, TotalConnections: 20, Active: 1, Hosts: 2, Dbs: 2},
+			},
+		},
+		{
+			filter.NewDatabaseFilter("db2"),
+			Rows{
+				{Username: "admin", SumTimerWait: 100, SumTimerOther: 100, CountStar: 1, TotalConnections: 1},
+				{Username: "app", SumTimerWait: 4500, SumTimerSelect: 3000, SumTimerUpdate: 1000, SumTimerOther: 500, CountStar: 14, SumTimerWaits: 700, Connections: 2, TotalConnections: 20, Hosts: 1, Dbs: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := collect(context.Background(), fixture, test.filter)
+		if err != nil {
+			t.Fatalf("collect() failed: %v", err)
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("collect(%q) failed:\ngot:      %+v\nexpected: %+v", test.filter, got, test.expected)
+		}
+	}
+}
+
+func TestRowsSubtract(t *testing.T) {
+	initial := Rows{{Username: "app", SumTimerWait: 100, SumTimerSelect: 100, CountStar: 1, TotalConnections: 5, Connections: 2}}
+	rows := Rows{
+		{Username: "app", SumTimerWait: 300, SumTimerSelect: 250, SumTimerOther: 50, CountStar: 4, TotalConnections: 6, Connections: 1},
+		{Username: "new", SumTimerWait: 10, CountStar: 1, Connections: 1},
+	}
+	expected := Rows{
+		{Username: "app", SumTimerWait: 200, SumTimerSelect: 150, SumTimerOther: 50, CountStar: 3, TotalConnections: 1, Connections: 1},
+		{Username: "new", SumTimerWait: 10, CountStar: 1, Connections: 1},
+	}
+
+	rows.subtract(initial)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows.subtract() failed:\ngot:      %+v\nexpected: %+v", rows, expected)
+	}
+
+	// fewer connections than initially (e.g. accounts was truncated) must not wrap round
+	rows = Rows{{Username: "app", SumTimerWait: 300, CountStar: 4, TotalConnections: 2}}
+	expected = Rows{{Username: "app", SumTimerWait: 300, CountStar: 4, TotalConnections: 2}}
+
+	rows.subtract(initial)
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Rows.subtract() failed:\ngot:      %+v\nexpected: %+v", rows, expected)
+	}
+}
+
+func TestRowsNeedsRefresh(t *testing.T) {
+	tests := []struct {
+		rows     Rows
+		other    Rows
+		expected bool
+	}{
+		{Rows{{Username: "app", SumTimerWait: 100, TotalConnections: 5}}, Rows{{Username: "app", SumTimerWait: 200, TotalConnections: 6}}, false},
+		{Rows{{Username: "app", SumTimerWait: 300, TotalConnections: 5}}, Rows{{Username: "app", SumTimerWait: 200, TotalConnections: 6}}, true},
+		{Rows{{Username: "app", SumTimerWait: 100, TotalConnections: 5}}, Rows{{Username: "app", SumTimerWait: 200, TotalConnections: 2}}, true},
+	}
+	for _, test := range tests {
+		if got := test.rows.needsRefresh(test.other); got != test.expected {
+			t.Errorf("%+v.needsRefresh(%+v) failed: got: %v, expected: %v", test.rows, test.other, got, test.expected)
+		}
+	}
+}
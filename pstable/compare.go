@@ -0,0 +1,24 @@
+package pstable
+
+// Comparable is implemented by Tablers whose rows can be compared by
+// name with the same view of another server or time window
+type Comparable interface {
+	Values() []Value // the latency of each row
+}
+
+// Value is the latency of a row, identified by its name
+type Value struct {
+	Name    string
+	Latency uint64 // picoseconds
+}
+
+// Latencies returns the latency of each of the rows
+func Latencies[T any](rows []T, name func(T) string, latency func(T) uint64) []Value {
+	values := make([]Value, 0, len(rows))
+
+	for _, row := range rows {
+		values = append(values, Value{Name: name(row), Latency: latency(row)})
+	}
+
+	return values
+}
@@ -0,0 +1,13 @@
+package pstable
+
+// Selectable is implemented by Tablers whose rows can be selected to
+// show more detail about them
+type Selectable interface {
+	RowName(row int) string // the name of the given row of RowContent(), empty if not known
+}
+
+// Relater is implemented by Tablers which can show the rows related to
+// a table, given as <schema>.<table>
+type Relater interface {
+	Related(name string) (headings string, rows []string)
+}
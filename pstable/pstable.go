@@ -3,12 +3,13 @@
 package pstable
 
 import (
+	"context"
 	"time"
 )
 
 // Tabler is the interface for access to performance_schema rows
 type Tabler interface {
-	Collect() // Collect collects data for the table from the database
+	Collect(ctx context.Context) error // Collect collects data for the table from the database
 	Description() string
 	EmptyRowContent() string
 	HaveRelativeStats() bool
@@ -20,3 +21,9 @@ type Tabler interface {
 	TotalRowContent() string
 	WantRelativeStats() bool
 }
+
+// Refilterer is implemented by Tablers which can filter the rows last
+// collected again without collecting them
+type Refilterer interface {
+	Refilter()
+}
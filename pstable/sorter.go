@@ -0,0 +1,111 @@
+package pstable
+
+import (
+	"cmp"
+	"sort"
+)
+
+const (
+	descendingIndicator = "▼"
+	ascendingIndicator  = "▲"
+)
+
+// Sortable is implemented by Tablers whose rows can be sorted by
+// different columns
+type Sortable interface {
+	SortNext()    // sort by the next sortable column
+	SortReverse() // reverse the current sort order
+}
+
+// SortColumn is a column which rows of type T can be sorted by
+type SortColumn[T any] struct {
+	Heading    string            // heading of the column as shown
+	Less       func(a, b T) bool // the default order of the rows
+	descending bool              // is the default order largest first?
+}
+
+// ByValue returns a column sorting rows by value, largest first.
+// Rows with the same value are sorted by name.
+func ByValue[T any, V cmp.Ordered](heading string, value func(T) V, name func(T) string) SortColumn[T] {
+	return SortColumn[T]{
+		Heading: heading,
+		Less: func(a, b T) bool {
+			if c := cmp.Compare(value(a), value(b)); c != 0 {
+				return c > 0
+			}
+			return name(a) < name(b)
+		},
+		descending: true,
+	}
+}
+
+// ByName returns a column sorting rows by name in alphabetical order
+func ByName[T any](heading string, name func(T) string) SortColumn[T] {
+	return SortColumn[T]{
+		Heading: heading,
+		Less:    func(a, b T) bool { return name(a) < name(b) },
+	}
+}
+
+// Sorter sorts rows of type T by one of a number of columns. The first
+// column is used by default.
+type Sorter[T any] struct {
+	columns  []SortColumn[T]
+	current  int
+	reversed bool
+}
+
+// NewSorter returns a Sorter for the given columns
+func NewSorter[T any](columns ...SortColumn[T]) *Sorter[T] {
+	return &Sorter[T]{columns: columns}
+}
+
+// Sort sorts the rows by the current column
+func (s *Sorter[T]) Sort(rows []T) {
+	if len(s.columns) == 0 {
+		return
+	}
+	less := s.columns[s.current].Less
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if s.reversed {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+}
+
+// SortNext changes to the next column using its default order
+func (s *Sorter[T]) SortNext() {
+	if len(s.columns) == 0 {
+		return
+	}
+	s.current = (s.current + 1) % len(s.columns)
+	s.reversed = false
+}
+
+// SortReverse reverses the current sort order
+func (s *Sorter[T]) SortReverse() {
+	s.reversed = !s.reversed
+}
+
+// Heading returns the heading marked with the sort direction if it is
+// the current sort column. If width is not 0 the heading is shortened
+// if needed so the result is no wider than width.
+func (s *Sorter[T]) Heading(heading string, width int) string {
+	if len(s.columns) == 0 || s.columns[s.current].Heading != heading {
+		return heading
+	}
+
+	indicator := ascendingIndicator
+	if s.columns[s.current].descending != s.reversed {
+		indicator = descendingIndicator
+	}
+
+	runes := []rune(heading)
+	if width > 0 && len(runes) >= width {
+		runes = runes[:width-1]
+	}
+
+	return string(runes) + indicator
+}
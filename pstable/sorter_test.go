@@ -0,0 +1,115 @@
+package pstable
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sjmudd/ps-top/utils"
+)
+
+type testRow struct {
+	name  string
+	value int
+}
+
+func testSorter() *Sorter[testRow] {
+	return NewSorter(
+		ByValue("Value", func(r testRow) int { return r.value }, func(r testRow) string { return r.name }),
+		ByName("Name", func(r testRow) string { return r.name }),
+	)
+}
+
+func names(rows []testRow) []string {
+	var result []string
+	for _, row := range rows {
+		result = append(result, row.name)
+	}
+	return result
+}
+
+func TestSorter(t *testing.T) {
+	rows := []testRow{{"b", 1}, {"c", 3}, {"a", 1}, {"d", 2}}
+	s := testSorter()
+
+	tests := []struct {
+		change   func()
+		expected []string
+	}{
+		{func() {}, []string{"c", "d", "a", "b"}},
+		{s.SortReverse, []string{"b", "a", "d", "c"}},
+		{s.SortNext, []string{"a", "b", "c", "d"}},
+		{s.SortReverse, []string{"d", "c", "b", "a"}},
+		{s.SortNext, []string{"c", "d", "a", "b"}},
+	}
+
+	for i, test := range tests {
+		test.change()
+		s.Sort(rows)
+		if got := names(rows); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("test %d: Sort() failed: got: %v, expected: %v", i, got, test.expected)
+		}
+	}
+}
+
+func TestSorterHeading(t *testing.T) {
+	s := testSorter()
+
+	tests := []struct {
+		heading  string
+		width    int
+		expected string
+	}{
+		{"Value", 10, "Value▼"},
+		{"Value", 5, "Valu▼"},
+		{"Value", 0, "Value▼"},
+		{"Name", 10, "Name"},
+	}
+	for _, test := range tests {
+		if got := s.Heading(test.heading, test.width); got != test.expected {
+			t.Errorf("Heading(%q, %d) failed: got: %q, expected: %q", test.heading, test.width, got, test.expected)
+		}
+	}
+
+	s.SortNext()
+	if got := s.Heading("Name", 0); got != "Name▲" {
+		t.Errorf("Heading() of name column failed: got: %q", got)
+	}
+	s.SortReverse()
+	if got := s.Heading("Name", 0); got != "Name▼" {
+		t.Errorf("Heading() of reversed name column failed: got: %q", got)
+	}
+}
+
+func TestTimerSorter(t *testing.T) {
+	rows := []testRow{{"a", 1}, {"b", 3}, {"c", 2}}
+	// the value is used as the number of waits, each of which took 10 - value
+	timers := func(r testRow) utils.Timers {
+		latency := uint64(10 - r.value)
+		return utils.Timers{Count: uint64(r.value), Sum: uint64(r.value) * latency, Min: latency, Max: latency}
+	}
+	s := NewTimerSorter(timers, func(r testRow) string { return r.name }, "Name")
+
+	tests := []struct {
+		heading  string
+		expected []string
+	}{
+		{"Latency", []string{"b", "c", "a"}}, // 21, 16, 9
+		{"Count", []string{"b", "c", "a"}},
+		{"Min", []string{"a", "c", "b"}},
+		{"Avg", []string{"a", "c", "b"}},
+		{"Max", []string{"a", "c", "b"}},
+		{"Name", []string{"a", "b", "c"}},
+	}
+	for i, test := range tests {
+		if i > 0 {
+			s.SortNext()
+		}
+		s.Sort(rows)
+		if got := names(rows); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("sorting by %s failed: got: %v, expected: %v", test.heading, got, test.expected)
+		}
+		if got := s.Heading(test.heading, 0); got == test.heading {
+			t.Errorf("expected %s to be marked as sorted on", test.heading)
+		}
+	}
+}
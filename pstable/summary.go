@@ -0,0 +1,31 @@
+package pstable
+
+// Summariser is implemented by Tablers whose rows can be summarised in
+// a few values, e.g. to compare servers in the fleet view
+type Summariser interface {
+	Summary() Summary
+}
+
+// Summary summarises the rows of a Tabler
+type Summary struct {
+	Rows       int    // number of rows
+	Latency    uint64 // total latency of the rows (picoseconds)
+	Top        string // name of the row with the highest latency
+	TopLatency uint64 // latency of that row (picoseconds)
+}
+
+// Summarise returns the summary of the given rows
+func Summarise[T any](rows []T, name func(T) string, latency func(T) uint64) Summary {
+	summary := Summary{Rows: len(rows)}
+
+	for _, row := range rows {
+		value := latency(row)
+		summary.Latency += value
+		if value > summary.TopLatency {
+			summary.Top = name(row)
+			summary.TopLatency = value
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,23 @@
+package pstable
+
+import "github.com/sjmudd/ps-top/utils"
+
+// TimerToggler is implemented by Tablers which can show the number of
+// waits with their minimum, average and maximum latency instead of
+// how the latency is split
+type TimerToggler interface {
+	ToggleTimers() // switch between showing the split of the latency and the timers
+}
+
+// NewTimerSorter returns a Sorter for rows shown with their latency,
+// the columns of utils.TimerHeadings and their name
+func NewTimerSorter[T any](timers func(T) utils.Timers, name func(T) string, nameHeading string) *Sorter[T] {
+	return NewSorter(
+		ByValue("Latency", func(row T) uint64 { return timers(row).Sum }, name),
+		ByValue("Count", func(row T) uint64 { return timers(row).Count }, name),
+		ByValue("Min", func(row T) uint64 { return timers(row).Min }, name),
+		ByValue("Avg", func(row T) uint64 { return timers(row).Avg() }, name),
+		ByValue("Max", func(row T) uint64 { return timers(row).Max }, name),
+		ByName(nameHeading, name),
+	)
+}
@@ -0,0 +1,14 @@
+package pstable
+
+// Classifier is implemented by Tablers which show one of several
+// classes of their rows
+type Classifier interface {
+	NextClass() // show the next class of rows
+}
+
+// Tree is implemented by Tablers which can show their rows as a tree
+// whose nodes can be expanded and collapsed
+type Tree interface {
+	ToggleTree()         // switch between showing a tree and a flat list
+	Toggle(row int) bool // expand or collapse the given row of RowContent(), false if it has no children
+}
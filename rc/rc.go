@@ -1,11 +1,15 @@
 // Package rc provides routines to read ~/.pstoprc
 // ps-top / ps-stats configuration
 // - and to munge some table names based on the [munge] section (if present)
+// - and to configure the views shown in the [views] and [view:<name>] sections
 package rc
 
 import (
 	"os"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	go_ini "github.com/vaughan0/go-ini" // not sure what to do with dashes in names
 
@@ -27,7 +31,9 @@ type mungeRegexp struct {
 var (
 	haveRegexps bool // Do we have any valid data? We don't check yet if it's valid.
 	regexps     []mungeRegexp
-	loaded      bool // not concurrency safe, but not needed yet!
+	loaded      bool        // has ~/.pstoprc been loaded?
+	loadMutex   sync.Mutex  // protects loading as servers may be collected concurrently
+	file        go_ini.File // the contents of ~/.pstoprc, empty if there is none
 )
 
 // modifyFilename replaces ~ with contents of HOME environment variable
@@ -42,32 +48,38 @@ func modifyFilename(filename string) string {
 	return filename
 }
 
-// Load the ~/.pstoprc regexp expressions in section [munge]
-func loadRegexps() {
-	haveRegexps = false
-	filename := modifyFilename(pstoprc)
+// load reads ~/.pstoprc the first time it is needed
+func load() {
+	loadMutex.Lock()
+	defer loadMutex.Unlock()
 
-	// Is the file there? If not it is not fatal and we just return.
-	f, err := os.Open(filename)
-	if err != nil {
+	if loaded {
 		return
 	}
-	// If we get here the file is readable, so close it again.
-	if err = f.Close(); err != nil {
-		log.Fatalf("loadRegexps: Failed to close file %q: %v", filename, err)
+	loaded = true
+	file = make(go_ini.File)
+	filename := modifyFilename(pstoprc)
+
+	// Is the file there? If not it is not fatal and we just return.
+	if _, err := os.Stat(filename); err != nil {
 		return
 	}
 
 	// Load and process the ini file.
-	i, err := go_ini.LoadFile(filename)
-	if err != nil {
+	if err := file.LoadFile(filename); err != nil {
 		log.Fatalf("Could not load %q: %v", filename, err)
 	}
+	loadRegexps()
+}
+
+// Load the ~/.pstoprc regexp expressions in section [munge]
+func loadRegexps() {
+	haveRegexps = false
 
 	// Note: This is wrong if I want to have an _ordered_ list of regexps
 	// as go-ini provides me a hash so I lose the ordering. This may not
 	// be desirable but as a first step accept this is broken.
-	section := i.Section("munge")
+	section := file.Section("munge")
 
 	regexps = make([]mungeRegexp, 0, len(section))
 
@@ -109,10 +121,7 @@ func addPattern(pattern string, replace string) {
 // _[0-9]{6}$ = _YYYYMM
 func Munge(name string) string {
 	// lazy loading of regexp expressions when needed
-	if !loaded {
-		loadRegexps()
-		loaded = true
-	}
+	load()
 	if !haveRegexps {
 		return name // nothing to do so return what we were given.
 	}
@@ -129,3 +138,56 @@ func Munge(name string) string {
 
 	return munged
 }
+
+// View holds a view defined by the user in a [view:<name>] section, e.g.
+// [view:schema_tables]
+// description = Table statistics (sys.x$schema_table_statistics)
+// query = SELECT table_schema, table_name, total_latency, rows_fetched FROM sys.x$schema_table_statistics
+// columns = table_schema, table_name, total_latency:time, rows_fetched:count
+type View struct {
+	Name        string
+	Description string
+	Query       string
+	Columns     string // the columns returned by the query with optional formatting hints
+}
+
+// viewPrefix starts the name of the sections defining views
+const viewPrefix = "view:"
+
+// ViewOrder returns the names of the views to show in the order they
+// are shown, or nil if not configured, e.g.
+// [views]
+// order = table_io_latency, threads, schema_tables
+func ViewOrder() []string {
+	load()
+
+	var order []string
+	for _, name := range strings.Split(file.Section("views")["order"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			order = append(order, name)
+		}
+	}
+
+	return order
+}
+
+// Views returns the views defined by the user sorted by name
+func Views() []View {
+	load()
+
+	var views []View
+	for section, values := range file {
+		if !strings.HasPrefix(section, viewPrefix) {
+			continue
+		}
+		views = append(views, View{
+			Name:        strings.TrimSpace(strings.TrimPrefix(section, viewPrefix)),
+			Description: values["description"],
+			Query:       values["query"],
+			Columns:     values["columns"],
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+
+	return views
+}
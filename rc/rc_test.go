@@ -1,7 +1,11 @@
 package rc
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+
+	go_ini "github.com/vaughan0/go-ini"
 )
 
 // Munge Optionally munges table names so they can be combined.
@@ -45,3 +49,35 @@ func TestMunge(t *testing.T) {
 		}
 	}
 }
+
+func TestViews(t *testing.T) {
+	var err error
+	file, err = go_ini.Load(strings.NewReader(`
+[views]
+order = threads, schema_tables ,,table_io_latency
+
+[view:schema_tables]
+description = Table statistics
+query = SELECT table_name, total_latency FROM sys.x$schema_table_statistics WHERE table_schema = 'db1'
+columns = table_name, total_latency:time
+
+[view: digests]
+query = SELECT digest_text FROM events_statements_summary_by_digest
+`))
+	if err != nil {
+		t.Fatalf("failed to load test configuration: %v", err)
+	}
+	loaded = true
+
+	if got, expected := ViewOrder(), []string{"threads", "schema_tables", "table_io_latency"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("ViewOrder() failed: got %q, expected: %q", got, expected)
+	}
+
+	expected := []View{
+		{Name: "digests", Query: "SELECT digest_text FROM events_statements_summary_by_digest"},
+		{Name: "schema_tables", Description: "Table statistics", Query: "SELECT table_name, total_latency FROM sys.x$schema_table_statistics WHERE table_schema = 'db1'", Columns: "table_name, total_latency:time"},
+	}
+	if got := Views(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Views() failed:\ngot:      %+v\nexpected: %+v", got, expected)
+	}
+}
@@ -1,10 +1,12 @@
 // Package setupinstruments manages the configuration of
-// performance_schema.setupinstruments.
+// performance_schema.setupinstruments and setup_consumers.
 package setupinstruments
 
 import (
-	"database/sql"
+	"context"
+	"slices"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
@@ -22,28 +24,84 @@ type Row struct {
 	timed   string
 }
 
+// consumer contains one row of performance_schema.setup_consumers
+type consumer struct {
+	name    string
+	enabled string
+}
+
 // SetupInstruments "object"
 type SetupInstruments struct {
 	updateTried     bool
 	updateSucceeded bool
 	rows            []Row
-	db              *sql.DB
+	consumers       []consumer // setup_consumers rows changed, with their previous settings
+	db              datasource.DataSource
 }
 
 // NewSetupInstruments returns a pointer to a newly initialised
 // SetupInstruments.
-func NewSetupInstruments(db *sql.DB) *SetupInstruments {
+func NewSetupInstruments(db datasource.DataSource) *SetupInstruments {
 	return &SetupInstruments{db: db}
 }
 
-// EnableMonitoring enables mutex and stage monitoring
-func (si *SetupInstruments) EnableMonitoring() {
-	si.EnableMutexMonitoring()
-	si.EnableStageMonitoring()
+// EnableMonitoring enables mutex, stage and metadata lock monitoring
+func (si *SetupInstruments) EnableMonitoring(ctx context.Context) error {
+	if err := si.EnableMutexMonitoring(ctx); err != nil {
+		return err
+	}
+	if err := si.EnableStageMonitoring(ctx); err != nil {
+		return err
+	}
+	return si.EnableMetadataLockMonitoring(ctx)
+}
+
+// EnableCurrentEventsMonitoring enables the events_waits_current and
+// events_stages_current consumers, which are disabled by default, so
+// that the threads currently waiting on a mutex or stage can be shown.
+// As this affects every session it is only done once they are needed.
+func (si *SetupInstruments) EnableCurrentEventsMonitoring(ctx context.Context) error {
+	const updateSQL = "UPDATE setup_consumers SET ENABLED = 'YES' WHERE NAME = ?"
+
+	log.Println("EnableCurrentEventsMonitoring")
+	rows, err := si.db.Query(ctx, "SELECT NAME, ENABLED FROM setup_consumers WHERE NAME IN ('events_waits_current', 'events_stages_current') AND ENABLED <> 'YES'")
+	if err != nil {
+		return err
+	}
+	var disabled []consumer
+	for rows.Next() {
+		var c consumer
+		if err := rows.Scan(&c.name, &c.enabled); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		disabled = append(disabled, c)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+	_ = rows.Close()
+
+	for _, c := range disabled {
+		log.Println("- enabling consumer:", c.name)
+		if _, err := si.db.Exec(ctx, updateSQL, c.name); err != nil {
+			if isExpectedError(err.Error()) {
+				log.Println("Insufficient privileges to UPDATE setup_consumers: " + err.Error())
+				return nil
+			}
+			return err
+		}
+		if !slices.ContainsFunc(si.consumers, func(changed consumer) bool { return changed.name == c.name }) {
+			si.consumers = append(si.consumers, c)
+		}
+	}
+	log.Println("EnableCurrentEventsMonitoring finishes")
+	return nil
 }
 
 // EnableStageMonitoring change settings to monitor stage/sql/%
-func (si *SetupInstruments) EnableStageMonitoring() {
+func (si *SetupInstruments) EnableStageMonitoring(ctx context.Context) error {
 	log.Println("EnableStageMonitoring")
 	sqlMatch := "stage/sql/%"
 	sqlSelect := "SELECT NAME, ENABLED, TIMED FROM setup_instruments WHERE NAME LIKE '" + sqlMatch + "' AND 'YES' NOT IN (ENABLED,TIMED)"
@@ -51,20 +109,41 @@ func (si *SetupInstruments) EnableStageMonitoring() {
 	collecting := "Collecting setup_instruments stage/sql configuration settings"
 	updating := "Updating setup_instruments configuration for: stage/sql"
 
-	si.Configure(sqlSelect, collecting, updating)
+	if err := si.Configure(ctx, sqlSelect, collecting, updating); err != nil {
+		return err
+	}
 	log.Println("EnableStageMonitoring finishes")
+	return nil
 }
 
 // EnableMutexMonitoring changes settings to monitor wait/synch/mutex/%
-func (si *SetupInstruments) EnableMutexMonitoring() {
+func (si *SetupInstruments) EnableMutexMonitoring(ctx context.Context) error {
 	log.Println("EnableMutexMonitoring")
 	sqlMatch := "wait/synch/mutex/%"
 	sqlSelect := "SELECT NAME, ENABLED, TIMED FROM setup_instruments WHERE NAME LIKE '" + sqlMatch + "' AND 'YES' NOT IN (ENABLED,TIMED)"
 	collecting := "Collecting setup_instruments wait/synch/mutex configuration settings"
 	updating := "Updating setup_instruments configuration for: wait/synch/mutex"
 
-	si.Configure(sqlSelect, collecting, updating)
+	if err := si.Configure(ctx, sqlSelect, collecting, updating); err != nil {
+		return err
+	}
 	log.Println("EnableMutexMonitoring finishes")
+	return nil
+}
+
+// EnableMetadataLockMonitoring changes settings to monitor wait/lock/metadata/sql/mdl
+// so that metadata_locks is populated (the default from MySQL 8.0)
+func (si *SetupInstruments) EnableMetadataLockMonitoring(ctx context.Context) error {
+	log.Println("EnableMetadataLockMonitoring")
+	sqlMatch := "wait/lock/metadata/sql/mdl"
+	sqlSelect := "SELECT NAME, ENABLED, TIMED FROM setup_instruments WHERE NAME LIKE '" + sqlMatch + "' AND 'YES' NOT IN (ENABLED,TIMED)"
+	collecting := "Collecting setup_instruments wait/lock/metadata/sql/mdl configuration settings"
+	updating := "Updating setup_instruments configuration for: wait/lock/metadata/sql/mdl"
+	if err := si.Configure(ctx, sqlSelect, collecting, updating); err != nil {
+		return err
+	}
+	log.Println("EnableMetadataLockMonitoring finishes")
+	return nil
 }
 
 // isExpectedError returns true if the error is in the expected list of errors
@@ -72,6 +151,9 @@ func (si *SetupInstruments) EnableMutexMonitoring() {
 func isExpectedError(actualError string) bool {
 	var expected bool
 
+	if len(actualError) < 11 {
+		return false
+	}
 	e := actualError[0:11]
 	for _, val := range expectedErrors {
 		if e == val[0:11] {
@@ -83,14 +165,14 @@ func isExpectedError(actualError string) bool {
 }
 
 // Configure updates setup_instruments so we can monitor tables correctly.
-func (si *SetupInstruments) Configure(sqlSelect string, collecting, updating string) {
+func (si *SetupInstruments) Configure(ctx context.Context, sqlSelect string, collecting, updating string) error {
 	const updateSQL = "UPDATE setup_instruments SET enabled = ?, TIMED = ? WHERE NAME = ?"
 
 	log.Printf("Configure(%q,%q,%q)", sqlSelect, collecting, updating)
 	// skip if we've tried and failed
 	if si.updateTried && !si.updateSucceeded {
 		log.Println("SetupInstruments.Configure() - Skipping further configuration")
-		return
+		return nil
 	}
 
 	// setup the old values in case they're not set
@@ -101,9 +183,9 @@ func (si *SetupInstruments) Configure(sqlSelect string, collecting, updating str
 	log.Println(collecting)
 
 	log.Println("db.query", sqlSelect)
-	rows, err := si.db.Query(sqlSelect)
+	rows, err := si.db.Query(ctx, sqlSelect)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	count := 0
@@ -113,13 +195,15 @@ func (si *SetupInstruments) Configure(sqlSelect string, collecting, updating str
 			&r.name,
 			&r.enabled,
 			&r.timed); err != nil {
-			log.Fatal(err)
+			_ = rows.Close()
+			return err
 		}
 		si.rows = append(si.rows, r)
 		count++
 	}
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		_ = rows.Close()
+		return err
 	}
 	log.Println("- found", count, "rows whose configuration need changing")
 	_ = rows.Close()
@@ -127,73 +211,63 @@ func (si *SetupInstruments) Configure(sqlSelect string, collecting, updating str
 	// update the rows which need to be set - do multiple updates but I don't care
 	log.Println(updating)
 
-	log.Println("Preparing statement:", updateSQL)
 	si.updateTried = true
-	log.Println("db.Prepare", updateSQL)
-	stmt, err := si.db.Prepare(updateSQL)
-	if err != nil {
-		log.Println("- prepare gave error:", err.Error())
-		if !isExpectedError(err.Error()) {
-			log.Fatal("Not expected error so giving up")
+	log.Println("Trying to update", len(si.rows), "row(s) with:", updateSQL)
+	count = 0
+	for i := range si.rows {
+		log.Println("- changing row:", si.rows[i].name)
+		log.Println("db.Exec", "YES", "YES", si.rows[i].name)
+		if res, err := si.db.Exec(ctx, updateSQL, "YES", "YES", si.rows[i].name); err == nil {
+			log.Println("update succeeded")
+			si.updateSucceeded = true
+			c, _ := res.RowsAffected()
+			count += int(c)
 		} else {
-			log.Println("- expected error so not running statement")
-			_ = stmt.Close()
-		}
-	} else {
-		log.Println("Prepare succeeded, trying to update", len(si.rows), "row(s)")
-		count = 0
-		for i := range si.rows {
-			log.Println("- changing row:", si.rows[i].name)
-			log.Println("stmt.Exec", "YES", "YES", si.rows[i].name)
-			if res, err := stmt.Exec("YES", "YES", si.rows[i].name); err == nil {
-				log.Println("update succeeded")
-				si.updateSucceeded = true
-				c, _ := res.RowsAffected()
-				count += int(c)
-			} else {
-				si.updateSucceeded = false
-				if isExpectedError(err.Error()) {
-					log.Println("Insufficient privileges to UPDATE setup_instruments: " + err.Error())
-					log.Println("Not attempting further updates")
-					return
-				}
-				log.Fatal(err)
+			si.updateSucceeded = false
+			if isExpectedError(err.Error()) {
+				log.Println("Insufficient privileges to UPDATE setup_instruments: " + err.Error())
+				log.Println("Not attempting further updates")
+				return nil
 			}
+			return err
 		}
-		if si.updateSucceeded {
-			log.Println(count, "rows changed in p_s.setup_instruments")
-		}
-		_ = stmt.Close()
+	}
+	if si.updateSucceeded {
+		log.Println(count, "rows changed in p_s.setup_instruments")
 	}
 	log.Println("Configure() returns updateTried", si.updateTried, ", updateSucceeded", si.updateSucceeded)
+	return nil
 }
 
-// RestoreConfiguration restores setup_instruments rows to their previous settings (if changed previously).
-func (si *SetupInstruments) RestoreConfiguration() {
+// RestoreConfiguration restores setup_instruments and setup_consumers rows to their previous settings (if changed previously).
+func (si *SetupInstruments) RestoreConfiguration() error {
 	log.Println("RestoreConfiguration()")
+	const consumerSQL = "UPDATE setup_consumers SET ENABLED = ? WHERE NAME = ?"
+	for _, c := range si.consumers {
+		log.Println("db.Exec(", consumerSQL, c.enabled, c.name, ")")
+		if _, err := si.db.Exec(context.Background(), consumerSQL, c.enabled, c.name); err != nil {
+			return err
+		}
+	}
+	si.consumers = nil
+
 	// If the previous update didn't work then don't try to restore
 	if !si.updateSucceeded {
 		log.Println("Not restoring p_s.setup_instruments to original settings as initial configuration attempt failed")
-		return
+		return nil
 	}
 	log.Println("Restoring p_s.setup_instruments to its original settings")
 
 	// update the rows which need to be set - do multiple updates but I don't care
 	updateSQL := "UPDATE setup_instruments SET enabled = ?, TIMED = ? WHERE NAME = ?"
-	log.Println("db.Prepare(", updateSQL, ")")
-	stmt, err := si.db.Prepare(updateSQL)
-	if err != nil {
-		log.Fatal(err)
-	}
 	count := 0
 	for i := range si.rows {
-		log.Println("stmt.Exec(", si.rows[i].enabled, si.rows[i].timed, si.rows[i].name, ")")
-		if _, err := stmt.Exec(si.rows[i].enabled, si.rows[i].timed, si.rows[i].name); err != nil {
-			log.Fatal(err)
+		log.Println("db.Exec(", updateSQL, si.rows[i].enabled, si.rows[i].timed, si.rows[i].name, ")")
+		if _, err := si.db.Exec(context.Background(), updateSQL, si.rows[i].enabled, si.rows[i].timed, si.rows[i].name); err != nil {
+			return err
 		}
 		count++
 	}
-	log.Println("stmt.Close()")
-	_ = stmt.Close()
 	log.Println(count, "rows changed in p_s.setup_instruments")
+	return nil
 }
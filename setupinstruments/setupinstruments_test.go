@@ -3,7 +3,12 @@
 package setupinstruments
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
+
+	"github.com/sjmudd/ps-top/datasource"
 )
 
 func TestIsExpectedError(t *testing.T) {
@@ -12,6 +17,8 @@ func TestIsExpectedError(t *testing.T) {
 		expected bool
 	}{
 		{"Error 0000: some other error message", false},
+		{"driver: bad connection", false},
+		{"EOF", false},
 		{"Error 1142: UPDATE command denied to user 'myuser'@'10.11.12.13' for table 'setup_instruments'", true},
 		{"Error 1146: Table 'test.no_such_table' doesn't exist", false},
 		{"Error 1290: The MySQL server is running with the --read-only option so it cannot execute this statement", true},
@@ -24,3 +31,43 @@ func TestIsExpectedError(t *testing.T) {
 		}
 	}
 }
+
+func TestEnableCurrentEventsMonitoring(t *testing.T) {
+	fixture := datasource.NewFixture().
+		Add("FROM setup_consumers", []any{"events_waits_current", "NO"}, []any{"events_stages_current", "NO"})
+	si := NewSetupInstruments(fixture)
+
+	// enabling again, e.g. after MySQL restarts, restores the settings once
+	for range 2 {
+		if err := si.EnableCurrentEventsMonitoring(context.Background()); err != nil {
+			t.Fatalf("EnableCurrentEventsMonitoring() failed: %v", err)
+		}
+	}
+	if err := si.RestoreConfiguration(); err != nil {
+		t.Fatalf("RestoreConfiguration() failed: %v", err)
+	}
+	expected := []string{
+		"UPDATE setup_consumers SET ENABLED = 'YES' WHERE NAME = ? events_waits_current",
+		"UPDATE setup_consumers SET ENABLED = 'YES' WHERE NAME = ? events_stages_current",
+		"UPDATE setup_consumers SET ENABLED = 'YES' WHERE NAME = ? events_waits_current",
+		"UPDATE setup_consumers SET ENABLED = 'YES' WHERE NAME = ? events_stages_current",
+		"UPDATE setup_consumers SET ENABLED = ? WHERE NAME = ? NO events_waits_current",
+		"UPDATE setup_consumers SET ENABLED = ? WHERE NAME = ? NO events_stages_current",
+	}
+	if got := fixture.Executed(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements executed:\ngot:      %q\nexpected: %q", got, expected)
+	}
+
+	// without the privilege to change them nothing is restored
+	denied := errors.New("Error 1142: UPDATE command denied to user 'myuser'@'10.11.12.13' for table 'setup_consumers'")
+	fixture = datasource.NewFixture().
+		Add("SELECT NAME, ENABLED FROM setup_consumers", []any{"events_waits_current", "NO"}).
+		AddError("UPDATE setup_consumers", denied)
+	si = NewSetupInstruments(fixture)
+	if err := si.EnableCurrentEventsMonitoring(context.Background()); err != nil {
+		t.Fatalf("EnableCurrentEventsMonitoring() failed: %v", err)
+	}
+	if err := si.RestoreConfiguration(); err != nil || len(fixture.Executed()) != 1 {
+		t.Errorf("expected nothing to be restored, got: %v, executed: %q", err, fixture.Executed())
+	}
+}
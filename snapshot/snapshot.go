@@ -0,0 +1,151 @@
+// Package snapshot records the raw rows collected by the models to a
+// file and reads them back again so that a session can be replayed
+// later without a connection to MySQL.
+//
+// The file is gzip compressed and contains JSON Lines: a header line
+// followed by one line per collection.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Version is the version of the file format
+// - 2: user_latency records the activity by user rather than the processlist
+const Version = 2
+
+// Snapshotter is implemented by Tablers whose collected rows can be
+// recorded and later restored.
+type Snapshotter interface {
+	Snapshot() any                                           // the last collected rows (before subtracting initial values)
+	Restore(rows json.RawMessage, collected time.Time) error // replace the collected rows with recorded rows
+}
+
+// Header holds the information recorded once at the start of the file
+type Header struct {
+	Version   int               `json:"version"`
+	Variables map[string]string `json:"variables"` // global variables needed to display the data
+}
+
+// Snapshot holds the rows collected at a point in time by each model
+type Snapshot struct {
+	Collected time.Time                  `json:"collected"`
+	Uptime    int                        `json:"uptime"`
+	Rows      map[string]json.RawMessage `json:"rows"`
+}
+
+// Writer writes snapshots to a file
+type Writer struct {
+	closer  io.Closer
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+}
+
+// NewWriter returns a Writer which writes to w, starting with the given header
+func NewWriter(w io.WriteCloser, header Header) (*Writer, error) {
+	gz := gzip.NewWriter(w)
+	writer := &Writer{
+		closer:  w,
+		gzip:    gz,
+		encoder: json.NewEncoder(gz),
+	}
+
+	header.Version = Version
+	if err := writer.encoder.Encode(header); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+// Write records the rows collected at the given time. Each value in
+// rows is encoded as JSON.
+func (w *Writer) Write(collected time.Time, uptime int, rows map[string]any) error {
+	s := Snapshot{
+		Collected: collected,
+		Uptime:    uptime,
+		Rows:      make(map[string]json.RawMessage),
+	}
+	for name, value := range rows {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("snapshot.Writer.Write(): failed to encode %s: %w", name, err)
+		}
+		s.Rows[name] = raw
+	}
+
+	if err := w.encoder.Encode(s); err != nil {
+		return err
+	}
+
+	// flush so that the file is usable even if we do not stop cleanly
+	return w.gzip.Flush()
+}
+
+// Close finishes writing and closes the underlying file
+func (w *Writer) Close() error {
+	if err := w.gzip.Close(); err != nil {
+		_ = w.closer.Close()
+		return err
+	}
+	return w.closer.Close()
+}
+
+// Reader reads snapshots from a file
+type Reader struct {
+	closer  io.Closer
+	decoder *json.Decoder
+	header  Header
+}
+
+// NewReader returns a Reader which reads from r after checking the header
+func NewReader(r io.ReadCloser) (*Reader, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	reader := &Reader{
+		closer:  r,
+		decoder: json.NewDecoder(gz),
+	}
+
+	if err := reader.decoder.Decode(&reader.header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if reader.header.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d, expected %d", reader.header.Version, Version)
+	}
+
+	return reader, nil
+}
+
+// Header returns the header read from the file
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next snapshot or io.EOF if there are no more.
+// A file which was not closed cleanly, e.g. because recording was
+// interrupted, is also treated as having no more snapshots.
+func (r *Reader) Next() (*Snapshot, error) {
+	var s Snapshot
+
+	if err := r.decoder.Decode(&s); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Close closes the underlying file
+func (r *Reader) Close() error {
+	return r.closer.Close()
+}
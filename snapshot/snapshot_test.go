@@ -0,0 +1,70 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+type testRow struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	buf := nopCloser{new(bytes.Buffer)}
+	collected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	writer, err := NewWriter(buf, Header{Variables: map[string]string{"hostname": "myhost"}})
+	if err != nil {
+		t.Fatalf("NewWriter() failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := writer.Write(collected.Add(time.Duration(i)*time.Second), 100+i, map[string]any{
+			"rows": []testRow{{"a", uint64(i)}},
+		}); err != nil {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	reader, err := NewReader(nopCloser{bytes.NewBuffer(buf.Bytes())})
+	if err != nil {
+		t.Fatalf("NewReader() failed: %v", err)
+	}
+	if reader.Header().Variables["hostname"] != "myhost" {
+		t.Errorf("Header() failed: got %+v", reader.Header())
+	}
+	for i := 0; i < 2; i++ {
+		s, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next() failed: %v", err)
+		}
+		var rows []testRow
+		if err := json.Unmarshal(s.Rows["rows"], &rows); err != nil {
+			t.Fatalf("Unmarshal() failed: %v", err)
+		}
+		if !s.Collected.Equal(collected.Add(time.Duration(i)*time.Second)) || s.Uptime != 100+i || len(rows) != 1 || rows[0] != (testRow{"a", uint64(i)}) {
+			t.Errorf("Next() %d returned unexpected snapshot: %+v, rows: %+v", i, s, rows)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Next() at end of file: expected io.EOF, got %v", err)
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	if _, err := NewReader(nopCloser{bytes.NewBufferString("not gzipped")}); err == nil {
+		t.Errorf("NewReader() with invalid data did not return an error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/sjmudd/anonymiser"
 )
@@ -85,6 +86,18 @@ func FormatTime(picoseconds uint64) string {
 	return strconv.Itoa(int(picoseconds)) + " ps"
 }
 
+// SignedFormatTime formats a signed number of picoseconds as per
+// FormatTime() but with a leading + or -
+func SignedFormatTime(picoseconds int64) string {
+	switch {
+	case picoseconds > 0:
+		return "+" + strings.TrimSpace(FormatTime(uint64(picoseconds)))
+	case picoseconds < 0:
+		return "-" + strings.TrimSpace(FormatTime(uint64(-picoseconds)))
+	}
+	return ""
+}
+
 // FormatPct formats a floating point number as a percentage
 // including the trailing % sign. Print the value as a %5.1f with
 // a % suffix if there's a value.
@@ -141,6 +154,48 @@ func FormatAmount(amount uint64) string {
 	return formatted
 }
 
+// FormatSeconds formats the given seconds into xxh xxm xxs or xxd xxh xxm
+// for periods longer than 24h.  If seconds is 0 return an empty string.
+// Leading 0 values are omitted.
+// e.g.  0  -> ""
+//
+//	   10 -> "10s"
+//	   70 -> "1m 10s"
+//	 3601 -> "1h 0m 1s"
+//	86400 -> "1d 0h 0m"
+//
+// Note: we assume a 10 character width as formatting will get messed up so if there's not enough space don't add the lower values.
+func FormatSeconds(d uint64) string {
+	if d == 0 {
+		return ""
+	}
+
+	days := d / 86400
+	hours := (d - days*86400) / 3600
+	minutes := (d - days*86400 - hours*3600) / 60
+	seconds := d - days*86400 - hours*3600 - minutes*60
+
+	if days > 0 {
+		result := fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+		if len(result) > 10 {
+			result = fmt.Sprintf("%dd %dh", days, hours)
+		}
+		return result
+	}
+	if hours > 0 {
+		result := fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
+		if len(result) > 10 {
+			result = fmt.Sprintf("%dh %dm", hours, minutes)
+		}
+		return result
+	}
+	if minutes > 0 {
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	}
+
+	return fmt.Sprintf("%ds", seconds)
+}
+
 // SignedFormatAmount formats a signed integer as per FormatAmount()
 func SignedFormatAmount(amount int64) string {
 	var suffix string
@@ -222,3 +277,78 @@ func QualifiedTableName(schema, table string) string {
 	}
 	return name
 }
+
+// Truncate returns the statement on a single line shortened to at most length characters
+func Truncate(statement string, length int) string {
+	runes := []rune(strings.Join(strings.Fields(statement), " "))
+	if len(runes) > length {
+		runes = append(runes[:length-3], []rune("...")...)
+	}
+
+	return string(runes)
+}
+
+// MajorVersion returns the major version of a MySQL server given its
+// version, e.g. 8 for 8.0.36-log, or 0 if it can not be determined
+func MajorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Timers holds the number of waits with their total, minimum and
+// maximum latency
+type Timers struct {
+	Count uint64
+	Sum   uint64
+	Min   uint64
+	Max   uint64
+}
+
+// Avg returns the average latency of the waits
+func (t Timers) Avg() uint64 {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Sum / t.Count
+}
+
+// MinTimer returns the smaller of two minimum latencies ignoring 0,
+// which is used when there have been no waits
+func MinTimer(a, b uint64) uint64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// TimerHeadings returns the headings of the columns returned by
+// FormatTimers, using heading to mark the column sorted on
+func TimerHeadings(heading func(string, int) string) string {
+	return fmt.Sprintf("%8s %10s %10s %10s",
+		heading("Count", 8),
+		heading("Min", 10),
+		heading("Avg", 10),
+		heading("Max", 10))
+}
+
+// FormatTimers returns the number of waits and their minimum, average
+// and maximum latency as columns. performance_schema only keeps the
+// minimum and maximum since the server started (or the table was
+// truncated) so they cannot be made relative and are left blank
+// when relative is set.
+func FormatTimers(t Timers, relative bool) string {
+	minimum, maximum := FormatTime(t.Min), FormatTime(t.Max)
+	if relative {
+		minimum, maximum = "", ""
+	}
+
+	return fmt.Sprintf("%8s %10s %10s %10s",
+		FormatAmount(t.Count),
+		minimum,
+		FormatTime(t.Avg()),
+		maximum)
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -61,6 +63,22 @@ func TestFormatTime(t *testing.T) {
 	}
 }
 
+func TestSignedFormatTime(t *testing.T) {
+	tests := []struct {
+		picoseconds int64
+		expected    string
+	}{
+		{0, ""},
+		{1000000000000, "+1.00 s"},
+		{-1500000000, "-1.50 ms"},
+	}
+	for _, test := range tests {
+		if got := SignedFormatTime(test.picoseconds); got != test.expected {
+			t.Errorf("SignedFormatTime(%v) failed: expected: %q, got %q", test.picoseconds, test.expected, got)
+		}
+	}
+}
+
 func TestSecToTime(t *testing.T) {
 	tests := []struct {
 		seconds  uint64
@@ -173,6 +191,35 @@ func TestFormatAmount(t *testing.T) {
 	}
 }
 
+func TestFormatSeconds(t *testing.T) {
+	data := []struct {
+		input  uint64
+		output string
+	}{
+		{0, ""},
+		{1, "1s"},
+		{10, "10s"},
+		{60, "1m 0s"},
+		{70, "1m 10s"},
+		{3599, "59m 59s"},
+		{3600, "1h 0m 0s"},
+		{3601, "1h 0m 1s"},
+		{36001, "10h 0m 1s"},
+		{36010, "10h 0m 10s"}, // max width
+		{36600, "10h 10m 0s"}, // max width
+		{36610, "10h 10m"},    // truncate due to > 10 characters
+		{86399, "23h 59m"},    // truncate due to > 10 characters
+		{86400, "1d 0h 0m"},
+		{86401, "1d 0h 0m"},
+		{86460, "1d 0h 1m"},
+	}
+	for i := range data {
+		if FormatSeconds(data[i].input) != data[i].output {
+			t.Errorf("FormatSeconds(%v) expected: %v, got: %v", data[i].input, data[i].output, FormatSeconds(data[i].input))
+		}
+	}
+}
+
 func TestFormatCounter(t *testing.T) {
 	tests := []struct {
 		counter  int
@@ -219,3 +266,89 @@ func TestDuplicateSlice(t *testing.T) {
 		t.Errorf("DuplicateSlice(%v) failed. Got: %+v", test4, got4)
 	}
 }
+
+func TestMajorVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected int
+	}{
+		{"5.7.44-log", 5},
+		{"8.0.36", 8},
+		{"10.6.12-MariaDB", 10},
+		{"", 0},
+	}
+	for _, test := range tests {
+		if got := MajorVersion(test.version); got != test.expected {
+			t.Errorf("MajorVersion(%q) failed: got: %d, expected: %d", test.version, got, test.expected)
+		}
+	}
+}
+
+func TestMinTimer(t *testing.T) {
+	tests := []struct {
+		a, b     uint64
+		expected uint64
+	}{
+		{0, 0, 0},
+		{0, 5, 5},
+		{5, 0, 5},
+		{3, 5, 3},
+		{5, 3, 3},
+	}
+	for _, test := range tests {
+		if got := MinTimer(test.a, test.b); got != test.expected {
+			t.Errorf("MinTimer(%d, %d) failed: got: %d, expected: %d", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestTimersAvg(t *testing.T) {
+	tests := []struct {
+		timers   Timers
+		expected uint64
+	}{
+		{Timers{}, 0},
+		{Timers{Count: 4, Sum: 10, Min: 1, Max: 5}, 2},
+		{Timers{Count: 1, Sum: 7000000, Min: 7000000, Max: 7000000}, 7000000},
+	}
+	for _, test := range tests {
+		if got := test.timers.Avg(); got != test.expected {
+			t.Errorf("%+v.Avg() failed: got: %d, expected: %d", test.timers, got, test.expected)
+		}
+	}
+}
+
+func TestFormatTimers(t *testing.T) {
+	timers := Timers{Count: 4, Sum: 10000, Min: 1000, Max: 5000}
+	tests := []struct {
+		relative bool
+		expected string
+	}{
+		{false, fmt.Sprintf("%8s %10s %10s %10s", "4", FormatTime(1000), FormatTime(2500), FormatTime(5000))},
+		{true, fmt.Sprintf("%8s %10s %10s %10s", "4", "", FormatTime(2500), "")},
+	}
+	for _, test := range tests {
+		if got := FormatTimers(timers, test.relative); got != test.expected {
+			t.Errorf("FormatTimers(%+v, %v) failed: got: %q, expected: %q", timers, test.relative, got, test.expected)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		statement string
+		length    int
+		expected  string
+	}{
+		{"", 10, ""},
+		{"SELECT 1", 10, "SELECT 1"},
+		{"SELECT *\n  FROM\tt1", 20, "SELECT * FROM t1"},
+		{"SELECT * FROM t1", 10, "SELECT ..."},
+		{strings.Repeat("é", 12), 10, strings.Repeat("é", 7) + "..."},
+	}
+	for _, test := range tests {
+		if got := Truncate(test.statement, test.length); got != test.expected {
+			t.Errorf("Truncate(%q, %d) failed: got: %q, expected: %q", test.statement, test.length, got, test.expected)
+		}
+	}
+}
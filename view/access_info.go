@@ -2,15 +2,19 @@
 package view
 
 import (
+	"context"
 	"database/sql"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
-// AccessInfo holds a database and table name and information on whether the table is reachable
+// AccessInfo holds a database and table name, or the query of a view
+// defined by the user, and information on whether the table is reachable
 type AccessInfo struct {
 	Database           string
 	Table              string
+	Query              string
 	checkedSelectError bool
 	selectError        error
 }
@@ -21,23 +25,37 @@ func NewAccessInfo(database, table string) AccessInfo {
 	return AccessInfo{Database: database, Table: table}
 }
 
-// Name returns the fully qualified table name
+// NewQueryAccessInfo returns an AccessInfo checking the given query can be run
+func NewQueryAccessInfo(query string) AccessInfo {
+	log.Println("NewQueryAccessInfo(", query, ")")
+	return AccessInfo{Query: query}
+}
+
+// Name returns the fully qualified table name or the query
 func (ta AccessInfo) Name() string {
 	if len(ta.Database) > 0 && len(ta.Table) > 0 {
 		return ta.Database + "." + ta.Table
 	}
-	return ""
+	return ta.Query
+}
+
+// from returns what to select from to check access
+func (ta AccessInfo) from() string {
+	if ta.Query != "" {
+		return "(" + ta.Query + ") AS q"
+	}
+	return ta.Name()
 }
 
 // CheckSelectError returns whether SELECT works on the table
-func (ta *AccessInfo) CheckSelectError(db *sql.DB) error {
+func (ta *AccessInfo) CheckSelectError(db datasource.DataSource) error {
 	// return cached result if we have one
 	if ta.checkedSelectError {
 		return ta.selectError
 	}
 
 	var one int
-	err := db.QueryRow("SELECT 1 FROM " + ta.Name() + " LIMIT 1").Scan(&one)
+	err := db.QueryRow(context.Background(), "SELECT 1 FROM "+ta.from()+" LIMIT 1").Scan(&one)
 
 	switch {
 	case err == sql.ErrNoRows:
@@ -5,9 +5,13 @@
 package view
 
 import (
-	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
 
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/log"
 )
 
@@ -20,17 +24,43 @@ func (s Code) String() string {
 
 // View* constants represent different views we can see
 const (
-	ViewNone    Code = iota // view nothing (should never be set)
-	ViewLatency             // view the table latency information
-	ViewOps                 // view the table information by number of operations
-	ViewIO                  // view the file I/O information
-	ViewLocks               // view lock information
-	ViewUsers               // view user information
-	ViewMutex               // view mutex information
-	ViewStages              // view SQL stages information
-	ViewMemory              // view memory usage (5.7+)
+	ViewNone          Code = iota // view nothing (should never be set)
+	ViewLatency                   // view the table latency information
+	ViewOps                       // view the table information by number of operations
+	ViewIO                        // view the file I/O information
+	ViewLocks                     // view lock information
+	ViewUsers                     // view user information
+	ViewMutex                     // view mutex information
+	ViewStages                    // view SQL stages information
+	ViewMemory                    // view memory usage (5.7+)
+	ViewDigest                    // view statement digest information
+	ViewThreads                   // view the connections to MySQL
+	ViewIndexes                   // view the index latency information
+	ViewUnusedIndexes             // view the indexes which have not been used
+	ViewLockWaits                 // view the sessions waiting for locks
+	ViewReplication               // view the replication threads
+	ViewWaits                     // view the wait events
+	ViewCurrentStages             // view the stages currently being executed
+	firstCustom                   // views defined by the user are numbered from here
 )
 
+// defaultOrder is the order views are shown in if not configured.
+// Views defined by the user follow.
+var defaultOrder = []Code{ViewLatency, ViewOps, ViewIndexes, ViewUnusedIndexes, ViewIO, ViewLocks, ViewLockWaits, ViewDigest, ViewUsers, ViewThreads, ViewReplication, ViewMutex, ViewWaits, ViewStages, ViewCurrentStages, ViewMemory}
+
+// Options configures which views are shown and in which order
+type Options struct {
+	Order  []string // names of the views in the order they are shown, all views if empty
+	Custom []Custom // views defined by the user
+}
+
+// Custom holds the name of a view defined by the user and the query
+// which provides its data
+type Custom struct {
+	Name  string
+	Query string
+}
+
 // View holds the integer type of view (maybe need to fix this setup)
 type View struct {
 	code Code
@@ -40,51 +70,155 @@ var (
 	setup  bool                // not protected by a mutex!
 	names  map[Code]string     // map a View to a string name
 	tables map[Code]AccessInfo // map a view to a table name and whether it's selectable or not
+	order  []Code              // the views in the order they are shown
 
 	nextView map[Code]Code // map from one view to the next taking into account invalid views
 	prevView map[Code]Code // map from one view to the next taking into account invalid views
 )
 
+// setupMaps sets up the mapping of views to their names and tables
+// and the order they are shown in
+func setupMaps(options Options) error {
+	names = map[Code]string{
+		ViewLatency:       "table_io_latency",
+		ViewOps:           "table_io_ops",
+		ViewIO:            "file_io_latency",
+		ViewLocks:         "table_lock_latency",
+		ViewUsers:         "user_latency",
+		ViewMutex:         "mutex_latency",
+		ViewStages:        "stages_latency",
+		ViewMemory:        "memory_usage",
+		ViewDigest:        "statement_latency",
+		ViewThreads:       "threads",
+		ViewIndexes:       "index_io_latency",
+		ViewUnusedIndexes: "unused_indexes",
+		ViewLockWaits:     "lock_waits",
+		ViewReplication:   "replication",
+		ViewWaits:         "wait_events",
+		ViewCurrentStages: "current_stages",
+	}
+
+	tables = map[Code]AccessInfo{
+		ViewLatency:       NewAccessInfo("performance_schema", "table_io_waits_summary_by_table"),
+		ViewOps:           NewAccessInfo("performance_schema", "table_io_waits_summary_by_table"),
+		ViewIO:            NewAccessInfo("performance_schema", "file_summary_by_instance"),
+		ViewLocks:         NewAccessInfo("performance_schema", "table_lock_waits_summary_by_table"),
+		ViewUsers:         NewAccessInfo("performance_schema", "events_statements_summary_by_user_by_event_name"),
+		ViewMutex:         NewAccessInfo("performance_schema", "events_waits_summary_global_by_event_name"),
+		ViewStages:        NewAccessInfo("performance_schema", "events_stages_summary_global_by_event_name"),
+		ViewMemory:        NewAccessInfo("performance_schema", "memory_summary_global_by_event_name"),
+		ViewDigest:        NewAccessInfo("performance_schema", "events_statements_summary_by_digest"),
+		ViewThreads:       NewAccessInfo("performance_schema", "threads"),
+		ViewIndexes:       NewAccessInfo("performance_schema", "table_io_waits_summary_by_index_usage"),
+		ViewUnusedIndexes: NewAccessInfo("performance_schema", "table_io_waits_summary_by_index_usage"),
+		ViewLockWaits:     NewAccessInfo("performance_schema", "metadata_locks"),
+		ViewReplication:   NewAccessInfo("performance_schema", "replication_connection_status"),
+		ViewWaits:         NewAccessInfo("performance_schema", "events_waits_summary_global_by_event_name"),
+		ViewCurrentStages: NewAccessInfo("performance_schema", "events_stages_current"),
+	}
+
+	order = append([]Code(nil), defaultOrder...)
+	for i, custom := range options.Custom {
+		code := firstCustom + Code(i)
+		if _, found := codeByName(custom.Name); found {
+			return fmt.Errorf("view %q is defined more than once", custom.Name)
+		}
+		names[code] = custom.Name
+		tables[code] = NewQueryAccessInfo(custom.Query)
+		order = append(order, code)
+	}
+
+	if len(options.Order) > 0 {
+		order = nil
+		for _, name := range options.Order {
+			code, found := codeByName(name)
+			if !found {
+				return fmt.Errorf("unknown view %q in the view order. Try one of: %s", name, strings.Join(Names(), " "))
+			}
+			if slices.Contains(order, code) {
+				return fmt.Errorf("view %q is given more than once in the view order", name)
+			}
+			order = append(order, code)
+		}
+	}
+
+	return nil
+}
+
+// codeByName returns the view with the given name
+func codeByName(name string) (Code, bool) {
+	for code := range names {
+		if names[code] == name {
+			return code, true
+		}
+	}
+	return ViewNone, false
+}
+
+// Names returns the names of the views in the order they are shown
+// followed by any views not in the order
+func Names() []string {
+	var result []string
+	for _, code := range order {
+		result = append(result, names[code])
+	}
+	var others []string
+	for code := range names {
+		if !slices.Contains(order, code) {
+			others = append(others, names[code])
+		}
+	}
+	sort.Strings(others)
+
+	return append(result, others...)
+}
+
 // SetupAndValidate setups the view configuration and validates if accesss to the p_s tables is permitted.
-func SetupAndValidate(name string, db *sql.DB) View {
+func SetupAndValidate(name string, db datasource.DataSource, options Options) (View, error) {
 	log.Printf("view.SetupAndValidate(%q,%v)", name, db)
 
 	if !setup {
-		names = map[Code]string{
-			ViewLatency: "table_io_latency",
-			ViewOps:     "table_io_ops",
-			ViewIO:      "file_io_latency",
-			ViewLocks:   "table_lock_latency",
-			ViewUsers:   "user_latency",
-			ViewMutex:   "mutex_latency",
-			ViewStages:  "stages_latency",
-			ViewMemory:  "memory_usage",
+		if err := setupMaps(options); err != nil {
+			return View{}, err
 		}
 
-		tables = map[Code]AccessInfo{
-			ViewLatency: NewAccessInfo("performance_schema", "table_io_waits_summary_by_table"),
-			ViewOps:     NewAccessInfo("performance_schema", "table_io_waits_summary_by_table"),
-			ViewIO:      NewAccessInfo("performance_schema", "file_summary_by_instance"),
-			ViewLocks:   NewAccessInfo("performance_schema", "table_lock_waits_summary_by_table"),
-			ViewUsers:   NewAccessInfo("information_schema", "processlist"),
-			ViewMutex:   NewAccessInfo("performance_schema", "events_waits_summary_global_by_event_name"),
-			ViewStages:  NewAccessInfo("performance_schema", "events_stages_summary_global_by_event_name"),
-			ViewMemory:  NewAccessInfo("performance_schema", "memory_summary_global_by_event_name"),
+		if err := validateViews(db); err != nil {
+			return View{}, err
 		}
+	}
 
-		if err := validateViews(db); err != nil {
-			log.Fatal(err)
+	var v View
+
+	v.SetByName(name) // if empty will use the default
+	return v, nil
+}
+
+// Setup sets up the view configuration without accessing the database,
+// treating all views as SELECTable. It is used when replaying recorded data.
+func Setup(name string, options Options) (View, error) {
+	log.Printf("view.Setup(%q)", name)
+
+	if !setup {
+		if err := setupMaps(options); err != nil {
+			return View{}, err
 		}
+
+		for v := range tables {
+			ta := tables[v]
+			ta.checkedSelectError = true
+			tables[v] = ta
+		}
+		setPrevAndNextViews()
 	}
 
 	var v View
 
 	v.SetByName(name) // if empty will use the default
-	return v
+	return v, nil
 }
 
 // validateViews check which views are readable. If none are we give a fatal error
-func validateViews(db *sql.DB) error {
+func validateViews(db datasource.DataSource) error {
 	var count int
 	var isOrIsNot string
 	log.Println("Validating access to views...")
@@ -125,6 +259,9 @@ v3       false          v2        v4
 v4       true           v2        v2
 v5       false          v4        v2
 
+Views which are not in the order, but may be chosen when starting, are
+followed by the first view in the order and preceded by the last one.
+
 */
 
 func setPrevAndNextViews() {
@@ -138,9 +275,9 @@ func setPrevAndNextViews() {
 		prevView[v] = ViewNone
 	}
 
-	// Cleaner way to do this? Probably. Fix later.
-	prevCodeOrder := []Code{ViewMemory, ViewStages, ViewMutex, ViewUsers, ViewLocks, ViewIO, ViewOps, ViewLatency}
-	nextCodeOrder := []Code{ViewLatency, ViewOps, ViewIO, ViewLocks, ViewUsers, ViewMutex, ViewStages, ViewMemory}
+	nextCodeOrder := order
+	prevCodeOrder := slices.Clone(order)
+	slices.Reverse(prevCodeOrder)
 	prevView = setValidByValues(prevCodeOrder)
 	nextView = setValidByValues(nextCodeOrder)
 
@@ -196,6 +333,13 @@ func setValidByValues(orderedCodes []Code) map[Code]Code {
 		}
 	}
 
+	// as should the views not in the order
+	for code := range names {
+		if _, found := orderedMap[code]; !found {
+			orderedMap[code] = first
+		}
+	}
+
 	return orderedMap
 }
 
@@ -213,6 +357,17 @@ func (v *View) SetPrev() Code {
 	return v.code
 }
 
+// SetNumber changes the current view to the given view in the order
+// they are shown, starting at 1. It returns false if there is no such view.
+func (v *View) SetNumber(number int) bool {
+	if number < 1 || number > len(order) {
+		return false
+	}
+	v.Set(order[number-1])
+
+	return true
+}
+
 // Set sets the view to the given view (by Code)
 func (v *View) Set(viewCode Code) {
 	v.code = viewCode
@@ -228,27 +383,19 @@ func (v *View) Set(viewCode Code) {
 func (v *View) SetByName(name string) {
 	log.Println("View.SetByName(" + name + ")")
 	if name == "" {
-		log.Println("View.SetByName(): name is empty so setting to:", ViewLatency.String())
-		v.Set(ViewLatency)
+		log.Println("View.SetByName(): name is empty so setting to:", order[0].String())
+		v.Set(order[0])
 		return
 	}
 
-	for i := range names {
-		if name == names[i] {
-			v.code = Code(i)
-			log.Println("View.SetByName(", name, ")")
-			return
-		}
+	if code, found := codeByName(name); found {
+		v.code = code
+		log.Println("View.SetByName(", name, ")")
+		return
 	}
 
 	// suggest what should be used
-	allViews := ""
-	for i := range names {
-		allViews = allViews + " " + names[i]
-	}
-
-	// no need for now to strip off leading space from allViews.
-	log.Fatal("Asked for a view name, '", name, "' which doesn't exist. Try one of:", allViews)
+	log.Fatal("Asked for a view name, '", name, "' which doesn't exist. Try one of: ", strings.Join(Names(), " "))
 }
 
 // Get returns the Code version of the current view
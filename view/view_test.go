@@ -0,0 +1,56 @@
+package view
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	options := Options{
+		Order:  []string{"threads", "schema_tables", "table_io_latency"},
+		Custom: []Custom{{Name: "schema_tables", Query: "SELECT table_name FROM sys.x$schema_table_statistics"}},
+	}
+	v, err := Setup("", options)
+	if err != nil {
+		t.Fatalf("Setup() failed: %v", err)
+	}
+
+	// the first view in the order is the default
+	var names []string
+	for range 4 {
+		names = append(names, v.Name())
+		v.SetNext()
+	}
+	if got := strings.Join(names, " "); got != "threads schema_tables table_io_latency threads" {
+		t.Errorf("SetNext() failed: got: %q", got)
+	}
+
+	if !v.SetNumber(2) || v.Name() != "schema_tables" {
+		t.Errorf("SetNumber(2) failed: got: %q", v.Name())
+	}
+	if v.SetNumber(4) {
+		t.Errorf("SetNumber(4) should fail, got: %q", v.Name())
+	}
+
+	// a view not in the order returns to it
+	v.SetByName("memory_usage")
+	if v.SetPrev(); v.Name() != "table_io_latency" {
+		t.Errorf("SetPrev() from a view not in the order failed: got: %q", v.Name())
+	}
+}
+
+func TestOrderErrors(t *testing.T) {
+	tests := []struct {
+		options  Options
+		expected string
+	}{
+		{Options{Order: []string{"threads", "unknown"}}, `unknown view "unknown"`},
+		{Options{Order: []string{"threads", "threads"}}, `view "threads" is given more than once`},
+		{Options{Custom: []Custom{{Name: "threads", Query: "SELECT 1"}}}, `view "threads" is defined more than once`},
+	}
+	for _, test := range tests {
+		if _, err := Setup("", test.options); err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("Setup(%+v): expected error %q, got: %v", test.options, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package compare holds the routines which show the rows of a view
+// from two servers or time windows side by side
+package compare
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/model/compare"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a Compare struct
+type Wrapper struct {
+	c      *compare.Compare
+	view   string // name of the view being compared
+	sorter *pstable.Sorter[compare.Row]
+}
+
+// NewServers returns a wrapper comparing the same view of two servers,
+// or nil if the view can not be compared
+func NewServers(view string, left, right compare.Side) *Wrapper {
+	return newWrapper(view, compare.NewServers(left, right))
+}
+
+// NewWindows returns a wrapper comparing the rows collected by tabler
+// so far with those collected from now on, or nil if they can not be
+// compared
+func NewWindows(view string, tabler pstable.Tabler) *Wrapper {
+	return newWrapper(view, compare.NewWindows(tabler))
+}
+
+// newWrapper returns a wrapper around c unless it is nil
+func newWrapper(view string, c *compare.Compare) *Wrapper {
+	if c == nil {
+		return nil
+	}
+	cw := &Wrapper{
+		c:      c,
+		view:   view,
+		sorter: newSorter(c.Labels()),
+	}
+	cw.sorter.Sort(cw.c.Results)
+
+	return cw
+}
+
+// ResetStatistics resets the statistics to last values
+func (cw *Wrapper) ResetStatistics() {
+	cw.c.ResetStatistics()
+	cw.sorter.Sort(cw.c.Results)
+}
+
+// Collect data from both sides, then sort the results.
+func (cw *Wrapper) Collect(ctx context.Context) error {
+	err := cw.c.Collect(ctx)
+	cw.sorter.Sort(cw.c.Results)
+
+	return err
+}
+
+// Refilter joins the rows last collected by both sides again without
+// collecting them, then sorts the results.
+func (cw *Wrapper) Refilter() {
+	cw.c.Refilter()
+	cw.sorter.Sort(cw.c.Results)
+}
+
+// SortNext sorts the rows by the next sortable column
+func (cw *Wrapper) SortNext() {
+	cw.sorter.SortNext()
+	cw.sorter.Sort(cw.c.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (cw *Wrapper) SortReverse() {
+	cw.sorter.SortReverse()
+	cw.sorter.Sort(cw.c.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (cw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(cw.c.Results))
+
+	for i := range cw.c.Results {
+		rows = append(rows, cw.content(cw.c.Results[i]))
+	}
+
+	return rows
+}
+
+// RowName returns the name of the given row
+func (cw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(cw.c.Results) {
+		return ""
+	}
+	return cw.c.Results[row].Name
+}
+
+// TotalRowContent returns the totals of both sides
+func (cw Wrapper) TotalRowContent() string {
+	return cw.content(cw.c.Totals)
+}
+
+// Rows returns the rows which contain data so that they can be exported
+func (cw Wrapper) Rows() any {
+	rows := make(compare.Rows, 0, len(cw.c.Results))
+
+	for _, row := range cw.c.Results {
+		if row.Left > 0 || row.Right > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (cw Wrapper) EmptyRowContent() string {
+	return cw.content(compare.Row{})
+}
+
+// HaveRelativeStats returns whether the rows compared have relative statistics
+func (cw Wrapper) HaveRelativeStats() bool {
+	return cw.c.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (cw Wrapper) FirstCollectTime() time.Time {
+	return cw.c.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (cw Wrapper) LastCollectTime() time.Time {
+	return cw.c.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (cw Wrapper) WantRelativeStats() bool {
+	return cw.c.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (cw Wrapper) Description() string {
+	left, right := cw.c.Labels()
+
+	return fmt.Sprintf("Compare %s with %s (%s) %d rows", left, right, cw.view, len(cw.c.Results))
+}
+
+// Headings returns the headings for a table
+func (cw Wrapper) Headings() string {
+	heading := cw.sorter.Heading
+	left, right := cw.c.Labels()
+
+	return fmt.Sprintf("%10s %10s %11s|%s",
+		heading(left, 10),
+		heading(right, 10),
+		heading("Diff", 11),
+		heading("Name", 0))
+}
+
+// content generate a printable result for a row
+func (cw Wrapper) content(row compare.Row) string {
+	// assume the data is empty so hide it.
+	name := row.Name
+	if row.Left == 0 && row.Right == 0 && name != "Totals" {
+		name = ""
+	}
+
+	return fmt.Sprintf("%10s %10s %11s|%s",
+		utils.FormatTime(row.Left),
+		utils.FormatTime(row.Right),
+		utils.SignedFormatTime(row.Diff()),
+		name)
+}
+
+// name returns the name of a row for sorting
+func name(row compare.Row) string {
+	return row.Name
+}
+
+// newSorter returns a sorter for the columns which can be sorted, given
+// the labels of both sides. The rows which changed most are shown first.
+func newSorter(left, right string) *pstable.Sorter[compare.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Diff", compare.Row.AbsDiff, name),
+		pstable.ByValue(left, func(row compare.Row) uint64 { return row.Left }, name),
+		pstable.ByValue(right, func(row compare.Row) uint64 { return row.Right }, name),
+		pstable.ByName("Name", name),
+	)
+}
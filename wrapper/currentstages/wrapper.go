@@ -0,0 +1,174 @@
+// Package currentstages holds the routines which manage the stages currently being executed
+package currentstages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/currentstages"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a CurrentStages struct
+type Wrapper struct {
+	cs     *currentstages.CurrentStages
+	sorter *pstable.Sorter[currentstages.Row]
+}
+
+// NewCurrentStages creates a wrapper around currentstages.CurrentStages
+func NewCurrentStages(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		cs:     currentstages.NewCurrentStages(cfg, db),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (csw *Wrapper) ResetStatistics() {
+	csw.cs.ResetStatistics()
+	csw.sorter.Sort(csw.cs.Results)
+}
+
+// Collect data from the db, then sort the results.
+func (csw *Wrapper) Collect(ctx context.Context) error {
+	if err := csw.cs.Collect(ctx); err != nil {
+		return err
+	}
+	csw.sorter.Sort(csw.cs.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (csw *Wrapper) Refilter() {
+	csw.cs.Refilter()
+	csw.sorter.Sort(csw.cs.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (csw Wrapper) Snapshot() any {
+	return csw.cs.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (csw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last currentstages.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	csw.cs.AddRows(last, collected)
+	csw.sorter.Sort(csw.cs.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (csw *Wrapper) SortNext() {
+	csw.sorter.SortNext()
+	csw.sorter.Sort(csw.cs.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (csw *Wrapper) SortReverse() {
+	csw.sorter.SortReverse()
+	csw.sorter.Sort(csw.cs.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (csw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(csw.cs.Results))
+
+	for i := range csw.cs.Results {
+		rows = append(rows, csw.content(csw.cs.Results[i]))
+	}
+
+	return rows
+}
+
+// RowName returns the name of the stage of the given row
+func (csw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(csw.cs.Results) {
+		return ""
+	}
+	return csw.cs.Results[row].Stage
+}
+
+// TotalRowContent returns the number of stages being executed
+func (csw Wrapper) TotalRowContent() string {
+	return fmt.Sprintf("%10d stage(s)", len(csw.cs.Results))
+}
+
+// Rows returns the rows so that they can be exported
+func (csw Wrapper) Rows() any {
+	return utils.DuplicateSlice(csw.cs.Results)
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (csw Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is false for this object
+func (csw Wrapper) HaveRelativeStats() bool {
+	return csw.cs.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (csw Wrapper) FirstCollectTime() time.Time {
+	return csw.cs.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (csw Wrapper) LastCollectTime() time.Time {
+	return csw.cs.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (csw Wrapper) WantRelativeStats() bool {
+	return csw.cs.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (csw Wrapper) Description() string {
+	return fmt.Sprintf("Current Stages (events_stages_current) %d rows", len(csw.cs.Results))
+}
+
+// Headings returns the headings for a table
+func (csw Wrapper) Headings() string {
+	heading := csw.sorter.Heading
+
+	return fmt.Sprintf("%10s %6s %-24s %-32s|%s",
+		heading("Time", 10), "Done", heading("Thread", 24), heading("Stage", 32), "Statement")
+}
+
+// content generate a printable result for a row. The progress is
+// only known for stages which estimate the work they have to do.
+func (csw Wrapper) content(row currentstages.Row) string {
+	var done string
+	if row.WorkEstimated > 0 {
+		done = utils.FormatPct(utils.Divide(row.WorkCompleted, row.WorkEstimated))
+	}
+
+	return fmt.Sprintf("%10s %6s %-24.24s %-32.32s|%s",
+		utils.FormatTime(row.Time),
+		done,
+		row.Thread,
+		row.Stage,
+		row.Statement)
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[currentstages.Row] {
+	name := func(row currentstages.Row) string { return row.Thread }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Time", func(row currentstages.Row) uint64 { return row.Time }, name),
+		pstable.ByName("Thread", name),
+		pstable.ByName("Stage", func(row currentstages.Row) string { return row.Stage }),
+	)
+}
@@ -0,0 +1,239 @@
+// Package customview holds the routines which manage the views defined by the user
+package customview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/customview"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// textWidth is the width of the text columns other than the last one
+const textWidth = 20
+
+// Wrapper wraps a CustomView struct
+type Wrapper struct {
+	cv     *customview.CustomView
+	sorter *pstable.Sorter[customview.Row]
+}
+
+// NewCustomView creates a wrapper around a view defined by the user
+// returning an error if the columns are not valid
+func NewCustomView(cfg *config.Config, db datasource.DataSource, name, description, query, columns string) (*Wrapper, error) {
+	parsed, err := customview.ParseColumns(columns)
+	if err != nil {
+		return nil, fmt.Errorf("view %s: %w", name, err)
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("view %s: no query given", name)
+	}
+
+	return &Wrapper{
+		cv:     customview.NewCustomView(cfg, db, name, description, query, parsed),
+		sorter: newSorter(parsed),
+	}, nil
+}
+
+// ResetStatistics resets the statistics to last values
+func (cvw *Wrapper) ResetStatistics() {
+	cvw.cv.ResetStatistics()
+	cvw.sorter.Sort(cvw.cv.Results)
+}
+
+// Collect data from the db, then sort the results.
+func (cvw *Wrapper) Collect(ctx context.Context) error {
+	if err := cvw.cv.Collect(ctx); err != nil {
+		return err
+	}
+	cvw.sorter.Sort(cvw.cv.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (cvw *Wrapper) Refilter() {
+	cvw.cv.Refilter()
+	cvw.sorter.Sort(cvw.cv.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (cvw Wrapper) Snapshot() any {
+	return cvw.cv.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (cvw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last []customview.Row
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	cvw.cv.AddRows(last, collected)
+	cvw.sorter.Sort(cvw.cv.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (cvw *Wrapper) SortNext() {
+	cvw.sorter.SortNext()
+	cvw.sorter.Sort(cvw.cv.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (cvw *Wrapper) SortReverse() {
+	cvw.sorter.SortReverse()
+	cvw.sorter.Sort(cvw.cv.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (cvw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(cvw.cv.Results))
+
+	for i := range cvw.cv.Results {
+		rows = append(rows, cvw.content(cvw.cv.Results[i]))
+	}
+
+	return rows
+}
+
+// TotalRowContent returns the totals of the columns which can be added together
+func (cvw Wrapper) TotalRowContent() string {
+	return cvw.content(cvw.cv.Totals)
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (cvw Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is false for this object
+func (cvw Wrapper) HaveRelativeStats() bool {
+	return cvw.cv.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (cvw Wrapper) FirstCollectTime() time.Time {
+	return cvw.cv.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (cvw Wrapper) LastCollectTime() time.Time {
+	return cvw.cv.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (cvw Wrapper) WantRelativeStats() bool {
+	return cvw.cv.WantRelativeStats()
+}
+
+// Description returns the description of the view or the error
+// returned by its query
+func (cvw Wrapper) Description() string {
+	description := cvw.cv.Description
+	if description == "" {
+		description = "Custom view"
+	}
+	if cvw.cv.Err != nil {
+		return fmt.Sprintf("%s (%s) error: %v", description, cvw.cv.Name, cvw.cv.Err)
+	}
+
+	return fmt.Sprintf("%s (%s) %d rows", description, cvw.cv.Name, len(cvw.cv.Results))
+}
+
+// Headings returns the headings for a table
+func (cvw Wrapper) Headings() string {
+	headings := make([]string, len(cvw.cv.Columns))
+
+	for i, column := range cvw.cv.Columns {
+		width := columnWidth(column, i == len(cvw.cv.Columns)-1)
+		headings[i] = align(column, cvw.sorter.Heading(column.Name, width), width)
+	}
+
+	return strings.Join(headings, " ")
+}
+
+// content generate a printable result for a row
+func (cvw Wrapper) content(row customview.Row) string {
+	values := make([]string, len(cvw.cv.Columns))
+
+	for i, column := range cvw.cv.Columns {
+		var value string
+		if i < len(row) && row[i] != "" {
+			value = format(column.Format, row, i)
+		}
+		values[i] = align(column, value, columnWidth(column, i == len(cvw.cv.Columns)-1))
+	}
+
+	return strings.Join(values, " ")
+}
+
+// format returns the value of the given column of a row as shown
+func format(f customview.Format, row customview.Row, column int) string {
+	switch f {
+	case customview.FormatTime:
+		return utils.FormatTime(uint64(row.Number(column)))
+	case customview.FormatBytes, customview.FormatCount:
+		return utils.FormatAmount(uint64(row.Number(column)))
+	case customview.FormatPercent:
+		return utils.FormatPct(row.Number(column) / 100)
+	}
+
+	return row[column]
+}
+
+// columnWidth returns the width of a column. The last column may be as
+// wide as needed if it is text.
+func columnWidth(column customview.Column, last bool) int {
+	switch {
+	case column.Format == customview.FormatPercent:
+		return 6
+	case column.Format.Numeric():
+		return 10
+	case last:
+		return 0
+	}
+
+	return textWidth
+}
+
+// align pads or shortens a value to the width of the column, numbers
+// being aligned to the right and text to the left
+func align(column customview.Column, value string, width int) string {
+	switch {
+	case width == 0:
+		return value
+	case column.Format.Numeric():
+		return fmt.Sprintf("%*.*s", width, width, value)
+	}
+
+	return fmt.Sprintf("%-*.*s", width, width, value)
+}
+
+// newSorter returns a sorter for the columns of the view. The numeric
+// columns come first so the rows are sorted by the first one by default.
+func newSorter(columns []customview.Column) *pstable.Sorter[customview.Row] {
+	var numeric, text []pstable.SortColumn[customview.Row]
+
+	name := func(row customview.Row) string { return strings.Join(row, " ") }
+	for i, column := range columns {
+		if column.Format.Numeric() {
+			numeric = append(numeric, pstable.ByValue(column.Name, func(row customview.Row) float64 { return row.Number(i) }, name))
+		} else {
+			text = append(text, pstable.ByName(column.Name, func(row customview.Row) string {
+				if i >= len(row) {
+					return ""
+				}
+				return row[i]
+			}))
+		}
+	}
+
+	return pstable.NewSorter(append(numeric, text...)...)
+}
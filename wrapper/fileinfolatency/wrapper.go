@@ -2,25 +2,32 @@
 package fileinfolatency
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/model/fileinfo"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper wraps a FileIoLatency struct representing the contents of the data collected from file_summary_by_instance, but adding formatting for presentation in the terminal
 type Wrapper struct {
-	fiol *fileinfo.FileIoLatency
+	fiol   *fileinfo.FileIoLatency
+	sorter *pstable.Sorter[fileinfo.Row]
+	hidden *pstable.Sorter[fileinfo.Row] // sorter of the columns not shown
+	timers bool                          // show the timers rather than the split of the latency?
 }
 
 // NewFileSummaryByInstance creates a wrapper around FileIoLatency
-func NewFileSummaryByInstance(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewFileSummaryByInstance(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		fiol: fileinfo.NewFileSummaryByInstance(cfg, db),
+		fiol:   fileinfo.NewFileSummaryByInstance(cfg, db),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(fileinfo.Row.Timers, name, "Table Name"),
 	}
 }
 
@@ -30,26 +37,83 @@ func (fiolw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (fiolw *Wrapper) Collect() {
-	fiolw.fiol.Collect()
-	sort.Sort(byLatency(fiolw.fiol.Results))
+func (fiolw *Wrapper) Collect(ctx context.Context) error {
+	if err := fiolw.fiol.Collect(ctx); err != nil {
+		return err
+	}
+	fiolw.sorter.Sort(fiolw.fiol.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (fiolw *Wrapper) Refilter() {
+	fiolw.fiol.Refilter()
+	fiolw.sorter.Sort(fiolw.fiol.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (fiolw Wrapper) Snapshot() any {
+	return fiolw.fiol.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (fiolw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last fileinfo.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	fiolw.fiol.AddRows(last, collected)
+	fiolw.sorter.Sort(fiolw.fiol.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (fiolw *Wrapper) SortNext() {
+	fiolw.sorter.SortNext()
+	fiolw.sorter.Sort(fiolw.fiol.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (fiolw *Wrapper) SortReverse() {
+	fiolw.sorter.SortReverse()
+	fiolw.sorter.Sort(fiolw.fiol.Results)
+}
+
+// ToggleTimers switches between showing how the latency is split and
+// the number of waits with their minimum, average and maximum latency
+func (fiolw *Wrapper) ToggleTimers() {
+	fiolw.timers = !fiolw.timers
+	fiolw.sorter, fiolw.hidden = fiolw.hidden, fiolw.sorter
+	fiolw.sorter.Sort(fiolw.fiol.Results)
 }
 
 // Headings returns the headings for a table
 func (fiolw Wrapper) Headings() string {
+	heading := fiolw.sorter.Heading
+
+	if fiolw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Table Name", 0))
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s %6s|%8s %8s|%8s %6s %6s %6s|%s",
-		"Latency",
+		heading("Latency", 10),
 		"%",
-		"Read",
-		"Write",
-		"Misc",
-		"Rd bytes",
-		"Wr bytes",
-		"Ops",
-		"R Ops",
-		"W Ops",
-		"M Ops",
-		"Table Name")
+		heading("Read", 6),
+		heading("Write", 6),
+		heading("Misc", 6),
+		heading("Rd bytes", 8),
+		heading("Wr bytes", 8),
+		heading("Ops", 8),
+		heading("R Ops", 6),
+		heading("W Ops", 6),
+		heading("M Ops", 6),
+		heading("Table Name", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -63,11 +127,55 @@ func (fiolw Wrapper) RowContent() []string {
 	return rows
 }
 
+// RowName returns the name of the given row
+func (fiolw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(fiolw.fiol.Results) {
+		return ""
+	}
+	return fiolw.fiol.Results[row].Name
+}
+
+// Related returns the headings and the file I/O of the given table
+func (fiolw Wrapper) Related(name string) (string, []string) {
+	var rows []string
+
+	for i := range fiolw.fiol.Results {
+		if fiolw.fiol.Results[i].Name == name {
+			rows = append(rows, fiolw.content(fiolw.fiol.Results[i], fiolw.fiol.Totals))
+		}
+	}
+
+	return fiolw.Headings(), rows
+}
+
 // TotalRowContent returns all the totals
 func (fiolw Wrapper) TotalRowContent() string {
 	return fiolw.content(fiolw.fiol.Totals, fiolw.fiol.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (fiolw Wrapper) Rows() any {
+	rows := make(fileinfo.Rows, 0, len(fiolw.fiol.Results))
+
+	for i := range fiolw.fiol.Results {
+		if fiolw.fiol.Results[i].HasData() {
+			rows = append(rows, fiolw.fiol.Results[i])
+		}
+	}
+
+	return rows
+}
+
+// Summary returns the total latency and the file with the highest latency
+func (fiolw Wrapper) Summary() pstable.Summary {
+	return pstable.Summarise(fiolw.fiol.Results, name, func(row fileinfo.Row) uint64 { return row.SumTimerWait })
+}
+
+// Values returns the latency of each file so that it can be compared
+func (fiolw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(fiolw.fiol.Results, name, func(row fileinfo.Row) uint64 { return row.SumTimerWait })
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (fiolw Wrapper) EmptyRowContent() string {
 	var empty fileinfo.Row
@@ -118,6 +226,14 @@ func (fiolw Wrapper) content(row, totals fileinfo.Row) string {
 		name = ""
 	}
 
+	if fiolw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), fiolw.WantRelativeStats()),
+			name)
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s %6s|%8s %8s|%8s %6s %6s %6s|%s",
 		utils.FormatTime(row.SumTimerWait),
 		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
@@ -133,11 +249,24 @@ func (fiolw Wrapper) content(row, totals fileinfo.Row) string {
 		name)
 }
 
-type byLatency fileinfo.Rows
+// name returns the name of a row for sorting
+func name(row fileinfo.Row) string {
+	return row.Name
+}
 
-func (rows byLatency) Len() int      { return len(rows) }
-func (rows byLatency) Swap(i, j int) { rows[i], rows[j] = rows[j], rows[i] }
-func (rows byLatency) Less(i, j int) bool {
-	return (rows[i].SumTimerWait > rows[j].SumTimerWait) ||
-		((rows[i].SumTimerWait == rows[j].SumTimerWait) && (rows[i].Name < rows[j].Name))
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[fileinfo.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row fileinfo.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Read", func(row fileinfo.Row) float64 { return utils.Divide(row.SumTimerRead, row.SumTimerWait) }, name),
+		pstable.ByValue("Write", func(row fileinfo.Row) float64 { return utils.Divide(row.SumTimerWrite, row.SumTimerWait) }, name),
+		pstable.ByValue("Misc", func(row fileinfo.Row) float64 { return utils.Divide(row.SumTimerMisc, row.SumTimerWait) }, name),
+		pstable.ByValue("Rd bytes", func(row fileinfo.Row) uint64 { return row.SumNumberOfBytesRead }, name),
+		pstable.ByValue("Wr bytes", func(row fileinfo.Row) uint64 { return row.SumNumberOfBytesWrite }, name),
+		pstable.ByValue("Ops", func(row fileinfo.Row) uint64 { return row.CountStar }, name),
+		pstable.ByValue("R Ops", func(row fileinfo.Row) float64 { return utils.Divide(row.CountRead, row.CountStar) }, name),
+		pstable.ByValue("W Ops", func(row fileinfo.Row) float64 { return utils.Divide(row.CountWrite, row.CountStar) }, name),
+		pstable.ByValue("M Ops", func(row fileinfo.Row) float64 { return utils.Divide(row.CountMisc, row.CountStar) }, name),
+		pstable.ByName("Table Name", name),
+	)
 }
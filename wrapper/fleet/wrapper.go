@@ -0,0 +1,184 @@
+// Package fleet holds the routines which show the totals of several servers
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/model/fleet"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a Fleet struct
+type Wrapper struct {
+	f      *fleet.Fleet
+	sorter *pstable.Sorter[fleet.Row]
+}
+
+// NewFleet creates a wrapper around fleet.Fleet
+func NewFleet(cfg fleet.Config, collect func(context.Context) fleet.Rows) *Wrapper {
+	return &Wrapper{
+		f:      fleet.NewFleet(cfg, collect),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (fw *Wrapper) ResetStatistics() {
+	fw.f.ResetStatistics()
+}
+
+// Collect data from the servers, then sort the results.
+func (fw *Wrapper) Collect(ctx context.Context) error {
+	if err := fw.f.Collect(ctx); err != nil {
+		return err
+	}
+	fw.sorter.Sort(fw.f.Results)
+
+	return nil
+}
+
+// Update replaces the totals of each server without collecting them,
+// e.g. once the rows of the servers have been filtered again, then
+// sorts the results.
+func (fw *Wrapper) Update(rows fleet.Rows) {
+	fw.f.AddRows(rows, fw.f.LastCollected)
+	fw.sorter.Sort(fw.f.Results)
+}
+
+// SortNext sorts the rows by the next sortable column
+func (fw *Wrapper) SortNext() {
+	fw.sorter.SortNext()
+	fw.sorter.Sort(fw.f.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (fw *Wrapper) SortReverse() {
+	fw.sorter.SortReverse()
+	fw.sorter.Sort(fw.f.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (fw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(fw.f.Results))
+
+	for i := range fw.f.Results {
+		rows = append(rows, fw.content(fw.f.Results[i]))
+	}
+
+	return rows
+}
+
+// RowName returns the server of the given row, as given on the
+// command line, or "" if nothing could be collected from it
+func (fw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(fw.f.Results) || fw.f.Results[row].Error != "" {
+		return ""
+	}
+	return fw.f.Results[row].Host
+}
+
+// TotalRowContent returns the totals of all servers
+func (fw Wrapper) TotalRowContent() string {
+	return fw.content(fw.f.Totals)
+}
+
+// Rows returns the rows so that they can be exported
+func (fw Wrapper) Rows() any {
+	return utils.DuplicateSlice(fw.f.Results)
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (fw Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is true for this object
+func (fw Wrapper) HaveRelativeStats() bool {
+	return fw.f.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (fw Wrapper) FirstCollectTime() time.Time {
+	return fw.f.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (fw Wrapper) LastCollectTime() time.Time {
+	return fw.f.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (fw Wrapper) WantRelativeStats() bool {
+	return fw.f.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (fw Wrapper) Description() string {
+	return fmt.Sprintf("Fleet of %d servers (table I/O, file I/O and table lock latency, lock waits) - Enter shows a server", len(fw.f.Results))
+}
+
+// Headings returns the headings for a table
+func (fw Wrapper) Headings() string {
+	heading := fw.sorter.Heading
+
+	return fmt.Sprintf("%-20s %-10s|%10s %10s %10s %6s|%10s %s",
+		heading("Server", 20),
+		"Version",
+		heading("Table I/O", 10),
+		heading("File I/O", 10),
+		heading("Locks", 10),
+		heading("Waits", 6),
+		heading("Top Table", 10),
+		"Table Name")
+}
+
+// content generate a printable result for a row
+func (fw Wrapper) content(row fleet.Row) string {
+	host := row.Host
+	if host != "Totals" {
+		host = anonymiser.Anonymise("hostname", host)
+	}
+	if row.Error != "" {
+		return fmt.Sprintf("%-20.20s %-10.10s|%s", host, row.Version, row.Error)
+	}
+
+	return fmt.Sprintf("%-20.20s %-10.10s|%10s %10s %10s %6s|%10s %s",
+		host,
+		row.Version,
+		collected(row, fleet.TableIOLatency, utils.FormatTime(row.TableIOLatency)),
+		collected(row, fleet.FileIOLatency, utils.FormatTime(row.FileIOLatency)),
+		collected(row, fleet.TableLockLatency, utils.FormatTime(row.TableLockLatency)),
+		collected(row, fleet.LockWaits, fmt.Sprint(row.LockWaits)),
+		collected(row, fleet.TableIOLatency, utils.FormatTime(row.TopTableLatency)),
+		row.TopTable)
+}
+
+// collected returns the value of a column, which is left empty if the
+// totals of its view could not be collected
+func collected(row fleet.Row, view, value string) string {
+	if !row.Collected(view) {
+		return ""
+	}
+	return value
+}
+
+// name returns the name of a row for sorting
+func name(row fleet.Row) string {
+	return row.Host
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[fleet.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Table I/O", func(row fleet.Row) uint64 { return row.TableIOLatency }, name),
+		pstable.ByValue("File I/O", func(row fleet.Row) uint64 { return row.FileIOLatency }, name),
+		pstable.ByValue("Locks", func(row fleet.Row) uint64 { return row.TableLockLatency }, name),
+		pstable.ByValue("Waits", func(row fleet.Row) int { return row.LockWaits }, name),
+		pstable.ByValue("Top Table", func(row fleet.Row) uint64 { return row.TopTableLatency }, name),
+		pstable.ByName("Server", name),
+	)
+}
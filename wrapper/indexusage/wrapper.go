@@ -0,0 +1,239 @@
+// Package indexusage holds the routines which manage the index usage statistics.
+package indexusage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/indexusage"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// FullScan is shown instead of the index name for I/O not using an index
+const FullScan = "<full scan>"
+
+// Wrapper represents the contents of the data collected related to index usage statistics
+type Wrapper struct {
+	iu     *indexusage.IndexUsage
+	sorter *pstable.Sorter[indexusage.Row]
+	hidden *pstable.Sorter[indexusage.Row] // sorter of the columns not shown
+	timers bool                            // show the timers rather than the split of the latency?
+}
+
+// NewIndexUsage creates a wrapper around index usage statistics
+func NewIndexUsage(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		iu:     indexusage.NewIndexUsage(cfg, db),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(indexusage.Row.Timers, displayName, "Table Name: Index"),
+	}
+}
+
+// IndexUsage returns the IndexUsage value so it can be shared
+func (iuw *Wrapper) IndexUsage() *indexusage.IndexUsage {
+	return iuw.iu
+}
+
+// ResetStatistics resets the statistics to last values
+func (iuw *Wrapper) ResetStatistics() {
+	iuw.iu.ResetStatistics()
+}
+
+// Collect data from the db, then merge it in.
+func (iuw *Wrapper) Collect(ctx context.Context) error {
+	if err := iuw.iu.Collect(ctx); err != nil {
+		return err
+	}
+	iuw.sorter.Sort(iuw.iu.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (iuw *Wrapper) Refilter() {
+	iuw.iu.Refilter()
+	iuw.sorter.Sort(iuw.iu.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (iuw Wrapper) Snapshot() any {
+	return iuw.iu.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (iuw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last indexusage.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	iuw.iu.AddRows(last, collected)
+	iuw.sorter.Sort(iuw.iu.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (iuw *Wrapper) SortNext() {
+	iuw.sorter.SortNext()
+	iuw.sorter.Sort(iuw.iu.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (iuw *Wrapper) SortReverse() {
+	iuw.sorter.SortReverse()
+	iuw.sorter.Sort(iuw.iu.Results)
+}
+
+// ToggleTimers switches between showing how the latency is split and
+// the number of waits with their minimum, average and maximum latency
+func (iuw *Wrapper) ToggleTimers() {
+	iuw.timers = !iuw.timers
+	iuw.sorter, iuw.hidden = iuw.hidden, iuw.sorter
+	iuw.sorter.Sort(iuw.iu.Results)
+}
+
+// Headings returns the latency headings as a string
+func (iuw Wrapper) Headings() string {
+	heading := iuw.sorter.Heading
+
+	if iuw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Table Name: Index", 0))
+	}
+
+	return fmt.Sprintf("%10s %6s|%6s %6s %6s %6s|%8s|%s",
+		heading("Latency", 10),
+		"%",
+		heading("Fetch", 6),
+		heading("Insert", 6),
+		heading("Update", 6),
+		heading("Delete", 6),
+		heading("Ops", 8),
+		heading("Table Name: Index", 0))
+}
+
+// RowContent returns the rows we need for displaying
+func (iuw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(iuw.iu.Results))
+
+	for i := range iuw.iu.Results {
+		rows = append(rows, iuw.content(iuw.iu.Results[i], iuw.iu.Totals))
+	}
+
+	return rows
+}
+
+// RowName returns the name of the table of the given row
+func (iuw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(iuw.iu.Results) {
+		return ""
+	}
+	return iuw.iu.Results[row].Name
+}
+
+// TotalRowContent returns all the totals
+func (iuw Wrapper) TotalRowContent() string {
+	return iuw.content(iuw.iu.Totals, iuw.iu.Totals)
+}
+
+// Rows returns the rows which contain data so that they can be exported
+func (iuw Wrapper) Rows() any {
+	return iuw.iu.Results
+}
+
+// Values returns the latency of each index so that it can be compared
+func (iuw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(iuw.iu.Results, displayName, func(row indexusage.Row) uint64 { return row.SumTimerWait })
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (iuw Wrapper) EmptyRowContent() string {
+	var empty indexusage.Row
+
+	return iuw.content(empty, empty)
+}
+
+// Description returns a description of the table
+func (iuw Wrapper) Description() string {
+	return fmt.Sprintf("Index Latency (table_io_waits_summary_by_index_usage) %d rows", len(iuw.iu.Results))
+}
+
+// HaveRelativeStats is true for this object
+func (iuw Wrapper) HaveRelativeStats() bool {
+	return iuw.iu.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time of the first collection
+func (iuw Wrapper) FirstCollectTime() time.Time {
+	return iuw.iu.FirstCollected
+}
+
+// LastCollectTime returns the time of the last collection
+func (iuw Wrapper) LastCollectTime() time.Time {
+	return iuw.iu.LastCollected
+}
+
+// WantRelativeStats returns if we want to see relative stats
+func (iuw Wrapper) WantRelativeStats() bool {
+	return iuw.iu.WantRelativeStats()
+}
+
+// displayName returns the name shown for a row: the table and the index used
+func displayName(row indexusage.Row) string {
+	switch {
+	case row.Name == "" || row.Name == "Totals":
+		return row.Name
+	case row.FullScan():
+		return row.Name + ": " + FullScan
+	default:
+		return row.Name + ": " + row.Index
+	}
+}
+
+// content returns the printable result
+func (iuw Wrapper) content(row, totals indexusage.Row) string {
+	// assume the data is empty so hide it.
+	name := displayName(row)
+	if row.CountStar == 0 && name != "Totals" {
+		name = ""
+	}
+
+	if iuw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), iuw.WantRelativeStats()),
+			name)
+	}
+
+	return fmt.Sprintf("%10s %6s|%6s %6s %6s %6s|%8s|%s",
+		utils.FormatTime(row.SumTimerWait),
+		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerFetch, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerInsert, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerUpdate, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerDelete, row.SumTimerWait)),
+		utils.FormatAmount(row.CountStar),
+		name)
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[indexusage.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row indexusage.Row) uint64 { return row.SumTimerWait }, displayName),
+		pstable.ByValue("Fetch", func(row indexusage.Row) float64 { return utils.Divide(row.SumTimerFetch, row.SumTimerWait) }, displayName),
+		pstable.ByValue("Insert", func(row indexusage.Row) float64 { return utils.Divide(row.SumTimerInsert, row.SumTimerWait) }, displayName),
+		pstable.ByValue("Update", func(row indexusage.Row) float64 { return utils.Divide(row.SumTimerUpdate, row.SumTimerWait) }, displayName),
+		pstable.ByValue("Delete", func(row indexusage.Row) float64 { return utils.Divide(row.SumTimerDelete, row.SumTimerWait) }, displayName),
+		pstable.ByValue("Ops", func(row indexusage.Row) uint64 { return row.CountStar }, displayName),
+		pstable.ByName("Table Name: Index", displayName),
+	)
+}
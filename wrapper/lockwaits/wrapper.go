@@ -0,0 +1,183 @@
+// Package lockwaits holds the routines which show the sessions waiting for locks
+package lockwaits
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/lockwaits"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a LockWaits struct
+type Wrapper struct {
+	lw     *lockwaits.LockWaits
+	sorter *pstable.Sorter[lockwaits.Row]
+}
+
+// NewLockWaits creates a wrapper around lockwaits.LockWaits
+func NewLockWaits(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		lw:     lockwaits.NewLockWaits(cfg, db),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (lww *Wrapper) ResetStatistics() {
+	lww.lw.ResetStatistics()
+	lww.sorter.Sort(lww.lw.Results)
+}
+
+// Collect data from the db, then sort the results.
+func (lww *Wrapper) Collect(ctx context.Context) error {
+	if err := lww.lw.Collect(ctx); err != nil {
+		return err
+	}
+	lww.sorter.Sort(lww.lw.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (lww *Wrapper) Refilter() {
+	lww.lw.Refilter()
+	lww.sorter.Sort(lww.lw.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (lww Wrapper) Snapshot() any {
+	return lww.lw.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (lww *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last lockwaits.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	lww.lw.AddRows(last, collected)
+	lww.sorter.Sort(lww.lw.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (lww *Wrapper) SortNext() {
+	lww.sorter.SortNext()
+	lww.sorter.Sort(lww.lw.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (lww *Wrapper) SortReverse() {
+	lww.sorter.SortReverse()
+	lww.sorter.Sort(lww.lw.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (lww Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(lww.lw.Results))
+
+	for i := range lww.lw.Results {
+		rows = append(rows, lww.content(lww.lw.Results[i]))
+	}
+
+	return rows
+}
+
+// RowName returns the name of the object locked in the given row
+func (lww Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(lww.lw.Results) {
+		return ""
+	}
+	return lww.lw.Results[row].Object
+}
+
+// TotalRowContent returns the number of lock waits
+func (lww Wrapper) TotalRowContent() string {
+	return fmt.Sprintf("%10d lock wait(s)", len(lww.lw.Results))
+}
+
+// Rows returns the rows so that they can be exported
+func (lww Wrapper) Rows() any {
+	return utils.DuplicateSlice(lww.lw.Results)
+}
+
+// Summary returns the number of lock waits. They are counted rather
+// than timed as they are still waiting.
+func (lww Wrapper) Summary() pstable.Summary {
+	return pstable.Summary{Rows: len(lww.lw.Results)}
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (lww Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is false for this object
+func (lww Wrapper) HaveRelativeStats() bool {
+	return lww.lw.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (lww Wrapper) FirstCollectTime() time.Time {
+	return lww.lw.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (lww Wrapper) LastCollectTime() time.Time {
+	return lww.lw.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (lww Wrapper) WantRelativeStats() bool {
+	return lww.lw.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (lww Wrapper) Description() string {
+	return fmt.Sprintf("Lock Waits (metadata_locks and InnoDB lock waits) %d rows", len(lww.lw.Results))
+}
+
+// Headings returns the headings for a table
+func (lww Wrapper) Headings() string {
+	heading := lww.sorter.Heading
+
+	return fmt.Sprintf("%10s %-8s|%8s %-12s %-20s|%8s %-12s %-20s|%s",
+		heading("Wait", 10), heading("Lock", 8),
+		heading("Waiting", 8), "User", "Mode",
+		heading("Blocking", 8), "User", "Mode",
+		heading("Object", 0))
+}
+
+// content generate a printable result for a row
+func (lww Wrapper) content(row lockwaits.Row) string {
+	return fmt.Sprintf("%10s %-8.8s|%8d %-12.12s %-20.20s|%8d %-12.12s %-20.20s|%s",
+		utils.FormatSeconds(row.WaitTime),
+		row.Lock,
+		row.WaitingID,
+		row.WaitingUser,
+		row.WaitingMode,
+		row.BlockingID,
+		row.BlockingUser,
+		row.BlockingMode,
+		row.Object)
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[lockwaits.Row] {
+	name := func(row lockwaits.Row) string { return row.Object }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Wait", func(row lockwaits.Row) uint64 { return row.WaitTime }, name),
+		pstable.ByName("Lock", func(row lockwaits.Row) string { return row.Lock }),
+		pstable.ByValue("Waiting", func(row lockwaits.Row) uint64 { return row.WaitingID }, name),
+		pstable.ByValue("Blocking", func(row lockwaits.Row) uint64 { return row.BlockingID }, name),
+		pstable.ByName("Object", name),
+	)
+}
@@ -2,25 +2,31 @@
 package memoryusage
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/breakdown"
 	"github.com/sjmudd/ps-top/model/memoryusage"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper wraps a FileIoLatency struct  representing the contents of the data collected from file_summary_by_instance, but adding formatting for presentation in the terminal
 type Wrapper struct {
-	mu *memoryusage.MemoryUsage
+	mu     *memoryusage.MemoryUsage
+	sorter *pstable.Sorter[memoryusage.Row]
+	class  int // index of the breakdown shown
 }
 
 // NewMemoryUsage creates a wrapper around MemoryUsage
-func NewMemoryUsage(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewMemoryUsage(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		mu: memoryusage.NewMemoryUsage(cfg, db),
+		mu:     memoryusage.NewMemoryUsage(cfg, db),
+		sorter: newSorter(),
 	}
 }
 
@@ -30,15 +36,70 @@ func (muw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (muw *Wrapper) Collect() {
-	muw.mu.Collect()
-	sort.Sort(byBytes(muw.mu.Results))
+func (muw *Wrapper) Collect(ctx context.Context) error {
+	if err := muw.mu.Collect(ctx); err != nil {
+		return err
+	}
+	muw.sorter.Sort(muw.mu.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (muw *Wrapper) Refilter() {
+	muw.mu.Refilter()
+	muw.sorter.Sort(muw.mu.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (muw Wrapper) Snapshot() any {
+	return muw.mu.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (muw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last []memoryusage.Row
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	muw.mu.AddRows(last, collected)
+	muw.sorter.Sort(muw.mu.Results)
+
+	return nil
+}
+
+// NextClass shows the memory usage broken down in the next way
+func (muw *Wrapper) NextClass() {
+	muw.class = (muw.class + 1) % len(breakdown.All)
+	muw.mu.SetBreakdown(breakdown.All[muw.class])
+}
+
+// SortNext sorts the rows by the next sortable column
+func (muw *Wrapper) SortNext() {
+	muw.sorter.SortNext()
+	muw.sorter.Sort(muw.mu.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (muw *Wrapper) SortReverse() {
+	muw.sorter.SortReverse()
+	muw.sorter.Sort(muw.mu.Results)
 }
 
 // Headings returns the headings for a table
 func (muw Wrapper) Headings() string {
-	return "CurBytes         %  High Bytes|MemOps          %|CurAlloc       %   HiAlloc|Memory Area"
-	//      1234567890  100.0%  1234567890|123456789  100.0%|12345678  100.0%  12345678|Some memory name
+	heading := muw.sorter.Heading
+
+	return fmt.Sprintf("%-10s  %6s  %10s|%-10s %6s|%-8s  %6s  %8s|%s",
+		heading("CurBytes", 10),
+		"%",
+		heading("High Bytes", 10),
+		heading("MemOps", 10),
+		"%",
+		heading("CurAlloc", 8),
+		"%",
+		heading("HiAlloc", 8),
+		breakdown.Heading(muw.mu.Breakdown())+heading("Memory Area", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -57,6 +118,19 @@ func (muw Wrapper) TotalRowContent() string {
 	return muw.content(muw.mu.Totals, muw.mu.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (muw Wrapper) Rows() any {
+	rows := make([]memoryusage.Row, 0, len(muw.mu.Results))
+
+	for i := range muw.mu.Results {
+		if muw.mu.Results[i].HasData() {
+			rows = append(rows, muw.mu.Results[i])
+		}
+	}
+
+	return rows
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (muw Wrapper) EmptyRowContent() string {
 	var empty memoryusage.Row
@@ -74,7 +148,7 @@ func (muw Wrapper) Description() string {
 		}
 	}
 
-	return fmt.Sprintf("Memory Usage (memory_summary_global_by_event_name) %d rows", count)
+	return fmt.Sprintf("Memory Usage (%s) %d rows", breakdown.Table("memory_summary", muw.mu.Breakdown()), count)
 }
 
 // HaveRelativeStats is true for this object
@@ -100,7 +174,7 @@ func (muw Wrapper) WantRelativeStats() bool {
 // content generate a printable result for a row, given the totals
 func (muw Wrapper) content(row, totals memoryusage.Row) string {
 	// assume the data is empty so hide it.
-	name := row.Name
+	name := row.FullName()
 	if row.TotalMemoryOps == 0 && name != "Totals" {
 		name = ""
 	}
@@ -117,13 +191,16 @@ func (muw Wrapper) content(row, totals memoryusage.Row) string {
 		name)
 }
 
-type byBytes []memoryusage.Row
-
-func (t byBytes) Len() int      { return len(t) }
-func (t byBytes) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
-func (t byBytes) Less(i, j int) bool {
-	return (t[i].CurrentBytesUsed > t[j].CurrentBytesUsed) ||
-		((t[i].CurrentBytesUsed == t[j].CurrentBytesUsed) &&
-			(t[i].Name < t[j].Name))
-
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[memoryusage.Row] {
+	name := memoryusage.Row.FullName
+
+	return pstable.NewSorter(
+		pstable.ByValue("CurBytes", func(row memoryusage.Row) int64 { return row.CurrentBytesUsed }, name),
+		pstable.ByValue("High Bytes", func(row memoryusage.Row) int64 { return row.HighBytesUsed }, name),
+		pstable.ByValue("MemOps", func(row memoryusage.Row) int64 { return row.TotalMemoryOps }, name),
+		pstable.ByValue("CurAlloc", func(row memoryusage.Row) int64 { return row.CurrentCountUsed }, name),
+		pstable.ByValue("HiAlloc", func(row memoryusage.Row) int64 { return row.HighCountUsed }, name),
+		pstable.ByName("Memory Area", name),
+	)
 }
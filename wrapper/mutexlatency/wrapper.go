@@ -2,53 +2,111 @@
 package mutexlatency
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"sort"
 	"time"
 
-	"github.com/sjmudd/ps-top/config"
 	"github.com/sjmudd/ps-top/model/mutexlatency"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
+	"github.com/sjmudd/ps-top/wrapper/waitevents"
 )
 
 // Wrapper wraps a MutexLatency struct
 type Wrapper struct {
-	ml *mutexlatency.MutexLatency
+	waits  *waitevents.Wrapper // collects the wait events, including the mutexes
+	ml     *mutexlatency.MutexLatency
+	sorter *pstable.Sorter[mutexlatency.Row]
+	hidden *pstable.Sorter[mutexlatency.Row] // sorter of the columns not shown
+	timers bool                              // show the timers rather than the count?
 }
 
-// NewMutexLatency creates a wrapper around mutexlatency.MutexLatency
-func NewMutexLatency(cfg *config.Config, db *sql.DB) *Wrapper {
+// NewMutexLatency creates a wrapper around the InnoDB mutexes of
+// mutexlatency.MutexLatency, sharing the wait events collected by the
+// waitevents wrapper
+func NewMutexLatency(waits *waitevents.Wrapper) *Wrapper {
 	return &Wrapper{
-		ml: mutexlatency.NewMutexLatency(cfg, db),
+		waits:  waits,
+		ml:     waits.We(),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(mutexlatency.Row.Timers, name, "Mutex Name"),
 	}
 }
 
 // ResetStatistics resets the statistics to last values
 func (mlw *Wrapper) ResetStatistics() {
-	mlw.ml.ResetStatistics()
+	mlw.waits.ResetStatistics()
+	mlw.sorter.Sort(mlw.ml.Mutexes)
 }
 
 // Collect data from the db, then merge it in.
-func (mlw *Wrapper) Collect() {
-	mlw.ml.Collect()
-	sort.Sort(byLatency(mlw.ml.Results))
+func (mlw *Wrapper) Collect(ctx context.Context) error {
+	if err := mlw.waits.Collect(ctx); err != nil {
+		return err
+	}
+	mlw.sorter.Sort(mlw.ml.Mutexes)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (mlw *Wrapper) Refilter() {
+	mlw.waits.Refilter()
+	mlw.sorter.Sort(mlw.ml.Mutexes)
+}
+
+// SortNext sorts the rows by the next sortable column
+func (mlw *Wrapper) SortNext() {
+	mlw.sorter.SortNext()
+	mlw.sorter.Sort(mlw.ml.Mutexes)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (mlw *Wrapper) SortReverse() {
+	mlw.sorter.SortReverse()
+	mlw.sorter.Sort(mlw.ml.Mutexes)
 }
 
 // RowContent returns the rows we need for displaying
 func (mlw Wrapper) RowContent() []string {
-	rows := make([]string, 0, len(mlw.ml.Results))
+	rows := make([]string, 0, len(mlw.ml.Mutexes))
 
-	for i := range mlw.ml.Results {
-		rows = append(rows, mlw.content(mlw.ml.Results[i], mlw.ml.Totals))
+	for i := range mlw.ml.Mutexes {
+		rows = append(rows, mlw.content(mlw.ml.Mutexes[i], mlw.ml.MutexTotals))
 	}
 
 	return rows
 }
 
+// RowName returns the name of the given row
+func (mlw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(mlw.ml.Mutexes) {
+		return ""
+	}
+	return mlw.ml.Mutexes[row].Name
+}
+
 // TotalRowContent returns all the totals
 func (mlw Wrapper) TotalRowContent() string {
-	return mlw.content(mlw.ml.Totals, mlw.ml.Totals)
+	return mlw.content(mlw.ml.MutexTotals, mlw.ml.MutexTotals)
+}
+
+// Rows returns the rows which contain data so that they can be exported
+func (mlw Wrapper) Rows() any {
+	rows := make(mutexlatency.Rows, 0, len(mlw.ml.Mutexes))
+
+	for i := range mlw.ml.Mutexes {
+		if mlw.ml.Mutexes[i].SumTimerWait > 0 {
+			rows = append(rows, mlw.ml.Mutexes[i])
+		}
+	}
+
+	return rows
+}
+
+// Values returns the latency of each mutex so that it can be compared
+func (mlw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(mlw.ml.Mutexes, name, func(row mutexlatency.Row) uint64 { return row.SumTimerWait })
 }
 
 // EmptyRowContent returns an empty string of data (for filling in)
@@ -81,17 +139,35 @@ func (mlw Wrapper) WantRelativeStats() bool {
 // Description returns a description of the table
 func (mlw Wrapper) Description() string {
 	var count int
-	for row := range mlw.ml.Results {
-		if mlw.ml.Results[row].SumTimerWait > 0 {
+	for row := range mlw.ml.Mutexes {
+		if mlw.ml.Mutexes[row].SumTimerWait > 0 {
 			count++
 		}
 	}
 	return fmt.Sprintf("Mutex Latency (events_waits_summary_global_by_event_name) %d rows", count)
 }
 
+// ToggleTimers switches between showing the number of waits and
+// showing them with their minimum, average and maximum latency
+func (mlw *Wrapper) ToggleTimers() {
+	mlw.timers = !mlw.timers
+	mlw.sorter, mlw.hidden = mlw.hidden, mlw.sorter
+	mlw.sorter.Sort(mlw.ml.Mutexes)
+}
+
 // Headings returns the headings for a table
 func (mlw Wrapper) Headings() string {
-	return fmt.Sprintf("%10s %8s %8s|%s", "Latency", "MtxCnt", "%", "Mutex Name")
+	heading := mlw.sorter.Heading
+
+	if mlw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Mutex Name", 0))
+	}
+
+	return fmt.Sprintf("%10s %8s %8s|%s", heading("Latency", 10), heading("MtxCnt", 8), "%", heading("Mutex Name", 0))
 }
 
 // content generate a printable result for a row, given the totals
@@ -101,6 +177,14 @@ func (mlw Wrapper) content(row, totals mutexlatency.Row) string {
 		name = ""
 	}
 
+	if mlw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), mlw.WantRelativeStats()),
+			name)
+	}
+
 	return fmt.Sprintf("%10s %8s %8s|%s",
 		utils.FormatTime(row.SumTimerWait),
 		utils.FormatAmount(row.CountStar),
@@ -108,12 +192,16 @@ func (mlw Wrapper) content(row, totals mutexlatency.Row) string {
 		name)
 }
 
-type byLatency mutexlatency.Rows
-
-func (rows byLatency) Len() int      { return len(rows) }
-func (rows byLatency) Swap(i, j int) { rows[i], rows[j] = rows[j], rows[i] }
+// name returns the name of a row for sorting
+func name(row mutexlatency.Row) string {
+	return row.Name
+}
 
-// sort by value (descending) but also by "name" (ascending) if the values are the same
-func (rows byLatency) Less(i, j int) bool {
-	return rows[i].SumTimerWait > rows[j].SumTimerWait
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[mutexlatency.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row mutexlatency.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("MtxCnt", func(row mutexlatency.Row) uint64 { return row.CountStar }, name),
+		pstable.ByName("Mutex Name", name),
+	)
 }
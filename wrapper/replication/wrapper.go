@@ -0,0 +1,183 @@
+// Package replication holds the routines which show the state of replication
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/replication"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a Replication struct
+type Wrapper struct {
+	r      *replication.Replication
+	sorter *pstable.Sorter[replication.Row]
+}
+
+// NewReplication creates a wrapper around replication.Replication
+func NewReplication(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		r:      replication.NewReplication(cfg, db),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (rw *Wrapper) ResetStatistics() {
+	rw.r.ResetStatistics()
+	rw.sorter.Sort(rw.r.Results)
+}
+
+// Collect data from the db, then sort the results.
+func (rw *Wrapper) Collect(ctx context.Context) error {
+	if err := rw.r.Collect(ctx); err != nil {
+		return err
+	}
+	rw.sorter.Sort(rw.r.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (rw *Wrapper) Refilter() {
+	rw.r.Refilter()
+	rw.sorter.Sort(rw.r.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (rw Wrapper) Snapshot() any {
+	return rw.r.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (rw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last replication.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	rw.r.AddRows(last, collected)
+	rw.sorter.Sort(rw.r.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (rw *Wrapper) SortNext() {
+	rw.sorter.SortNext()
+	rw.sorter.Sort(rw.r.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (rw *Wrapper) SortReverse() {
+	rw.sorter.SortReverse()
+	rw.sorter.Sort(rw.r.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (rw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(rw.r.Results))
+
+	for i := range rw.r.Results {
+		rows = append(rows, rw.content(rw.r.Results[i]))
+	}
+
+	return rows
+}
+
+// TotalRowContent returns the number of replication threads and how many are not running
+func (rw Wrapper) TotalRowContent() string {
+	var stopped int
+	for i := range rw.r.Results {
+		if !rw.r.Results[i].Running() {
+			stopped++
+		}
+	}
+
+	return fmt.Sprintf("%d thread(s), %d not running", len(rw.r.Results), stopped)
+}
+
+// Rows returns the rows so that they can be exported
+func (rw Wrapper) Rows() any {
+	return utils.DuplicateSlice(rw.r.Results)
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (rw Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is false for this object
+func (rw Wrapper) HaveRelativeStats() bool {
+	return rw.r.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (rw Wrapper) FirstCollectTime() time.Time {
+	return rw.r.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (rw Wrapper) LastCollectTime() time.Time {
+	return rw.r.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (rw Wrapper) WantRelativeStats() bool {
+	return rw.r.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (rw Wrapper) Description() string {
+	return fmt.Sprintf("Replication (replication_connection_status and replication_applier_status_by_*) %d rows", len(rw.r.Results))
+}
+
+// Headings returns the headings for a table
+func (rw Wrapper) Headings() string {
+	heading := rw.sorter.Heading
+
+	return fmt.Sprintf("%-16s %-9s %-10s|%10s %10s %10s|%s",
+		heading("Channel", 16), "Thread", heading("State", 10),
+		heading("Lag", 10), heading("Imm Lag", 10), heading("Last Txn", 10),
+		"Last Error")
+}
+
+// content generate a printable result for a row
+func (rw Wrapper) content(row replication.Row) string {
+	var lastError string
+	if row.ErrorNumber != 0 {
+		lastError = fmt.Sprintf("%d: %s", row.ErrorNumber, row.Error)
+	}
+
+	return fmt.Sprintf("%-16.16s %-9.9s %-10.10s|%10s %10s %10s|%s",
+		row.Channel,
+		row.Name(),
+		row.State,
+		utils.FormatTime(row.Lag),
+		utils.FormatTime(row.ImmediateLag),
+		utils.FormatTime(row.LastLatency),
+		lastError)
+}
+
+// newSorter returns a sorter for the columns which can be sorted. By
+// default each channel's threads are shown in the order they process
+// transactions.
+func newSorter() *pstable.Sorter[replication.Row] {
+	order := map[string]int{replication.IO: 0, replication.SQL: 1, replication.Worker: 2}
+	name := func(row replication.Row) string {
+		return fmt.Sprintf("%s\x00%d\x00%08d", row.Channel, order[row.Thread], row.Worker)
+	}
+
+	return pstable.NewSorter(
+		pstable.ByName("Channel", name),
+		pstable.ByValue("Lag", func(row replication.Row) uint64 { return row.Lag }, name),
+		pstable.ByValue("Imm Lag", func(row replication.Row) uint64 { return row.ImmediateLag }, name),
+		pstable.ByValue("Last Txn", func(row replication.Row) uint64 { return row.LastLatency }, name),
+		pstable.ByName("State", func(row replication.Row) string { return row.State }),
+	)
+}
@@ -2,25 +2,34 @@
 package stageslatency
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/breakdown"
 	"github.com/sjmudd/ps-top/model/stageslatency"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper wraps a Stages struct
 type Wrapper struct {
-	sl *stageslatency.StagesLatency
+	sl     *stageslatency.StagesLatency
+	sorter *pstable.Sorter[stageslatency.Row]
+	hidden *pstable.Sorter[stageslatency.Row] // sorter of the columns not shown
+	timers bool                               // show the timers rather than the count?
+	class  int                                // index of the breakdown shown
 }
 
 // NewStagesLatency creates a wrapper around stageslatency
-func NewStagesLatency(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewStagesLatency(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		sl: stageslatency.NewStagesLatency(cfg, db),
+		sl:     stageslatency.NewStagesLatency(cfg, db),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(stageslatency.Row.Timers, name, "Stage Name"),
 	}
 }
 
@@ -30,15 +39,81 @@ func (slw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (slw *Wrapper) Collect() {
-	slw.sl.Collect()
-	sort.Sort(byLatency(slw.sl.Results))
+func (slw *Wrapper) Collect(ctx context.Context) error {
+	if err := slw.sl.Collect(ctx); err != nil {
+		return err
+	}
+	slw.sorter.Sort(slw.sl.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (slw *Wrapper) Refilter() {
+	slw.sl.Refilter()
+	slw.sorter.Sort(slw.sl.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (slw Wrapper) Snapshot() any {
+	return slw.sl.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (slw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last stageslatency.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	slw.sl.AddRows(last, collected)
+	slw.sorter.Sort(slw.sl.Results)
+
+	return nil
+}
+
+// NextClass shows the stages broken down in the next way
+func (slw *Wrapper) NextClass() {
+	slw.class = (slw.class + 1) % len(breakdown.All)
+	slw.sl.SetBreakdown(breakdown.All[slw.class])
+}
+
+// SortNext sorts the rows by the next sortable column
+func (slw *Wrapper) SortNext() {
+	slw.sorter.SortNext()
+	slw.sorter.Sort(slw.sl.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (slw *Wrapper) SortReverse() {
+	slw.sorter.SortReverse()
+	slw.sorter.Sort(slw.sl.Results)
+}
+
+// ToggleTimers switches between showing the number of waits and
+// showing them with their minimum, average and maximum latency
+func (slw *Wrapper) ToggleTimers() {
+	slw.timers = !slw.timers
+	slw.sorter, slw.hidden = slw.hidden, slw.sorter
+	slw.sorter.Sort(slw.sl.Results)
 }
 
 // Headings returns the headings for a table
 func (slw Wrapper) Headings() string {
-	return fmt.Sprintf("%10s %6s %8s|%s", "Latency", "%", "Counter", "Stage Name")
+	heading := slw.sorter.Heading
+
+	if slw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			breakdown.Heading(slw.sl.Breakdown())+heading("Stage Name", 0))
+	}
 
+	return fmt.Sprintf("%10s %6s %8s|%s",
+		heading("Latency", 10),
+		"%",
+		heading("Counter", 8),
+		breakdown.Heading(slw.sl.Breakdown())+heading("Stage Name", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -52,11 +127,37 @@ func (slw Wrapper) RowContent() []string {
 	return rows
 }
 
+// RowName returns the name of the stage of the given row
+func (slw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(slw.sl.Results) {
+		return ""
+	}
+	return slw.sl.Results[row].Name
+}
+
 // TotalRowContent returns all the totals
 func (slw Wrapper) TotalRowContent() string {
 	return slw.content(slw.sl.Totals, slw.sl.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (slw Wrapper) Rows() any {
+	rows := make(stageslatency.Rows, 0, len(slw.sl.Results))
+
+	for i := range slw.sl.Results {
+		if slw.sl.Results[i].SumTimerWait > 0 {
+			rows = append(rows, slw.sl.Results[i])
+		}
+	}
+
+	return rows
+}
+
+// Values returns the latency of each stage so that it can be compared
+func (slw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(slw.sl.Results, name, func(row stageslatency.Row) uint64 { return row.SumTimerWait })
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (slw Wrapper) EmptyRowContent() string {
 	var empty stageslatency.Row
@@ -73,7 +174,7 @@ func (slw Wrapper) Description() string {
 		}
 	}
 
-	return fmt.Sprintf("SQL Stage Latency (events_stages_summary_global_by_event_name) %d rows", count)
+	return fmt.Sprintf("SQL Stage Latency (%s) %d rows", breakdown.Table("events_stages_summary", slw.sl.Breakdown()), count)
 }
 
 // HaveRelativeStats is true for this object
@@ -98,11 +199,19 @@ func (slw Wrapper) WantRelativeStats() bool {
 
 // generate a printable result
 func (slw Wrapper) content(row, totals stageslatency.Row) string {
-	name := row.Name
+	name := row.FullName()
 	if row.CountStar == 0 && name != "Totals" {
 		name = ""
 	}
 
+	if slw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), slw.WantRelativeStats()),
+			name)
+	}
+
 	return fmt.Sprintf("%10s %6s %8s|%s",
 		utils.FormatTime(row.SumTimerWait),
 		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
@@ -110,13 +219,16 @@ func (slw Wrapper) content(row, totals stageslatency.Row) string {
 		name)
 }
 
-type byLatency stageslatency.Rows
-
-func (rows byLatency) Len() int      { return len(rows) }
-func (rows byLatency) Swap(i, j int) { rows[i], rows[j] = rows[j], rows[i] }
+// name returns the name of a row for sorting
+func name(row stageslatency.Row) string {
+	return row.FullName()
+}
 
-// sort by value (descending) but also by "name" (ascending) if the values are the same
-func (rows byLatency) Less(i, j int) bool {
-	return (rows[i].SumTimerWait > rows[j].SumTimerWait) ||
-		((rows[i].SumTimerWait == rows[j].SumTimerWait) && (rows[i].Name < rows[j].Name))
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[stageslatency.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row stageslatency.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Counter", func(row stageslatency.Row) uint64 { return row.CountStar }, name),
+		pstable.ByName("Stage Name", name),
+	)
 }
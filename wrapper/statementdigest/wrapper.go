@@ -0,0 +1,218 @@
+// Package statementdigest holds the routines which manage the statement digest statistics.
+package statementdigest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sjmudd/anonymiser"
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/statementdigest"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a StatementDigest struct
+type Wrapper struct {
+	sd     *statementdigest.StatementDigest
+	sorter *pstable.Sorter[statementdigest.Row]
+}
+
+// NewStatementDigest creates a wrapper around statementdigest.StatementDigest
+func NewStatementDigest(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		sd:     statementdigest.NewStatementDigest(cfg, db),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (sdw *Wrapper) ResetStatistics() {
+	sdw.sd.ResetStatistics()
+}
+
+// Collect data from the db, then merge it in.
+func (sdw *Wrapper) Collect(ctx context.Context) error {
+	if err := sdw.sd.Collect(ctx); err != nil {
+		return err
+	}
+	sdw.sorter.Sort(sdw.sd.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (sdw *Wrapper) Refilter() {
+	sdw.sd.Refilter()
+	sdw.sorter.Sort(sdw.sd.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (sdw Wrapper) Snapshot() any {
+	return sdw.sd.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (sdw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last statementdigest.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	sdw.sd.AddRows(last, collected)
+	sdw.sorter.Sort(sdw.sd.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (sdw *Wrapper) SortNext() {
+	sdw.sorter.SortNext()
+	sdw.sorter.Sort(sdw.sd.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (sdw *Wrapper) SortReverse() {
+	sdw.sorter.SortReverse()
+	sdw.sorter.Sort(sdw.sd.Results)
+}
+
+// Headings returns the headings for a table
+func (sdw Wrapper) Headings() string {
+	heading := sdw.sorter.Heading
+
+	return fmt.Sprintf("%10s %6s %8s %8s %8s %6s %6s|%s",
+		heading("Latency", 10),
+		"%",
+		heading("Count", 8),
+		heading("RowsExam", 8),
+		heading("RowsSent", 8),
+		heading("NoIdx", 6),
+		heading("TmpDsk", 6),
+		heading("Statement", 0))
+}
+
+// RowContent returns the rows we need for displaying
+func (sdw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(sdw.sd.Results))
+
+	for i := range sdw.sd.Results {
+		rows = append(rows, sdw.content(sdw.sd.Results[i], sdw.sd.Totals))
+	}
+
+	return rows
+}
+
+// Related returns the headings and the statements which reference the given table
+func (sdw Wrapper) Related(name string) (string, []string) {
+	var rows []string
+
+	schema, table, _ := strings.Cut(name, ".")
+	for i := range sdw.sd.Results {
+		if sdw.sd.Results[i].CountStar > 0 && sdw.sd.Results[i].References(schema, table) {
+			rows = append(rows, sdw.content(sdw.sd.Results[i], sdw.sd.Totals))
+		}
+	}
+
+	return sdw.Headings(), rows
+}
+
+// TotalRowContent returns all the totals
+func (sdw Wrapper) TotalRowContent() string {
+	return sdw.content(sdw.sd.Totals, sdw.sd.Totals)
+}
+
+// Rows returns the rows which contain data so that they can be exported
+func (sdw Wrapper) Rows() any {
+	rows := make(statementdigest.Rows, 0, len(sdw.sd.Results))
+
+	for i := range sdw.sd.Results {
+		if sdw.sd.Results[i].CountStar > 0 {
+			rows = append(rows, sdw.sd.Results[i])
+		}
+	}
+
+	return rows
+}
+
+// Values returns the latency of each statement so that it can be compared
+func (sdw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(sdw.sd.Results, func(row statementdigest.Row) string { return row.Name }, func(row statementdigest.Row) uint64 { return row.SumTimerWait })
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (sdw Wrapper) EmptyRowContent() string {
+	var empty statementdigest.Row
+
+	return sdw.content(empty, empty)
+}
+
+// HaveRelativeStats is true for this object
+func (sdw Wrapper) HaveRelativeStats() bool {
+	return sdw.sd.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (sdw Wrapper) FirstCollectTime() time.Time {
+	return sdw.sd.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (sdw Wrapper) LastCollectTime() time.Time {
+	return sdw.sd.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (sdw Wrapper) WantRelativeStats() bool {
+	return sdw.sd.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (sdw Wrapper) Description() string {
+	var count int
+	for row := range sdw.sd.Results {
+		if sdw.sd.Results[row].CountStar > 0 {
+			count++
+		}
+	}
+	return fmt.Sprintf("Statement Digests (events_statements_summary_by_digest) %d rows", count)
+}
+
+// content generate a printable result for a row, given the totals
+func (sdw Wrapper) content(row, totals statementdigest.Row) string {
+	name := row.Name
+	if row.Schema != "" {
+		name = anonymiser.Anonymise("schema", row.Schema) + ": " + name
+	}
+	if row.CountStar == 0 && name != "Totals" {
+		name = ""
+	}
+
+	return fmt.Sprintf("%10s %6s %8s %8s %8s %6s %6s|%s",
+		utils.FormatTime(row.SumTimerWait),
+		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+		utils.FormatAmount(row.CountStar),
+		utils.FormatAmount(row.SumRowsExamined),
+		utils.FormatAmount(row.SumRowsSent),
+		utils.FormatAmount(row.SumNoIndexUsed),
+		utils.FormatAmount(row.SumCreatedTmpDiskTables),
+		name)
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[statementdigest.Row] {
+	name := func(row statementdigest.Row) string { return row.Name }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row statementdigest.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Count", func(row statementdigest.Row) uint64 { return row.CountStar }, name),
+		pstable.ByValue("RowsExam", func(row statementdigest.Row) uint64 { return row.SumRowsExamined }, name),
+		pstable.ByValue("RowsSent", func(row statementdigest.Row) uint64 { return row.SumRowsSent }, name),
+		pstable.ByValue("NoIdx", func(row statementdigest.Row) uint64 { return row.SumNoIndexUsed }, name),
+		pstable.ByValue("TmpDsk", func(row statementdigest.Row) uint64 { return row.SumCreatedTmpDiskTables }, name),
+		pstable.ByName("Statement", name),
+	)
+}
@@ -2,25 +2,32 @@
 package tableiolatency
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/model/tableio"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper represents the contents of the data collected related to tableio statistics
 type Wrapper struct {
-	tiol *tableio.TableIo
+	tiol   *tableio.TableIo
+	sorter *pstable.Sorter[tableio.Row]
+	hidden *pstable.Sorter[tableio.Row] // sorter of the columns not shown
+	timers bool                         // show the timers rather than the split of the latency?
 }
 
 // NewTableIoLatency creates a wrapper around tableio statistics
-func NewTableIoLatency(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewTableIoLatency(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		tiol: tableio.NewTableIo(cfg, db),
+		tiol:   tableio.NewTableIo(cfg, db),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(tableio.Row.Timers, name, "Table Name"),
 	}
 }
 
@@ -35,23 +42,80 @@ func (tiolw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (tiolw *Wrapper) Collect() {
-	tiolw.tiol.Collect()
+func (tiolw *Wrapper) Collect(ctx context.Context) error {
+	if err := tiolw.tiol.Collect(ctx); err != nil {
+		return err
+	}
+
+	// sort the results by the chosen column
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (tiolw *Wrapper) Refilter() {
+	tiolw.tiol.Refilter()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (tiolw Wrapper) Snapshot() any {
+	return tiolw.tiol.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (tiolw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last tableio.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	tiolw.tiol.AddRows(last, collected)
+	tiolw.sorter.Sort(tiolw.tiol.Results)
 
-	// sort the results by latency (might be needed in other places)
-	sort.Sort(byLatency(tiolw.tiol.Results))
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (tiolw *Wrapper) SortNext() {
+	tiolw.sorter.SortNext()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (tiolw *Wrapper) SortReverse() {
+	tiolw.sorter.SortReverse()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+}
+
+// ToggleTimers switches between showing how the latency is split and
+// the number of waits with their minimum, average and maximum latency
+func (tiolw *Wrapper) ToggleTimers() {
+	tiolw.timers = !tiolw.timers
+	tiolw.sorter, tiolw.hidden = tiolw.hidden, tiolw.sorter
+	tiolw.sorter.Sort(tiolw.tiol.Results)
 }
 
 // Headings returns the latency headings as a string
 func (tiolw Wrapper) Headings() string {
+	heading := tiolw.sorter.Heading
+
+	if tiolw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Table Name", 0))
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s %6s %6s|%s",
-		"Latency",
+		heading("Latency", 10),
 		"%",
-		"Fetch",
-		"Insert",
-		"Update",
-		"Delete",
-		"Table Name")
+		heading("Fetch", 6),
+		heading("Insert", 6),
+		heading("Update", 6),
+		heading("Delete", 6),
+		heading("Table Name", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -65,11 +129,42 @@ func (tiolw Wrapper) RowContent() []string {
 	return rows
 }
 
+// RowName returns the name of the given row
+func (tiolw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(tiolw.tiol.Results) {
+		return ""
+	}
+	return tiolw.tiol.Results[row].Name
+}
+
 // TotalRowContent returns all the totals
 func (tiolw Wrapper) TotalRowContent() string {
 	return tiolw.content(tiolw.tiol.Totals, tiolw.tiol.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (tiolw Wrapper) Rows() any {
+	rows := make(tableio.Rows, 0, len(tiolw.tiol.Results))
+
+	for i := range tiolw.tiol.Results {
+		if tiolw.tiol.Results[i].HasData() {
+			rows = append(rows, tiolw.tiol.Results[i])
+		}
+	}
+
+	return rows
+}
+
+// Summary returns the total latency and the table with the highest latency
+func (tiolw Wrapper) Summary() pstable.Summary {
+	return pstable.Summarise(tiolw.tiol.Results, name, func(row tableio.Row) uint64 { return row.SumTimerWait })
+}
+
+// Values returns the latency of each table so that it can be compared
+func (tiolw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(tiolw.tiol.Results, name, func(row tableio.Row) uint64 { return row.SumTimerWait })
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (tiolw Wrapper) EmptyRowContent() string {
 	var empty tableio.Row
@@ -117,6 +212,14 @@ func (tiolw Wrapper) content(row, totals tableio.Row) string {
 		name = ""
 	}
 
+	if tiolw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), tiolw.WantRelativeStats()),
+			name)
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s %6s %6s|%s",
 		utils.FormatTime(row.SumTimerWait),
 		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
@@ -127,16 +230,19 @@ func (tiolw Wrapper) content(row, totals tableio.Row) string {
 		name)
 }
 
-// for sorting
-type byLatency tableio.Rows
-
-// sort the tableio.Rows by latency
-func (rows byLatency) Len() int      { return len(rows) }
-func (rows byLatency) Swap(i, j int) { rows[i], rows[j] = rows[j], rows[i] }
+// name returns the name of a row for sorting
+func name(row tableio.Row) string {
+	return row.Name
+}
 
-// sort by value (descending) but also by "name" (ascending) if the values are the same
-func (rows byLatency) Less(i, j int) bool {
-	return (rows[i].SumTimerWait > rows[j].SumTimerWait) ||
-		((rows[i].SumTimerWait == rows[j].SumTimerWait) &&
-			(rows[i].Name < rows[j].Name))
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[tableio.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row tableio.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Fetch", func(row tableio.Row) float64 { return utils.Divide(row.SumTimerFetch, row.SumTimerWait) }, name),
+		pstable.ByValue("Insert", func(row tableio.Row) float64 { return utils.Divide(row.SumTimerInsert, row.SumTimerWait) }, name),
+		pstable.ByValue("Update", func(row tableio.Row) float64 { return utils.Divide(row.SumTimerUpdate, row.SumTimerWait) }, name),
+		pstable.ByValue("Delete", func(row tableio.Row) float64 { return utils.Divide(row.SumTimerDelete, row.SumTimerWait) }, name),
+		pstable.ByName("Table Name", name),
+	)
 }
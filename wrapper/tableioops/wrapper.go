@@ -2,24 +2,27 @@
 package tableioops
 
 import (
+	"context"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/model/tableio"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 	"github.com/sjmudd/ps-top/wrapper/tableiolatency"
 )
 
 // Wrapper represents a wrapper around tableiolatency
 type Wrapper struct {
-	tiol *tableio.TableIo
+	tiol   *tableio.TableIo
+	sorter *pstable.Sorter[tableio.Row]
 }
 
 // NewTableIoOps creates a wrapper around TableIo, sharing the same connection with the tableiolatency wrapper
 func NewTableIoOps(latency *tableiolatency.Wrapper) *Wrapper {
 	return &Wrapper{
-		tiol: latency.Tiol(),
+		tiol:   latency.Tiol(),
+		sorter: newSorter(),
 	}
 }
 
@@ -29,23 +32,47 @@ func (tiolw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (tiolw *Wrapper) Collect() {
-	tiolw.tiol.Collect()
+func (tiolw *Wrapper) Collect(ctx context.Context) error {
+	if err := tiolw.tiol.Collect(ctx); err != nil {
+		return err
+	}
+
+	// sort the results by the chosen column
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (tiolw *Wrapper) Refilter() {
+	tiolw.tiol.Refilter()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+}
 
-	// sort the results by ops
-	sort.Sort(byOperations(tiolw.tiol.Results))
+// SortNext sorts the rows by the next sortable column
+func (tiolw *Wrapper) SortNext() {
+	tiolw.sorter.SortNext()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (tiolw *Wrapper) SortReverse() {
+	tiolw.sorter.SortReverse()
+	tiolw.sorter.Sort(tiolw.tiol.Results)
 }
 
 // Headings returns the headings by operations as a string
 func (tiolw Wrapper) Headings() string {
+	heading := tiolw.sorter.Heading
+
 	return fmt.Sprintf("%10s %6s|%6s %6s %6s %6s|%s",
-		"Ops",
+		heading("Ops", 10),
 		"%",
-		"Fetch",
-		"Insert",
-		"Update",
-		"Delete",
-		"Table Name")
+		heading("Fetch", 6),
+		heading("Insert", 6),
+		heading("Update", 6),
+		heading("Delete", 6),
+		heading("Table Name", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -59,11 +86,32 @@ func (tiolw Wrapper) RowContent() []string {
 	return rows
 }
 
+// RowName returns the name of the given row
+func (tiolw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(tiolw.tiol.Results) {
+		return ""
+	}
+	return tiolw.tiol.Results[row].Name
+}
+
 // TotalRowContent returns all the totals
 func (tiolw Wrapper) TotalRowContent() string {
 	return tiolw.content(tiolw.tiol.Totals, tiolw.tiol.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (tiolw Wrapper) Rows() any {
+	rows := make(tableio.Rows, 0, len(tiolw.tiol.Results))
+
+	for i := range tiolw.tiol.Results {
+		if tiolw.tiol.Results[i].HasData() {
+			rows = append(rows, tiolw.tiol.Results[i])
+		}
+	}
+
+	return rows
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (tiolw Wrapper) EmptyRowContent() string {
 	var empty tableio.Row
@@ -121,13 +169,16 @@ func (tiolw Wrapper) content(row, totals tableio.Row) string {
 		name)
 }
 
-// byOperations is used for sorting by the number of operations
-type byOperations tableio.Rows
-
-func (rows byOperations) Len() int      { return len(rows) }
-func (rows byOperations) Swap(i, j int) { rows[i], rows[j] = rows[j], rows[i] }
-func (rows byOperations) Less(i, j int) bool {
-	return (rows[i].CountStar > rows[j].CountStar) ||
-		((rows[i].SumTimerWait == rows[j].SumTimerWait) &&
-			(rows[i].Name < rows[j].Name))
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[tableio.Row] {
+	name := func(row tableio.Row) string { return row.Name }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Ops", func(row tableio.Row) uint64 { return row.CountStar }, name),
+		pstable.ByValue("Fetch", func(row tableio.Row) float64 { return utils.Divide(row.CountFetch, row.CountStar) }, name),
+		pstable.ByValue("Insert", func(row tableio.Row) float64 { return utils.Divide(row.CountInsert, row.CountStar) }, name),
+		pstable.ByValue("Update", func(row tableio.Row) float64 { return utils.Divide(row.CountUpdate, row.CountStar) }, name),
+		pstable.ByValue("Delete", func(row tableio.Row) float64 { return utils.Divide(row.CountDelete, row.CountStar) }, name),
+		pstable.ByName("Table Name", name),
+	)
 }
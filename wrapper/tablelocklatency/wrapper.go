@@ -2,25 +2,32 @@
 package tablelocklatency
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/model/tablelocks"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper wraps a TableLockLatency struct
 type Wrapper struct {
-	tl *tablelocks.TableLocks
+	tl     *tablelocks.TableLocks
+	sorter *pstable.Sorter[tablelocks.Row]
+	hidden *pstable.Sorter[tablelocks.Row] // sorter of the columns not shown
+	timers bool                            // show the timers rather than the split of the latency?
 }
 
 // NewTableLockLatency creates a wrapper around TableLockLatency
-func NewTableLockLatency(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewTableLockLatency(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		tl: tablelocks.NewTableLocks(cfg, db),
+		tl:     tablelocks.NewTableLocks(cfg, db),
+		sorter: newSorter(),
+		hidden: pstable.NewTimerSorter(tablelocks.Row.Timers, name, "Table Name"),
 	}
 }
 
@@ -30,19 +37,76 @@ func (tlw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then merge it in.
-func (tlw *Wrapper) Collect() {
-	tlw.tl.Collect()
-	sort.Sort(byLatency(tlw.tl.Results))
+func (tlw *Wrapper) Collect(ctx context.Context) error {
+	if err := tlw.tl.Collect(ctx); err != nil {
+		return err
+	}
+	tlw.sorter.Sort(tlw.tl.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (tlw *Wrapper) Refilter() {
+	tlw.tl.Refilter()
+	tlw.sorter.Sort(tlw.tl.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (tlw Wrapper) Snapshot() any {
+	return tlw.tl.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (tlw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last tablelocks.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	tlw.tl.AddRows(last, collected)
+	tlw.sorter.Sort(tlw.tl.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (tlw *Wrapper) SortNext() {
+	tlw.sorter.SortNext()
+	tlw.sorter.Sort(tlw.tl.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (tlw *Wrapper) SortReverse() {
+	tlw.sorter.SortReverse()
+	tlw.sorter.Sort(tlw.tl.Results)
+}
+
+// ToggleTimers switches between showing how the latency is split and
+// the number of waits with their minimum, average and maximum latency
+func (tlw *Wrapper) ToggleTimers() {
+	tlw.timers = !tlw.timers
+	tlw.sorter, tlw.hidden = tlw.hidden, tlw.sorter
+	tlw.sorter.Sort(tlw.tl.Results)
 }
 
 // Headings returns the headings for a table
 func (tlw Wrapper) Headings() string {
+	heading := tlw.sorter.Heading
+
+	if tlw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Table Name", 0))
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s|%6s %6s %6s %6s %6s|%6s %6s %6s %6s %6s|%-30s",
-		"Latency", "%",
-		"Read", "Write",
+		heading("Latency", 10), "%",
+		heading("Read", 6), heading("Write", 6),
 		"S.Lock", "High", "NoIns", "Normal", "Extrnl",
 		"AlloWr", "CncIns", "Low", "Normal", "Extrnl",
-		"Table Name")
+		heading("Table Name", 0))
 }
 
 // RowContent returns the rows we need for displaying
@@ -56,11 +120,55 @@ func (tlw Wrapper) RowContent() []string {
 	return rows
 }
 
+// RowName returns the name of the given row
+func (tlw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(tlw.tl.Results) {
+		return ""
+	}
+	return tlw.tl.Results[row].Name
+}
+
+// Related returns the headings and the table locks of the given table
+func (tlw Wrapper) Related(name string) (string, []string) {
+	var rows []string
+
+	for i := range tlw.tl.Results {
+		if tlw.tl.Results[i].Name == name {
+			rows = append(rows, tlw.content(tlw.tl.Results[i], tlw.tl.Totals))
+		}
+	}
+
+	return tlw.Headings(), rows
+}
+
 // TotalRowContent returns all the totals
 func (tlw Wrapper) TotalRowContent() string {
 	return tlw.content(tlw.tl.Totals, tlw.tl.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (tlw Wrapper) Rows() any {
+	rows := make(tablelocks.Rows, 0, len(tlw.tl.Results))
+
+	for i := range tlw.tl.Results {
+		if tlw.tl.Results[i].HasData() {
+			rows = append(rows, tlw.tl.Results[i])
+		}
+	}
+
+	return rows
+}
+
+// Summary returns the total latency and the table with the highest latency
+func (tlw Wrapper) Summary() pstable.Summary {
+	return pstable.Summarise(tlw.tl.Results, name, func(row tablelocks.Row) uint64 { return row.SumTimerWait })
+}
+
+// Values returns the latency of each table so that it can be compared
+func (tlw Wrapper) Values() []pstable.Value {
+	return pstable.Latencies(tlw.tl.Results, name, func(row tablelocks.Row) uint64 { return row.SumTimerWait })
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (tlw Wrapper) EmptyRowContent() string {
 	var empty tablelocks.Row
@@ -103,6 +211,14 @@ func (tlw Wrapper) content(row, totals tablelocks.Row) string {
 		name = ""
 	}
 
+	if tlw.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), tlw.WantRelativeStats()),
+			name)
+	}
+
 	return fmt.Sprintf("%10s %6s|%6s %6s|%6s %6s %6s %6s %6s|%6s %6s %6s %6s %6s|%s",
 		utils.FormatTime(row.SumTimerWait),
 		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
@@ -124,13 +240,17 @@ func (tlw Wrapper) content(row, totals tablelocks.Row) string {
 		name)
 }
 
-type byLatency tablelocks.Rows
-
-func (t byLatency) Len() int      { return len(t) }
-func (t byLatency) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
-func (t byLatency) Less(i, j int) bool {
-	return (t[i].SumTimerWait > t[j].SumTimerWait) ||
-		((t[i].SumTimerWait == t[j].SumTimerWait) &&
-			(t[i].Name < t[j].Name))
+// name returns the name of a row for sorting
+func name(row tablelocks.Row) string {
+	return row.Name
+}
 
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[tablelocks.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row tablelocks.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Read", func(row tablelocks.Row) float64 { return utils.Divide(row.SumTimerRead, row.SumTimerWait) }, name),
+		pstable.ByValue("Write", func(row tablelocks.Row) float64 { return utils.Divide(row.SumTimerWrite, row.SumTimerWait) }, name),
+		pstable.ByName("Table Name", name),
+	)
 }
@@ -0,0 +1,184 @@
+// Package threads holds the routines which manage the connections to MySQL
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/threads"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// Wrapper wraps a Threads struct
+type Wrapper struct {
+	t      *threads.Threads
+	sorter *pstable.Sorter[threads.Row]
+}
+
+// NewThreads creates a wrapper around threads.Threads
+func NewThreads(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		t:      threads.NewThreads(cfg, db),
+		sorter: newSorter(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (tw *Wrapper) ResetStatistics() {
+	tw.t.ResetStatistics()
+	tw.sorter.Sort(tw.t.Results)
+}
+
+// Collect data from the db, then sort the results.
+func (tw *Wrapper) Collect(ctx context.Context) error {
+	if err := tw.t.Collect(ctx); err != nil {
+		return err
+	}
+	tw.sorter.Sort(tw.t.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (tw *Wrapper) Refilter() {
+	tw.t.Refilter()
+	tw.sorter.Sort(tw.t.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (tw Wrapper) Snapshot() any {
+	return tw.t.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (tw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last threads.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	tw.t.AddRows(last, collected)
+	tw.sorter.Sort(tw.t.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (tw *Wrapper) SortNext() {
+	tw.sorter.SortNext()
+	tw.sorter.Sort(tw.t.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (tw *Wrapper) SortReverse() {
+	tw.sorter.SortReverse()
+	tw.sorter.Sort(tw.t.Results)
+}
+
+// RowContent returns the rows we need for displaying
+func (tw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(tw.t.Results))
+
+	for i := range tw.t.Results {
+		rows = append(rows, tw.content(tw.t.Results[i]))
+	}
+
+	return rows
+}
+
+// Waiting returns the headings and the threads currently waiting on the given event
+func (tw Wrapper) Waiting(ctx context.Context, eventName string) (string, []string, error) {
+	waiting, err := tw.t.Waiting(ctx, eventName)
+	if err != nil {
+		return "", nil, err
+	}
+	tw.sorter.Sort(waiting)
+
+	rows := make([]string, 0, len(waiting))
+	for i := range waiting {
+		rows = append(rows, tw.content(waiting[i]))
+	}
+
+	return tw.Headings(), rows, nil
+}
+
+// TotalRowContent returns the number of connections
+func (tw Wrapper) TotalRowContent() string {
+	return fmt.Sprintf("%8d connection(s)", len(tw.t.Results))
+}
+
+// Rows returns the rows so that they can be exported
+func (tw Wrapper) Rows() any {
+	return utils.DuplicateSlice(tw.t.Results)
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (tw Wrapper) EmptyRowContent() string {
+	return ""
+}
+
+// HaveRelativeStats is false for this object
+func (tw Wrapper) HaveRelativeStats() bool {
+	return tw.t.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (tw Wrapper) FirstCollectTime() time.Time {
+	return tw.t.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (tw Wrapper) LastCollectTime() time.Time {
+	return tw.t.LastCollected
+}
+
+// WantRelativeStats indicates if we want relative statistics
+func (tw Wrapper) WantRelativeStats() bool {
+	return tw.t.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (tw Wrapper) Description() string {
+	return fmt.Sprintf("Connections (threads) %d rows", len(tw.t.Results))
+}
+
+// Headings returns the headings for a table
+func (tw Wrapper) Headings() string {
+	heading := tw.sorter.Heading
+
+	return fmt.Sprintf("%8s %-12s %-16s %-12s %-8s %10s %-16s|%s",
+		heading("Id", 8), heading("User", 12), heading("Host", 16), heading("Db", 12),
+		heading("Command", 8), heading("Time", 10), heading("State", 16), "Statement")
+}
+
+// content generate a printable result for a row
+func (tw Wrapper) content(row threads.Row) string {
+	return fmt.Sprintf("%8d %-12.12s %-16.16s %-12.12s %-8.8s %10s %-16.16s|%s",
+		row.ID,
+		row.User,
+		row.Host,
+		row.DB,
+		row.Command,
+		utils.FormatSeconds(row.Time),
+		row.State,
+		row.Info)
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[threads.Row] {
+	name := func(row threads.Row) string { return row.User }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Time", func(row threads.Row) uint64 { return row.Time }, name),
+		pstable.ByValue("Id", func(row threads.Row) uint64 { return row.ID }, name),
+		pstable.ByName("User", name),
+		pstable.ByName("Host", func(row threads.Row) string { return row.Host }),
+		pstable.ByName("Db", func(row threads.Row) string { return row.DB }),
+		pstable.ByName("Command", func(row threads.Row) string { return row.Command }),
+		pstable.ByName("State", func(row threads.Row) string { return row.State }),
+	)
+}
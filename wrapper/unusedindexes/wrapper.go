@@ -0,0 +1,109 @@
+// Package unusedindexes holds the routines which list the indexes which have not been used
+package unusedindexes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sjmudd/ps-top/model/indexusage"
+	wrapper "github.com/sjmudd/ps-top/wrapper/indexusage"
+)
+
+// Wrapper lists the unused indexes of indexusage.IndexUsage
+type Wrapper struct {
+	iu *indexusage.IndexUsage
+}
+
+// NewUnusedIndexes creates a wrapper around IndexUsage, sharing the same connection with the indexusage wrapper
+func NewUnusedIndexes(usage *wrapper.Wrapper) *Wrapper {
+	return &Wrapper{
+		iu: usage.IndexUsage(),
+	}
+}
+
+// ResetStatistics resets the statistics to last values
+func (uiw *Wrapper) ResetStatistics() {
+	uiw.iu.ResetStatistics()
+}
+
+// Collect data from the db, then merge it in.
+func (uiw *Wrapper) Collect(ctx context.Context) error {
+	return uiw.iu.Collect(ctx)
+}
+
+// Refilter filters the rows last collected again
+func (uiw *Wrapper) Refilter() {
+	uiw.iu.Refilter()
+}
+
+// Headings returns the headings as a string
+func (uiw Wrapper) Headings() string {
+	return fmt.Sprintf("%-30s|%s", "Index", "Table Name")
+}
+
+// RowContent returns the rows we need for displaying
+func (uiw Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(uiw.iu.Unused))
+
+	for i := range uiw.iu.Unused {
+		rows = append(rows, uiw.content(uiw.iu.Unused[i]))
+	}
+
+	return rows
+}
+
+// RowName returns the name of the table of the given row
+func (uiw Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(uiw.iu.Unused) {
+		return ""
+	}
+	return uiw.iu.Unused[row].Name
+}
+
+// TotalRowContent returns the number of unused indexes
+func (uiw Wrapper) TotalRowContent() string {
+	return fmt.Sprintf("%-30s|%s", fmt.Sprintf("%d unused", len(uiw.iu.Unused)), "Totals")
+}
+
+// Rows returns the unused indexes so that they can be exported
+func (uiw Wrapper) Rows() any {
+	return uiw.iu.Unused
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (uiw Wrapper) EmptyRowContent() string {
+	var empty indexusage.Row
+
+	return uiw.content(empty)
+}
+
+// Description returns a description of the table
+func (uiw Wrapper) Description() string {
+	return fmt.Sprintf("Unused Indexes (table_io_waits_summary_by_index_usage) %d rows", len(uiw.iu.Unused))
+}
+
+// HaveRelativeStats is true for this object
+func (uiw Wrapper) HaveRelativeStats() bool {
+	return uiw.iu.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time of the first collection
+func (uiw Wrapper) FirstCollectTime() time.Time {
+	return uiw.iu.FirstCollected
+}
+
+// LastCollectTime returns the time of the last collection
+func (uiw Wrapper) LastCollectTime() time.Time {
+	return uiw.iu.LastCollected
+}
+
+// WantRelativeStats returns if we want to see relative stats
+func (uiw Wrapper) WantRelativeStats() bool {
+	return uiw.iu.WantRelativeStats()
+}
+
+// content returns the printable result
+func (uiw Wrapper) content(row indexusage.Row) string {
+	return fmt.Sprintf("%-30s|%s", row.Index, row.Name)
+}
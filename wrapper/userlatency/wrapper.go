@@ -2,25 +2,29 @@
 package userlatency
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"time"
 
 	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
 	"github.com/sjmudd/ps-top/model/userlatency"
+	"github.com/sjmudd/ps-top/pstable"
 	"github.com/sjmudd/ps-top/utils"
 )
 
 // Wrapper wraps a UserLatency struct
 type Wrapper struct {
-	ul *userlatency.UserLatency
+	ul     *userlatency.UserLatency
+	sorter *pstable.Sorter[userlatency.Row]
 }
 
 // NewUserLatency creates a wrapper around UserLatency
-func NewUserLatency(cfg *config.Config, db *sql.DB) *Wrapper {
+func NewUserLatency(cfg *config.Config, db datasource.DataSource) *Wrapper {
 	return &Wrapper{
-		ul: userlatency.NewUserLatency(cfg, db),
+		ul:     userlatency.NewUserLatency(cfg, db),
+		sorter: newSorter(),
 	}
 }
 
@@ -30,9 +34,48 @@ func (ulw *Wrapper) ResetStatistics() {
 }
 
 // Collect data from the db, then sort the results.
-func (ulw *Wrapper) Collect() {
-	ulw.ul.Collect()
-	sort.Sort(byTotalTime(ulw.ul.Results))
+func (ulw *Wrapper) Collect(ctx context.Context) error {
+	if err := ulw.ul.Collect(ctx); err != nil {
+		return err
+	}
+	ulw.sorter.Sort(ulw.ul.Results)
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (ulw *Wrapper) Refilter() {
+	ulw.ul.Refilter()
+	ulw.sorter.Sort(ulw.ul.Results)
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (ulw Wrapper) Snapshot() any {
+	return ulw.ul.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (ulw *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last userlatency.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	ulw.ul.AddRows(last, collected)
+	ulw.sorter.Sort(ulw.ul.Results)
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (ulw *Wrapper) SortNext() {
+	ulw.sorter.SortNext()
+	ulw.sorter.Sort(ulw.ul.Results)
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (ulw *Wrapper) SortReverse() {
+	ulw.sorter.SortReverse()
+	ulw.sorter.Sort(ulw.ul.Results)
 }
 
 // RowContent returns the rows we need for displaying
@@ -51,6 +94,19 @@ func (ulw Wrapper) TotalRowContent() string {
 	return ulw.content(ulw.ul.Totals, ulw.ul.Totals)
 }
 
+// Rows returns the rows which contain data so that they can be exported
+func (ulw Wrapper) Rows() any {
+	rows := make(userlatency.Rows, 0, len(ulw.ul.Results))
+
+	for i := range ulw.ul.Results {
+		if ulw.ul.Results[i].Username != "" {
+			rows = append(rows, ulw.ul.Results[i])
+		}
+	}
+
+	return rows
+}
+
 // EmptyRowContent returns an empty string of data (for filling in)
 func (ulw Wrapper) EmptyRowContent() string {
 	var empty userlatency.Row
@@ -86,83 +142,55 @@ func (ulw Wrapper) Description() string {
 			count++
 		}
 	}
-	return fmt.Sprintf("Activity by Username (processlist) %d rows", count)
+	return fmt.Sprintf("Activity by Username (events_statements_summary_by_user_by_event_name) %d rows", count)
 }
 
 // Headings returns the headings for a table
 func (ulw Wrapper) Headings() string {
-	return fmt.Sprintf("%-10s %6s|%-10s %6s|%4s %4s|%5s %3s|%3s %3s %3s %3s %3s|%s",
-		"Run Time", "%", "Sleeping", "%", "Conn", "Actv", "Hosts", "DBs", "Sel", "Ins", "Upd", "Del", "Oth", "User")
+	heading := ulw.sorter.Heading
+
+	return fmt.Sprintf("%10s %6s %8s|%6s %6s %6s %6s|%10s|%4s %4s|%5s %3s|%s",
+		heading("Latency", 10), "%", heading("Stmts", 8),
+		heading("Select", 6), heading("Insert", 6), heading("Update", 6), heading("Delete", 6),
+		heading("Waits", 10),
+		heading("Conn", 4), heading("Actv", 4), heading("Hosts", 5), heading("DBs", 3),
+		heading("User", 0))
 }
 
 // content generate a printable result for a row, given the totals
 func (ulw Wrapper) content(row, totals userlatency.Row) string {
-	return fmt.Sprintf("%10s %6s|%10s %6s|%4s %4s|%5s %3s|%3s %3s %3s %3s %3s|%s",
-		formatSeconds(row.Runtime),
-		utils.FormatPct(utils.Divide(row.Runtime, totals.Runtime)),
-		formatSeconds(row.Sleeptime),
-		utils.FormatPct(utils.Divide(row.Sleeptime, totals.Sleeptime)),
+	return fmt.Sprintf("%10s %6s %8s|%6s %6s %6s %6s|%10s|%4s %4s|%5s %3s|%s",
+		utils.FormatTime(row.SumTimerWait),
+		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+		utils.FormatAmount(row.CountStar),
+		utils.FormatPct(utils.Divide(row.SumTimerSelect, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerInsert, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerUpdate, row.SumTimerWait)),
+		utils.FormatPct(utils.Divide(row.SumTimerDelete, row.SumTimerWait)),
+		utils.FormatTime(row.SumTimerWaits),
 		utils.FormatCounter(int(row.Connections), 4),
 		utils.FormatCounter(int(row.Active), 4),
 		utils.FormatCounter(int(row.Hosts), 5),
 		utils.FormatCounter(int(row.Dbs), 3),
-		utils.FormatCounter(int(row.Selects), 3),
-		utils.FormatCounter(int(row.Inserts), 3),
-		utils.FormatCounter(int(row.Updates), 3),
-		utils.FormatCounter(int(row.Deletes), 3),
-		utils.FormatCounter(int(row.Other), 3),
 		row.Username)
 }
 
-// byTotalTime is for sorting rows by Runtime
-type byTotalTime []userlatency.Row
-
-func (t byTotalTime) Len() int      { return len(t) }
-func (t byTotalTime) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
-func (t byTotalTime) Less(i, j int) bool {
-	return (t[i].TotalTime() > t[j].TotalTime()) ||
-		((t[i].TotalTime() == t[j].TotalTime()) && (t[i].Connections > t[j].Connections)) ||
-		((t[i].TotalTime() == t[j].TotalTime()) && (t[i].Connections == t[j].Connections) && (t[i].Username < t[j].Username))
-}
-
-// formatSeconds formats the given seconds into xxh xxm xxs or xxd xxh xxm
-// for periods longer than 24h.  If seconds is 0 return an empty string.
-// Leading 0 values are omitted.
-// e.g.  0  -> ""
-//
-//	   10 -> "10s"
-//	   70 -> "1m 10s"
-//	 3601 -> "1h 0m 1s"
-//	86400 -> "1d 0h 0m"
-//
-// Note: we assume a 10 character width as formatting will get messed up so if there's not enough space don't add the lower values.
-func formatSeconds(d uint64) string {
-	if d == 0 {
-		return ""
-	}
-
-	days := d / 86400
-	hours := (d - days*86400) / 3600
-	minutes := (d - days*86400 - hours*3600) / 60
-	seconds := d - days*86400 - hours*3600 - minutes*60
-
-	if days > 0 {
-		result := fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
-		if len(result) > 10 {
-			result = fmt.Sprintf("%dd %dh", days, hours)
-		}
-		return result
-	}
-	if hours > 0 {
-		result := fmt.Sprintf("%dh %dm %ds", hours, minutes, seconds)
-		if len(result) > 10 {
-			result = fmt.Sprintf("%dh %dm", hours, minutes)
-		}
-		return result
-	}
-	if minutes > 0 {
-		return fmt.Sprintf("%dm %ds", minutes, seconds)
-	}
-
-	return fmt.Sprintf("%ds", seconds)
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[userlatency.Row] {
+	name := func(row userlatency.Row) string { return row.Username }
+
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row userlatency.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Stmts", func(row userlatency.Row) uint64 { return row.CountStar }, name),
+		pstable.ByValue("Select", func(row userlatency.Row) float64 { return utils.Divide(row.SumTimerSelect, row.SumTimerWait) }, name),
+		pstable.ByValue("Insert", func(row userlatency.Row) float64 { return utils.Divide(row.SumTimerInsert, row.SumTimerWait) }, name),
+		pstable.ByValue("Update", func(row userlatency.Row) float64 { return utils.Divide(row.SumTimerUpdate, row.SumTimerWait) }, name),
+		pstable.ByValue("Delete", func(row userlatency.Row) float64 { return utils.Divide(row.SumTimerDelete, row.SumTimerWait) }, name),
+		pstable.ByValue("Waits", func(row userlatency.Row) uint64 { return row.SumTimerWaits }, name),
+		pstable.ByValue("Conn", func(row userlatency.Row) uint64 { return row.Connections }, name),
+		pstable.ByValue("Actv", func(row userlatency.Row) uint64 { return row.Active }, name),
+		pstable.ByValue("Hosts", func(row userlatency.Row) uint64 { return row.Hosts }, name),
+		pstable.ByValue("DBs", func(row userlatency.Row) uint64 { return row.Dbs }, name),
+		pstable.ByName("User", name),
+	)
 }
@@ -0,0 +1,371 @@
+// Package waitevents holds the routines which explore the wait events
+package waitevents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sjmudd/ps-top/config"
+	"github.com/sjmudd/ps-top/datasource"
+	"github.com/sjmudd/ps-top/model/mutexlatency"
+	"github.com/sjmudd/ps-top/pstable"
+	"github.com/sjmudd/ps-top/utils"
+)
+
+// classes are the classes of wait events which can be shown, relative
+// to mutexlatency.WaitPrefix. The first shows all wait events.
+var classes = []string{
+	"",
+	"synch/mutex/",
+	"synch/rwlock/",
+	"synch/cond/",
+	"io/file/",
+	"io/table/",
+	"io/socket/",
+	"lock/table/",
+}
+
+// entry is a row shown: a wait event or, in a tree, a level of the
+// event names with the values of the events below it
+type entry struct {
+	row      mutexlatency.Row // Name is relative to the class
+	depth    int              // depth in the tree
+	children bool             // does the row have events below it?
+}
+
+// Wrapper wraps a MutexLatency struct collecting all wait events
+type Wrapper struct {
+	we       *mutexlatency.MutexLatency
+	sorter   *pstable.Sorter[mutexlatency.Row]
+	hidden   *pstable.Sorter[mutexlatency.Row] // sorter of the columns not shown
+	timers   bool                              // show the timers rather than the count?
+	class    int                               // index of the class shown
+	tree     bool                              // show the events as a tree?
+	expanded map[string]bool                   // the tree nodes expanded by name
+	rows     mutexlatency.Rows                 // the wait events of the class shown
+	totals   mutexlatency.Row                  // totals of rows
+	entries  []entry                           // the rows shown
+}
+
+// NewWaitEvents creates a wrapper around mutexlatency.MutexLatency for all wait events
+func NewWaitEvents(cfg *config.Config, db datasource.DataSource) *Wrapper {
+	return &Wrapper{
+		we:       mutexlatency.NewWaitLatency(cfg, db),
+		sorter:   newSorter(),
+		hidden:   pstable.NewTimerSorter(mutexlatency.Row.Timers, name, "Event Name"),
+		expanded: make(map[string]bool),
+	}
+}
+
+// We returns the wait events collected so that they can be shared
+func (wew *Wrapper) We() *mutexlatency.MutexLatency {
+	return wew.we
+}
+
+// ResetStatistics resets the statistics to last values
+func (wew *Wrapper) ResetStatistics() {
+	wew.we.ResetStatistics()
+	wew.update()
+}
+
+// Collect data from the db, then merge it in.
+func (wew *Wrapper) Collect(ctx context.Context) error {
+	if err := wew.we.Collect(ctx); err != nil {
+		return err
+	}
+	wew.update()
+
+	return nil
+}
+
+// Refilter filters the rows last collected again, then sorts the results.
+func (wew *Wrapper) Refilter() {
+	wew.we.Refilter()
+	wew.update()
+}
+
+// Snapshot returns the last collected rows so that they can be recorded
+func (wew Wrapper) Snapshot() any {
+	return wew.we.Last()
+}
+
+// Restore replaces the collected rows with previously recorded rows
+func (wew *Wrapper) Restore(rows json.RawMessage, collected time.Time) error {
+	var last mutexlatency.Rows
+	if err := json.Unmarshal(rows, &last); err != nil {
+		return err
+	}
+	wew.we.AddRows(last, collected)
+	wew.update()
+
+	return nil
+}
+
+// SortNext sorts the rows by the next sortable column
+func (wew *Wrapper) SortNext() {
+	wew.sorter.SortNext()
+	wew.update()
+}
+
+// SortReverse reverses the order the rows are sorted in
+func (wew *Wrapper) SortReverse() {
+	wew.sorter.SortReverse()
+	wew.update()
+}
+
+// ToggleTimers switches between showing the number of waits and
+// showing them with their minimum, average and maximum latency
+func (wew *Wrapper) ToggleTimers() {
+	wew.timers = !wew.timers
+	wew.sorter, wew.hidden = wew.hidden, wew.sorter
+	wew.update()
+}
+
+// NextClass shows the next class of wait events
+func (wew *Wrapper) NextClass() {
+	wew.class = (wew.class + 1) % len(classes)
+	wew.update()
+}
+
+// ToggleTree switches between showing the wait events as a tree and as a list
+func (wew *Wrapper) ToggleTree() {
+	wew.tree = !wew.tree
+	wew.update()
+}
+
+// Toggle expands or collapses the given row if it has events below it
+func (wew *Wrapper) Toggle(row int) bool {
+	if !wew.tree || row < 0 || row >= len(wew.entries) || !wew.entries[row].children {
+		return false
+	}
+	name := wew.entries[row].row.Name
+	wew.expanded[name] = !wew.expanded[name]
+	wew.update()
+
+	return true
+}
+
+// update sorts the wait events and builds the rows shown again. It is
+// called whenever the events collected or the way they are shown changes
+// so that the rows are not built each time they are displayed.
+func (wew *Wrapper) update() {
+	wew.sorter.Sort(wew.we.Results)
+	wew.rows = wew.classRows()
+	wew.totals = mutexlatency.Row{Name: "Totals"}
+	for _, row := range wew.rows {
+		add(&wew.totals, row)
+	}
+	wew.entries = wew.buildEntries()
+}
+
+// classRows returns the wait events of the current class with names relative to the class
+func (wew Wrapper) classRows() mutexlatency.Rows {
+	class := classes[wew.class]
+	rows := make(mutexlatency.Rows, 0, len(wew.we.Results))
+
+	for _, row := range wew.we.Results {
+		if row.SumTimerWait > 0 && strings.HasPrefix(row.Name, class) {
+			row.Name = strings.TrimPrefix(row.Name, class)
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// buildEntries returns the rows to show: the wait events or the expanded levels of the tree
+func (wew Wrapper) buildEntries() []entry {
+	rows := wew.rows
+
+	if !wew.tree {
+		entries := make([]entry, 0, len(rows))
+		for _, row := range rows {
+			entries = append(entries, entry{row: row})
+		}
+		return entries
+	}
+
+	// add the values of each event to every level of its name
+	nodes := make(map[string]*mutexlatency.Row)
+	children := make(map[string][]string)
+	for _, row := range rows {
+		parts := strings.Split(row.Name, "/")
+		for i := 1; i <= len(parts); i++ {
+			name := strings.Join(parts[:i], "/")
+			node, found := nodes[name]
+			if !found {
+				node = &mutexlatency.Row{Name: name}
+				nodes[name] = node
+				parent := strings.Join(parts[:i-1], "/")
+				children[parent] = append(children[parent], name)
+			}
+			add(node, row)
+		}
+	}
+
+	var entries []entry
+	var addLevel func(parent string, depth int)
+	addLevel = func(parent string, depth int) {
+		level := make(mutexlatency.Rows, 0, len(children[parent]))
+		for _, name := range children[parent] {
+			level = append(level, *nodes[name])
+		}
+		wew.sorter.Sort(level)
+
+		for _, row := range level {
+			hasChildren := len(children[row.Name]) > 0
+			entries = append(entries, entry{row: row, depth: depth, children: hasChildren})
+			if hasChildren && wew.expanded[row.Name] {
+				addLevel(row.Name, depth+1)
+			}
+		}
+	}
+	addLevel("", 0)
+
+	return entries
+}
+
+// add adds the values of a wait event to a total
+func add(total *mutexlatency.Row, row mutexlatency.Row) {
+	total.SumTimerWait += row.SumTimerWait
+	total.CountStar += row.CountStar
+	total.MinTimerWait = utils.MinTimer(total.MinTimerWait, row.MinTimerWait)
+	total.MaxTimerWait = max(total.MaxTimerWait, row.MaxTimerWait)
+}
+
+// RowContent returns the rows we need for displaying
+func (wew Wrapper) RowContent() []string {
+	rows := make([]string, 0, len(wew.entries))
+
+	for _, e := range wew.entries {
+		rows = append(rows, wew.content(e.row, wew.totals, wew.label(e)))
+	}
+
+	return rows
+}
+
+// label returns the name shown for an entry, indented and marked
+// as expanded (-) or collapsed (+) in a tree
+func (wew Wrapper) label(e entry) string {
+	if !wew.tree {
+		return e.row.Name
+	}
+
+	marker := "  "
+	if e.children {
+		marker = "+ "
+		if wew.expanded[e.row.Name] {
+			marker = "- "
+		}
+	}
+	name := e.row.Name[strings.LastIndex(e.row.Name, "/")+1:]
+
+	return strings.Repeat("  ", e.depth) + marker + name
+}
+
+// RowName returns the full event name of the given row
+func (wew Wrapper) RowName(row int) string {
+	if row < 0 || row >= len(wew.entries) {
+		return ""
+	}
+	return mutexlatency.WaitPrefix + classes[wew.class] + wew.entries[row].row.Name
+}
+
+// TotalRowContent returns all the totals
+func (wew Wrapper) TotalRowContent() string {
+	return wew.content(wew.totals, wew.totals, wew.totals.Name)
+}
+
+// Rows returns the wait events of the current class so that they can be exported
+func (wew Wrapper) Rows() any {
+	return wew.rows
+}
+
+// EmptyRowContent returns an empty string of data (for filling in)
+func (wew Wrapper) EmptyRowContent() string {
+	var empty mutexlatency.Row
+
+	return wew.content(empty, empty, "")
+}
+
+// HaveRelativeStats is true for this object
+func (wew Wrapper) HaveRelativeStats() bool {
+	return wew.we.HaveRelativeStats()
+}
+
+// FirstCollectTime returns the time the first value was collected
+func (wew Wrapper) FirstCollectTime() time.Time {
+	return wew.we.FirstCollected
+}
+
+// LastCollectTime returns the time the last value was collected
+func (wew Wrapper) LastCollectTime() time.Time {
+	return wew.we.LastCollected
+}
+
+// WantRelativeStats indiates if we want relative statistics
+func (wew Wrapper) WantRelativeStats() bool {
+	return wew.we.WantRelativeStats()
+}
+
+// Description returns a description of the table
+func (wew Wrapper) Description() string {
+	mode := "list"
+	if wew.tree {
+		mode = "tree"
+	}
+
+	return fmt.Sprintf("Wait Events %s* %s (events_waits_summary_global_by_event_name) %d rows",
+		mutexlatency.WaitPrefix+classes[wew.class],
+		mode,
+		len(wew.rows))
+}
+
+// Headings returns the headings for a table
+func (wew Wrapper) Headings() string {
+	heading := wew.sorter.Heading
+
+	if wew.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			heading("Latency", 10),
+			"%",
+			utils.TimerHeadings(heading),
+			heading("Event Name", 0))
+	}
+
+	return fmt.Sprintf("%10s %8s %8s|%s", heading("Latency", 10), heading("Count", 8), "%", heading("Event Name", 0))
+}
+
+// content generate a printable result for a row, given the totals and the name to show
+func (wew Wrapper) content(row, totals mutexlatency.Row, name string) string {
+	if wew.timers {
+		return fmt.Sprintf("%10s %6s|%s|%s",
+			utils.FormatTime(row.SumTimerWait),
+			utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+			utils.FormatTimers(row.Timers(), wew.WantRelativeStats()),
+			name)
+	}
+
+	return fmt.Sprintf("%10s %8s %8s|%s",
+		utils.FormatTime(row.SumTimerWait),
+		utils.FormatAmount(row.CountStar),
+		utils.FormatPct(utils.Divide(row.SumTimerWait, totals.SumTimerWait)),
+		name)
+}
+
+// name returns the name of a row for sorting
+func name(row mutexlatency.Row) string {
+	return row.Name
+}
+
+// newSorter returns a sorter for the columns which can be sorted
+func newSorter() *pstable.Sorter[mutexlatency.Row] {
+	return pstable.NewSorter(
+		pstable.ByValue("Latency", func(row mutexlatency.Row) uint64 { return row.SumTimerWait }, name),
+		pstable.ByValue("Count", func(row mutexlatency.Row) uint64 { return row.CountStar }, name),
+		pstable.ByName("Event Name", name),
+	)
+}